@@ -1,16 +1,7 @@
 package main
 
-import (
-	"glass/pkg/collectors"
-
-	"github.com/rs/zerolog/log"
-)
+import "glass/pkg/cli"
 
 func main() {
-	log.Info().Msg("Cloudways Looking Glass")
-	collectors := collectors.RegisterCollectors()
-	for _, collector := range collectors {
-		//log.Info().Msgf("Collector %d", index)
-		collector.Collector()
-	}
+	cli.Execute()
 }
@@ -0,0 +1,217 @@
+// Package aggregator keeps the metrics that many agents push to one glass
+// and serves them together, labelled by host.
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+	"glass/pkg/store"
+
+	"github.com/rs/zerolog/log"
+)
+
+// HostLabel is added to every metric an agent pushes, replacing any label
+// of that name the agent set itself.
+const HostLabel = "host"
+
+// Push is what an agent sends: the collections it made since its last
+// push.
+type Push struct {
+	Host        string           `json:"host"`
+	Collections []store.Snapshot `json:"collections"`
+}
+
+// Config controls how long hosts are remembered.
+type Config struct {
+	// StaleAfter is how long a host may go without pushing before its
+	// metrics are left out of /metrics.
+	StaleAfter time.Duration
+	// ForgetAfter is how long a silent host is still listed.
+	ForgetAfter time.Duration
+	// Retention and Resolution size the history kept across all hosts.
+	Retention  time.Duration
+	Resolution time.Duration
+}
+
+// HostStatus describes one agent.
+type HostStatus struct {
+	Host       string    `json:"host"`
+	Address    string    `json:"address"`
+	LastSeen   time.Time `json:"last_seen"`
+	Stale      bool      `json:"stale"`
+	Collectors int       `json:"collectors"`
+	Metrics    int       `json:"metrics"`
+}
+
+// Aggregator holds the latest collections of every host and a shared
+// history in which each series carries its host label.
+type Aggregator struct {
+	cfg     Config
+	history *store.History
+
+	mu    sync.RWMutex
+	hosts map[string]*host
+}
+
+type host struct {
+	address  string
+	lastSeen time.Time
+	latest   map[string]store.Snapshot
+}
+
+func New(cfg Config) *Aggregator {
+	return &Aggregator{
+		cfg:     cfg,
+		history: store.NewHistory(cfg.Retention, cfg.Resolution),
+		hosts:   make(map[string]*host),
+	}
+}
+
+// Ingest records a push received from address.
+func (a *Aggregator) Ingest(ctx context.Context, p Push, address string) error {
+	if p.Host == "" {
+		return errors.New("host is required")
+	}
+	for i, c := range p.Collections {
+		if c.Collector == "" {
+			return errors.New("collector is required")
+		}
+		p.Collections[i].Metrics = withHost(c.Metrics, p.Host)
+	}
+
+	a.mu.Lock()
+	h, ok := a.hosts[p.Host]
+	if !ok {
+		h = &host{latest: make(map[string]store.Snapshot)}
+		a.hosts[p.Host] = h
+		log.Info().Str("host", p.Host).Str("address", address).Msg("New host pushing metrics")
+	}
+	h.address = address
+	h.lastSeen = time.Now()
+	for _, c := range p.Collections {
+		if prev, ok := h.latest[c.Collector]; !ok || !c.Timestamp.Before(prev.Timestamp) {
+			h.latest[c.Collector] = c
+		}
+	}
+	a.mu.Unlock()
+
+	for _, c := range p.Collections {
+		a.history.Write(ctx, c.Collector, c.Metrics)
+	}
+	return nil
+}
+
+func withHost(metrics []metric.Metric, name string) []metric.Metric {
+	out := make([]metric.Metric, len(metrics))
+	for i, m := range metrics {
+		labels := make(map[string]string, len(m.Labels)+1)
+		for k, v := range m.Labels {
+			labels[k] = v
+		}
+		labels[HostLabel] = name
+		m.Labels = labels
+		out[i] = m
+	}
+	return out
+}
+
+func (a *Aggregator) stale(h *host, now time.Time) bool {
+	return now.Sub(h.lastSeen) > a.cfg.StaleAfter
+}
+
+// Hosts reports on every remembered host, ordered by name.
+func (a *Aggregator) Hosts() []HostStatus {
+	now := time.Now()
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := make([]HostStatus, 0, len(a.hosts))
+	for name, h := range a.hosts {
+		st := HostStatus{Host: name, Address: h.address, LastSeen: h.lastSeen, Stale: a.stale(h, now), Collectors: len(h.latest)}
+		for _, s := range h.latest {
+			st.Metrics += len(s.Metrics)
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Host < out[j].Host })
+	return out
+}
+
+// Snapshots returns a host's latest collections ordered by collector.
+func (a *Aggregator) Snapshots(name string) ([]store.Snapshot, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	h, ok := a.hosts[name]
+	if !ok {
+		return nil, false
+	}
+	out := make([]store.Snapshot, 0, len(h.latest))
+	for _, s := range h.latest {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Collector < out[j].Collector })
+	return out, true
+}
+
+// Metrics flattens the latest collections of every host that isn't stale,
+// and adds aggregator.host_up and aggregator.host_last_seen for every host,
+// stale or not.
+func (a *Aggregator) Metrics() []metric.Metric {
+	now := time.Now()
+	b := metric.NewBuilder(now)
+	var out []metric.Metric
+	a.mu.RLock()
+	for name, h := range a.hosts {
+		up := 0.0
+		if !a.stale(h, now) {
+			up = 1
+			for _, s := range h.latest {
+				out = append(out, s.Metrics...)
+			}
+		}
+		b.Gauge("aggregator.host_up", up, "", HostLabel, name)
+		b.Gauge("aggregator.host_last_seen", float64(h.lastSeen.Unix()), "seconds", HostLabel, name)
+	}
+	a.mu.RUnlock()
+	return append(out, b.Metrics()...)
+}
+
+// Query returns the history of every series matching sel, across hosts.
+func (a *Aggregator) Query(sel metric.Selector, from, to time.Time) []store.Series {
+	return a.history.Query(sel, from, to)
+}
+
+// Retention is how far back Query can look.
+func (a *Aggregator) Retention() time.Duration {
+	return a.history.Retention()
+}
+
+// Run forgets hosts that have been silent for ForgetAfter, until ctx is
+// cancelled.
+func (a *Aggregator) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.forget(now)
+		}
+	}
+}
+
+func (a *Aggregator) forget(now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for name, h := range a.hosts {
+		if now.Sub(h.lastSeen) > a.cfg.ForgetAfter {
+			delete(a.hosts, name)
+			log.Info().Str("host", name).Time("last_seen", h.lastSeen).Msg("Forgetting silent host")
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package aggregator
+
+import (
+	"context"
+
+	"glass/pkg/grpcapi/glassv1"
+	"glass/pkg/store"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	// Agents may gzip their pushes.
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type grpcService struct {
+	glassv1.UnimplementedAggregatorServer
+	a *Aggregator
+}
+
+// RegisterGRPC adds the Aggregator service to srv.
+func (a *Aggregator) RegisterGRPC(srv *grpc.Server) {
+	glassv1.RegisterAggregatorServer(srv, &grpcService{a: a})
+}
+
+func (g *grpcService) Push(ctx context.Context, req *glassv1.PushRequest) (*glassv1.PushResponse, error) {
+	p := Push{Host: req.GetHost(), Collections: make([]store.Snapshot, 0, len(req.GetCollections()))}
+	for _, c := range req.GetCollections() {
+		p.Collections = append(p.Collections, c.ToSnapshot())
+	}
+	var address string
+	if pr, ok := peer.FromContext(ctx); ok {
+		address = pr.Addr.String()
+	}
+	if err := g.a.Ingest(ctx, p, address); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &glassv1.PushResponse{}, nil
+}
@@ -0,0 +1,147 @@
+package aggregator
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"glass/pkg/metric"
+	"glass/pkg/prometheus"
+	"glass/pkg/store"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxPushSize bounds a decoded push body.
+const maxPushSize = 32 << 20
+
+// Handler serves the push endpoint and the combined API.
+func (a *Aggregator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", prometheus.Handler(func(context.Context) []metric.Metric {
+		return a.Metrics()
+	}, false))
+	mux.HandleFunc("GET /healthz", a.handleHealthz)
+	mux.HandleFunc("GET /readyz", a.handleHealthz)
+	mux.HandleFunc("POST /api/v1/push", a.handlePush)
+	mux.HandleFunc("GET /api/v1/hosts", a.handleHosts)
+	mux.HandleFunc("GET /api/v1/hosts/{host}/metrics", a.handleHostMetrics)
+	mux.HandleFunc("GET /api/v1/metrics", a.handleMetrics)
+	mux.HandleFunc("GET /api/v1/query", a.handleQuery)
+	return mux
+}
+
+func (a *Aggregator) handlePush(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	switch r.Header.Get("Content-Encoding") {
+	case "":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid gzip body: "+err.Error())
+			return
+		}
+		defer gz.Close()
+		body = gz
+	default:
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported content encoding "+r.Header.Get("Content-Encoding"))
+		return
+	}
+	var p Push
+	if err := json.NewDecoder(io.LimitReader(body, maxPushSize)).Decode(&p); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid push: "+err.Error())
+		return
+	}
+	if err := a.Ingest(r.Context(), p, r.RemoteAddr); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Aggregator) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "hosts": len(a.Hosts())})
+}
+
+func (a *Aggregator) handleHosts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"hosts": a.Hosts()})
+}
+
+func (a *Aggregator) handleHostMetrics(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("host")
+	snapshots, ok := a.Snapshots(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no metrics for host "+name)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"host": name, "collectors": snapshots})
+}
+
+// handleMetrics returns the latest collections of every host, stale ones
+// included, so a dashboard can show what a host last reported.
+func (a *Aggregator) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	type hostMetrics struct {
+		HostStatus
+		Collectors []store.Snapshot `json:"collectors"`
+	}
+	var hosts []hostMetrics
+	for _, st := range a.Hosts() {
+		snapshots, ok := a.Snapshots(st.Host)
+		if !ok {
+			continue
+		}
+		hosts = append(hosts, hostMetrics{HostStatus: st, Collectors: snapshots})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"hosts": hosts})
+}
+
+func (a *Aggregator) handleQuery(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("metric") == "" {
+		writeError(w, http.StatusBadRequest, "metric is required")
+		return
+	}
+	sel, err := metric.ParseSelector(q.Get("metric"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if host := q.Get("host"); host != "" {
+		if sel.Labels == nil {
+			sel.Labels = map[string]string{}
+		}
+		sel.Labels[HostLabel] = host
+	}
+	now := time.Now()
+	from, err := store.ParseTime(q.Get("from"), now, now.Add(-a.Retention()))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid from: "+err.Error())
+		return
+	}
+	to, err := store.ParseTime(q.Get("to"), now, now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid to: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"metric": sel.String(),
+		"from":   from,
+		"to":     to,
+		"series": a.Query(sel, from, to),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Err(err).Msg("Error writing API response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,224 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+type State string
+
+const (
+	Pending  State = "pending"
+	Firing   State = "firing"
+	Resolved State = "resolved"
+)
+
+// Alert is one rule applied to one series.
+type Alert struct {
+	Rule        string            `json:"rule"`
+	Expr        string            `json:"expr"`
+	Severity    string            `json:"severity"`
+	Description string            `json:"description,omitempty"`
+	State       State             `json:"state"`
+	Metric      string            `json:"metric"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Value       float64           `json:"value"`
+	Threshold   float64           `json:"threshold"`
+	Host        string            `json:"host"`
+	ActiveSince time.Time         `json:"active_since"`
+	FiredAt     time.Time         `json:"fired_at,omitempty"`
+	ResolvedAt  time.Time         `json:"resolved_at,omitempty"`
+	notifiedAt  time.Time
+}
+
+// Notifier delivers alert state changes.
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// notifyQueueSize is how many notifications wait for the notifiers before
+// new ones are dropped.
+const notifyQueueSize = 100
+
+// Engine evaluates rules against every batch of metrics it is given. It
+// implements output.Sink so it can sit in the output pipeline.
+// Notifications are sent by a goroutine of its own, so a slow webhook
+// doesn't hold up the collector whose metrics fired the alert.
+type Engine struct {
+	rules     []*Rule
+	repeat    time.Duration
+	notifiers []Notifier
+	host      string
+	now       func() time.Time
+
+	mu     sync.Mutex
+	active map[string]*Alert
+
+	queue chan Alert
+	done  chan struct{}
+}
+
+func New(cfg config.AlertsConfig, notifiers ...Notifier) (*Engine, error) {
+	e := &Engine{
+		repeat:    cfg.RepeatInterval.Duration(),
+		notifiers: notifiers,
+		now:       time.Now,
+		active:    make(map[string]*Alert),
+		queue:     make(chan Alert, notifyQueueSize),
+		done:      make(chan struct{}),
+	}
+	for _, rc := range cfg.Rules {
+		r, err := compileRule(rc)
+		if err != nil {
+			return nil, fmt.Errorf("alert rule %q: %w", rc.Name, err)
+		}
+		e.rules = append(e.rules, r)
+	}
+	e.host, _ = os.Hostname()
+	go e.loop()
+	return e, nil
+}
+
+func (e *Engine) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	e.Evaluate(ctx, metrics)
+	return nil
+}
+
+// Close sends the notifications still queued, giving up after a while on
+// notifiers that don't answer.
+func (e *Engine) Close() error {
+	close(e.queue)
+	select {
+	case <-e.done:
+	case <-time.After(30 * time.Second):
+		log.Warn().Int("pending", len(e.queue)).Msg("Timed out sending alert notifications")
+	}
+	return nil
+}
+
+// Evaluate checks every rule against metrics, advancing alert state and
+// queueing notifications for transitions.
+func (e *Engine) Evaluate(ctx context.Context, metrics []metric.Metric) {
+	var notify []Alert
+	e.mu.Lock()
+	now := e.now()
+	for _, r := range e.rules {
+		for _, m := range r.Selector.Select(metrics) {
+			if a := e.step(r, m, now); a != nil {
+				notify = append(notify, *a)
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	for _, a := range notify {
+		e.notify(a)
+	}
+}
+
+// step advances the state of rule r for series m and returns the alert if
+// it should be (re-)sent.
+func (e *Engine) step(r *Rule, m metric.Metric, now time.Time) *Alert {
+	key := r.Name + "\x00" + m.SeriesKey()
+	a, ok := e.active[key]
+	if !Compare(m.Value, r.Op, r.Threshold) {
+		if !ok {
+			return nil
+		}
+		delete(e.active, key)
+		if a.State != Firing {
+			return nil
+		}
+		a.State, a.Value, a.ResolvedAt = Resolved, m.Value, now
+		return a
+	}
+
+	if !ok {
+		labels := maps.Clone(m.Labels)
+		if len(r.Labels) > 0 && labels == nil {
+			labels = map[string]string{}
+		}
+		maps.Copy(labels, r.Labels)
+		a = &Alert{
+			Rule:        r.Name,
+			Expr:        r.Expr,
+			Severity:    r.Severity,
+			Description: r.Description,
+			State:       Pending,
+			Metric:      m.Name,
+			Labels:      labels,
+			Threshold:   r.Threshold,
+			Host:        e.host,
+			ActiveSince: now,
+		}
+		e.active[key] = a
+	}
+	a.Value = m.Value
+
+	switch {
+	case a.State == Pending && now.Sub(a.ActiveSince) >= r.For:
+		a.State, a.FiredAt, a.notifiedAt = Firing, now, now
+		return a
+	case a.State == Firing && e.repeat > 0 && now.Sub(a.notifiedAt) >= e.repeat:
+		a.notifiedAt = now
+		return a
+	}
+	return nil
+}
+
+// notify logs a and queues it for the notifiers, dropping it when the
+// queue is full.
+func (e *Engine) notify(a Alert) {
+	event := log.Warn()
+	if a.State == Resolved {
+		event = log.Info()
+	}
+	event.Str("rule", a.Rule).Str("state", string(a.State)).Str("severity", a.Severity).
+		Str("metric", a.Metric).Interface("labels", a.Labels).Float64("value", a.Value).Msg("Alert")
+	if len(e.notifiers) == 0 {
+		return
+	}
+	select {
+	case e.queue <- a:
+	default:
+		log.Warn().Str("rule", a.Rule).Str("state", string(a.State)).Msg("Alert notification queue full, dropping notification")
+	}
+}
+
+func (e *Engine) loop() {
+	defer close(e.done)
+	for a := range e.queue {
+		for _, n := range e.notifiers {
+			if err := n.Notify(context.Background(), a); err != nil {
+				log.Err(err).Str("rule", a.Rule).Msg("Error sending alert notification")
+			}
+		}
+	}
+}
+
+// Alerts returns the alerts that are currently pending or firing.
+func (e *Engine) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	alerts := make([]Alert, 0, len(e.active))
+	for _, a := range e.active {
+		alerts = append(alerts, *a)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Rule != alerts[j].Rule {
+			return alerts[i].Rule < alerts[j].Rule
+		}
+		return alerts[i].ActiveSince.Before(alerts[j].ActiveSince)
+	})
+	return alerts
+}
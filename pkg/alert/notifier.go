@@ -0,0 +1,33 @@
+package alert
+
+import (
+	"fmt"
+
+	"glass/pkg/config"
+)
+
+// NewNotifiers builds the notifiers described by the config.
+func NewNotifiers(cfgs []config.NotifierConfig) ([]Notifier, error) {
+	var notifiers []Notifier
+	for _, cfg := range cfgs {
+		var (
+			n   Notifier
+			err error
+		)
+		switch cfg.Type {
+		case "webhook":
+			n, err = NewWebhook(cfg)
+		case "syslog":
+			n, err = NewSyslog(cfg)
+		case "journald":
+			n, err = NewJournald(cfg)
+		default:
+			return nil, fmt.Errorf("unknown notifier type %q", cfg.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("configuring %s notifier: %w", cfg.Type, err)
+		}
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
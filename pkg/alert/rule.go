@@ -0,0 +1,103 @@
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// Rule is a compiled threshold rule.
+type Rule struct {
+	Name        string
+	Expr        string
+	Selector    metric.Selector
+	Op          string
+	Threshold   float64
+	For         time.Duration
+	Severity    string
+	Description string
+	Labels      map[string]string
+}
+
+var operators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// ParseCondition parses "<selector> <op> <threshold>" with an optional
+// trailing "for <duration>".
+func ParseCondition(expr string) (sel metric.Selector, op string, threshold float64, hold time.Duration, err error) {
+	cond := strings.TrimSpace(expr)
+	if i := strings.LastIndex(cond, " for "); i >= 0 {
+		if hold, err = time.ParseDuration(strings.TrimSpace(cond[i+5:])); err != nil {
+			return sel, "", 0, 0, fmt.Errorf("invalid duration in %q: %w", expr, err)
+		}
+		cond = cond[:i]
+	}
+	for _, candidate := range operators {
+		// Look for the operator after any label list so that "{a=b}" isn't
+		// mistaken for an equality test.
+		start := strings.LastIndexByte(cond, '}') + 1
+		if i := strings.Index(cond[start:], candidate); i >= 0 {
+			i += start
+			if sel, err = metric.ParseSelector(cond[:i]); err != nil {
+				return sel, "", 0, 0, err
+			}
+			value := strings.TrimSuffix(strings.TrimSpace(cond[i+len(candidate):]), "%")
+			if threshold, err = strconv.ParseFloat(value, 64); err != nil {
+				return sel, "", 0, 0, fmt.Errorf("invalid threshold in %q: %w", expr, err)
+			}
+			return sel, candidate, threshold, hold, nil
+		}
+	}
+	return sel, "", 0, 0, fmt.Errorf("no comparison operator in %q", expr)
+}
+
+func compileRule(cfg config.AlertRule) (*Rule, error) {
+	sel, op, threshold, hold, err := ParseCondition(cfg.Expr)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.For > 0 {
+		hold = cfg.For.Duration()
+	}
+	name := cfg.Name
+	if name == "" {
+		name = cfg.Expr
+	}
+	severity := cfg.Severity
+	if severity == "" {
+		severity = "warning"
+	}
+	return &Rule{
+		Name:        name,
+		Expr:        cfg.Expr,
+		Selector:    sel,
+		Op:          op,
+		Threshold:   threshold,
+		For:         hold,
+		Severity:    severity,
+		Description: cfg.Description,
+		Labels:      cfg.Labels,
+	}, nil
+}
+
+// Compare applies op to value and threshold.
+func Compare(value float64, op string, threshold float64) bool {
+	switch op {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/syslog"
+
+	"github.com/coreos/go-systemd/v22/journal"
+)
+
+// Syslog sends alert state changes as RFC 5424 messages, with the alert in
+// an alert@32473 structured data element and its labels in labels@32473.
+type Syslog struct {
+	Network  string `json:"network"`
+	Address  string `json:"address"`
+	Facility string `json:"facility"`
+	AppName  string `json:"app_name"`
+
+	writer *syslog.Writer
+}
+
+func NewSyslog(cfg config.NotifierConfig) (*Syslog, error) {
+	s := &Syslog{Facility: "daemon", AppName: "glass"}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	w, err := syslog.Dial(s.Network, s.Address, s.Facility, s.AppName)
+	if err != nil {
+		return nil, err
+	}
+	s.writer = w
+	return s, nil
+}
+
+func (s *Syslog) Notify(ctx context.Context, a Alert) error {
+	params := []syslog.Param{
+		{Name: "rule", Value: a.Rule},
+		{Name: "state", Value: string(a.State)},
+		{Name: "severity", Value: a.Severity},
+		{Name: "metric", Value: a.Metric},
+		{Name: "value", Value: strconv.FormatFloat(a.Value, 'f', -1, 64)},
+		{Name: "threshold", Value: strconv.FormatFloat(a.Threshold, 'f', -1, 64)},
+		{Name: "active_since", Value: a.ActiveSince.Format(time.RFC3339)},
+	}
+	data := []syslog.Element{{ID: "alert@" + syslog.EnterpriseID, Params: params}}
+	if len(a.Labels) > 0 {
+		labels := syslog.Element{ID: "labels@" + syslog.EnterpriseID}
+		for _, k := range slices.Sorted(maps.Keys(a.Labels)) {
+			labels.Params = append(labels.Params, syslog.Param{Name: k, Value: a.Labels[k]})
+		}
+		data = append(data, labels)
+	}
+	return s.writer.Send(syslog.Message{
+		Severity: alertSeverity(a),
+		MsgID:    "alert",
+		Data:     data,
+		Text:     alertText(a),
+	})
+}
+
+// Journald writes alert state changes to the systemd journal with the alert
+// in GLASS_* fields, e.g. journalctl GLASS_RULE=memory-high.
+type Journald struct {
+	Identifier string `json:"identifier"`
+}
+
+func NewJournald(cfg config.NotifierConfig) (*Journald, error) {
+	j := &Journald{Identifier: "glass"}
+	if err := cfg.Decode(j); err != nil {
+		return nil, err
+	}
+	if !journal.Enabled() {
+		return nil, errors.New("the systemd journal is not available")
+	}
+	return j, nil
+}
+
+func (j *Journald) Notify(ctx context.Context, a Alert) error {
+	fields := map[string]string{
+		"SYSLOG_IDENTIFIER": j.Identifier,
+		"GLASS_RULE":        a.Rule,
+		"GLASS_STATE":       string(a.State),
+		"GLASS_SEVERITY":    a.Severity,
+		"GLASS_METRIC":      a.Metric,
+		"GLASS_VALUE":       strconv.FormatFloat(a.Value, 'f', -1, 64),
+		"GLASS_THRESHOLD":   strconv.FormatFloat(a.Threshold, 'f', -1, 64),
+	}
+	for k, v := range a.Labels {
+		fields["GLASS_LABEL_"+syslog.JournalField(k)] = v
+	}
+	return journal.Send(alertText(a), journal.Priority(alertSeverity(a)), fields)
+}
+
+// alertSeverity maps a firing alert's rule severity to syslog's, falling
+// back to warning. Resolutions are notices.
+func alertSeverity(a Alert) syslog.Severity {
+	if a.State == Resolved {
+		return syslog.Notice
+	}
+	if sev, ok := syslog.ParseSeverity(a.Severity); ok {
+		return sev
+	}
+	return syslog.Warning
+}
+
+func alertText(a Alert) string {
+	return fmt.Sprintf("Alert %s is %s: %s = %s (threshold %s)", a.Rule, a.State, a.Metric,
+		strconv.FormatFloat(a.Value, 'f', -1, 64), strconv.FormatFloat(a.Threshold, 'f', -1, 64))
+}
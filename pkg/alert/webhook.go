@@ -0,0 +1,138 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/retry"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body when a
+// secret is configured, in the form "sha256=<hex>".
+const SignatureHeader = "X-Glass-Signature"
+
+// Webhook POSTs alerts to one or more URLs.
+type Webhook struct {
+	URLs []string `json:"urls"`
+	// Template is a text/template rendered with the Alert as its data. When
+	// empty the alert is sent as JSON.
+	Template    string            `json:"template"`
+	ContentType string            `json:"content_type"`
+	Headers     map[string]string `json:"headers"`
+	Secret      string            `json:"secret"`
+	Timeout     config.Duration   `json:"timeout"`
+	Retries     int               `json:"retries"`
+
+	tmpl   *template.Template
+	client *http.Client
+}
+
+func NewWebhook(cfg config.NotifierConfig) (*Webhook, error) {
+	w := &Webhook{
+		ContentType: "application/json",
+		Timeout:     config.Duration(10 * time.Second),
+		Retries:     3,
+	}
+	if err := cfg.Decode(w); err != nil {
+		return nil, err
+	}
+	if len(w.URLs) == 0 {
+		return nil, errors.New("at least one url is required")
+	}
+	if w.Template != "" {
+		tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(w.Template)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template: %w", err)
+		}
+		w.tmpl = tmpl
+	}
+	w.client = &http.Client{Timeout: w.Timeout.Duration()}
+	return w, nil
+}
+
+func (w *Webhook) Notify(ctx context.Context, a Alert) error {
+	body, err := w.render(a)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, url := range w.URLs {
+		err := retry.Do(ctx, retry.Policy{Attempts: w.Retries + 1, Base: time.Second, Max: 30 * time.Second}, func() error {
+			return w.post(ctx, url, body)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("posting to %s: %w", url, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (w *Webhook) render(a Alert) ([]byte, error) {
+	if w.tmpl == nil {
+		return json.Marshal(a)
+	}
+	var buf bytes.Buffer
+	if err := w.tmpl.Execute(&buf, a); err != nil {
+		return nil, fmt.Errorf("rendering template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (w *Webhook) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	req.Header.Set("Content-Type", w.ContentType)
+	req.Header.Set("User-Agent", "glass")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	if w.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Glass-Timestamp", timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(w.Secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("webhook returned %s", resp.Status)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return retry.Permanent(err)
+}
+
+// Sign computes the webhook signature over "<timestamp>.<body>" so
+// receivers can reject replayed requests.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
@@ -0,0 +1,231 @@
+// Package check evaluates thresholds against one collection the way a
+// Nagios plugin does: a status, an exit code and perfdata.
+package check
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"glass/pkg/alert"
+	"glass/pkg/metric"
+)
+
+// Status is a Nagios service state; its value is the plugin exit code.
+type Status int
+
+const (
+	OK Status = iota
+	Warning
+	Critical
+	Unknown
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Warning:
+		return "WARNING"
+	case Critical:
+		return "CRITICAL"
+	}
+	return "UNKNOWN"
+}
+
+// Rule is "<selector><op><warning>[:<critical>]", for example
+// "disk./.used_percent>90:95" or "mem.available_percent<10:5". Without a
+// critical threshold a breach is only a warning.
+type Rule struct {
+	Expr     string
+	Selector metric.Selector
+	Op       string
+	Warning  float64
+	Critical *float64
+}
+
+// ParseRule parses a --rule argument.
+func ParseRule(expr string) (Rule, error) {
+	cond := strings.TrimSpace(expr)
+	var critical *float64
+	// The critical threshold follows the last colon, unless that colon is
+	// inside the selector, as in {device="C:"}.
+	if i := strings.LastIndexByte(cond, ':'); i >= 0 {
+		if v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(cond[i+1:]), "%"), 64); err == nil {
+			critical = &v
+			cond = cond[:i]
+		}
+	}
+	sel, op, warning, hold, err := alert.ParseCondition(cond)
+	if err != nil {
+		return Rule{}, err
+	}
+	if hold > 0 {
+		return Rule{}, fmt.Errorf("invalid rule %q: checks look at one collection and can't hold for a duration", expr)
+	}
+	if op == "==" || op == "!=" {
+		if critical != nil {
+			return Rule{}, fmt.Errorf("invalid rule %q: %s takes a single threshold", expr, op)
+		}
+	} else if critical != nil && !alert.Compare(*critical, op, warning) && *critical != warning {
+		return Rule{}, fmt.Errorf("invalid rule %q: critical threshold %g is not beyond warning %g", expr, *critical, warning)
+	}
+	return Rule{Expr: expr, Selector: sel, Op: op, Warning: warning, Critical: critical}, nil
+}
+
+// Result is the outcome of one rule against one series.
+type Result struct {
+	Rule   Rule
+	Metric metric.Metric
+	Status Status
+}
+
+// Report is the outcome of every rule.
+type Report struct {
+	Results []Result
+	// Problems are what made the check UNKNOWN, such as a rule that
+	// matched nothing or a collector that failed.
+	Problems []string
+}
+
+// Evaluate applies rules to metrics. A rule that matches no series makes
+// the report UNKNOWN.
+func Evaluate(rules []Rule, metrics []metric.Metric) *Report {
+	r := &Report{}
+	for _, rule := range rules {
+		matched := rule.Selector.Select(metrics)
+		if len(matched) == 0 {
+			r.Problems = append(r.Problems, "no metric matches "+rule.Selector.String())
+			continue
+		}
+		sort.Slice(matched, func(i, j int) bool { return matched[i].SeriesKey() < matched[j].SeriesKey() })
+		for _, m := range matched {
+			status := OK
+			switch {
+			case rule.Critical != nil && alert.Compare(m.Value, rule.Op, *rule.Critical):
+				status = Critical
+			case alert.Compare(m.Value, rule.Op, rule.Warning):
+				status = Warning
+			}
+			r.Results = append(r.Results, Result{Rule: rule, Metric: m, Status: status})
+		}
+	}
+	return r
+}
+
+// Status is the worst status of every result, or UNKNOWN if there were
+// problems.
+func (r *Report) Status() Status {
+	if len(r.Problems) > 0 {
+		return Unknown
+	}
+	worst := OK
+	for _, res := range r.Results {
+		worst = max(worst, res.Status)
+	}
+	return worst
+}
+
+// Output is the plugin's output: "<name> <STATUS> - <summary> | <perfdata>",
+// with the summary naming what isn't OK.
+func (r *Report) Output(name string) string {
+	status := r.Status()
+	var summary []string
+	summary = append(summary, r.Problems...)
+	for _, res := range r.Results {
+		if res.Status != OK {
+			summary = append(summary, fmt.Sprintf("%s is %s (%s %s)", seriesName(res.Metric),
+				formatValue(res.Metric.Value, res.Metric.Unit), res.Rule.Op, formatValue(threshold(res), res.Metric.Unit)))
+		}
+	}
+	if len(summary) == 0 {
+		summary = append(summary, fmt.Sprintf("%d series within thresholds", len(r.Results)))
+	}
+	out := fmt.Sprintf("%s %s - %s", name, status, strings.Join(summary, ", "))
+	if perf := r.perfdata(); perf != "" {
+		out += " | " + perf
+	}
+	return out
+}
+
+func threshold(res Result) float64 {
+	if res.Status == Critical {
+		return *res.Rule.Critical
+	}
+	return res.Rule.Warning
+}
+
+// perfdata renders 'label'=value[UOM];warn;crit;min;max for every result.
+func (r *Report) perfdata() string {
+	var parts []string
+	for _, res := range r.Results {
+		m := res.Metric
+		uom, lo, hi := "", "", ""
+		switch m.Unit {
+		case "percent":
+			uom, lo, hi = "%", "0", "100"
+		case "bytes":
+			uom = "B"
+		case "seconds":
+			uom = "s"
+		}
+		if m.Kind == metric.Counter {
+			uom = "c"
+		}
+		crit := ""
+		if res.Rule.Critical != nil {
+			crit = perfThreshold(res.Rule.Op, *res.Rule.Critical)
+		}
+		label := strings.ReplaceAll(seriesName(m), "'", "")
+		parts = append(parts, fmt.Sprintf("'%s'=%s%s;%s;%s;%s;%s",
+			label, perfNumber(m.Value), uom, perfThreshold(res.Rule.Op, res.Rule.Warning), crit, lo, hi))
+	}
+	return strings.Join(parts, " ")
+}
+
+// seriesName is the metric name followed by its label values, e.g.
+// "disk.used_percent[/dev/sda1,/]", short enough for a status line.
+func seriesName(m metric.Metric) string {
+	if len(m.Labels) == 0 {
+		return m.Name
+	}
+	values := make([]string, 0, len(m.Labels))
+	for _, k := range m.LabelKeys() {
+		values = append(values, m.Labels[k])
+	}
+	return m.Name + "[" + strings.Join(values, ",") + "]"
+}
+
+func formatValue(v float64, unit string) string {
+	s := strconv.FormatFloat(v, 'f', 1, 64)
+	if v == math.Trunc(v) {
+		s = strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	switch unit {
+	case "percent":
+		return s + "%"
+	case "bytes":
+		return s + "B"
+	case "seconds":
+		return s + "s"
+	}
+	return s
+}
+
+// perfThreshold writes a threshold as a Nagios range: "90" alerts above
+// 90 and "10:" below 10.
+func perfThreshold(op string, v float64) string {
+	switch op {
+	case ">", ">=":
+		return perfNumber(v)
+	case "<", "<=":
+		return perfNumber(v) + ":"
+	}
+	return ""
+}
+
+func perfNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
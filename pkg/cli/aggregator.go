@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"glass/pkg/aggregator"
+	"glass/pkg/server"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+func newAggregatorCmd(a *app) *cobra.Command {
+	var (
+		listen     string
+		grpcListen string
+		tlsFlags   serverTLSFlags
+	)
+	cmd := &cobra.Command{
+		Use:   "aggregator",
+		Short: "Receive metrics pushed by other glass agents and serve them together",
+		Long: "aggregator collects nothing itself. Agents push their collections to POST /api/v1/push\n" +
+			"(JSON, optionally gzipped) or to the Aggregator gRPC service on --grpc-listen, and it keeps\n" +
+			"the latest collections of each host plus recent history (see history in the config).\n" +
+			"/metrics exposes every host's metrics labelled host=<name>, leaving out hosts that\n" +
+			"haven't pushed for aggregator.stale_after; /api/v1/hosts lists the hosts and\n" +
+			"/api/v1/query?metric=cpu.usage_percent&host=web1 queries the history. server.tls and\n" +
+			"server.auth apply as they do for serve.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Info().Msg("Cloudways Looking Glass aggregator")
+			agg := aggregator.New(aggregator.Config{
+				StaleAfter:  a.config.Aggregator.StaleAfter.Duration(),
+				ForgetAfter: a.config.Aggregator.ForgetAfter.Duration(),
+				Retention:   a.config.History.Retention.Duration(),
+				Resolution:  a.config.History.Resolution.Duration(),
+			})
+			if !cmd.Flags().Changed("listen") && a.config.Server.Listen != "" {
+				listen = a.config.Server.Listen
+			}
+			if !cmd.Flags().Changed("grpc-listen") {
+				grpcListen = a.config.Server.GRPCListen
+			}
+			serverTLS, auth, err := a.serverSecurity(cmd, tlsFlags)
+			if err != nil {
+				return err
+			}
+			handler := agg.Handler()
+			if auth != nil {
+				handler = auth.Wrap(handler)
+			}
+			var grpcSrv *grpc.Server
+			if grpcListen != "" {
+				grpcSrv = grpc.NewServer(server.GRPCOptions(serverTLS, auth)...)
+				agg.RegisterGRPC(grpcSrv)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go agg.Run(ctx)
+			return server.Serve(ctx, server.Listeners{
+				Listen:     listen,
+				Handler:    handler,
+				TLS:        serverTLS,
+				GRPCListen: grpcListen,
+				GRPC:       grpcSrv,
+			})
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", ":9123", "address to serve HTTP on")
+	cmd.Flags().StringVar(&grpcListen, "grpc-listen", "", "address to receive gRPC pushes on (disabled when empty)")
+	tlsFlags.register(cmd)
+	return cmd
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"glass/pkg/check"
+	"glass/pkg/metric"
+	"glass/pkg/rate"
+	"glass/pkg/scheduler"
+
+	"github.com/spf13/cobra"
+)
+
+// exitCode is returned by commands that choose their own exit status, such
+// as check; Execute exits with it without printing anything more.
+type exitCode int
+
+func (c exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(c))
+}
+
+func newCheckCmd(a *app) *cobra.Command {
+	var (
+		rules []string
+		name  string
+		delay time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "check --rule 'disk./.used_percent>90:95'",
+		Short: "Evaluate thresholds once, as a Nagios or Icinga plugin",
+		Long: "check runs the enabled collectors once and compares each --rule, written\n" +
+			"<metric><op><warning>[:<critical>], against every series it matches. It prints one\n" +
+			"status line with perfdata and exits 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN,\n" +
+			"when a rule is invalid, matches nothing or a collector fails). Pass --collectors to run\n" +
+			"only the collectors the rules need. Rules on _per_sec rates collect twice, --delay apart.",
+		Example: "  glass check --collectors disk --rule 'disk./.used_percent>90:95'\n" +
+			"  glass check --collectors mem,cpu --rule 'mem.available_percent<10:5' --rule 'cpu.usage_percent{cpu=\"cpu-total\"}>80:95'",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report, err := a.runCheck(cmd.Context(), rules, delay)
+			if err != nil {
+				report = &check.Report{Problems: []string{err.Error()}}
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), report.Output(name))
+			if status := report.Status(); status != check.OK {
+				return exitCode(status)
+			}
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringArrayVar(&rules, "rule", nil, "threshold rule <metric><op><warning>[:<critical>], repeatable")
+	flags.StringVar(&name, "name", "GLASS", "service name at the start of the status line")
+	flags.DurationVar(&delay, "delay", time.Second, "interval _per_sec rates are measured over")
+	return cmd
+}
+
+func (a *app) runCheck(ctx context.Context, exprs []string, delay time.Duration) (*check.Report, error) {
+	if len(exprs) == 0 {
+		return nil, fmt.Errorf("no --rule given")
+	}
+	rules := make([]check.Rule, 0, len(exprs))
+	needRates := false
+	for _, expr := range exprs {
+		rule, err := check.ParseRule(expr)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+		needRates = needRates || strings.HasSuffix(rule.Selector.Name, "_per_sec")
+	}
+	cs, err := a.newCollectors()
+	if err != nil {
+		return nil, err
+	}
+	timeout := a.schedule(0).Timeout
+	rates := rate.New()
+	if needRates {
+		for _, res := range scheduler.CollectAll(ctx, cs, timeout, a.config.Workers) {
+			rates.Process(res.Metrics)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	var metrics []metric.Metric
+	var problems []string
+	for _, res := range scheduler.CollectAll(ctx, cs, timeout, a.config.Workers) {
+		if res.Err != nil {
+			problems = append(problems, fmt.Sprintf("%s collector failed: %s", res.Collector, firstLine(res.Err.Error())))
+		}
+		metrics = append(metrics, rates.Process(res.Metrics)...)
+	}
+	report := check.Evaluate(rules, metrics)
+	report.Problems = append(problems, report.Problems...)
+	return report, nil
+}
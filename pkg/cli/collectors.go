@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"glass/pkg/collectors"
+
+	"github.com/spf13/cobra"
+)
+
+func newCollectorsCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "collectors",
+		Short: "Inspect the available collectors",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List collectors, what they report and whether they are enabled",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tSTATUS\tDESCRIPTION")
+			for _, r := range collectors.Registered() {
+				status := "disabled"
+				if collectors.Enabled(a.config, r.Name) {
+					status = "enabled"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, status, r.Description)
+			}
+			return w.Flush()
+		},
+	})
+	return cmd
+}
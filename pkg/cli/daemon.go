@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"glass/pkg/output"
+	"glass/pkg/rate"
+	"glass/pkg/scheduler"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func newDaemonCmd(a *app) *cobra.Command {
+	var (
+		jitter  func() time.Duration
+		pidfile string
+	)
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Collect continuously on each collector's interval",
+		Long: "daemon runs until SIGTERM or SIGINT, then flushes the outputs and exits.\n" +
+			"SIGHUP reloads the config file; if the new config is invalid the old one\n" +
+			"keeps running.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if pidfile != "" {
+				if err := writePidfile(pidfile); err != nil {
+					return err
+				}
+				defer os.Remove(pidfile)
+			}
+			return a.daemon(cmd, jitter)
+		},
+	}
+	jitter = a.jitterFlag(cmd)
+	cmd.Flags().StringVar(&pidfile, "pidfile", "", "write the process ID to this file while running")
+	return cmd
+}
+
+// pipeline is what a config turns into: the collectors on their schedule
+// and the sink they write to.
+type pipeline struct {
+	sched *scheduler.Scheduler
+	sink  output.Sink
+}
+
+func (a *app) newPipeline(jitter time.Duration) (*pipeline, error) {
+	cs, err := a.newCollectors()
+	if err != nil {
+		return nil, err
+	}
+	sink, err := a.newSink(true)
+	if err != nil {
+		return nil, err
+	}
+	disk, err := a.newStorage()
+	if err != nil {
+		closeSink(sink)
+		return nil, err
+	}
+	if disk != nil {
+		sink = output.Multi{sink, disk}
+	}
+	sink = output.Tag(rate.New().Wrap(sink), a.tags())
+	return &pipeline{sched: scheduler.New(a.schedule(jitter), cs, sink), sink: sink}, nil
+}
+
+// start runs the scheduler until ctx is done. The returned channel is
+// closed once it has stopped.
+func (p *pipeline) start(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		p.sched.Run(ctx)
+		close(done)
+	}()
+	return done
+}
+
+func (a *app) daemon(cmd *cobra.Command, jitter func() time.Duration) error {
+	log.Info().Msg("Cloudways Looking Glass")
+	p, err := a.newPipeline(jitter())
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		runCtx, cancel := context.WithCancel(ctx)
+		done := p.start(runCtx)
+		next := a.awaitReload(ctx, hup, cmd, jitter)
+		cancel()
+		<-done
+		closeSink(p.sink)
+		if next == nil {
+			log.Info().Msg("Shutting down")
+			return nil
+		}
+		p = next
+		log.Info().Msg("Configuration reloaded")
+	}
+}
+
+// awaitReload blocks until a SIGHUP yields a working pipeline, or returns
+// nil once ctx is done.
+func (a *app) awaitReload(ctx context.Context, hup <-chan os.Signal, cmd *cobra.Command, jitter func() time.Duration) *pipeline {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+		}
+		log.Info().Msg("Reloading configuration")
+		p, err := a.reload(cmd, jitter)
+		if err != nil {
+			log.Err(err).Msg("Error reloading configuration, keeping the current one")
+			continue
+		}
+		return p
+	}
+}
+
+// reload re-reads the config file and builds a pipeline from it, leaving
+// the current config in place if either step fails.
+func (a *app) reload(cmd *cobra.Command, jitter func() time.Duration) (*pipeline, error) {
+	old := a.config
+	if err := a.load(cmd); err != nil {
+		return nil, err
+	}
+	p, err := a.newPipeline(jitter())
+	if err != nil {
+		a.config = old
+		return nil, err
+	}
+	return p, nil
+}
+
+// writePidfile records the process ID at path, refusing to overwrite the
+// pidfile of a glass that is still running.
+func writePidfile(path string) error {
+	if data, err := os.ReadFile(path); err == nil {
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid != os.Getpid() {
+			// Signal 0 only checks that the process exists.
+			if proc, err := os.FindProcess(pid); err == nil && proc.Signal(syscall.Signal(0)) == nil {
+				return fmt.Errorf("already running with pid %d (%s)", pid, path)
+			}
+		}
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return fmt.Errorf("writing pidfile: %w", err)
+	}
+	return nil
+}
+
+// jitterFlag adds --jitter to cmd. The returned function gives the flag
+// when it was set and the config's jitter otherwise, read each time so a
+// reloaded config takes effect.
+func (a *app) jitterFlag(cmd *cobra.Command) func() time.Duration {
+	var jitter time.Duration
+	cmd.Flags().DurationVar(&jitter, "jitter", time.Second, "maximum random delay added before each collection")
+	return func() time.Duration {
+		if !cmd.Flags().Changed("jitter") && a.config.Jitter > 0 {
+			return a.config.Jitter.Duration()
+		}
+		return jitter
+	}
+}
+
+func (a *app) schedule(jitter time.Duration) scheduler.Config {
+	schedule := scheduler.Config{
+		DefaultInterval: a.config.Interval.Duration(),
+		Jitter:          jitter,
+		Intervals:       map[string]time.Duration{},
+		Timeouts:        map[string]time.Duration{},
+	}
+	for name, c := range a.config.Collectors {
+		schedule.Intervals[name] = c.Interval.Duration()
+		schedule.Timeouts[name] = c.Timeout.Duration()
+	}
+	return schedule
+}
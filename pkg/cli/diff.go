@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"glass/pkg/collectors"
+	"glass/pkg/snapshot"
+
+	"github.com/spf13/cobra"
+)
+
+func newDiffCmd() *cobra.Command {
+	var (
+		threshold float64
+		rssGrowth uint64
+		limit     int
+		format    string
+	)
+	cmd := &cobra.Command{
+		Use:   "diff BEFORE AFTER",
+		Short: "Compare two snapshots and show what changed",
+		Long: "diff compares two files written by glass snapshot, either .json snapshots or .tar.gz\n" +
+			"bundles, and reports memory and filesystem usage that moved by --threshold points or\n" +
+			"more, processes that grew by --rss-growth MiB or more, processes that started or\n" +
+			"exited, listening sockets that opened or closed, and collectors that started or\n" +
+			"stopped failing.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			before, err := snapshot.Read(args[0])
+			if err != nil {
+				return err
+			}
+			after, err := snapshot.Read(args[1])
+			if err != nil {
+				return err
+			}
+			d := snapshot.Compare(before, after, snapshot.Thresholds{Percent: threshold, RSSGrowth: rssGrowth << 20})
+			switch format {
+			case "text":
+				return printDiff(cmd.OutOrStdout(), d, limit)
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(d)
+			default:
+				return fmt.Errorf("unknown format %q", format)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.Float64Var(&threshold, "threshold", 5, "smallest change in memory or filesystem usage to report, in percentage points")
+	flags.Uint64Var(&rssGrowth, "rss-growth", 50, "smallest growth of a process's resident memory to report, in MiB")
+	flags.IntVarP(&limit, "number", "n", 20, "processes to list per section, 0 for all")
+	flags.StringVar(&format, "format", "text", "output format: text or json")
+	return cmd
+}
+
+func printDiff(out io.Writer, d *snapshot.Diff, limit int) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%s -> %s (%s apart)\n", d.Before.Format(time.DateTime), d.After.Format(time.DateTime),
+		d.After.Sub(d.Before).Round(time.Second))
+	if d.Empty() {
+		fmt.Fprintln(w, "\nNo significant changes.")
+		return w.Flush()
+	}
+	if d.Rebooted {
+		fmt.Fprintln(w, "\nThe host rebooted in between.")
+	}
+
+	section := func(title string, n int) {
+		if n > 0 {
+			fmt.Fprintf(w, "\n%s\n", title)
+		}
+	}
+	section("Memory", len(d.Memory))
+	for _, c := range d.Memory {
+		fmt.Fprintf(w, "  %s\t%s\n", c.Name, changeText(c))
+	}
+	section("Filesystems", len(d.Disks))
+	for _, c := range d.Disks {
+		name := c.Labels["mountpoint"]
+		if name == "" {
+			name = c.Labels["device"]
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", name, changeText(c))
+	}
+
+	section(fmt.Sprintf("Growing processes (%d)", len(d.Growing)), len(d.Growing))
+	for i, p := range d.Growing {
+		if limit > 0 && i == limit {
+			fmt.Fprintf(w, "  ... and %d more\n", len(d.Growing)-i)
+			break
+		}
+		fmt.Fprintf(w, "  %d\t%s\t%s -> %s\t+%s\t%s\n", p.PID, p.User, humanBytes(float64(p.RSSBefore)),
+			humanBytes(float64(p.RSSAfter)), humanBytes(float64(p.RSSAfter-p.RSSBefore)), commandLine(p.ProcessSummary))
+	}
+	printProcesses(w, "New processes", d.NewProcs, limit)
+	printProcesses(w, "Exited processes", d.GoneProcs, limit)
+
+	printListeners(w, "New listening sockets", d.NewListen)
+	printListeners(w, "Closed listening sockets", d.GoneListen)
+
+	section("Collector errors", len(d.NewErrors)+len(d.FixedErrors))
+	names := make([]string, 0, len(d.NewErrors))
+	for name := range d.NewErrors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\tnow failing: %s\n", name, firstLine(d.NewErrors[name]))
+	}
+	for _, name := range d.FixedErrors {
+		fmt.Fprintf(w, "  %s\tno longer failing\n", name)
+	}
+	return w.Flush()
+}
+
+func changeText(c snapshot.Change) string {
+	format := func(v float64) string {
+		switch c.Unit {
+		case "percent":
+			return strconv.FormatFloat(v, 'f', 1, 64) + "%"
+		case "bytes":
+			return humanBytes(v)
+		}
+		return strconv.FormatFloat(v, 'g', 4, 64)
+	}
+	switch {
+	case c.Added:
+		return "new, " + format(c.After)
+	case c.Removed:
+		return "gone, was " + format(c.Before)
+	}
+	delta := c.After - c.Before
+	sign := "+"
+	if delta < 0 {
+		sign, delta = "-", -delta
+	}
+	if c.Unit == "percent" {
+		return fmt.Sprintf("%s -> %s\t%s%.1f points", format(c.Before), format(c.After), sign, delta)
+	}
+	return fmt.Sprintf("%s -> %s\t%s%s", format(c.Before), format(c.After), sign, format(delta))
+}
+
+func printProcesses(w io.Writer, title string, procs []snapshot.ProcessSummary, limit int) {
+	if len(procs) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s (%d)\n", title, len(procs))
+	for i, p := range procs {
+		if limit > 0 && i == limit {
+			fmt.Fprintf(w, "  ... and %d more\n", len(procs)-i)
+			return
+		}
+		fmt.Fprintf(w, "  %d\t%s\t%s\t%s\n", p.PID, p.User, humanBytes(float64(p.RSS)), commandLine(p))
+	}
+}
+
+func printListeners(w io.Writer, title string, ls []collectors.Listener) {
+	if len(ls) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", title)
+	for _, l := range ls {
+		owner := ""
+		if l.Process != "" {
+			owner = fmt.Sprintf("%s (pid %d)", l.Process, l.PID)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", l.Protocol, net.JoinHostPort(l.Address, strconv.Itoa(int(l.Port))), owner)
+	}
+}
+
+func commandLine(p snapshot.ProcessSummary) string {
+	command := strings.Join(strings.Fields(p.Cmdline), " ")
+	if command == "" {
+		command = "[" + p.Name + "]"
+	}
+	if r := []rune(command); len(r) > maxCommandWidth {
+		command = string(r[:maxCommandWidth-3]) + "..."
+	}
+	return command
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
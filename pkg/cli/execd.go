@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"glass/pkg/output"
+	"glass/pkg/rate"
+	"glass/pkg/scheduler"
+
+	"github.com/spf13/cobra"
+)
+
+func newExecdCmd(a *app) *cobra.Command {
+	var trigger string
+	cmd := &cobra.Command{
+		Use:   "execd",
+		Short: "Run as a Telegraf execd input, writing line protocol to stdout",
+		Long: "execd makes glass a Telegraf execd input. Set --signal to the signal option of the\n" +
+			"input: with STDIN (the one to use) glass collects whenever Telegraf writes a line to\n" +
+			"its stdin and exits when stdin closes; with SIGHUP, SIGUSR1 or SIGUSR2 it collects on\n" +
+			"that signal; with none it collects on its own intervals. Every collection is written\n" +
+			"to stdout as InfluxDB line protocol, and logs go to stderr, which Telegraf logs.",
+		Example: "  [[inputs.execd]]\n" +
+			"    command = [\"glass\", \"execd\", \"--signal\", \"STDIN\", \"-c\", \"/etc/glass/glass.yaml\"]\n" +
+			"    signal = \"STDIN\"\n" +
+			"    data_format = \"influx\"",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			trigger = strings.ToLower(trigger)
+			sig, ok := execdSignals[trigger]
+			if !ok {
+				return fmt.Errorf("invalid --signal %q: must be %s", trigger, execdSignalChoices)
+			}
+			cs, err := a.newCollectors()
+			if err != nil {
+				return err
+			}
+			sink := output.Tag(rate.New().Wrap(output.NewLineProtocolSink(os.Stdout)), a.tags())
+			defer closeSink(sink)
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if trigger == "none" {
+				scheduler.New(a.schedule(0), cs, sink).Run(ctx)
+				return nil
+			}
+
+			triggers := make(chan struct{})
+			if sig != nil {
+				signals := make(chan os.Signal, 1)
+				signal.Notify(signals, sig)
+				defer signal.Stop(signals)
+				go func() {
+					for range signals {
+						triggers <- struct{}{}
+					}
+				}()
+			} else {
+				// Telegraf closes stdin when it stops the input.
+				go readLines(os.Stdin, triggers)
+			}
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case _, ok := <-triggers:
+					if !ok {
+						return nil
+					}
+					a.collectInto(ctx, cs, sink)
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVar(&trigger, "signal", "STDIN", "what starts a collection, as Telegraf's execd signal option: STDIN, SIGHUP, SIGUSR1, SIGUSR2 or none")
+	return cmd
+}
+
+// readLines sends on lines once per line of r and closes it when r ends.
+func readLines(r io.Reader, lines chan<- struct{}) {
+	defer close(lines)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		lines <- struct{}{}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// execdSignals are the values of Telegraf's execd signal option that glass
+// answers to.
+var execdSignals = map[string]os.Signal{
+	"none":    nil,
+	"stdin":   nil,
+	"sighup":  syscall.SIGHUP,
+	"sigusr1": syscall.SIGUSR1,
+	"sigusr2": syscall.SIGUSR2,
+}
+
+const execdSignalChoices = "STDIN, SIGHUP, SIGUSR1, SIGUSR2 or none"
@@ -0,0 +1,13 @@
+package cli
+
+import "os"
+
+// execdSignals are the values of Telegraf's execd signal option that glass
+// answers to. Windows has no signals to send a process, so Telegraf can
+// only use STDIN there.
+var execdSignals = map[string]os.Signal{
+	"none":  nil,
+	"stdin": nil,
+}
+
+const execdSignalChoices = "STDIN or none"
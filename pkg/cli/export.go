@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/metric"
+	"glass/pkg/store"
+
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd(a *app) *cobra.Command {
+	var from, to, format, sel, path string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Dump a time range from the on-disk store as JSON or CSV",
+		Long: "export reads samples from the store configured under storage in the config file.\n" +
+			"--from and --to take RFC 3339 times, Unix seconds or a duration ago (\"24h\").",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if path == "" {
+				path = a.config.Storage.Path
+			}
+			if path == "" {
+				return errors.New("no storage path configured; set storage.path in the config or pass --path")
+			}
+			now := time.Now()
+			start, err := store.ParseTime(from, now, now.Add(-a.config.Storage.Retention.Duration()))
+			if err != nil {
+				return fmt.Errorf("invalid --from: %w", err)
+			}
+			end, err := store.ParseTime(to, now, now)
+			if err != nil {
+				return fmt.Errorf("invalid --to: %w", err)
+			}
+			var selector *metric.Selector
+			if sel != "" {
+				s, err := metric.ParseSelector(sel)
+				if err != nil {
+					return err
+				}
+				selector = &s
+			}
+			series, err := store.ReadDisk(path, selector, start, end)
+			if err != nil {
+				return fmt.Errorf("reading store: %w", err)
+			}
+			switch format {
+			case "json":
+				return exportJSON(cmd.OutOrStdout(), series)
+			case "csv":
+				return exportCSV(cmd.OutOrStdout(), series)
+			default:
+				return fmt.Errorf("unknown format %q", format)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&from, "from", "", "start of the range (default: the retention period ago)")
+	flags.StringVar(&to, "to", "", "end of the range (default: now)")
+	flags.StringVar(&format, "format", "json", "output format: json or csv")
+	flags.StringVar(&sel, "metric", "", "only export metrics matching this selector")
+	flags.StringVar(&path, "path", "", "store to read, overriding storage.path")
+	return cmd
+}
+
+func exportJSON(w io.Writer, series []store.Series) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if series == nil {
+		series = []store.Series{}
+	}
+	return enc.Encode(map[string]any{"series": series})
+}
+
+func exportCSV(w io.Writer, series []store.Series) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"timestamp", "name", "labels", "kind", "unit", "value"})
+	for _, s := range series {
+		labels := formatLabels(s.Labels)
+		for _, p := range s.Points {
+			cw.Write([]string{
+				p.Timestamp.Format(time.RFC3339Nano),
+				s.Name,
+				labels,
+				string(s.Kind),
+				s.Unit,
+				strconv.FormatFloat(p.Value, 'g', -1, 64),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatLabels renders labels as a selector would: k="v",k2="v2".
+func formatLabels(labels map[string]string) string {
+	m := metric.Metric{Labels: labels}
+	parts := make([]string, 0, len(labels))
+	for _, k := range m.LabelKeys() {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,265 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"glass/pkg/alert"
+	"glass/pkg/cloud"
+	"glass/pkg/collectors"
+	"glass/pkg/config"
+	"glass/pkg/output"
+	"glass/pkg/store"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// app carries the global flags and the config they resolve to.
+type app struct {
+	configPath string
+	output     string
+	collectors []string
+	interval   time.Duration
+
+	config *config.Config
+	// cloud is detected once and kept across config reloads.
+	cloud *cloud.Metadata
+}
+
+func Execute() {
+	if err := newRootCmd().Execute(); err != nil {
+		var code exitCode
+		if errors.As(err, &code) {
+			os.Exit(int(code))
+		}
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	a := &app{}
+	root := &cobra.Command{
+		Use:          "glass",
+		Short:        "Cloudways Looking Glass",
+		Long:         "glass collects host metrics and ships them to logs, files, Prometheus and time-series databases.",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return a.load(cmd)
+		},
+		// Running glass without a subcommand collects once, as before.
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runOnce(cmd.Context())
+		},
+	}
+	flags := root.PersistentFlags()
+	flags.StringVarP(&a.configPath, "config", "c", "", "path to a YAML or TOML config file")
+	flags.StringVarP(&a.output, "output", "o", "", "output format (log, json, influxdb), overriding the config file")
+	flags.StringSliceVar(&a.collectors, "collectors", nil, "comma-separated collectors to enable, overriding the config file")
+	flags.DurationVar(&a.interval, "interval", 0, "default collection interval, overriding the config file")
+
+	root.AddCommand(
+		newRunCmd(a),
+		newDaemonCmd(a),
+		newServeCmd(a),
+		newAggregatorCmd(a),
+		newExportCmd(a),
+		newSnapshotCmd(a),
+		newDiffCmd(),
+		newCheckCmd(a),
+		newExecdCmd(a),
+		newTraceCmd(),
+		newTopCmd(),
+		newTUICmd(a),
+		newInstallServiceCmd(a),
+		newUninstallServiceCmd(),
+		newCollectorsCmd(a),
+		newVersionCmd(),
+	)
+	return root
+}
+
+// load reads the config file and applies command-line overrides on top.
+func (a *app) load(cmd *cobra.Command) error {
+	cfg := config.Default()
+	if a.configPath != "" {
+		var err error
+		if cfg, err = config.Load(a.configPath); err != nil {
+			return err
+		}
+	}
+	level, err := zerolog.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q", cfg.LogLevel)
+	}
+	zerolog.SetGlobalLevel(level)
+
+	if a.output != "" {
+		cfg.Outputs = []config.OutputConfig{{Type: a.output}}
+	}
+	if a.interval > 0 {
+		cfg.Interval = config.Duration(a.interval)
+	}
+	if err := envTags(cfg); err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("collectors") {
+		if err := enableOnly(cfg, a.collectors); err != nil {
+			return err
+		}
+	}
+	a.config = cfg
+	return nil
+}
+
+// tagEnvPrefix marks environment variables that set tags:
+// GLASS_TAG_ROLE=web tags every metric with role="web".
+const tagEnvPrefix = "GLASS_TAG_"
+
+// envTags merges tags from the environment into cfg and checks that every
+// tag has a usable name.
+func envTags(cfg *config.Config) error {
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, tagEnvPrefix) {
+			continue
+		}
+		if cfg.Tags == nil {
+			cfg.Tags = map[string]string{}
+		}
+		cfg.Tags[strings.ToLower(strings.TrimPrefix(name, tagEnvPrefix))] = value
+	}
+	for name := range cfg.Tags {
+		if !validTagName(name) {
+			return fmt.Errorf("invalid tag name %q: use letters, digits and underscores", name)
+		}
+	}
+	return nil
+}
+
+// validTagName accepts names that every output can carry as a label or tag
+// key unchanged.
+func validTagName(name string) bool {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// cloudMetadata detects the cloud instance the first time it is asked,
+// returning nil when detection is disabled or finds nothing.
+func (a *app) cloudMetadata() *cloud.Metadata {
+	if !a.config.Cloud.Enabled {
+		return nil
+	}
+	if a.cloud == nil {
+		md, err := cloud.Detect(context.Background(), a.config.Cloud.Providers, a.config.Cloud.Timeout.Duration())
+		if err != nil {
+			if !errors.Is(err, cloud.ErrNotDetected) {
+				log.Err(err).Msg("Error detecting cloud instance")
+			} else {
+				log.Warn().Msg("Cloud detection is enabled but no metadata service answered")
+			}
+			return nil
+		}
+		log.Info().Str("provider", md.Provider).Str("instance", md.InstanceID).Msg("Detected cloud instance")
+		a.cloud = md
+	}
+	return a.cloud
+}
+
+// tags returns the global tags: the configured ones on top of the cloud
+// instance's metadata when detection is enabled.
+func (a *app) tags() map[string]string {
+	md := a.cloudMetadata()
+	if md == nil {
+		return a.config.Tags
+	}
+	tags := md.Labels()
+	for k, v := range a.config.Tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+func enableOnly(cfg *config.Config, names []string) error {
+	available := collectors.Available()
+	for _, name := range names {
+		if !slices.Contains(available, name) {
+			return fmt.Errorf("unknown collector %q", name)
+		}
+	}
+	if cfg.Collectors == nil {
+		cfg.Collectors = map[string]config.CollectorConfig{}
+	}
+	for _, name := range available {
+		c := cfg.Collectors[name]
+		enabled := slices.Contains(names, name)
+		c.Enabled = &enabled
+		cfg.Collectors[name] = c
+	}
+	return nil
+}
+
+func (a *app) newCollectors() ([]collectors.Collector, error) {
+	cs, err := collectors.RegisterCollectors(a.config)
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// newSink builds the configured outputs, with the alert engine attached
+// when any rules are defined.
+func (a *app) newSink(stream bool) (output.Sink, error) {
+	outputs := a.config.Outputs
+	if len(outputs) == 0 {
+		outputs = []config.OutputConfig{{Type: "log"}}
+	}
+	sink, err := output.New(outputs, stream, a.cloudMetadata())
+	if err != nil {
+		return nil, err
+	}
+	engine, err := a.newAlertEngine()
+	if err != nil || engine == nil {
+		return sink, err
+	}
+	return output.Multi{sink, engine}, nil
+}
+
+// newStorage returns nil when no storage path is configured.
+func (a *app) newStorage() (*store.Disk, error) {
+	if a.config.Storage.Path == "" {
+		return nil, nil
+	}
+	return store.OpenDisk(a.config.Storage)
+}
+
+// newAlertEngine returns nil when no alert rules are configured.
+func (a *app) newAlertEngine() (*alert.Engine, error) {
+	if len(a.config.Alerts.Rules) == 0 {
+		return nil, nil
+	}
+	notifiers, err := alert.NewNotifiers(a.config.Alerts.Notifiers)
+	if err != nil {
+		return nil, err
+	}
+	return alert.New(a.config.Alerts, notifiers...)
+}
+
+func closeSink(sink output.Sink) {
+	if err := sink.Close(); err != nil {
+		log.Err(err).Msg("Error flushing outputs")
+	}
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+
+	"glass/pkg/collectors"
+	"glass/pkg/output"
+	"glass/pkg/scheduler"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func newRunCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "run",
+		Short: "Collect every enabled collector once and exit",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runOnce(cmd.Context())
+		},
+	}
+}
+
+func (a *app) runOnce(ctx context.Context) error {
+	log.Info().Msg("Cloudways Looking Glass")
+	cs, err := a.newCollectors()
+	if err != nil {
+		return err
+	}
+	sink, err := a.newSink(false)
+	if err != nil {
+		return err
+	}
+	sink = output.Tag(sink, a.tags())
+	defer closeSink(sink)
+	a.collectInto(ctx, cs, sink)
+	return nil
+}
+
+// collectInto runs every collector once and writes the results, and glass's
+// own metrics about the run, to sink.
+func (a *app) collectInto(ctx context.Context, cs []collectors.Collector, sink output.Sink) {
+	results := scheduler.CollectAll(ctx, cs, a.schedule(0).Timeout, a.config.Workers)
+	for _, res := range results {
+		if res.Err != nil {
+			log.Err(res.Err).Str("collector", res.Collector).Msg("Error collecting metrics")
+		}
+		if err := sink.Write(ctx, res.Collector, res.Metrics); err != nil {
+			log.Err(err).Msg("Error writing metrics")
+		}
+	}
+	if err := sink.Write(ctx, scheduler.SelfCollector, scheduler.SelfMetrics(results...)); err != nil {
+		log.Err(err).Msg("Error writing metrics")
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"crypto/tls"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"glass/pkg/output"
+	"glass/pkg/rate"
+	"glass/pkg/scheduler"
+	"glass/pkg/server"
+	"glass/pkg/store"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func newServeCmd(a *app) *cobra.Command {
+	var (
+		listen       string
+		grpcListen   string
+		nodeExporter bool
+		tlsFlags     serverTLSFlags
+		jitter       func() time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Collect continuously and serve metrics over HTTP",
+		Long: "serve runs the collectors on their intervals, keeps the latest sample of each in memory\n" +
+			"and exposes them as Prometheus metrics on /metrics and as JSON under /api/v1.\n" +
+			"/api/v1/stream is a WebSocket that pushes each collection as it happens; pass\n" +
+			"?collector=cpu,mem to receive only those collectors. Recent samples are kept in\n" +
+			"memory (see history in the config) and served by /api/v1/query. --grpc-listen also\n" +
+			"serves the gRPC API defined in pkg/grpcapi/glassv1/glass.proto. Point a browser at /\n" +
+			"for a live dashboard.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Info().Msg("Cloudways Looking Glass")
+			if !cmd.Flags().Changed("listen") && a.config.Server.Listen != "" {
+				listen = a.config.Server.Listen
+			}
+			if !cmd.Flags().Changed("grpc-listen") {
+				grpcListen = a.config.Server.GRPCListen
+			}
+			serverTLS, auth, err := a.serverSecurity(cmd, tlsFlags)
+			if err != nil {
+				return err
+			}
+			cs, err := a.newCollectors()
+			if err != nil {
+				return err
+			}
+			latest := store.NewLatest()
+			history := store.NewHistory(a.config.History.Retention.Duration(), a.config.History.Resolution.Duration())
+			hub := server.NewHub()
+			sinks := output.Multi{latest, history, hub}
+			if len(a.config.Outputs) > 0 {
+				outputs, err := output.New(a.config.Outputs, true, a.cloudMetadata())
+				if err != nil {
+					return err
+				}
+				sinks = append(sinks, outputs)
+			}
+			engine, err := a.newAlertEngine()
+			if err != nil {
+				return err
+			}
+			if engine != nil {
+				sinks = append(sinks, engine)
+			}
+			disk, err := a.newStorage()
+			if err != nil {
+				return err
+			}
+			if disk != nil {
+				sinks = append(sinks, disk)
+			}
+			sink := output.Tag(rate.New().Wrap(sinks), a.tags())
+			sched := scheduler.New(a.schedule(jitter()), cs, sink)
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			done := make(chan struct{})
+			go func() {
+				sched.Run(ctx)
+				close(done)
+			}()
+			err = server.New(server.Options{
+				Listen:            listen,
+				GRPCListen:        grpcListen,
+				Latest:            latest,
+				History:           history,
+				Hub:               hub,
+				Scheduler:         sched,
+				Alerts:            engine,
+				NodeExporterNames: nodeExporter,
+				TLS:               serverTLS,
+				Auth:              auth,
+			}).Run(ctx)
+			stop()
+			<-done
+			closeSink(sink)
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", ":9123", "address to serve HTTP on")
+	cmd.Flags().StringVar(&grpcListen, "grpc-listen", "", "address to serve the gRPC API on (disabled when empty)")
+	tlsFlags.register(cmd)
+	jitter = a.jitterFlag(cmd)
+	cmd.Flags().BoolVar(&nodeExporter, "node-exporter-names", false, "expose /metrics under node_exporter metric names where one exists")
+	return cmd
+}
+
+// serverTLSFlags override server.tls for serve and aggregator.
+type serverTLSFlags struct {
+	cert, key, clientCA string
+}
+
+func (f *serverTLSFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.cert, "tls-cert", "", "serve HTTPS with this certificate file (overrides server.tls)")
+	cmd.Flags().StringVar(&f.key, "tls-key", "", "private key file for --tls-cert")
+	cmd.Flags().StringVar(&f.clientCA, "tls-client-ca", "", "require client certificates signed by these CAs")
+}
+
+// serverSecurity sets up TLS and authentication from server.tls and
+// server.auth, with the TLS flags applied on top. Either may be nil.
+func (a *app) serverSecurity(cmd *cobra.Command, flags serverTLSFlags) (*tls.Config, *server.Auth, error) {
+	tlsCfg := a.config.Server.TLS
+	if cmd.Flags().Changed("tls-cert") || cmd.Flags().Changed("tls-key") {
+		tlsCfg.CertFile, tlsCfg.KeyFile = flags.cert, flags.key
+	}
+	if cmd.Flags().Changed("tls-client-ca") {
+		tlsCfg.ClientCAFile = flags.clientCA
+	}
+	var serverTLS *tls.Config
+	if tlsCfg.Enabled() || tlsCfg.ClientCAFile != "" {
+		var err error
+		if serverTLS, err = server.TLSConfig(tlsCfg); err != nil {
+			return nil, nil, err
+		}
+	}
+	auth, err := server.NewAuth(a.config.Server.Auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	if auth != nil && serverTLS == nil {
+		log.Warn().Msg("API authentication is enabled without TLS; credentials are sent in clear text")
+	}
+	return serverTLS, auth, nil
+}
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var unitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description=Cloudways Looking Glass
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+User={{.User}}
+{{- if .Group}}
+Group={{.Group}}
+{{- end}}
+ExecStart={{.ExecStart}}
+{{- if eq .Command "daemon"}}
+ExecReload=/bin/kill -HUP $MAINPID
+{{- end}}
+Restart={{.Restart}}
+RestartSec=5s
+{{- if ne .User "root"}}
+# Raw sockets for the ping collector and traces.
+AmbientCapabilities=CAP_NET_RAW
+{{- end}}
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+type serviceOptions struct {
+	Name    string
+	User    string
+	Group   string
+	Command string
+	Restart string
+	Args    []string
+	UnitDir string
+	DryRun  bool
+	NoStart bool
+
+	ExecStart string
+}
+
+func newInstallServiceCmd(a *app) *cobra.Command {
+	opts := serviceOptions{}
+	cmd := &cobra.Command{
+		Use:   "install-service",
+		Short: "Install and start a systemd unit running glass",
+		Long: "install-service writes a systemd unit that runs this glass binary with the\n" +
+			"config given by --config, then enables and starts it.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch opts.Command {
+			case "daemon", "serve":
+			default:
+				return fmt.Errorf("invalid --command %q: must be daemon or serve", opts.Command)
+			}
+			switch opts.Restart {
+			case "always", "on-failure", "on-abnormal", "no":
+			default:
+				return fmt.Errorf("invalid --restart %q", opts.Restart)
+			}
+			binary, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("finding the glass binary: %w", err)
+			}
+			argv := []string{binary, opts.Command}
+			if a.configPath != "" {
+				// The config was loaded already, so it exists and parses.
+				path, err := filepath.Abs(a.configPath)
+				if err != nil {
+					return err
+				}
+				argv = append(argv, "--config", path)
+			}
+			opts.ExecStart = systemdQuote(append(argv, opts.Args...))
+
+			var unit bytes.Buffer
+			if err := unitTemplate.Execute(&unit, opts); err != nil {
+				return err
+			}
+			if opts.DryRun {
+				_, err := cmd.OutOrStdout().Write(unit.Bytes())
+				return err
+			}
+			path := filepath.Join(opts.UnitDir, opts.Name+".service")
+			if err := os.WriteFile(path, unit.Bytes(), 0o644); err != nil {
+				return fmt.Errorf("writing unit: %w", err)
+			}
+			log.Info().Str("unit", path).Msg("Installed systemd unit")
+			if err := systemctl("daemon-reload"); err != nil {
+				return err
+			}
+			if opts.NoStart {
+				return systemctl("enable", opts.Name)
+			}
+			return systemctl("enable", "--now", opts.Name)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&opts.Name, "name", "glass", "unit name, without .service")
+	flags.StringVar(&opts.User, "user", "root", "user the service runs as")
+	flags.StringVar(&opts.Group, "group", "", "group the service runs as (default: the user's primary group)")
+	flags.StringVar(&opts.Command, "command", "daemon", "glass command to run: daemon or serve")
+	flags.StringVar(&opts.Restart, "restart", "on-failure", "systemd restart policy: always, on-failure, on-abnormal or no")
+	flags.StringArrayVar(&opts.Args, "arg", nil, "extra argument for the glass command; may be repeated")
+	flags.StringVar(&opts.UnitDir, "unit-dir", "/etc/systemd/system", "directory to install the unit in")
+	flags.BoolVar(&opts.DryRun, "dry-run", false, "print the unit instead of installing it")
+	flags.BoolVar(&opts.NoStart, "no-start", false, "enable the unit without starting it")
+	return cmd
+}
+
+func newUninstallServiceCmd() *cobra.Command {
+	var name, unitDir string
+	cmd := &cobra.Command{
+		Use:   "uninstall-service",
+		Short: "Stop, disable and remove the systemd unit installed by install-service",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := filepath.Join(unitDir, name+".service")
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("no unit installed at %s", path)
+			}
+			// A unit that was never started can't be stopped; carry on and
+			// remove it anyway.
+			if err := systemctl("disable", "--now", name); err != nil {
+				log.Warn().Err(err).Msg("Error stopping unit")
+			}
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("removing unit: %w", err)
+			}
+			log.Info().Str("unit", path).Msg("Removed systemd unit")
+			return systemctl("daemon-reload")
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "glass", "unit name, without .service")
+	cmd.Flags().StringVar(&unitDir, "unit-dir", "/etc/systemd/system", "directory the unit is installed in")
+	return cmd
+}
+
+func systemctl(args ...string) error {
+	out, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if msg == "" {
+			return fmt.Errorf("systemctl %s: %w", strings.Join(args, " "), err)
+		}
+		return errors.New("systemctl " + strings.Join(args, " ") + ": " + msg)
+	}
+	return nil
+}
+
+// systemdQuote joins argv for ExecStart, quoting words that systemd would
+// otherwise split or expand.
+func systemdQuote(argv []string) string {
+	words := make([]string, len(argv))
+	for i, arg := range argv {
+		if arg != "" && !strings.ContainsAny(arg, " \t\"'\\$%;") {
+			words[i] = arg
+			continue
+		}
+		r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`, `%`, `%%`)
+		words[i] = `"` + r.Replace(arg) + `"`
+	}
+	return strings.Join(words, " ")
+}
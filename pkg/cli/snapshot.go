@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/snapshot"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// kernelLogLines caps the kernel messages put in a bundle.
+const kernelLogLines = 500
+
+func newSnapshotCmd(a *app) *cobra.Command {
+	var (
+		out   string
+		delay time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Capture a diagnostic bundle for a support ticket",
+		Long: "snapshot runs every enabled collector and records host info, every process, listening\n" +
+			"sockets, recent kernel errors and the glass config with credentials redacted. With an\n" +
+			"--out ending in .json it writes the snapshot alone, for glass diff; otherwise it writes a\n" +
+			"gzipped tar. Run it as root to see every process and socket.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cs, err := a.newCollectors()
+			if err != nil {
+				return err
+			}
+			s, err := snapshot.Take(ctx, cs, snapshot.Options{
+				Version: Version,
+				Timeout: a.schedule(0).Timeout,
+				Workers: a.config.Workers,
+				Delay:   delay,
+			})
+			if err != nil {
+				return err
+			}
+			if out == "" {
+				host := "host"
+				if s.Host != nil {
+					host = s.Host.Hostname
+				}
+				out = fmt.Sprintf("glass-snapshot-%s-%s.tar.gz", host, s.TakenAt.UTC().Format("20060102T150405Z"))
+			}
+
+			var data []byte
+			if strings.EqualFold(filepath.Ext(out), ".json") {
+				if data, err = json.MarshalIndent(s, "", "  "); err != nil {
+					return err
+				}
+				data = append(data, '\n')
+			} else if data, err = a.bundle(cmd, s); err != nil {
+				return err
+			}
+			// The bundle lists every process's command line, which may
+			// hold secrets of its own.
+			if err := os.WriteFile(out, data, 0o600); err != nil {
+				return fmt.Errorf("writing snapshot: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s (%d collectors, %d processes, %d listening sockets)\n",
+				out, len(s.Collections), len(s.Processes), len(s.Listening))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&out, "out", "", "file to write: a .tar.gz bundle, or a .json snapshot (default glass-snapshot-<host>-<time>.tar.gz)")
+	cmd.Flags().DurationVar(&delay, "delay", time.Second, "interval rates and process CPU and IO are measured over; 0 collects once without them")
+	return cmd
+}
+
+// bundle packs s with the kernel log and the redacted config.
+func (a *app) bundle(cmd *cobra.Command, s *snapshot.Snapshot) ([]byte, error) {
+	var files []snapshot.File
+	kernel, err := snapshot.KernelErrors(cmd.Context(), kernelLogLines)
+	if err != nil {
+		log.Warn().Err(err).Msg("Error reading kernel log")
+		kernel = []byte(err.Error() + "\n")
+	}
+	files = append(files, snapshot.File{Name: "kernel-errors.log", Data: kernel})
+
+	cfg, err := config.Redacted(a.config)
+	if err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	files = append(files, snapshot.File{Name: "config.json", Data: append(cfg, '\n')})
+
+	var buf bytes.Buffer
+	if err := snapshot.WriteBundle(&buf, s, files...); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
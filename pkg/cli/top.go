@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"glass/pkg/collectors"
+
+	"github.com/spf13/cobra"
+)
+
+func newTopCmd() *cobra.Command {
+	var (
+		by     string
+		n      int
+		delay  time.Duration
+		format string
+	)
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Show the heaviest processes by CPU, memory, IO or open files",
+		Long: "top samples every process twice, --delay apart, so CPU and IO are rates over\n" +
+			"that interval, and prints the top N.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(collectors.ProcessSortKeys, by) {
+				return fmt.Errorf("invalid --by %q: must be one of %s", by, strings.Join(collectors.ProcessSortKeys, ", "))
+			}
+			var sampler collectors.ProcessSampler
+			if delay > 0 {
+				if _, err := sampler.Sample(cmd.Context()); err != nil {
+					return err
+				}
+				select {
+				case <-cmd.Context().Done():
+					return cmd.Context().Err()
+				case <-time.After(delay):
+				}
+			}
+			infos, err := sampler.Sample(cmd.Context())
+			if err != nil {
+				return err
+			}
+			infos = collectors.TopProcesses(infos, by, n)
+			switch format {
+			case "text":
+				return printTop(cmd.OutOrStdout(), infos)
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(map[string]any{"processes": infos})
+			default:
+				return fmt.Errorf("unknown format %q", format)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&by, "by", "cpu", "sort by: "+strings.Join(collectors.ProcessSortKeys, ", "))
+	flags.IntVarP(&n, "number", "n", 10, "number of processes to show, 0 for all")
+	flags.DurationVar(&delay, "delay", time.Second, "interval CPU and IO rates are measured over; 0 uses lifetime averages")
+	flags.StringVar(&format, "format", "text", "output format: text or json")
+	return cmd
+}
+
+const maxCommandWidth = 80
+
+func printTop(out io.Writer, infos []collectors.ProcessInfo) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PID\tUSER\tCPU%\tMEM%\tRSS\tREAD/s\tWRITE/s\tFDS\tAGE\tCOMMAND")
+	for _, p := range infos {
+		// Keep each process on one line, as top does.
+		command := strings.Join(strings.Fields(p.Cmdline), " ")
+		if command == "" {
+			command = "[" + p.Name + "]"
+		}
+		if r := []rune(command); len(r) > maxCommandWidth {
+			command = string(r[:maxCommandWidth-3]) + "..."
+		}
+		fmt.Fprintf(w, "%d\t%s\t%.1f\t%.1f\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			p.PID, p.User, p.CPUPercent, p.MemoryPercent,
+			humanBytes(float64(p.RSS)), humanBytes(p.ReadRate), humanBytes(p.WriteRate),
+			p.FDs, (time.Duration(p.Age) * time.Second).String(), command)
+	}
+	return w.Flush()
+}
+
+// humanBytes formats n with a binary unit suffix, as top does.
+func humanBytes(n float64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return strconv.FormatFloat(n, 'f', 0, 64)
+	}
+	i := -1
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	return strconv.FormatFloat(n, 'f', 1, 64) + string(units[i])
+}
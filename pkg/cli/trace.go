@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"glass/pkg/trace"
+
+	"github.com/spf13/cobra"
+)
+
+func newTraceCmd() *cobra.Command {
+	opts := trace.DefaultOptions()
+	var (
+		format string
+		noDNS  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "trace <host>",
+		Short: "Trace the network path to a host with per-hop latency and loss",
+		Long: "trace probes every hop to the host several times, as mtr does, and reports\n" +
+			"latency and loss for each. It needs root or CAP_NET_RAW for raw ICMP sockets.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Resolve = !noDNS
+			result, err := trace.Run(cmd.Context(), args[0], opts)
+			if err != nil {
+				return err
+			}
+			switch format {
+			case "text":
+				return printTrace(cmd.OutOrStdout(), result)
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(result)
+			default:
+				return fmt.Errorf("unknown format %q", format)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.IntVar(&opts.MaxHops, "max-hops", opts.MaxHops, "maximum number of hops")
+	flags.IntVar(&opts.Probes, "probes", opts.Probes, "probes sent to each hop")
+	flags.DurationVar(&opts.Timeout, "timeout", opts.Timeout, "how long to wait for each reply")
+	flags.BoolVarP(&noDNS, "no-dns", "n", false, "show addresses without looking up names")
+	flags.StringVar(&format, "format", "text", "output format: text or json")
+	return cmd
+}
+
+func printTrace(out io.Writer, r *trace.Result) error {
+	fmt.Fprintf(out, "trace to %s (%s)\n", r.Host, r.Address)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "HOP\tHOST\tLOSS%\tSENT\tLAST\tAVG\tBEST\tWORST\tSTDEV")
+	for _, h := range r.Hops {
+		host := "???"
+		if len(h.Hosts) > 0 {
+			host = strings.Join(h.Hosts, " ")
+		} else if len(h.Addresses) > 0 {
+			host = strings.Join(h.Addresses, " ")
+		}
+		if h.Received == 0 {
+			fmt.Fprintf(w, "%d\t%s\t%.1f\t%d\n", h.TTL, host, h.Loss, h.Sent)
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%s\t%.1f\t%d\t%s\t%s\t%s\t%s\t%s\n", h.TTL, host, h.Loss, h.Sent,
+			millis(h.Last), millis(h.Avg), millis(h.Min), millis(h.Max), millis(h.Stddev))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !r.Reached {
+		fmt.Fprintln(out, "host not reached")
+	}
+	return nil
+}
+
+func millis(seconds float64) string {
+	return fmt.Sprintf("%.1fms", seconds*1000)
+}
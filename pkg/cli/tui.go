@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"glass/pkg/collectors"
+	"glass/pkg/output"
+	"glass/pkg/rate"
+	"glass/pkg/scheduler"
+	"glass/pkg/store"
+	"glass/pkg/tui"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// tuiInterval is the dashboard's refresh rate when no interval is
+// configured; the usual 10s default is too slow to watch.
+const tuiInterval = 2 * time.Second
+
+func newTUICmd(a *app) *cobra.Command {
+	var by string
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Live terminal dashboard of CPU, memory, network, disk, processes and alerts",
+		Long: "tui runs the configured collectors and alert rules and redraws a dashboard on every\n" +
+			"collection: sparklines for CPU, memory, network and disk IO, filesystem usage, active\n" +
+			"alerts and a process table. Press c, m, i or f to sort processes by CPU, memory, IO or\n" +
+			"open files, and q to quit. It refreshes every --interval, or every 2s by default.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(collectors.ProcessSortKeys, by) {
+				return fmt.Errorf("invalid --by %q: must be one of %s", by, strings.Join(collectors.ProcessSortKeys, ", "))
+			}
+			cs, err := a.newCollectors()
+			if err != nil {
+				return err
+			}
+			latest := store.NewLatest()
+			sinks := output.Multi{latest}
+			engine, err := a.newAlertEngine()
+			if err != nil {
+				return err
+			}
+			if engine != nil {
+				sinks = append(sinks, engine)
+			}
+			sink := rate.New().Wrap(sinks)
+			schedule := a.schedule(0)
+			if schedule.DefaultInterval == 0 {
+				schedule.DefaultInterval = tuiInterval
+			}
+			sched := scheduler.New(schedule, cs, sink)
+
+			// Log lines would scribble over the dashboard.
+			logger := log.Logger
+			log.Logger = log.Output(io.Discard)
+			defer func() { log.Logger = logger }()
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			done := make(chan struct{})
+			go func() {
+				sched.Run(ctx)
+				close(done)
+			}()
+			opts := tui.Options{
+				Latest:   latest,
+				Interval: schedule.DefaultInterval,
+				SortBy:   by,
+				In:       os.Stdin,
+				Out:      os.Stdout,
+				Failing: func() []string {
+					var names []string
+					for _, st := range sched.Statuses() {
+						if st.ConsecutiveFailures > 0 {
+							names = append(names, st.Name)
+						}
+					}
+					return names
+				},
+			}
+			if engine != nil {
+				opts.Alerts = engine.Alerts
+			}
+			err = tui.Run(ctx, opts)
+			stop()
+			<-done
+			closeSink(sink)
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&by, "by", "cpu", "sort processes by: "+strings.Join(collectors.ProcessSortKeys, ", "))
+	return cmd
+}
@@ -0,0 +1,207 @@
+// Package cloud identifies the instance glass runs on from its cloud
+// provider's metadata service.
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metadataHost is the link-local address every supported provider serves
+// its metadata on, so no DNS lookup is needed.
+const metadataHost = "http://169.254.169.254"
+
+// Providers lists the providers Detect knows.
+var Providers = []string{"aws", "gcp", "azure", "digitalocean"}
+
+// Metadata describes the instance.
+type Metadata struct {
+	Provider     string `json:"provider"`
+	InstanceID   string `json:"instance_id"`
+	InstanceType string `json:"instance_type,omitempty"`
+	Region       string `json:"region,omitempty"`
+	Zone         string `json:"zone,omitempty"`
+}
+
+// Labels returns the metadata as cloud_* labels, leaving out unknown
+// values.
+func (m *Metadata) Labels() map[string]string {
+	labels := map[string]string{}
+	for k, v := range map[string]string{
+		"cloud_provider":      m.Provider,
+		"cloud_instance_id":   m.InstanceID,
+		"cloud_instance_type": m.InstanceType,
+		"cloud_region":        m.Region,
+		"cloud_zone":          m.Zone,
+	} {
+		if v != "" {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// ErrNotDetected is returned when no metadata service answered.
+var ErrNotDetected = errors.New("no cloud metadata service found")
+
+// Detect asks the metadata services of the given providers, all at once,
+// and returns the first answer. An empty list means all of Providers.
+func Detect(ctx context.Context, providers []string, timeout time.Duration) (*Metadata, error) {
+	if len(providers) == 0 {
+		providers = Providers
+	}
+	for _, p := range providers {
+		if _, ok := probes[p]; !ok {
+			return nil, fmt.Errorf("unknown cloud provider %q", p)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := &http.Client{
+		// Metadata services never redirect; a proxy or captive portal
+		// answering for them would.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		// The link-local address must not go through a configured proxy.
+		Transport: &http.Transport{Proxy: nil},
+	}
+
+	type result struct {
+		md  *Metadata
+		err error
+	}
+	results := make(chan result, len(providers))
+	for _, p := range providers {
+		go func() {
+			md, err := probes[p](ctx, client)
+			if md != nil {
+				md.Provider = p
+			}
+			results <- result{md, err}
+		}()
+	}
+	for range providers {
+		if r := <-results; r.err == nil {
+			return r.md, nil
+		}
+	}
+	return nil, ErrNotDetected
+}
+
+var probes = map[string]func(context.Context, *http.Client) (*Metadata, error){
+	"aws":          probeAWS,
+	"gcp":          probeGCP,
+	"azure":        probeAzure,
+	"digitalocean": probeDigitalOcean,
+}
+
+func probeAWS(ctx context.Context, client *http.Client) (*Metadata, error) {
+	// IMDSv2 needs a session token first.
+	token, err := fetch(ctx, client, http.MethodPut, metadataHost+"/latest/api/token",
+		map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+	if err != nil {
+		return nil, err
+	}
+	data, err := fetch(ctx, client, http.MethodGet, metadataHost+"/latest/dynamic/instance-identity/document",
+		map[string]string{"X-aws-ec2-metadata-token": string(token)})
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		InstanceID       string `json:"instanceId"`
+		InstanceType     string `json:"instanceType"`
+		Region           string `json:"region"`
+		AvailabilityZone string `json:"availabilityZone"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil || doc.InstanceID == "" {
+		return nil, errors.New("not an EC2 identity document")
+	}
+	return &Metadata{InstanceID: doc.InstanceID, InstanceType: doc.InstanceType, Region: doc.Region, Zone: doc.AvailabilityZone}, nil
+}
+
+func probeGCP(ctx context.Context, client *http.Client) (*Metadata, error) {
+	data, err := fetch(ctx, client, http.MethodGet, metadataHost+"/computeMetadata/v1/instance/?recursive=true",
+		map[string]string{"Metadata-Flavor": "Google"})
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		ID          json.Number `json:"id"`
+		MachineType string      `json:"machineType"`
+		Zone        string      `json:"zone"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil || doc.ID == "" {
+		return nil, errors.New("not a GCE instance document")
+	}
+	// machineType and zone are resource paths such as
+	// projects/123/zones/us-central1-a.
+	zone := path.Base(doc.Zone)
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+	return &Metadata{InstanceID: doc.ID.String(), InstanceType: path.Base(doc.MachineType), Region: region, Zone: zone}, nil
+}
+
+func probeAzure(ctx context.Context, client *http.Client) (*Metadata, error) {
+	data, err := fetch(ctx, client, http.MethodGet, metadataHost+"/metadata/instance/compute?api-version=2021-02-01",
+		map[string]string{"Metadata": "true"})
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		VMID     string `json:"vmId"`
+		VMSize   string `json:"vmSize"`
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil || doc.VMID == "" {
+		return nil, errors.New("not an Azure compute document")
+	}
+	md := &Metadata{InstanceID: doc.VMID, InstanceType: doc.VMSize, Region: doc.Location}
+	if doc.Zone != "" {
+		md.Zone = doc.Location + "-" + doc.Zone
+	}
+	return md, nil
+}
+
+func probeDigitalOcean(ctx context.Context, client *http.Client) (*Metadata, error) {
+	data, err := fetch(ctx, client, http.MethodGet, metadataHost+"/metadata/v1.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		DropletID int64  `json:"droplet_id"`
+		Region    string `json:"region"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil || doc.DropletID == 0 {
+		return nil, errors.New("not a droplet document")
+	}
+	return &Metadata{InstanceID: strconv.FormatInt(doc.DropletID, 10), Region: doc.Region}, nil
+}
+
+func fetch(ctx context.Context, client *http.Client, method, url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata service returned %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
@@ -0,0 +1,137 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// apacheScoreboard names the worker states in the mod_status scoreboard.
+var apacheScoreboard = map[rune]string{
+	'_': "waiting",
+	'S': "starting",
+	'R': "reading",
+	'W': "sending",
+	'K': "keepalive",
+	'D': "dns_lookup",
+	'C': "closing",
+	'L': "logging",
+	'G': "finishing",
+	'I': "idle_cleanup",
+	'.': "open",
+}
+
+type ApacheCollector struct {
+	// URL is the machine-readable status page, usually ending in ?auto.
+	URL string `json:"url"`
+
+	client *http.Client
+}
+
+func NewApacheCollector(cfg config.CollectorConfig) (Collector, error) {
+	a := &ApacheCollector{URL: "http://127.0.0.1/server-status?auto"}
+	if err := cfg.Decode(a); err != nil {
+		return nil, err
+	}
+	a.client = &http.Client{}
+	return a, nil
+}
+
+func (a *ApacheCollector) Name() string {
+	return "apache"
+}
+
+func (a *ApacheCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("getting server status: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getting server status: %s", resp.Status)
+	}
+	status, err := parseApacheStatus(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading server status: %w", err)
+	}
+
+	b := metric.NewBuilder(time.Now())
+	num := func(key string) (float64, bool) {
+		v, err := strconv.ParseFloat(status[key], 64)
+		return v, err == nil
+	}
+	if v, ok := num("BusyWorkers"); ok {
+		b.Gauge("apache.busy_workers", v, "")
+	}
+	if v, ok := num("IdleWorkers"); ok {
+		b.Gauge("apache.idle_workers", v, "")
+	}
+	if busy, ok := num("BusyWorkers"); ok {
+		if idle, ok := num("IdleWorkers"); ok && busy+idle > 0 {
+			b.Gauge("apache.busy_workers_percent", 100*busy/(busy+idle), "percent")
+		}
+	}
+	if v, ok := num("Total Accesses"); ok {
+		b.Counter("apache.requests", v, "")
+	}
+	if v, ok := num("Total kBytes"); ok {
+		b.Counter("apache.bytes", v*1024, "bytes")
+	}
+	if v, ok := num("ServerUptimeSeconds"); ok {
+		b.Gauge("apache.uptime", v, "seconds")
+	} else if v, ok := num("Uptime"); ok {
+		b.Gauge("apache.uptime", v, "seconds")
+	}
+	if v, ok := num("ConnsTotal"); ok {
+		b.Gauge("apache.connections", v, "")
+	}
+
+	if board, ok := status["Scoreboard"]; ok {
+		counts := make(map[string]int, len(apacheScoreboard))
+		for _, state := range apacheScoreboard {
+			counts[state] = 0
+		}
+		for _, r := range board {
+			if state, ok := apacheScoreboard[r]; ok {
+				counts[state]++
+			}
+		}
+		for state, n := range counts {
+			b.Gauge("apache.scoreboard", float64(n), "", "state", state)
+		}
+	}
+	return b.Metrics(), nil
+}
+
+// parseApacheStatus reads the "Key: value" lines of server-status?auto.
+func parseApacheStatus(r io.Reader) (map[string]string, error) {
+	status := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		status[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := status["Scoreboard"]; !ok {
+		return nil, errors.New("no scoreboard in response, check that the URL is the ?auto status page")
+	}
+	return status, nil
+}
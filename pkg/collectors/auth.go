@@ -0,0 +1,202 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+// sshFailurePattern matches sshd's "Failed password for root from
+// 192.0.2.1 port 4242 ssh2" and "Failed publickey for invalid user admin
+// from ...": the method, whether the user exists, and the source.
+var sshFailurePattern = regexp.MustCompile(`Failed (\S+) for (invalid user )?.*? from (\S+) port \d+`)
+
+// sshFailureReasons are the reasons failures are counted by, so each is
+// reported from the first collection.
+var sshFailureReasons = []string{"invalid_user", "password", "publickey", "keyboard-interactive", "other"}
+
+// AuthCollector reports brute-force pressure: how many addresses each of
+// fail2ban's jails is failing and banning, and failed SSH logins read from
+// the auth log or, on hosts that only log to the journal, from journalctl. Only
+// failures logged since glass started are counted. fail2ban-client needs
+// root to reach fail2ban's socket.
+type AuthCollector struct {
+	// AuthLog is where sshd logs: /var/log/auth.log on Debian and
+	// /var/log/secure on Red Hat by default, whichever exists.
+	AuthLog        string `json:"auth_log"`
+	Fail2banClient string `json:"fail2ban_client"`
+	Journalctl     string `json:"journalctl"`
+
+	noFail2ban sync.Once
+	noLog      sync.Once
+
+	mu       sync.Mutex
+	tail     logTail
+	journal  journalTail
+	failures map[string]float64
+}
+
+func NewAuthCollector(cfg config.CollectorConfig) (Collector, error) {
+	a := &AuthCollector{Fail2banClient: "fail2ban-client", Journalctl: "journalctl"}
+	if err := cfg.Decode(a); err != nil {
+		return nil, err
+	}
+	if a.AuthLog == "" {
+		for _, path := range []string{"/var/log/auth.log", "/var/log/secure"} {
+			if _, err := os.Stat(path); err == nil {
+				a.AuthLog = path
+				break
+			}
+		}
+	}
+	a.tail.path = a.AuthLog
+	// OpenSSH 9.8 and later log from sshd-session instead of sshd.
+	a.journal = journalTail{journalctl: a.Journalctl, output: "cat", matches: []string{"_COMM=sshd", "_COMM=sshd-session"}}
+	return a, nil
+}
+
+func (a *AuthCollector) Name() string {
+	return "auth"
+}
+
+func (a *AuthCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	if err := a.addFail2ban(ctx, b); err != nil {
+		errs = append(errs, err)
+	}
+	if err := a.addSSHFailures(ctx, b); err != nil {
+		errs = append(errs, err)
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// addFail2ban reports each jail from `fail2ban-client status`, which
+// lists them as "`- Jail list:	sshd, nginx-http-auth", and
+// `fail2ban-client status JAIL`, which has lines like
+// "|  |- Currently failed:	3" and "   |- Total banned:	12".
+func (a *AuthCollector) addFail2ban(ctx context.Context, b *metric.Builder) error {
+	out, err := runCommand(ctx, a.Fail2banClient, "status")
+	if errors.Is(err, exec.ErrNotFound) {
+		a.noFail2ban.Do(func() { log.Info().Msg("fail2ban-client not found; not reporting fail2ban jails") })
+		return nil
+	}
+	if err != nil {
+		// The client is installed but the server isn't running.
+		b.Gauge("auth.fail2ban_up", 0, "")
+		return err
+	}
+	b.Gauge("auth.fail2ban_up", 1, "")
+	var jails []string
+	if list, ok := fail2banFields(string(out))["Jail list"]; ok {
+		for _, jail := range strings.Split(list, ",") {
+			if jail = strings.TrimSpace(jail); jail != "" {
+				jails = append(jails, jail)
+			}
+		}
+	}
+	b.Gauge("auth.fail2ban_jails", float64(len(jails)), "")
+
+	var errs []error
+	for _, jail := range jails {
+		out, err := runCommand(ctx, a.Fail2banClient, "status", jail)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fields := fail2banFields(string(out))
+		labels := []string{"jail", jail}
+		for _, f := range []struct {
+			field, name string
+			counter     bool
+		}{
+			{"Currently failed", "auth.fail2ban_failed", false},
+			{"Total failed", "auth.fail2ban_total_failed", true},
+			{"Currently banned", "auth.fail2ban_banned", false},
+			{"Total banned", "auth.fail2ban_total_banned", true},
+		} {
+			v, err := strconv.ParseFloat(fields[f.field], 64)
+			if err != nil {
+				continue
+			}
+			if f.counter {
+				b.Counter(f.name, v, "", labels...)
+			} else {
+				b.Gauge(f.name, v, "", labels...)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fail2banFields reads "name:	value" lines, ignoring the tree drawing.
+func fail2banFields(out string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimLeft(line, "|`- ")
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			fields[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return fields
+}
+
+// addSSHFailures counts the failed SSH logins logged since the last
+// collection, and how many sources they came from.
+func (a *AuthCollector) addSSHFailures(ctx context.Context, b *metric.Builder) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var (
+		lines []string
+		err   error
+	)
+	if a.AuthLog != "" {
+		lines, err = a.tail.read()
+	} else {
+		lines, err = a.journal.read(ctx)
+		if errors.Is(err, exec.ErrNotFound) {
+			a.noLog.Do(func() { log.Info().Msg("No auth log or journalctl; not counting failed SSH logins") })
+			return nil
+		}
+	}
+	if a.failures == nil {
+		a.failures = map[string]float64{}
+	}
+
+	fresh := 0
+	sources := map[string]bool{}
+	for _, line := range lines {
+		m := sshFailurePattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		reason := m[1]
+		if m[2] != "" {
+			reason = "invalid_user"
+		} else if reason != "password" && reason != "publickey" && reason != "keyboard-interactive" {
+			reason = "other"
+		}
+		a.failures[reason]++
+		fresh++
+		sources[m[3]] = true
+	}
+	for _, reason := range sshFailureReasons {
+		b.Counter("auth.ssh_failures", a.failures[reason], "", "reason", reason)
+	}
+	b.Gauge("auth.ssh_new_failures", float64(fresh), "")
+	b.Gauge("auth.ssh_new_failure_sources", float64(len(sources)), "")
+	return err
+}
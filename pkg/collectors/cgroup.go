@@ -0,0 +1,366 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupUnlimited is the smallest value cgroup v1 uses for "no limit",
+// which is the largest multiple of the page size an int64 can hold.
+const cgroupUnlimited = 1 << 62
+
+// CgroupCollector reports the limits and usage of cgroups, by default the
+// one glass runs in, so a container that is short of memory or CPU shows
+// it even when the host isn't. Both cgroup v2 and the v1 controllers are
+// read.
+type CgroupCollector struct {
+	// Cgroups are cgroup paths, such as /system.slice/mysql.service, to
+	// report instead of glass's own. A missing one is an error.
+	Cgroups []string `json:"cgroups"`
+}
+
+func NewCgroupCollector(cfg config.CollectorConfig) (Collector, error) {
+	c := &CgroupCollector{}
+	if err := cfg.Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *CgroupCollector) Name() string {
+	return "cgroup"
+}
+
+func (c *CgroupCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	own, err := ownCgroups()
+	if err != nil {
+		return nil, fmt.Errorf("reading /proc/self/cgroup: %w", err)
+	}
+	_, err = os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	unified := err == nil
+
+	paths, self := c.Cgroups, len(c.Cgroups) == 0
+	if self {
+		if unified {
+			paths = []string{own[""]}
+		} else {
+			paths = []string{own["memory"]}
+		}
+	}
+
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+	for _, path := range paths {
+		var err error
+		if unified {
+			err = addCgroupV2(b, path, self)
+		} else {
+			err = addCgroupV1(b, path, own, self)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading cgroup %s: %w", path, err))
+		}
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// ownCgroups maps each v1 controller, and "" for v2, to the cgroup glass
+// is in, from lines of /proc/self/cgroup such as "4:memory:/docker/abc"
+// or "0::/system.slice/glass.service".
+func ownCgroups() (map[string]string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return nil, err
+	}
+	own := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			own[controller] = parts[2]
+		}
+	}
+	return own, nil
+}
+
+// cgroupDir is where a cgroup's files are under a hierarchy mount. In a
+// container without its own cgroup namespace, /proc/self/cgroup names the
+// host's path while the container's cgroup is mounted at the root, so
+// glass's own cgroup falls back to the root; configured ones must exist.
+func cgroupDir(mount, path string, self bool) (string, error) {
+	dir := filepath.Join(mount, path)
+	if _, err := os.Stat(dir); err != nil {
+		if self && errors.Is(err, fs.ErrNotExist) {
+			return mount, nil
+		}
+		return "", err
+	}
+	return dir, nil
+}
+
+func addCgroupV2(b *metric.Builder, path string, self bool) error {
+	dir, err := cgroupDir(cgroupRoot, path, self)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cgroup.procs")); err != nil {
+		return err
+	}
+	labels := []string{"cgroup", path}
+
+	// cpu.max is "<quota> <period>", or "max <period>" without a limit.
+	if f := strings.Fields(readSysfsString(filepath.Join(dir, "cpu.max"))); len(f) == 2 && f[0] != "max" {
+		quota, _ := strconv.ParseFloat(f[0], 64)
+		period, _ := strconv.ParseFloat(f[1], 64)
+		if period > 0 {
+			b.Gauge("cgroup.cpu_limit", quota/period, "cores", labels...)
+		}
+	}
+	if stat, err := readKeyValues(filepath.Join(dir, "cpu.stat")); err == nil {
+		usec := func(field, name string) {
+			if v, err := strconv.ParseFloat(stat[field], 64); err == nil {
+				b.Counter(name, v/1e6, "seconds", labels...)
+			}
+		}
+		usec("usage_usec", "cgroup.cpu_usage")
+		usec("throttled_usec", "cgroup.cpu_throttled_time")
+		addCgroupCounter(b, stat, "nr_periods", "cgroup.cpu_periods", labels)
+		addCgroupCounter(b, stat, "nr_throttled", "cgroup.cpu_throttled_periods", labels)
+	}
+
+	usage, err := readSysfsFloat(filepath.Join(dir, "memory.current"))
+	if err == nil {
+		addCgroupMemory(b, usage, readCgroupLimit(filepath.Join(dir, "memory.max")), labels)
+		if high := readCgroupLimit(filepath.Join(dir, "memory.high")); high > 0 {
+			b.Gauge("cgroup.memory_high", high, "bytes", labels...)
+		}
+		if swap, err := readSysfsFloat(filepath.Join(dir, "memory.swap.current")); err == nil {
+			b.Gauge("cgroup.memory_swap_usage", swap, "bytes", labels...)
+		}
+	}
+	if stat, err := readKeyValues(filepath.Join(dir, "memory.stat")); err == nil {
+		addCgroupGauge(b, stat, "anon", "cgroup.memory_anon", "bytes", labels)
+		addCgroupGauge(b, stat, "file", "cgroup.memory_cache", "bytes", labels)
+	}
+	// memory.events counts how often usage hit memory.max and how often
+	// the OOM killer ran because of it.
+	if events, err := readKeyValues(filepath.Join(dir, "memory.events")); err == nil {
+		addCgroupCounter(b, events, "max", "cgroup.memory_limit_hits", labels)
+		addCgroupCounter(b, events, "oom_kill", "cgroup.memory_oom_kills", labels)
+	}
+
+	addCgroupPids(b, dir, labels)
+
+	// io.max and io.stat have a line per device: "8:0 rbps=max wbps=1048576
+	// riops=max wiops=max" and "8:0 rbytes=1 wbytes=2 rios=3 wios=4 ...".
+	for _, line := range readLines(filepath.Join(dir, "io.max")) {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		for _, kv := range f[1:] {
+			k, v, _ := strings.Cut(kv, "=")
+			if limit, err := strconv.ParseFloat(v, 64); err == nil {
+				b.Gauge("cgroup.io_limit", limit, "", "cgroup", path, "device", blockDeviceName(f[0]), "limit", k)
+			}
+		}
+	}
+	for _, line := range readLines(filepath.Join(dir, "io.stat")) {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		devLabels := []string{"cgroup", path, "device", blockDeviceName(f[0])}
+		for _, kv := range f[1:] {
+			k, v, _ := strings.Cut(kv, "=")
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			switch k {
+			case "rbytes":
+				b.Counter("cgroup.io_read_bytes", n, "bytes", devLabels...)
+			case "wbytes":
+				b.Counter("cgroup.io_write_bytes", n, "bytes", devLabels...)
+			case "rios":
+				b.Counter("cgroup.io_reads", n, "", devLabels...)
+			case "wios":
+				b.Counter("cgroup.io_writes", n, "", devLabels...)
+			}
+		}
+	}
+	return nil
+}
+
+func addCgroupV1(b *metric.Builder, path string, own map[string]string, self bool) error {
+	// A path from the config applies to every controller; glass's own
+	// cgroup can differ between them. A configured cgroup may be missing
+	// from some controllers, whose files then fail to read, but not from
+	// both cpu and memory.
+	var missing error
+	controllerDir := func(controller string) string {
+		mount, p := filepath.Join(cgroupRoot, controller), path
+		if ownPath, ok := own[controller]; ok && self {
+			p = ownPath
+		}
+		dir, err := cgroupDir(mount, p, self)
+		if err != nil {
+			if missing == nil {
+				missing = err
+			}
+			return filepath.Join(mount, p)
+		}
+		return dir
+	}
+	labels := []string{"cgroup", path}
+	found := false
+
+	cpu := controllerDir("cpu")
+	if quota, err := readSysfsFloat(filepath.Join(cpu, "cpu.cfs_quota_us")); err == nil {
+		found = true
+		if period, err := readSysfsFloat(filepath.Join(cpu, "cpu.cfs_period_us")); err == nil && quota > 0 && period > 0 {
+			b.Gauge("cgroup.cpu_limit", quota/period, "cores", labels...)
+		}
+	}
+	if stat, err := readKeyValues(filepath.Join(cpu, "cpu.stat")); err == nil {
+		addCgroupCounter(b, stat, "nr_periods", "cgroup.cpu_periods", labels)
+		addCgroupCounter(b, stat, "nr_throttled", "cgroup.cpu_throttled_periods", labels)
+		if ns, err := strconv.ParseFloat(stat["throttled_time"], 64); err == nil {
+			b.Counter("cgroup.cpu_throttled_time", ns/1e9, "seconds", labels...)
+		}
+	}
+	if ns, err := readSysfsFloat(filepath.Join(controllerDir("cpuacct"), "cpuacct.usage")); err == nil {
+		b.Counter("cgroup.cpu_usage", ns/1e9, "seconds", labels...)
+	}
+
+	memory := controllerDir("memory")
+	if usage, err := readSysfsFloat(filepath.Join(memory, "memory.usage_in_bytes")); err == nil {
+		found = true
+		addCgroupMemory(b, usage, readCgroupLimit(filepath.Join(memory, "memory.limit_in_bytes")), labels)
+		if failcnt, err := readSysfsFloat(filepath.Join(memory, "memory.failcnt")); err == nil {
+			b.Counter("cgroup.memory_limit_hits", failcnt, "", labels...)
+		}
+	}
+	if stat, err := readKeyValues(filepath.Join(memory, "memory.stat")); err == nil {
+		addCgroupGauge(b, stat, "rss", "cgroup.memory_anon", "bytes", labels)
+		addCgroupGauge(b, stat, "cache", "cgroup.memory_cache", "bytes", labels)
+	}
+	if oom, err := readKeyValues(filepath.Join(memory, "memory.oom_control")); err == nil {
+		addCgroupCounter(b, oom, "oom_kill", "cgroup.memory_oom_kills", labels)
+	}
+
+	addCgroupPids(b, controllerDir("pids"), labels)
+
+	// The throttle files have a "<major>:<minor> <limit>" line per limited
+	// device.
+	blkio := controllerDir("blkio")
+	for _, t := range []struct{ file, limit string }{
+		{"blkio.throttle.read_bps_device", "rbps"},
+		{"blkio.throttle.write_bps_device", "wbps"},
+		{"blkio.throttle.read_iops_device", "riops"},
+		{"blkio.throttle.write_iops_device", "wiops"},
+	} {
+		for _, line := range readLines(filepath.Join(blkio, t.file)) {
+			if f := strings.Fields(line); len(f) == 2 {
+				if v, err := strconv.ParseFloat(f[1], 64); err == nil {
+					b.Gauge("cgroup.io_limit", v, "", "cgroup", path, "device", blockDeviceName(f[0]), "limit", t.limit)
+				}
+			}
+		}
+	}
+	for _, line := range readLines(filepath.Join(blkio, "blkio.throttle.io_service_bytes")) {
+		if f := strings.Fields(line); len(f) == 3 {
+			v, err := strconv.ParseFloat(f[2], 64)
+			if err != nil {
+				continue
+			}
+			switch f[1] {
+			case "Read":
+				b.Counter("cgroup.io_read_bytes", v, "bytes", "cgroup", path, "device", blockDeviceName(f[0]))
+			case "Write":
+				b.Counter("cgroup.io_write_bytes", v, "bytes", "cgroup", path, "device", blockDeviceName(f[0]))
+			}
+		}
+	}
+
+	if !found && missing != nil {
+		return missing
+	}
+	if !found {
+		return errors.New("no cpu or memory controller found")
+	}
+	return nil
+}
+
+func addCgroupMemory(b *metric.Builder, usage, limit float64, labels []string) {
+	b.Gauge("cgroup.memory_usage", usage, "bytes", labels...)
+	if limit > 0 {
+		b.Gauge("cgroup.memory_limit", limit, "bytes", labels...)
+		b.Gauge("cgroup.memory_used_percent", 100*usage/limit, "percent", labels...)
+	}
+}
+
+func addCgroupPids(b *metric.Builder, dir string, labels []string) {
+	if current, err := readSysfsFloat(filepath.Join(dir, "pids.current")); err == nil {
+		b.Gauge("cgroup.pids", current, "", labels...)
+		if limit := readCgroupLimit(filepath.Join(dir, "pids.max")); limit > 0 {
+			b.Gauge("cgroup.pids_limit", limit, "", labels...)
+		}
+	}
+}
+
+func addCgroupCounter(b *metric.Builder, values map[string]string, field, name string, labels []string) {
+	if v, err := strconv.ParseFloat(values[field], 64); err == nil {
+		b.Counter(name, v, "", labels...)
+	}
+}
+
+func addCgroupGauge(b *metric.Builder, values map[string]string, field, name, unit string, labels []string) {
+	if v, err := strconv.ParseFloat(values[field], 64); err == nil {
+		b.Gauge(name, v, unit, labels...)
+	}
+}
+
+// readCgroupLimit reads a limit file, returning 0 for "max", the v1
+// equivalent and missing files.
+func readCgroupLimit(path string) float64 {
+	s := readSysfsString(path)
+	if s == "" || s == "max" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v >= cgroupUnlimited {
+		return 0
+	}
+	return v
+}
+
+func readLines(path string) []string {
+	s := readSysfsString(path)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// blockDeviceName turns "8:0" into "sda" when sysfs knows the device.
+func blockDeviceName(majorMinor string) string {
+	if target, err := os.Readlink(filepath.Join("/sys/dev/block", majorMinor)); err == nil {
+		return filepath.Base(target)
+	}
+	return majorMinor
+}
@@ -1,14 +1,163 @@
 package collectors
 
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
 type Collector interface {
-	Collector() error
+	Name() string
+	Collect(ctx context.Context) ([]metric.Metric, error)
+}
+
+// Factory builds a collector from its config.
+type Factory func(cfg config.CollectorConfig) (Collector, error)
+
+// Registration is a collector as known to the registry.
+type Registration struct {
+	Name        string
+	Description string
+	// Enabled is whether the collector runs when the config doesn't say.
+	Enabled bool
+	// Linux is whether the collector reads /proc, /sys, netlink, the kernel
+	// log or the journal, or runs tools only Linux has, so it's only
+	// registered there.
+	Linux bool
+	New   Factory
+}
+
+// registry holds collectors in registration order, which is also the
+// order they are listed and run in.
+var registry []Registration
+
+// Register adds a collector under name. It is meant to be called from
+// init functions and panics if the name is taken, as database/sql does for
+// drivers.
+func Register(name string, factory Factory, description string, enabled bool) {
+	register(Registration{Name: name, Description: description, Enabled: enabled, New: factory})
+}
+
+// RegisterLinux adds a collector that only has something to report on
+// Linux, and is a no-op elsewhere.
+func RegisterLinux(name string, factory Factory, description string, enabled bool) {
+	register(Registration{Name: name, Description: description, Enabled: enabled, Linux: true, New: factory})
+}
+
+func register(r Registration) {
+	if _, ok := lookup(r.Name); ok {
+		panic("collectors: Register called twice for " + r.Name)
+	}
+	if r.Linux && runtime.GOOS != "linux" {
+		return
+	}
+	registry = append(registry, r)
 }
 
-func RegisterCollectors() []Collector {
-	return []Collector{
-		&CPUCollector{},
-		&MemoryCollector{},
-		&DiskCollector{},
-		&NetworkCollector{},
+func lookup(name string) (Registration, bool) {
+	for _, r := range registry {
+		if r.Name == name {
+			return r, true
+		}
 	}
+	return Registration{}, false
+}
+
+// Collectors that need a service which isn't present on every host are off
+// unless the config enables them.
+func init() {
+	Register("cpu", NewCPUCollector, "CPU usage, times, cores and frequency", true)
+	Register("mem", func(config.CollectorConfig) (Collector, error) { return &MemoryCollector{}, nil }, "Memory, swap and commit usage", true)
+	Register("disk", NewDiskCollector, "Filesystem space and inode usage", true)
+	Register("net", NewNetworkCollector, "Per-interface traffic, errors and link state; TCP connection states", true)
+	Register("proc", NewProcessCollector, "Top processes by CPU, memory, IO or open files", true)
+	Register("host", func(config.CollectorConfig) (Collector, error) { return &HostCollector{}, nil }, "Host identity, uptime and load averages", true)
+	RegisterLinux("vmstat", func(config.CollectorConfig) (Collector, error) { return &VMStatCollector{}, nil }, "Context switches, interrupts, forks, paging and swapping", true)
+	RegisterLinux("psi", func(config.CollectorConfig) (Collector, error) { return &PSICollector{}, nil }, "CPU, memory and IO pressure stall information", true)
+	RegisterLinux("sensors", func(config.CollectorConfig) (Collector, error) { return &SensorsCollector{}, nil }, "Temperatures and fan speeds", true)
+	RegisterLinux("fd", NewFDCollector, "Open file descriptors against system and per-process limits", true)
+	Register("gpu", NewGPUCollector, "NVIDIA and AMD GPU utilization, memory, temperature and power", true)
+	RegisterLinux("conntrack", NewConntrackCollector, "Connection tracking table usage, drops and entries per protocol", false)
+	RegisterLinux("firewall", NewFirewallCollector, "Firewall backend, rule counts, default policies and whether ports are open", false)
+	Register("listen", func(config.CollectorConfig) (Collector, error) { return &ListenCollector{}, nil }, "Listening TCP and UDP sockets, their owners and exposure; warns on new ones", false)
+	RegisterLinux("routing", func(config.CollectorConfig) (Collector, error) { return &RoutingCollector{}, nil }, "Neighbor table usage against gc_thresh, route counts and default gateway changes", false)
+	RegisterLinux("tcp", func(config.CollectorConfig) (Collector, error) { return &TCPCollector{}, nil }, "TCP retransmits, resets, listen queue overflows and SYN cookies", false)
+	RegisterLinux("sockets", NewSocketsCollector, "Ephemeral port, orphan, TIME_WAIT and socket memory usage against their limits", false)
+	RegisterLinux("ntp", NewNTPCollector, "Clock synchronization, offset, jitter and stratum from the kernel and chronyd or ntpd", false)
+	RegisterLinux("entropy", func(config.CollectorConfig) (Collector, error) { return &EntropyCollector{}, nil }, "Kernel entropy pool and processes blocked waiting for random bytes", false)
+	RegisterLinux("lsm", NewLSMCollector, "SELinux and AppArmor modes, complain mode profiles and denials from the audit log", false)
+	RegisterLinux("updates", NewUpdatesCollector, "Pending and security package updates, outdated kernel and reboot-required flag", false)
+	RegisterLinux("auth", NewAuthCollector, "fail2ban jails and bans, and failed SSH logins from the auth log or journal", false)
+	RegisterLinux("sessions", func(config.CollectorConfig) (Collector, error) { return &SessionsCollector{}, nil }, "Logged-in users and their sessions; warns on new ones", false)
+	RegisterLinux("cron", NewCronCollector, "Last run, exit status and overdue state of configured cron jobs", false)
+	Register("logfiles", NewLogFilesCollector, "Size, growth rate and last write of log files", false)
+	Register("dirsize", NewDirSizeCollector, "Disk use of configured directories and their largest entries", false)
+	RegisterLinux("cgroup", NewCgroupCollector, "cgroup CPU, memory, IO and pids limits, usage and throttling", false)
+	RegisterLinux("numa", func(config.CollectorConfig) (Collector, error) { return &NUMACollector{}, nil }, "Hugepage pools, transparent hugepages and per-NUMA-node memory", false)
+	RegisterLinux("kmsg", func(config.CollectorConfig) (Collector, error) { return &KmsgCollector{}, nil }, "OOM kills, IO errors, read-only remounts and link flaps from the kernel log", false)
+	RegisterLinux("oom", func(config.CollectorConfig) (Collector, error) { return &OOMCollector{}, nil }, "OOM killer kills by process, from the kernel log", false)
+	Register("smart", NewSmartCollector, "Drive health, bad sectors, wear and temperature from smartctl", false)
+	RegisterLinux("mdraid", func(config.CollectorConfig) (Collector, error) { return &MDRaidCollector{}, nil }, "Software RAID array state, failed disks and sync progress", false)
+	RegisterLinux("lvm", NewLVMCollector, "LVM volume group space, logical volumes and thin pool usage", false)
+	RegisterLinux("zfs", NewZFSCollector, "ZFS pool health, capacity, scrubs and ARC hit ratio", false)
+	RegisterLinux("nfs", NewNFSCollector, "NFS client and server RPC statistics and per-mount operation latency", false)
+	Register("ipmi", NewIPMICollector, "BMC sensors, power supplies, chassis intrusion and event log via ipmitool", false)
+	Register("power", NewPowerCollector, "UPS charge, load and runtime from NUT or apcupsd; laptop batteries", false)
+	Register("docker", NewDockerCollector, "Container state and resource usage from the Docker API", false)
+	RegisterLinux("systemd", NewSystemdCollector, "Unit states, restarts and failed units", false)
+	Register("mysql", NewMySQLCollector, "MySQL/MariaDB status, InnoDB and replication", false)
+	Register("apache", NewApacheCollector, "Apache mod_status workers and traffic", false)
+	Register("phpfpm", NewPHPFPMCollector, "PHP-FPM pool status over FastCGI or HTTP", false)
+	Register("redis", NewRedisCollector, "Redis INFO: memory, clients, keyspace, replication", false)
+	Register("memcached", NewMemcachedCollector, "memcached stats: hit ratio, evictions, connections, memory", false)
+	Register("varnish", NewVarnishCollector, "Varnish hit ratio, backend health and failures, threads and dropped sessions", false)
+	Register("elasticsearch", NewElasticsearchCollector, "Elasticsearch/OpenSearch cluster health, shards, heap and search/index latency", false)
+	Register("mongodb", NewMongoDBCollector, "MongoDB connections, operations, WiredTiger cache, lock queues and replication lag", false)
+	Register("rabbitmq", NewRabbitMQCollector, "RabbitMQ queue depths, unacked messages, consumers and node memory and disk alarms", false)
+	Register("supervisor", NewSupervisorCollector, "supervisord program and PM2 app states, uptime and restarts", false)
+	Register("mailqueue", NewMailQueueCollector, "Postfix or Exim mail queue length, oldest message age and top senders", false)
+	Register("tls", NewTLSCollector, "Certificate expiry and chain validity for endpoints and files", false)
+	Register("http", NewHTTPProbeCollector, "HTTP probes with phase timings, status and content checks", false)
+	Register("ping", NewPingCollector, "ICMP round-trip times and packet loss", false)
+}
+
+// RegisterCollectors builds every collector that the config leaves enabled.
+func RegisterCollectors(cfg *config.Config) ([]Collector, error) {
+	var cs []Collector
+	for _, r := range registry {
+		ccfg := cfg.Collector(r.Name)
+		if !ccfg.IsEnabled(r.Enabled) {
+			continue
+		}
+		c, err := r.New(ccfg)
+		if err != nil {
+			return nil, fmt.Errorf("configuring %s collector: %w", r.Name, err)
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
+
+// Available returns the names of all registered collectors.
+func Available() []string {
+	names := make([]string, 0, len(registry))
+	for _, r := range registry {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// Registered returns every registered collector.
+func Registered() []Registration {
+	return slices.Clone(registry)
+}
+
+// Enabled reports whether the named collector would run under cfg.
+func Enabled(cfg *config.Config, name string) bool {
+	r, ok := lookup(name)
+	return ok && cfg.Collector(name).IsEnabled(r.Enabled)
 }
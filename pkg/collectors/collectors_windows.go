@@ -0,0 +1,9 @@
+package collectors
+
+import "glass/pkg/config"
+
+func init() {
+	Register("winservices", NewWinServicesCollector, "Windows service states and start modes, and automatic services that failed", true)
+	Register("perfcounters", func(config.CollectorConfig) (Collector, error) { return &PerfCountersCollector{}, nil }, "Processor queue, committed bytes and disk queue length from performance counters", true)
+	Register("eventlog", NewEventLogCollector, "Errors and critical events in the System and Application event logs", true)
+}
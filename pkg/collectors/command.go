@@ -0,0 +1,36 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runCommand runs a tool and returns what it printed. Errors read
+// "running <tool>: exit status 1: <stderr>"; a missing tool satisfies
+// errors.Is(err, exec.ErrNotFound) and a failed one errors.As with
+// *exec.ExitError. The output is returned even then, for tools such as
+// smartctl that exit non-zero alongside a report.
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return runCmd(exec.CommandContext(ctx, name, args...))
+}
+
+// runCmd is runCommand for a command that needs more setting up, such as
+// its environment.
+func runCmd(cmd *exec.Cmd) ([]byte, error) {
+	name := filepath.Base(cmd.Path)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+				return out, fmt.Errorf("running %s: %w: %s", name, err, msg)
+			}
+		}
+		return out, fmt.Errorf("running %s: %w", name, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,171 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+const conntrackSysctl = "/proc/sys/net/netfilter"
+
+// conntrackStats maps the columns of /proc/net/stat/nf_conntrack, one hex
+// row per CPU, to counters. A drop is a new connection refused because the
+// table was full.
+var conntrackStats = []struct {
+	column string
+	name   string
+}{
+	{"drop", "conntrack.drops"},
+	{"early_drop", "conntrack.early_drops"},
+	{"insert_failed", "conntrack.insert_failed"},
+	{"invalid", "conntrack.invalid"},
+	{"search_restart", "conntrack.search_restarts"},
+}
+
+// ConntrackCollector reports how full the netfilter connection tracking
+// table is. When it fills, the kernel drops new connections and only logs
+// "nf_conntrack: table full, dropping packet".
+type ConntrackCollector struct {
+	// PerProtocol counts entries by protocol and TCP state, reading the
+	// whole table from /proc/net/nf_conntrack or, where the kernel doesn't
+	// have it, from `conntrack -L`.
+	PerProtocol bool `json:"per_protocol"`
+	// Conntrack is the conntrack binary from conntrack-tools.
+	Conntrack string `json:"conntrack"`
+
+	missing sync.Once
+	noTable sync.Once
+}
+
+func NewConntrackCollector(cfg config.CollectorConfig) (Collector, error) {
+	c := &ConntrackCollector{PerProtocol: true, Conntrack: "conntrack"}
+	if err := cfg.Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *ConntrackCollector) Name() string {
+	return "conntrack"
+}
+
+func (c *ConntrackCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	count, err := readSysfsFloat(filepath.Join(conntrackSysctl, "nf_conntrack_count"))
+	if errors.Is(err, os.ErrNotExist) {
+		// Without the nf_conntrack module there is no table to fill.
+		c.missing.Do(func() { log.Info().Msg("Connection tracking is not loaded") })
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading conntrack count: %w", err)
+	}
+
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+	b.Gauge("conntrack.entries", count, "")
+	if max, err := readSysfsFloat(filepath.Join(conntrackSysctl, "nf_conntrack_max")); err == nil {
+		b.Gauge("conntrack.max", max, "")
+		if max > 0 {
+			b.Gauge("conntrack.used_percent", 100*count/max, "percent")
+		}
+	}
+	if buckets, err := readSysfsFloat(filepath.Join(conntrackSysctl, "nf_conntrack_buckets")); err == nil {
+		b.Gauge("conntrack.buckets", buckets, "")
+	}
+	addConntrackStats(b)
+	if c.PerProtocol {
+		if err := c.addEntries(ctx, b); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func addConntrackStats(b *metric.Builder) {
+	lines := readLines("/proc/net/stat/nf_conntrack")
+	if len(lines) == 0 {
+		return
+	}
+	header := strings.Fields(lines[0])
+	totals := make([]float64, len(header))
+	for _, line := range lines[1:] {
+		for i, field := range strings.Fields(line) {
+			if v, err := strconv.ParseUint(field, 16, 64); err == nil && i < len(totals) {
+				totals[i] += float64(v)
+			}
+		}
+	}
+	for _, s := range conntrackStats {
+		if i := slices.Index(header, s.column); i >= 0 {
+			b.Counter(s.name, totals[i], "")
+		}
+	}
+}
+
+// addEntries counts entries, which look like
+// "ipv4 2 tcp 6 431999 ESTABLISHED src=... [ASSURED] ...", by layer 4
+// protocol, and TCP ones by state too.
+func (c *ConntrackCollector) addEntries(ctx context.Context, b *metric.Builder) error {
+	var r io.Reader
+	f, err := os.Open("/proc/net/nf_conntrack")
+	switch {
+	case err == nil:
+		defer f.Close()
+		r = f
+	case errors.Is(err, os.ErrNotExist):
+		out, err := runCommand(ctx, c.Conntrack, "-L", "-o", "extended")
+		if errors.Is(err, exec.ErrNotFound) {
+			c.noTable.Do(func() {
+				log.Info().Msg("Counting conntrack entries needs /proc/net/nf_conntrack or conntrack-tools")
+			})
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(out)
+	default:
+		return fmt.Errorf("reading conntrack table: %w", err)
+	}
+
+	protocols := map[string]float64{}
+	states := map[string]float64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		if len(f) < 6 {
+			continue
+		}
+		protocols[f[2]]++
+		if f[2] == "tcp" {
+			states[f[5]]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading conntrack table: %w", err)
+	}
+	for _, p := range slices.Sorted(maps.Keys(protocols)) {
+		b.Gauge("conntrack.protocol_entries", protocols[p], "", "protocol", p)
+	}
+	for _, s := range slices.Sorted(maps.Keys(states)) {
+		b.Gauge("conntrack.tcp_entries", states[s], "", "state", strings.ToLower(s))
+	}
+	return nil
+}
@@ -1,11 +1,32 @@
 package collectors
 
 import (
-	"github.com/rs/zerolog/log"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
 	"github.com/shirou/gopsutil/v4/cpu"
 )
 
 type CPUCollector struct {
+	// PerCore adds per-core times and usage percentages next to the aggregate.
+	PerCore bool `json:"per_core"`
+
+	mu   sync.Mutex
+	last map[string]cpu.TimesStat
+}
+
+func NewCPUCollector(cfg config.CollectorConfig) (Collector, error) {
+	c := &CPUCollector{PerCore: true}
+	if err := cfg.Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 type CPUInformation struct {
@@ -16,13 +37,8 @@ type CPUInformation struct {
 	VCPU   int     `json:"vCPU"`
 }
 
-type CPUTimesValues struct {
-	Timestamp int64         `json:"timestamp"`
-	CPUTimes  cpu.TimesStat `json:"cpu-times"`
-}
-
 func (c *CPUCollector) Name() string {
-	return "CPU Collector"
+	return "cpu"
 }
 
 func (c *CPUCollector) CPUInformation() (CPUInformation, error) {
@@ -30,6 +46,9 @@ func (c *CPUCollector) CPUInformation() (CPUInformation, error) {
 	if err != nil {
 		return CPUInformation{}, err
 	}
+	if len(cpuInfo) == 0 {
+		return CPUInformation{}, errors.New("no CPU info reported")
+	}
 	Info := CPUInformation{
 		Vendor: cpuInfo[0].VendorID,
 		Freq:   cpuInfo[0].Mhz,
@@ -40,26 +59,105 @@ func (c *CPUCollector) CPUInformation() (CPUInformation, error) {
 	return Info, nil
 }
 
-func (c *CPUCollector) Collector() error {
-	cpuInfo, err := cpu.Info()
+func (c *CPUCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	cpuInfo, err := cpu.InfoWithContext(ctx)
 	if err != nil {
-		log.Error().Err(err).Msg("Error getting CPU info")
+		errs = append(errs, fmt.Errorf("getting CPU info: %w", err))
+	} else if len(cpuInfo) > 0 {
+		info := cpuInfo[0]
+		b.Gauge("cpu.info", 1, "", "vendor", info.VendorID, "model", info.ModelName)
+		b.Gauge("cpu.frequency", info.Mhz, "MHz")
+		b.Gauge("cpu.cores", float64(info.Cores), "")
+		b.Gauge("cpu.cache_size", float64(info.CacheSize), "KB")
 	}
-	vendor := cpuInfo[0].VendorID
-	freq := cpuInfo[0].Mhz
-	cores := cpuInfo[0].Cores
-	cache := cpuInfo[0].CacheSize
-	vCPU, err := cpu.Counts(true)
+
+	vCPU, err := cpu.CountsWithContext(ctx, true)
 	if err != nil {
-		log.Error().Err(err).Msg("Error getting vCPU count")
+		errs = append(errs, fmt.Errorf("getting vCPU count: %w", err))
+	} else {
+		b.Gauge("cpu.vcpus", float64(vCPU), "")
 	}
-	log.Info().Str("vendor", vendor).Float64("freq", freq).Int("cores", int(cores)).Int("cache", int(cache)).Int("vCPU", vCPU).Msg("")
-	times, err := cpu.Times(false)
+
+	times, err := cpu.TimesWithContext(ctx, false)
 	if err != nil {
-		log.Error().Err(err).Msg("Error getting CPU times")
+		errs = append(errs, fmt.Errorf("getting CPU times: %w", err))
+	}
+	for _, t := range times {
+		addCPUTimes(b, t)
+	}
+	if c.PerCore {
+		perCore, err := cpu.TimesWithContext(ctx, true)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("getting per-core CPU times: %w", err))
+		}
+		for _, t := range perCore {
+			addCPUTimes(b, t)
+		}
+		times = append(times, perCore...)
+	}
+	c.addPercentages(b, times)
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// addPercentages reports how CPU time was split between modes since the
+// previous collection. On the first collection the split since boot is
+// reported instead, so a one-shot run still shows something meaningful
+// without sleeping.
+func (c *CPUCollector) addPercentages(b *metric.Builder, times []cpu.TimesStat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	last := make(map[string]cpu.TimesStat, len(times))
+	for _, t := range times {
+		last[t.CPU] = t
+		prev := c.last[t.CPU]
+		delta := cpu.TimesStat{
+			User:    t.User - prev.User,
+			Nice:    t.Nice - prev.Nice,
+			System:  t.System - prev.System,
+			Idle:    t.Idle - prev.Idle,
+			Iowait:  t.Iowait - prev.Iowait,
+			Irq:     t.Irq - prev.Irq,
+			Softirq: t.Softirq - prev.Softirq,
+			Steal:   t.Steal - prev.Steal,
+		}
+		// Guest time is already accounted for in user time.
+		total := delta.User + delta.Nice + delta.System + delta.Idle + delta.Iowait + delta.Irq + delta.Softirq + delta.Steal
+		if total <= 0 || delta.Idle < 0 {
+			// No time passed, or the counters went backwards (e.g. a CPU was
+			// hot-plugged); wait for the next sample.
+			continue
+		}
+		pct := func(v float64) float64 { return 100 * v / total }
+		b.Gauge("cpu.usage_percent", pct(total-delta.Idle-delta.Iowait), "percent", "cpu", t.CPU)
+		b.Gauge("cpu.idle_percent", pct(delta.Idle), "percent", "cpu", t.CPU)
+		b.Gauge("cpu.iowait_percent", pct(delta.Iowait), "percent", "cpu", t.CPU)
+		b.Gauge("cpu.steal_percent", pct(delta.Steal), "percent", "cpu", t.CPU)
+		b.Gauge("cpu.user_percent", pct(delta.User+delta.Nice), "percent", "cpu", t.CPU)
+		b.Gauge("cpu.system_percent", pct(delta.System+delta.Irq+delta.Softirq), "percent", "cpu", t.CPU)
+	}
+	c.last = last
+}
+
+func addCPUTimes(b *metric.Builder, t cpu.TimesStat) {
+	modes := []struct {
+		mode  string
+		value float64
+	}{
+		{"user", t.User},
+		{"system", t.System},
+		{"idle", t.Idle},
+		{"nice", t.Nice},
+		{"iowait", t.Iowait},
+		{"irq", t.Irq},
+		{"softirq", t.Softirq},
+		{"steal", t.Steal},
+		{"guest", t.Guest},
+		{"guest_nice", t.GuestNice},
 	}
-	for _, time := range times {
-		log.Info().Str("cpu", time.CPU).Float64("user", time.User).Float64("system", time.System).Float64("idle", time.Idle).Float64("nice", time.Nice).Float64("iowait", time.Iowait).Float64("irq", time.Irq).Float64("softirq", time.Softirq).Float64("steal", time.Steal).Float64("guest", time.Guest).Float64("guest-nice", time.GuestNice).Msg("")
+	for _, m := range modes {
+		b.Counter("cpu.time", m.value, "seconds", "cpu", t.CPU, "mode", m.mode)
 	}
-	return nil
 }
@@ -0,0 +1,238 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// A cron daemon's syslog line, with a traditional, RFC 3339 or (from
+	// journalctl -o short-unix) Unix timestamp: "Oct 14 03:00:01 web1
+	// CRON[4242]: (root) CMD (/usr/local/bin/backup.sh)".
+	cronLinePattern  = regexp.MustCompile(`^(\w{3} +\d+ [\d:]+|\S+) \S+ (?:CRON|CROND|crond)\[(\d+)\]: (.*)$`)
+	cronStartPattern = regexp.MustCompile(`^\((\S+)\) CMD \((.*)\)$`)
+	// Debian's cron logs "END" with -L 2, cronie logs "CMDEND".
+	cronEndPattern = regexp.MustCompile(`^\(\S+\) (?:CMD)?END \(`)
+	// Debian's cron logs failures from the process that started the job:
+	// "(CRON) error (grandchild #4243 failed with exit status 1)".
+	cronFailPattern = regexp.MustCompile(`^\(CRON\) error \(grandchild #\d+ failed with exit status (\d+)\)`)
+)
+
+// CronJob is a job expected to run at least every Interval, recognized by
+// Match, a regular expression, matching its command line.
+type CronJob struct {
+	Name     string          `json:"name"`
+	Match    string          `json:"match"`
+	User     string          `json:"user"`
+	Interval config.Duration `json:"interval"`
+
+	match *regexp.Regexp
+}
+
+// cronJobState is what has been seen of a job.
+type cronJobState struct {
+	lastRun        time.Time
+	runs, failures float64
+	lastStatus     int
+	statusKnown    bool
+	overdue        bool
+}
+
+// CronCollector watches the cron daemon's log for the configured jobs and
+// reports when each last started, its last exit status where the daemon
+// logs it, and whether it is overdue: it hasn't started within its
+// interval. A job that silently stopped running, like a backup whose
+// crontab entry was lost in a migration, is otherwise only noticed when
+// it's needed. The log is read back far enough to find each job's last run
+// at startup.
+type CronCollector struct {
+	Jobs []CronJob `json:"jobs"`
+	// Log is the cron daemon's syslog file: /var/log/cron on Red Hat,
+	// /var/log/syslog on Debian, whichever exists. Without one, cron's
+	// messages are read from the journal.
+	Log        string `json:"log"`
+	Journalctl string `json:"journalctl"`
+
+	noLog sync.Once
+
+	mu      sync.Mutex
+	started time.Time
+	tail    logTail
+	journal journalTail
+	states  []cronJobState
+	// pending maps the pids of running jobs to their index in Jobs.
+	pending map[string]int
+}
+
+func NewCronCollector(cfg config.CollectorConfig) (Collector, error) {
+	c := &CronCollector{Journalctl: "journalctl"}
+	if err := cfg.Decode(c); err != nil {
+		return nil, err
+	}
+	longest := 24 * time.Hour
+	for i := range c.Jobs {
+		j := &c.Jobs[i]
+		if j.Name == "" || j.Match == "" || j.Interval <= 0 {
+			return nil, errors.New("cron jobs need a name, match and interval")
+		}
+		re, err := regexp.Compile(j.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match for cron job %s: %w", j.Name, err)
+		}
+		j.match = re
+		longest = max(longest, 2*j.Interval.Duration())
+	}
+	if c.Log == "" {
+		for _, path := range []string{"/var/log/cron", "/var/log/syslog"} {
+			if _, err := os.Stat(path); err == nil {
+				c.Log = path
+				break
+			}
+		}
+	}
+	c.started = time.Now()
+	c.tail = logTail{path: c.Log, fromStart: true}
+	c.journal = journalTail{
+		journalctl: c.Journalctl,
+		output:     "short-unix",
+		matches:    []string{"SYSLOG_IDENTIFIER=CRON", "SYSLOG_IDENTIFIER=CROND", "SYSLOG_IDENTIFIER=crond"},
+		since:      c.started.Add(-longest),
+	}
+	c.states = make([]cronJobState, len(c.Jobs))
+	c.pending = map[string]int{}
+	return c, nil
+}
+
+func (c *CronCollector) Name() string {
+	return "cron"
+}
+
+func (c *CronCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	if len(c.Jobs) == 0 {
+		return nil, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var (
+		lines []string
+		err   error
+	)
+	if c.Log != "" {
+		lines, err = c.tail.read()
+	} else {
+		lines, err = c.journal.read(ctx)
+		if errors.Is(err, exec.ErrNotFound) {
+			c.noLog.Do(func() { log.Info().Msg("No cron log or journalctl; not reporting cron jobs") })
+			return nil, nil
+		}
+	}
+	for _, line := range lines {
+		c.parse(line)
+	}
+
+	now := time.Now()
+	b := metric.NewBuilder(now)
+	for i, j := range c.Jobs {
+		s := &c.states[i]
+		labels := []string{"job", j.Name}
+		interval := j.Interval.Duration()
+		b.Gauge("cron.job_interval", interval.Seconds(), "seconds", labels...)
+		b.Counter("cron.job_runs", s.runs, "", labels...)
+		b.Counter("cron.job_failures", s.failures, "", labels...)
+		// A job never seen is overdue once glass has watched for longer
+		// than its interval.
+		since := c.started
+		if !s.lastRun.IsZero() {
+			since = s.lastRun
+			b.Gauge("cron.job_last_run", float64(s.lastRun.Unix()), "seconds", labels...)
+			b.Gauge("cron.job_last_run_age", now.Sub(s.lastRun).Seconds(), "seconds", labels...)
+		}
+		if s.statusKnown {
+			b.Gauge("cron.job_last_exit_status", float64(s.lastStatus), "", labels...)
+		}
+		overdue := now.Sub(since) > interval
+		if overdue && !s.overdue {
+			ev := log.Warn().Str("collector", "cron").Str("job", j.Name).Dur("interval", interval)
+			if !s.lastRun.IsZero() {
+				ev = ev.Time("last_run", s.lastRun)
+			}
+			ev.Msg("Cron job overdue")
+		}
+		s.overdue = overdue
+		b.Gauge("cron.job_overdue", boolValue(overdue), "", labels...)
+	}
+	return b.Metrics(), err
+}
+
+// parse records a job starting, failing or finishing.
+func (c *CronCollector) parse(line string) {
+	m := cronLinePattern.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	pid, message := m[2], m[3]
+	if start := cronStartPattern.FindStringSubmatch(message); start != nil {
+		for i, j := range c.Jobs {
+			if (j.User != "" && j.User != start[1]) || !j.match.MatchString(start[2]) {
+				continue
+			}
+			s := &c.states[i]
+			s.runs++
+			s.lastRun = parseCronTime(m[1])
+			c.pending[pid] = i
+			break
+		}
+		return
+	}
+	i, ok := c.pending[pid]
+	if !ok {
+		return
+	}
+	s := &c.states[i]
+	if fail := cronFailPattern.FindStringSubmatch(message); fail != nil {
+		status, _ := strconv.Atoi(fail[1])
+		s.failures++
+		s.lastStatus, s.statusKnown = status, true
+		delete(c.pending, pid)
+	} else if cronEndPattern.MatchString(message) {
+		// Failures are logged before the end, so a job ending without one
+		// succeeded.
+		s.lastStatus, s.statusKnown = 0, true
+		delete(c.pending, pid)
+	}
+}
+
+// parseCronTime parses a syslog timestamp. Traditional ones have no year,
+// so they are in the last twelve months.
+func parseCronTime(s string) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t
+	}
+	if sec, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(sec*1e9))
+	}
+	now := time.Now()
+	t, err := time.ParseInLocation("Jan _2 15:04:05", strings.Join(strings.Fields(s), " "), time.Local)
+	if err != nil {
+		return now
+	}
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
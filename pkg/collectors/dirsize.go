@@ -0,0 +1,207 @@
+package collectors
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// dirUsage is what a scan of a directory found.
+type dirUsage struct {
+	bytes, files, unreadable float64
+	// children is the disk use under each entry directly in the directory.
+	children map[string]float64
+	scanned  time.Time
+	took     time.Duration
+}
+
+// DirSizeCollector reports what is eating the disk: the disk use of each
+// directory matching Paths, globs like /home/*/public_html, and of its
+// largest entries. Usage is counted the way du -sx does: allocated blocks,
+// hard links once, and not crossing into other filesystems. Walking a large
+// tree is slow, so each directory is rescanned only once its last scan is
+// older than Refresh, at most Concurrency at a time; collections in between
+// report the cached usage.
+type DirSizeCollector struct {
+	Paths       []string        `json:"paths"`
+	Refresh     config.Duration `json:"refresh"`
+	Concurrency int             `json:"concurrency"`
+	// Top is how many of each directory's largest entries are reported.
+	Top int `json:"top"`
+
+	mu    sync.Mutex
+	cache map[string]dirUsage
+}
+
+func NewDirSizeCollector(cfg config.CollectorConfig) (Collector, error) {
+	d := &DirSizeCollector{Refresh: config.Duration(time.Hour), Concurrency: 2, Top: 5}
+	if err := cfg.Decode(d); err != nil {
+		return nil, err
+	}
+	for _, p := range d.Paths {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid directory path %q: %w", p, err)
+		}
+	}
+	d.Concurrency = max(d.Concurrency, 1)
+	return d, nil
+}
+
+func (d *DirSizeCollector) Name() string {
+	return "dirsize"
+}
+
+func (d *DirSizeCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cache == nil {
+		d.cache = map[string]dirUsage{}
+	}
+
+	var dirs []string
+	for _, pattern := range d.Paths {
+		paths, _ := filepath.Glob(pattern)
+		for _, path := range paths {
+			if info, err := os.Stat(path); err == nil && info.IsDir() && !slices.Contains(dirs, path) {
+				dirs = append(dirs, path)
+			}
+		}
+	}
+	// Directories that no longer match are forgotten.
+	for path := range d.cache {
+		if !slices.Contains(dirs, path) {
+			delete(d.cache, path)
+		}
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	sem := make(chan struct{}, d.Concurrency)
+	for _, dir := range dirs {
+		if time.Since(d.cache[dir].scanned) < d.Refresh.Duration() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			usage, err := scanDir(ctx, dir)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("scanning %s: %w", dir, err))
+				return
+			}
+			d.cache[dir] = usage
+		}()
+	}
+	wg.Wait()
+
+	now := time.Now()
+	b := metric.NewBuilder(now)
+	for _, dir := range dirs {
+		usage, ok := d.cache[dir]
+		if !ok {
+			continue
+		}
+		labels := []string{"path", dir}
+		b.Gauge("dirsize.bytes", usage.bytes, "bytes", labels...)
+		b.Gauge("dirsize.files", usage.files, "", labels...)
+		b.Gauge("dirsize.unreadable", usage.unreadable, "", labels...)
+		b.Gauge("dirsize.scan_age", now.Sub(usage.scanned).Seconds(), "seconds", labels...)
+		b.Gauge("dirsize.scan_duration", usage.took.Seconds(), "seconds", labels...)
+		children := slices.SortedFunc(maps.Keys(usage.children), func(x, y string) int {
+			return cmp.Or(cmp.Compare(usage.children[y], usage.children[x]), strings.Compare(x, y))
+		})
+		for _, child := range children[:min(d.Top, len(children))] {
+			b.Gauge("dirsize.entry_bytes", usage.children[child], "bytes", "path", dir, "entry", child)
+		}
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// scanDir walks dir, or where it links to, staying on its filesystem.
+// Entries that can't be read, usually for permissions, are counted and
+// skipped.
+func scanDir(ctx context.Context, dir string) (dirUsage, error) {
+	start := time.Now()
+	usage := dirUsage{children: map[string]float64{}}
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return usage, err
+	}
+	rootInfo, err := os.Lstat(dir)
+	if err != nil {
+		return usage, err
+	}
+	root, _ := statOf(rootInfo)
+	type inode struct{ dev, ino uint64 }
+	linked := map[inode]bool{}
+	entries := 0
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			usage.unreadable++
+			if entry != nil && entry.IsDir() && path != dir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entries++; entries%1000 == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		info, err := entry.Info()
+		if err != nil {
+			usage.unreadable++
+			return nil
+		}
+		st, ok := statOf(info)
+		if !ok {
+			return nil
+		}
+		if entry.IsDir() && st.dev != root.dev {
+			return fs.SkipDir
+		}
+		if st.nlink > 1 && !entry.IsDir() {
+			key := inode{st.dev, st.ino}
+			if linked[key] {
+				return nil
+			}
+			linked[key] = true
+		}
+		bytes := st.allocated
+		usage.bytes += bytes
+		if !entry.IsDir() {
+			usage.files++
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != "." {
+			child, _, _ := strings.Cut(rel, string(filepath.Separator))
+			usage.children[child] += bytes
+		}
+		return nil
+	})
+	if err != nil {
+		return usage, err
+	}
+	usage.scanned, usage.took = time.Now(), time.Since(start)
+	return usage, nil
+}
@@ -1,18 +1,146 @@
 package collectors
 
 import (
-	"github.com/rs/zerolog/log"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
 	"github.com/shirou/gopsutil/v4/disk"
 )
 
-type DiskCollector struct{}
+// defaultExcludeFSTypes are pseudo and in-memory filesystems that don't say
+// anything about real storage.
+var defaultExcludeFSTypes = []string{
+	"tmpfs", "devtmpfs", "overlay", "squashfs", "proc", "sysfs", "cgroup", "cgroup2",
+	"devpts", "mqueue", "debugfs", "tracefs", "securityfs", "pstore", "bpf",
+	"autofs", "fusectl", "configfs", "hugetlbfs", "nsfs", "ramfs", "efivarfs",
+}
+
+type DiskCollector struct {
+	// Mountpoints lists paths to report explicitly. When empty, mount points
+	// are discovered from the partition table and filtered by the globs below.
+	Mountpoints   []string `json:"mountpoints"`
+	IncludeMounts []string `json:"include_mounts"`
+	ExcludeMounts []string `json:"exclude_mounts"`
+	// ExcludeFSTypes defaults to pseudo filesystems only when neither
+	// fstype list is set, so include_fstypes: [tmpfs] reports tmpfs.
+	IncludeFSTypes []string `json:"include_fstypes"`
+	ExcludeFSTypes []string `json:"exclude_fstypes"`
+}
+
+func NewDiskCollector(cfg config.CollectorConfig) (Collector, error) {
+	d := &DiskCollector{}
+	if err := cfg.Decode(d); err != nil {
+		return nil, err
+	}
+	if d.ExcludeFSTypes == nil && len(d.IncludeFSTypes) == 0 {
+		d.ExcludeFSTypes = defaultExcludeFSTypes
+	}
+	if err := validatePatterns(d.IncludeMounts, d.ExcludeMounts, d.IncludeFSTypes, d.ExcludeFSTypes); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *DiskCollector) Name() string {
+	return "disk"
+}
+
+func (d *DiskCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	partitions, err := d.partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+	for _, p := range partitions {
+		usage, err := disk.UsageWithContext(ctx, p.Mountpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("getting disk info for %s: %w", p.Mountpoint, err))
+			continue
+		}
+		fstype := p.Fstype
+		if fstype == "" {
+			fstype = usage.Fstype
+		}
+		labels := []string{"mountpoint", usage.Path, "device", p.Device, "fstype", fstype}
+		b.Gauge("disk.total", float64(usage.Total), "bytes", labels...)
+		b.Gauge("disk.free", float64(usage.Free), "bytes", labels...)
+		b.Gauge("disk.used", float64(usage.Used), "bytes", labels...)
+		b.Gauge("disk.used_percent", usage.UsedPercent, "percent", labels...)
+		if usage.InodesTotal > 0 {
+			b.Gauge("disk.inodes_total", float64(usage.InodesTotal), "", labels...)
+			b.Gauge("disk.inodes_used", float64(usage.InodesUsed), "", labels...)
+			b.Gauge("disk.inodes_free", float64(usage.InodesFree), "", labels...)
+			b.Gauge("disk.inodes_used_percent", usage.InodesUsedPercent, "percent", labels...)
+		}
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// partitions returns the mount points to report, either as configured or
+// discovered and filtered.
+func (d *DiskCollector) partitions(ctx context.Context) ([]disk.PartitionStat, error) {
+	if len(d.Mountpoints) > 0 {
+		parts := make([]disk.PartitionStat, 0, len(d.Mountpoints))
+		for _, mp := range d.Mountpoints {
+			parts = append(parts, disk.PartitionStat{Mountpoint: mp})
+		}
+		return parts, nil
+	}
 
-func (d *DiskCollector) Collector() error {
-	diskstat, err := disk.Usage("/")
+	all, err := disk.PartitionsWithContext(ctx, false)
 	if err != nil {
-		log.Err(err).Msg("Error getting disk info")
+		return nil, fmt.Errorf("listing partitions: %w", err)
+	}
+	seen := make(map[string]bool, len(all))
+	var parts []disk.PartitionStat
+	for _, p := range all {
+		if seen[p.Mountpoint] || !d.wanted(p) {
+			continue
+		}
+		seen[p.Mountpoint] = true
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+func (d *DiskCollector) wanted(p disk.PartitionStat) bool {
+	return filterMatch(p.Mountpoint, d.IncludeMounts, d.ExcludeMounts) &&
+		filterMatch(p.Fstype, d.IncludeFSTypes, d.ExcludeFSTypes)
+}
+
+func validatePatterns(lists ...[]string) error {
+	for _, patterns := range lists {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+		}
 	}
-	log.Info().Uint64("total", diskstat.Total).Uint64("free", diskstat.Free).Uint64("used", diskstat.Used).Float64("used-percent", diskstat.UsedPercent).Msg("")
-	//log.Info().Msg("Disk collector")
 	return nil
 }
+
+// filterMatch reports whether s passes a glob include/exclude filter. An
+// empty include list admits everything not excluded.
+func filterMatch(s string, include, exclude []string) bool {
+	for _, p := range exclude {
+		if ok, _ := path.Match(p, s); ok {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, p := range include {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+type DockerCollector struct {
+	Socket string `json:"socket"`
+	// Labels filters containers the same way `docker ps --filter label=...`
+	// does: "key" or "key=value".
+	Labels []string `json:"labels"`
+	// All includes stopped containers.
+	All bool `json:"all"`
+	// Concurrency limits how many containers are queried for stats at once.
+	Concurrency int `json:"concurrency"`
+
+	client *http.Client
+}
+
+func NewDockerCollector(cfg config.CollectorConfig) (Collector, error) {
+	d := &DockerCollector{Socket: "/var/run/docker.sock", Concurrency: 8}
+	if err := cfg.Decode(d); err != nil {
+		return nil, err
+	}
+	d.client = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", d.Socket)
+			},
+		},
+	}
+	return d, nil
+}
+
+func (d *DockerCollector) Name() string {
+	return "docker"
+}
+
+type dockerContainer struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Labels map[string]string `json:"Labels"`
+}
+
+type dockerInspect struct {
+	RestartCount int `json:"RestartCount"`
+	State        struct {
+		Health *struct {
+			Status string `json:"Status"`
+		} `json:"Health"`
+	} `json:"State"`
+}
+
+type dockerCPUStats struct {
+	CPUUsage struct {
+		TotalUsage uint64 `json:"total_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  int    `json:"online_cpus"`
+}
+
+type dockerStats struct {
+	CPUStats    dockerCPUStats `json:"cpu_stats"`
+	PreCPUStats dockerCPUStats `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+	BlkioStats struct {
+		IOServiceBytesRecursive []struct {
+			Op    string `json:"op"`
+			Value uint64 `json:"value"`
+		} `json:"io_service_bytes_recursive"`
+	} `json:"blkio_stats"`
+}
+
+func (d *DockerCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	query := url.Values{}
+	if d.All {
+		query.Set("all", "1")
+	}
+	if len(d.Labels) > 0 {
+		filters, _ := json.Marshal(map[string][]string{"label": d.Labels})
+		query.Set("filters", string(filters))
+	}
+	var containers []dockerContainer
+	if err := d.get(ctx, "/containers/json?"+query.Encode(), &containers); err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+
+	now := time.Now()
+	b := metric.NewBuilder(now)
+	b.Gauge("docker.containers", float64(len(containers)), "")
+	metrics := b.Metrics()
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+		sem  = make(chan struct{}, max(d.Concurrency, 1))
+	)
+	for _, c := range containers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			cb := metric.NewBuilder(now)
+			err := d.container(ctx, cb, c)
+			mu.Lock()
+			defer mu.Unlock()
+			metrics = append(metrics, cb.Metrics()...)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return metrics, errors.Join(errs...)
+}
+
+func (d *DockerCollector) container(ctx context.Context, b *metric.Builder, c dockerContainer) error {
+	name := c.ID[:min(12, len(c.ID))]
+	if len(c.Names) > 0 {
+		name = strings.TrimPrefix(c.Names[0], "/")
+	}
+	add := func(kind metric.Kind, n string, v float64, unit string, extra ...string) {
+		b.Add(kind, n, v, unit, append([]string{"container", name, "image", c.Image}, extra...)...)
+	}
+
+	add(metric.Gauge, "docker.container_running", boolValue(c.State == "running"), "", "state", c.State)
+
+	var inspect dockerInspect
+	if err := d.get(ctx, "/containers/"+c.ID+"/json", &inspect); err != nil {
+		return fmt.Errorf("inspecting container %s: %w", name, err)
+	}
+	add(metric.Counter, "docker.restarts", float64(inspect.RestartCount), "")
+	if h := inspect.State.Health; h != nil {
+		add(metric.Gauge, "docker.container_healthy", boolValue(h.Status == "healthy"), "", "health", h.Status)
+	}
+	if c.State != "running" {
+		return nil
+	}
+
+	// stream=false makes the daemon sample twice so precpu_stats is filled in.
+	var stats dockerStats
+	if err := d.get(ctx, "/containers/"+c.ID+"/stats?stream=false", &stats); err != nil {
+		return fmt.Errorf("getting stats for container %s: %w", name, err)
+	}
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta >= 0 && systemDelta > 0 {
+		cpus := float64(max(stats.CPUStats.OnlineCPUs, 1))
+		add(metric.Gauge, "docker.cpu_percent", cpuDelta/systemDelta*cpus*100, "percent")
+	}
+
+	mem := stats.MemoryStats
+	used := mem.Usage
+	// Page cache isn't attributable to the container; cgroup v1 calls it
+	// "cache", v2 "inactive_file".
+	if cache, ok := mem.Stats["inactive_file"]; ok && cache < used {
+		used -= cache
+	} else if cache, ok := mem.Stats["cache"]; ok && cache < used {
+		used -= cache
+	}
+	add(metric.Gauge, "docker.memory_usage", float64(used), "bytes")
+	add(metric.Gauge, "docker.memory_limit", float64(mem.Limit), "bytes")
+	if mem.Limit > 0 {
+		add(metric.Gauge, "docker.memory_percent", 100*float64(used)/float64(mem.Limit), "percent")
+	}
+
+	for iface, n := range stats.Networks {
+		add(metric.Counter, "docker.net_bytes_recv", float64(n.RxBytes), "bytes", "interface", iface)
+		add(metric.Counter, "docker.net_bytes_sent", float64(n.TxBytes), "bytes", "interface", iface)
+	}
+	var read, write uint64
+	for _, e := range stats.BlkioStats.IOServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			read += e.Value
+		case "write":
+			write += e.Value
+		}
+	}
+	add(metric.Counter, "docker.blkio_read", float64(read), "bytes")
+	add(metric.Counter, "docker.blkio_write", float64(write), "bytes")
+	return nil
+}
+
+func (d *DockerCollector) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker"+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("docker API returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
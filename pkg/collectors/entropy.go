@@ -0,0 +1,90 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"glass/pkg/metric"
+)
+
+const randomSysctl = "/proc/sys/kernel/random"
+
+// EntropyCollector reports the kernel entropy pool and processes stuck
+// waiting on it. Before Linux 5.6 reads from /dev/random block whenever
+// entropy_avail runs low, which on VMs without a hardware RNG or haveged
+// stalls TLS handshakes and key generation; on any kernel getrandom(2)
+// blocks until the pool is first initialized, which can hang services
+// early in boot.
+type EntropyCollector struct{}
+
+func (e *EntropyCollector) Name() string {
+	return "entropy"
+}
+
+func (e *EntropyCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	avail, err := readSysfsFloat(filepath.Join(randomSysctl, "entropy_avail"))
+	if err != nil {
+		return nil, fmt.Errorf("reading entropy_avail: %w", err)
+	}
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	b.Gauge("entropy.available", avail, "")
+	if size, err := readSysfsFloat(filepath.Join(randomSysctl, "poolsize")); err == nil && size > 0 {
+		b.Gauge("entropy.pool_size", size, "")
+		b.Gauge("entropy.available_percent", 100*avail/size, "percent")
+	}
+	// Writers to /dev/random are woken below this; read_wakeup_threshold
+	// went away with the blocking pool in 5.6.
+	for _, t := range []string{"read_wakeup_threshold", "write_wakeup_threshold"} {
+		if v, err := readSysfsFloat(filepath.Join(randomSysctl, t)); err == nil {
+			b.Gauge("entropy."+t, v, "")
+		}
+	}
+	// A hardware RNG such as virtio-rng keeps the pool fed by itself.
+	if rng := readSysfsString("/sys/class/misc/hw_random/rng_current"); rng != "" {
+		b.Gauge("entropy.hardware_rng", boolValue(rng != "none"), "")
+	}
+
+	blocked, err := randomWaiters(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		b.Gauge("entropy.blocked_readers", float64(blocked), "")
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// randomWaitChans are the kernel functions a reader of /dev/random or
+// getrandom(2) sleeps in waiting for entropy. The hwrng thread sleeps in
+// add_hwgenerator_randomness, which is the writing side.
+var randomWaitChans = []string{"wait_for_random_bytes", "random_read", "_random_read", "random_read_iter"}
+
+// randomWaiters counts processes blocked waiting for entropy. wchan names
+// the function a process sleeps in and reads "0" for other users'
+// processes unless glass runs as root.
+func randomWaiters(ctx context.Context) (int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, fmt.Errorf("listing processes: %w", err)
+	}
+	blocked := 0
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+		if slices.Contains(randomWaitChans, readSysfsString(filepath.Join("/proc", e.Name(), "wchan"))) {
+			blocked++
+		}
+	}
+	return blocked, nil
+}
@@ -0,0 +1,137 @@
+package collectors
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+	"github.com/yusufpapurcu/wmi"
+)
+
+// Win32_NTLogEvent event types. Critical events are errors to it too.
+const (
+	eventTypeError   = 1
+	eventTypeWarning = 2
+)
+
+type win32NTLogEvent struct {
+	RecordNumber  uint32
+	SourceName    string
+	EventCode     uint16
+	EventType     uint8
+	TimeGenerated time.Time
+	Message       string
+}
+
+// EventLogCollector counts errors, and optionally warnings, in Windows
+// event logs from WMI's Win32_NTLogEvent: those written within Window, and
+// by source, those written since glass started, which are also logged as
+// warnings, as the kmsg collector does for kernel messages on Linux. Each
+// collection only looks back over Window, so it needs to be longer than
+// the interval.
+type EventLogCollector struct {
+	// Logs are the event logs to read, System and Application by default.
+	Logs []string `json:"logs"`
+	// Warnings counts warnings as well as errors.
+	Warnings bool `json:"warnings"`
+	// Window is how far back recent events are counted.
+	Window config.Duration `json:"window"`
+
+	mu sync.Mutex
+	// last is the newest record read from each log, by log.
+	last   map[string]uint32
+	counts map[eventLogKey]float64
+}
+
+type eventLogKey struct{ log, level, source string }
+
+func NewEventLogCollector(cfg config.CollectorConfig) (Collector, error) {
+	e := &EventLogCollector{Logs: []string{"System", "Application"}, Window: config.Duration(time.Hour)}
+	if err := cfg.Decode(e); err != nil {
+		return nil, err
+	}
+	for _, name := range e.Logs {
+		if strings.ContainsAny(name, `'\`) {
+			return nil, fmt.Errorf("invalid event log name %q", name)
+		}
+	}
+	return e, nil
+}
+
+func (e *EventLogCollector) Name() string {
+	return "eventlog"
+}
+
+func (e *EventLogCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.last == nil {
+		e.last, e.counts = map[string]uint32{}, map[eventLogKey]float64{}
+	}
+	maxType := eventTypeError
+	if e.Warnings {
+		maxType = eventTypeWarning
+	}
+	// WMI datetimes carry the UTC offset in minutes.
+	since := time.Now().Add(-e.Window.Duration()).UTC().Format("20060102150405.000000") + "+000"
+
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+	for _, name := range e.Logs {
+		var events []win32NTLogEvent
+		query := fmt.Sprintf("SELECT RecordNumber, SourceName, EventCode, EventType, TimeGenerated, Message FROM Win32_NTLogEvent "+
+			"WHERE Logfile = '%s' AND EventType <= %d AND TimeGenerated >= '%s'", name, maxType, since)
+		if err := wmi.Query(query, &events); err != nil {
+			errs = append(errs, fmt.Errorf("reading the %s event log: %w", name, err))
+			continue
+		}
+		recent := map[string]float64{}
+		var newest uint32
+		for _, ev := range events {
+			newest = max(newest, ev.RecordNumber)
+		}
+		last, seen := e.last[name]
+		// Records are numbered from 1 again after the log is cleared.
+		if newest < last {
+			last = 0
+		}
+		for _, ev := range events {
+			level := "error"
+			if ev.EventType == eventTypeWarning {
+				level = "warning"
+			}
+			recent[level]++
+			// Events from before glass started aren't counted.
+			if !seen || ev.RecordNumber <= last {
+				continue
+			}
+			e.counts[eventLogKey{name, level, ev.SourceName}]++
+			message, _, _ := strings.Cut(strings.TrimSpace(ev.Message), "\n")
+			log.Warn().Str("collector", "eventlog").Str("log", name).Str("level", level).
+				Str("source", ev.SourceName).Uint16("event_id", ev.EventCode).
+				Time("event_time", ev.TimeGenerated).Msg(strings.TrimSpace(message))
+		}
+		e.last[name] = max(last, newest)
+		b.Gauge("eventlog.recent_events", recent["error"], "", "log", name, "level", "error")
+		if e.Warnings {
+			b.Gauge("eventlog.recent_events", recent["warning"], "", "log", name, "level", "warning")
+		}
+	}
+	keys := slices.SortedFunc(maps.Keys(e.counts), func(x, y eventLogKey) int {
+		return cmp.Or(strings.Compare(x.log, y.log), strings.Compare(x.level, y.level), strings.Compare(x.source, y.source))
+	})
+	for _, k := range keys {
+		b.Counter("eventlog.events", e.counts[k], "", "log", k.log, "level", k.level, "source", k.source)
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
@@ -0,0 +1,131 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FastCGI record types and roles used by fcgiGet; see the FastCGI 1.0
+// specification.
+const (
+	fcgiBeginRequest = 1
+	fcgiEndRequest   = 3
+	fcgiParams       = 4
+	fcgiStdin        = 5
+	fcgiStdout       = 6
+	fcgiStderr       = 7
+	fcgiResponder    = 1
+)
+
+// fcgiGet performs a single GET against a FastCGI responder such as
+// PHP-FPM and returns the response body. network and addr are as for
+// net.Dial.
+func fcgiGet(ctx context.Context, network, addr, script, query string) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+	}
+
+	var req bytes.Buffer
+	writeFCGIRecord(&req, fcgiBeginRequest, []byte{0, fcgiResponder, 0, 0, 0, 0, 0, 0})
+	var params bytes.Buffer
+	for _, kv := range [][2]string{
+		{"SCRIPT_NAME", script},
+		{"SCRIPT_FILENAME", script},
+		{"REQUEST_URI", script + "?" + query},
+		{"QUERY_STRING", query},
+		{"REQUEST_METHOD", "GET"},
+		{"SERVER_PROTOCOL", "HTTP/1.1"},
+	} {
+		writeFCGILength(&params, len(kv[0]))
+		writeFCGILength(&params, len(kv[1]))
+		params.WriteString(kv[0])
+		params.WriteString(kv[1])
+	}
+	writeFCGIRecord(&req, fcgiParams, params.Bytes())
+	writeFCGIRecord(&req, fcgiParams, nil)
+	writeFCGIRecord(&req, fcgiStdin, nil)
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	r := bufio.NewReader(conn)
+	for {
+		var h [8]byte
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return nil, fmt.Errorf("reading FastCGI response: %w", err)
+		}
+		length := binary.BigEndian.Uint16(h[4:6])
+		content := make([]byte, int(length)+int(h[6]))
+		if _, err := io.ReadFull(r, content); err != nil {
+			return nil, fmt.Errorf("reading FastCGI response: %w", err)
+		}
+		content = content[:length]
+		switch h[1] {
+		case fcgiStdout:
+			stdout.Write(content)
+		case fcgiStderr:
+			stderr.Write(content)
+		case fcgiEndRequest:
+			return parseCGIResponse(stdout.Bytes(), stderr.String())
+		}
+	}
+}
+
+func writeFCGIRecord(w *bytes.Buffer, typ byte, content []byte) {
+	var h [8]byte
+	h[0] = 1 // version
+	h[1] = typ
+	binary.BigEndian.PutUint16(h[2:4], 1) // request ID
+	binary.BigEndian.PutUint16(h[4:6], uint16(len(content)))
+	w.Write(h[:])
+	w.Write(content)
+}
+
+func writeFCGILength(w *bytes.Buffer, n int) {
+	if n < 128 {
+		w.WriteByte(byte(n))
+		return
+	}
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(n)|1<<31)
+	w.Write(b[:])
+}
+
+// parseCGIResponse splits the CGI headers from the body and turns a
+// non-2xx Status header into an error.
+func parseCGIResponse(out []byte, stderr string) ([]byte, error) {
+	tp := textproto.NewReader(bufio.NewReader(bytes.NewReader(out)))
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing FastCGI response headers: %w", err)
+	}
+	if status := header.Get("Status"); status != "" {
+		code, _ := strconv.Atoi(strings.Fields(status)[0])
+		if code/100 != 2 {
+			if stderr != "" {
+				return nil, fmt.Errorf("FastCGI responder returned %s: %s", status, strings.TrimSpace(stderr))
+			}
+			return nil, fmt.Errorf("FastCGI responder returned %s", status)
+		}
+	}
+	return io.ReadAll(tp.R)
+}
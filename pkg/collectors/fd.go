@@ -0,0 +1,151 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+type FDCollector struct {
+	// Processes is a regular expression matched against process names;
+	// matching processes get their own FD usage series.
+	Processes string `json:"processes"`
+	// NearLimitPercent is the share of its nofile limit at which a process
+	// counts as near the limit.
+	NearLimitPercent float64 `json:"near_limit_percent"`
+
+	processes *regexp.Regexp
+}
+
+func NewFDCollector(cfg config.CollectorConfig) (Collector, error) {
+	f := &FDCollector{NearLimitPercent: 80}
+	if err := cfg.Decode(f); err != nil {
+		return nil, err
+	}
+	if f.Processes != "" {
+		re, err := regexp.Compile(f.Processes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid processes pattern: %w", err)
+		}
+		f.processes = re
+	}
+	return f, nil
+}
+
+func (f *FDCollector) Name() string {
+	return "fd"
+}
+
+func (f *FDCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	// file-nr is "allocated unused max"; unused has been 0 since Linux 2.6.
+	data, err := os.ReadFile("/proc/sys/fs/file-nr")
+	if err != nil {
+		errs = append(errs, fmt.Errorf("getting file-nr: %w", err))
+	} else if fields := strings.Fields(string(data)); len(fields) == 3 {
+		allocated, _ := strconv.ParseFloat(fields[0], 64)
+		unused, _ := strconv.ParseFloat(fields[1], 64)
+		limit, _ := strconv.ParseFloat(fields[2], 64)
+		b.Gauge("fd.allocated", allocated-unused, "")
+		b.Gauge("fd.max", limit, "")
+		if limit > 0 {
+			b.Gauge("fd.used_percent", 100*(allocated-unused)/limit, "percent")
+		}
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		errs = append(errs, fmt.Errorf("listing processes: %w", err))
+		return b.Metrics(), errors.Join(errs...)
+	}
+	var (
+		near    int
+		highest float64
+	)
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		// Processes can exit mid-scan, and other users' fd directories are
+		// unreadable without root; both are skipped.
+		open, err := countFDs(pid)
+		if err != nil {
+			continue
+		}
+		limit, err := nofileLimit(pid)
+		if err != nil || limit <= 0 {
+			continue
+		}
+		used := 100 * float64(open) / limit
+		highest = max(highest, used)
+		if used >= f.NearLimitPercent {
+			near++
+		}
+		if f.processes == nil {
+			continue
+		}
+		name := readSysfsString(filepath.Join("/proc", e.Name(), "comm"))
+		if !f.processes.MatchString(name) {
+			continue
+		}
+		labels := []string{"pid", e.Name(), "name", name}
+		b.Gauge("fd.process_open", float64(open), "", labels...)
+		b.Gauge("fd.process_limit", limit, "", labels...)
+		b.Gauge("fd.process_used_percent", used, "percent", labels...)
+	}
+	b.Gauge("fd.processes_near_limit", float64(near), "")
+	b.Gauge("fd.process_max_used_percent", highest, "percent")
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func countFDs(pid int) (int, error) {
+	d, err := os.Open(filepath.Join("/proc", strconv.Itoa(pid), "fd"))
+	if err != nil {
+		return 0, err
+	}
+	defer d.Close()
+	names, err := d.Readdirnames(-1)
+	return len(names), err
+}
+
+// nofileLimit returns the soft "Max open files" limit from
+// /proc/<pid>/limits, or -1 when unlimited.
+func nofileLimit(pid int) (float64, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "limits"))
+	if err != nil {
+		return 0, err
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		rest, ok := strings.CutPrefix(s.Text(), "Max open files")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			break
+		}
+		if fields[0] == "unlimited" {
+			return -1, nil
+		}
+		return strconv.ParseFloat(fields[0], 64)
+	}
+	return 0, errors.New("no open files limit")
+}
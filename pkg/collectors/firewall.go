@@ -0,0 +1,863 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// iptablesTables are the tables iptables-nft keeps in nftables; nft shows
+// them too, so they are only read through iptables-save.
+var iptablesTables = []string{"filter", "nat", "mangle", "raw", "security"}
+
+// FirewallCollector reports which firewall manages the host, how many
+// rules each table and chain has, the built-in chains' default policies,
+// and whether a list of ports accept new connections. Missing tools are
+// skipped; reading rules needs root.
+type FirewallCollector struct {
+	// Ports are checked against the IPv4 input rules, as "22/tcp". A port
+	// is open when a new connection from any address would be accepted.
+	// Rules that depend on the source, interface or anything else glass
+	// can't know about are passed over when they only block connections,
+	// and leave the port unknown when they could let one through.
+	Ports []string `json:"ports"`
+}
+
+func NewFirewallCollector(cfg config.CollectorConfig) (Collector, error) {
+	f := &FirewallCollector{Ports: []string{"22/tcp", "80/tcp", "443/tcp"}}
+	if err := cfg.Decode(f); err != nil {
+		return nil, err
+	}
+	for _, p := range f.Ports {
+		if _, _, err := parseFirewallPort(p); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (f *FirewallCollector) Name() string {
+	return "firewall"
+}
+
+// fwVerdict is what a chain decides for a packet; fwContinue means it
+// fell off the end or returned, and fwUnknown that it depends on a rule
+// glass can't judge.
+type fwVerdict int
+
+const (
+	fwContinue fwVerdict = iota
+	fwAccept
+	fwDrop
+	fwUnknown
+)
+
+// fwMatch is whether a rule matches a new connection from a remote host.
+type fwMatch int
+
+const (
+	fwNoMatch fwMatch = iota
+	fwMatches
+	fwMaybe
+)
+
+// fwRule is a rule reduced to what decides whether a new connection to a
+// port is accepted.
+type fwRule struct {
+	protocols []string
+	// ports are ranges of destination ports; none means any.
+	ports [][2]int
+	// states are the conntrack states matched; none means any.
+	states []string
+	// conditional rules match on something else, such as the source or
+	// interface, so they may or may not match.
+	conditional bool
+	// never is set for rules no remote connection matches, such as those
+	// for the loopback interface.
+	never   bool
+	verdict fwVerdict
+	// jump is the chain a rule jumps to instead of deciding; with goto
+	// set, the chain doesn't return to this one.
+	jump     string
+	gotoJump bool
+	ret      bool
+	// vmap maps ports or conntrack states to verdicts, as nftables' vmap
+	// does.
+	vmap    map[string]fwVerdict
+	vmapKey string
+}
+
+// matches reports whether a new connection to port over proto matches,
+// and for vmap rules the verdict it maps to.
+func (r fwRule) matches(proto string, port int) (fwMatch, fwVerdict) {
+	if r.never {
+		return fwNoMatch, fwContinue
+	}
+	if len(r.protocols) > 0 && !slices.Contains(r.protocols, proto) {
+		return fwNoMatch, fwContinue
+	}
+	if len(r.states) > 0 && !slices.Contains(r.states, "new") {
+		return fwNoMatch, fwContinue
+	}
+	if len(r.ports) > 0 && !slices.ContainsFunc(r.ports, func(pr [2]int) bool { return port >= pr[0] && port <= pr[1] }) {
+		return fwNoMatch, fwContinue
+	}
+	m := fwMatches
+	if r.conditional {
+		m = fwMaybe
+	}
+	if r.vmap != nil {
+		key := "new"
+		if r.vmapKey == "dport" {
+			key = strconv.Itoa(port)
+		}
+		v, ok := r.vmap[key]
+		if !ok {
+			return fwNoMatch, fwContinue
+		}
+		return m, v
+	}
+	return m, r.verdict
+}
+
+// fwTable is a table's chains, with the policy of built-in or base chains.
+type fwTable struct {
+	source, family, name string
+	chains               map[string][]fwRule
+	order                []string
+	policies             map[string]string
+	// hooks are the base chains on the input hook, in priority order.
+	hooks []string
+}
+
+func newFWTable(source, family, name string) *fwTable {
+	return &fwTable{source: source, family: family, name: name, chains: map[string][]fwRule{}, policies: map[string]string{}}
+}
+
+func (t *fwTable) addChain(name string) {
+	if _, ok := t.chains[name]; !ok {
+		t.chains[name] = nil
+		t.order = append(t.order, name)
+	}
+}
+
+// eval decides a new connection from rule i of chain on; done is what
+// happens when the chain runs out or returns. A rule that may or may not
+// match only matters when matching it would end differently from passing
+// it over: if it would drop, the port is judged on the rules after it,
+// and otherwise the verdict is unknown.
+func (t *fwTable) eval(chain string, i int, proto string, port, depth int, done func() fwVerdict) fwVerdict {
+	if depth > 32 {
+		return fwUnknown
+	}
+	rules := t.chains[chain]
+	for ; i < len(rules); i++ {
+		r := rules[i]
+		m, v := r.matches(proto, port)
+		if m == fwNoMatch {
+			continue
+		}
+		next := i + 1
+		rest := sync.OnceValue(func() fwVerdict { return t.eval(chain, next, proto, port, depth, done) })
+		var matched fwVerdict
+		switch _, known := t.chains[r.jump]; {
+		case r.ret:
+			matched = done()
+		case r.jump != "" && known && r.gotoJump:
+			matched = t.eval(r.jump, 0, proto, port, depth+1, done)
+		case r.jump != "" && known:
+			matched = t.eval(r.jump, 0, proto, port, depth+1, rest)
+		case v == fwContinue:
+			// Targets such as LOG go on to the next rule.
+			matched = rest()
+		default:
+			matched = v
+		}
+		if m == fwMatches {
+			return matched
+		}
+		if skipped := rest(); matched == skipped || matched == fwDrop {
+			return skipped
+		}
+		return fwUnknown
+	}
+	return done()
+}
+
+// decide runs a new connection through each input chain, falling back to
+// its policy; every one has to accept it.
+func (t *fwTable) decide(proto string, port int) fwVerdict {
+	verdict := fwAccept
+	for _, chain := range t.hooks {
+		policy := fwAccept
+		if t.policies[chain] == "drop" {
+			policy = fwDrop
+		}
+		switch t.eval(chain, 0, proto, port, 0, func() fwVerdict { return policy }) {
+		case fwDrop:
+			return fwDrop
+		case fwUnknown:
+			verdict = fwUnknown
+		}
+	}
+	return verdict
+}
+
+// firewallPortVerdict decides a port across the IPv4 tables: closed if any
+// drops it, unknown if any can't say, and open otherwise.
+func firewallPortVerdict(tables []*fwTable, proto string, port int) fwVerdict {
+	verdict := fwAccept
+	for _, t := range tables {
+		if t.family != "ip" && t.family != "inet" {
+			continue
+		}
+		switch t.decide(proto, port) {
+		case fwDrop:
+			return fwDrop
+		case fwUnknown:
+			verdict = fwUnknown
+		}
+	}
+	return verdict
+}
+
+func (f *FirewallCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var (
+		errs   []error
+		tables []*fwTable
+	)
+
+	iptablesNFT := false
+	for _, tool := range []struct{ cmd, family string }{{"iptables-save", "ip"}, {"ip6tables-save", "ip6"}} {
+		out, err := runCommand(ctx, tool.cmd)
+		if errors.Is(err, exec.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if strings.Contains(string(out), "iptables-nft-save") {
+			iptablesNFT = true
+		}
+		tables = append(tables, parseIptablesSave(string(out), tool.family)...)
+	}
+	if out, err := runCommand(ctx, "nft", "-j", "list", "ruleset"); err == nil {
+		nftTables, err := parseNftRuleset(out)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, t := range nftTables {
+			if iptablesNFT && (t.family == "ip" || t.family == "ip6") && slices.Contains(iptablesTables, t.name) {
+				continue
+			}
+			tables = append(tables, t)
+		}
+	} else if !errors.Is(err, exec.ErrNotFound) {
+		errs = append(errs, err)
+	}
+
+	backend := "none"
+	rules := map[string]int{}
+	for _, t := range tables {
+		for _, chain := range t.order {
+			n := len(t.chains[chain])
+			rules[t.source] += n
+			b.Gauge("firewall.rules", float64(n), "", "source", t.source, "family", t.family, "table", t.name, "chain", chain)
+			if policy, ok := t.policies[chain]; ok {
+				b.Gauge("firewall.chain_policy", 1, "", "source", t.source, "family", t.family, "table", t.name, "chain", chain, "policy", policy)
+			}
+		}
+	}
+	switch {
+	case firewallRunning(ctx, "running", "firewall-cmd", "--state"):
+		backend = "firewalld"
+	case firewallRunning(ctx, "Status: active", "ufw", "status"):
+		backend = "ufw"
+	case rules["nftables"] > 0:
+		backend = "nftables"
+	case rules["iptables"] > 0:
+		backend = "iptables"
+	}
+	b.Gauge("firewall.info", 1, "", "backend", backend)
+	b.Gauge("firewall.active", boolValue(backend != "none"), "")
+
+	for _, p := range f.Ports {
+		port, proto, _ := parseFirewallPort(p)
+		labels := []string{"port", strconv.Itoa(port), "protocol", proto}
+		verdict := firewallPortVerdict(tables, proto, port)
+		b.Gauge("firewall.port_known", boolValue(verdict != fwUnknown), "", labels...)
+		if verdict != fwUnknown {
+			b.Gauge("firewall.port_open", boolValue(verdict == fwAccept), "", labels...)
+		}
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// firewallRunning reports whether a tool ran and said want.
+func firewallRunning(ctx context.Context, want, name string, args ...string) bool {
+	out, err := runCommand(ctx, name, args...)
+	return err == nil && strings.Contains(string(out), want)
+}
+
+func parseFirewallPort(s string) (int, string, error) {
+	p, proto, ok := strings.Cut(s, "/")
+	if !ok {
+		proto = "tcp"
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil || port < 1 || port > 65535 || (proto != "tcp" && proto != "udp") {
+		return 0, "", fmt.Errorf("invalid firewall port %q, want e.g. 22/tcp", s)
+	}
+	return port, proto, nil
+}
+
+// parseIptablesSave reads iptables-save's output: a "*table" line, a
+// ":CHAIN POLICY [packets:bytes]" line per chain ("-" for user chains),
+// "-A CHAIN ..." rules and COMMIT.
+func parseIptablesSave(out, family string) []*fwTable {
+	var (
+		tables []*fwTable
+		t      *fwTable
+	)
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "*"):
+			t = newFWTable("iptables", family, line[1:])
+			tables = append(tables, t)
+		case t == nil:
+		case strings.HasPrefix(line, ":"):
+			f := strings.Fields(line[1:])
+			if len(f) < 2 {
+				continue
+			}
+			t.addChain(f[0])
+			if f[1] != "-" {
+				t.policies[f[0]] = strings.ToLower(f[1])
+			}
+			if t.name == "filter" && f[0] == "INPUT" {
+				t.hooks = []string{"INPUT"}
+			}
+		case strings.HasPrefix(line, "-A "):
+			args := splitIptablesArgs(line)
+			if len(args) < 2 {
+				continue
+			}
+			t.addChain(args[1])
+			t.chains[args[1]] = append(t.chains[args[1]], parseIptablesRule(args[2:]))
+		}
+	}
+	return tables
+}
+
+// splitIptablesArgs splits a rule into words, keeping quoted comments
+// whole.
+func splitIptablesArgs(line string) []string {
+	var (
+		args   []string
+		word   strings.Builder
+		quoted bool
+	)
+	for _, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ' ' && !quoted:
+			if word.Len() > 0 {
+				args = append(args, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(c)
+		}
+	}
+	if word.Len() > 0 {
+		args = append(args, word.String())
+	}
+	return args
+}
+
+// parseIptablesRule reads a rule's matches, then its target. Everything
+// after -j or -g is the target's options, such as REJECT's --reject-with.
+func parseIptablesRule(args []string) fwRule {
+	var r fwRule
+	negate := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		next := ""
+		if i+1 < len(args) {
+			next = args[i+1]
+		}
+		if arg == "!" {
+			negate = true
+			continue
+		}
+		neg := negate
+		negate = false
+		switch arg {
+		case "-m", "--match", "--comment":
+			i++
+		case "-p", "--protocol":
+			if neg {
+				r.conditional = true
+			} else {
+				r.protocols = []string{next}
+			}
+			i++
+		case "--dport", "--destination-port", "--dports", "--destination-ports":
+			if neg {
+				r.conditional = true
+			} else {
+				for _, p := range strings.Split(next, ",") {
+					if pr, ok := parsePortRange(p, ":"); ok {
+						r.ports = append(r.ports, pr)
+					}
+				}
+			}
+			i++
+		case "--ctstate", "--state":
+			if neg {
+				r.conditional = true
+			} else {
+				r.states = strings.Split(strings.ToLower(next), ",")
+			}
+			i++
+		case "--syn":
+			// New connections start with a SYN.
+			r.addCondition(fwCondTrue, neg)
+		case "-i", "--in-interface":
+			r.addCondition(fwIsLoopback(next), neg)
+			i++
+		case "-s", "--source":
+			r.addCondition(fwLoopbackSource(next), neg)
+			i++
+		case "--dst-type":
+			r.addCondition(fwLocalType(next), neg)
+			i++
+		case "-j", "--jump", "-g", "--goto":
+			switch next {
+			case "ACCEPT":
+				r.verdict = fwAccept
+			case "DROP", "REJECT":
+				r.verdict = fwDrop
+			case "RETURN":
+				r.ret = true
+			default:
+				r.jump, r.gotoJump = next, arg == "-g" || arg == "--goto"
+			}
+			return r
+		default:
+			// Anything else narrows the rule down to packets glass can't
+			// judge.
+			r.conditional = true
+			if !strings.HasPrefix(next, "-") && next != "!" {
+				i++
+			}
+		}
+	}
+	return r
+}
+
+// fwCondition is whether a match holds for a new connection from a remote
+// host to one of the host's own addresses.
+type fwCondition int
+
+const (
+	fwCondUnknown fwCondition = iota
+	fwCondTrue
+	fwCondFalse
+)
+
+func (r *fwRule) addCondition(c fwCondition, negate bool) {
+	if negate {
+		switch c {
+		case fwCondTrue:
+			c = fwCondFalse
+		case fwCondFalse:
+			c = fwCondTrue
+		}
+	}
+	switch c {
+	case fwCondUnknown:
+		r.conditional = true
+	case fwCondFalse:
+		r.never = true
+	}
+}
+
+// fwIsLoopback says a match on the input interface is false for "lo",
+// which remote connections don't arrive on.
+func fwIsLoopback(iface string) fwCondition {
+	if iface == "lo" {
+		return fwCondFalse
+	}
+	return fwCondUnknown
+}
+
+// fwLoopbackSource says a match on a source address in 127.0.0.0/8 is
+// false.
+func fwLoopbackSource(s string) fwCondition {
+	if prefix, err := netip.ParsePrefix(s); err == nil && prefix.Addr().IsLoopback() {
+		return fwCondFalse
+	}
+	if addr, err := netip.ParseAddr(s); err == nil && addr.IsLoopback() {
+		return fwCondFalse
+	}
+	return fwCondUnknown
+}
+
+// fwLocalType reads an address type match on the destination: local and
+// unicast addresses are the host's own, the rest aren't.
+func fwLocalType(t string) fwCondition {
+	switch strings.ToUpper(t) {
+	case "LOCAL", "UNICAST":
+		return fwCondTrue
+	case "BROADCAST", "MULTICAST", "ANYCAST", "BLACKHOLE", "UNREACHABLE", "PROHIBIT", "NAT", "XRESOLVE", "THROW", "UNSPEC":
+		return fwCondFalse
+	}
+	return fwCondUnknown
+}
+
+func parsePortRange(s, sep string) ([2]int, bool) {
+	lo, hi, isRange := strings.Cut(s, sep)
+	a, err := strconv.Atoi(lo)
+	if err != nil {
+		return [2]int{}, false
+	}
+	b := a
+	if isRange {
+		if b, err = strconv.Atoi(hi); err != nil {
+			return [2]int{}, false
+		}
+	}
+	return [2]int{a, b}, true
+}
+
+// nftRuleset is the shape of `nft -j list ruleset`: a list of objects
+// each holding one table, chain or rule.
+type nftRuleset struct {
+	Nftables []struct {
+		Table *struct {
+			Family string `json:"family"`
+			Name   string `json:"name"`
+		} `json:"table"`
+		Chain *struct {
+			Family string `json:"family"`
+			Table  string `json:"table"`
+			Name   string `json:"name"`
+			Type   string `json:"type"`
+			Hook   string `json:"hook"`
+			Prio   int    `json:"prio"`
+			Policy string `json:"policy"`
+		} `json:"chain"`
+		Rule *struct {
+			Family string                       `json:"family"`
+			Table  string                       `json:"table"`
+			Chain  string                       `json:"chain"`
+			Expr   []map[string]json.RawMessage `json:"expr"`
+		} `json:"rule"`
+	} `json:"nftables"`
+}
+
+func parseNftRuleset(out []byte) ([]*fwTable, error) {
+	var rs nftRuleset
+	if err := json.Unmarshal(out, &rs); err != nil {
+		return nil, fmt.Errorf("parsing nft ruleset: %w", err)
+	}
+	var tables []*fwTable
+	byName := map[string]*fwTable{}
+	prio := map[string]int{}
+	table := func(family, name string) *fwTable {
+		t, ok := byName[family+" "+name]
+		if !ok {
+			t = newFWTable("nftables", family, name)
+			byName[family+" "+name] = t
+			tables = append(tables, t)
+		}
+		return t
+	}
+	for _, obj := range rs.Nftables {
+		switch {
+		case obj.Table != nil:
+			table(obj.Table.Family, obj.Table.Name)
+		case obj.Chain != nil:
+			c := obj.Chain
+			t := table(c.Family, c.Table)
+			t.addChain(c.Name)
+			if c.Hook == "" {
+				continue
+			}
+			if c.Policy == "" {
+				c.Policy = "accept"
+			}
+			t.policies[c.Name] = c.Policy
+			if c.Hook == "input" && c.Type == "filter" {
+				t.hooks = append(t.hooks, c.Name)
+				prio[c.Family+" "+c.Table+" "+c.Name] = c.Prio
+			}
+		case obj.Rule != nil:
+			t := table(obj.Rule.Family, obj.Rule.Table)
+			t.addChain(obj.Rule.Chain)
+			t.chains[obj.Rule.Chain] = append(t.chains[obj.Rule.Chain], parseNftRule(obj.Rule.Expr))
+		}
+	}
+	for _, t := range tables {
+		slices.SortStableFunc(t.hooks, func(a, b string) int {
+			return prio[t.family+" "+t.name+" "+a] - prio[t.family+" "+t.name+" "+b]
+		})
+	}
+	return tables, nil
+}
+
+// nftMatch is a match expression; left is what is matched, such as
+// {"payload": {"protocol": "tcp", "field": "dport"}} or
+// {"ct": {"key": "state"}}.
+type nftMatch struct {
+	Op    string          `json:"op"`
+	Left  json.RawMessage `json:"left"`
+	Right json.RawMessage `json:"right"`
+}
+
+type nftSelector struct {
+	Payload *struct {
+		Protocol string `json:"protocol"`
+		Field    string `json:"field"`
+	} `json:"payload"`
+	Meta *struct {
+		Key string `json:"key"`
+	} `json:"meta"`
+	Ct *struct {
+		Key string `json:"key"`
+	} `json:"ct"`
+	Fib *struct {
+		Result string   `json:"result"`
+		Flags  []string `json:"flags"`
+	} `json:"fib"`
+}
+
+// key names what a selector matches: "dport", "l4proto", "state",
+// "iif", "saddr", "ctstatus", "fibtype", "ip6" for any IPv6 header field,
+// or "".
+func (s nftSelector) key() (string, string) {
+	switch {
+	case s.Payload != nil && s.Payload.Protocol == "ip6":
+		return "ip6", ""
+	case s.Payload != nil && s.Payload.Field == "dport":
+		return "dport", s.Payload.Protocol
+	case s.Payload != nil && s.Payload.Field == "saddr":
+		return "saddr", ""
+	case s.Payload != nil && s.Payload.Protocol == "ip" && s.Payload.Field == "protocol":
+		return "l4proto", ""
+	case s.Meta != nil && s.Meta.Key == "l4proto":
+		return "l4proto", ""
+	case s.Meta != nil && (s.Meta.Key == "iif" || s.Meta.Key == "iifname"):
+		return "iif", ""
+	case s.Ct != nil && s.Ct.Key == "state":
+		return "state", ""
+	case s.Ct != nil && s.Ct.Key == "status":
+		return "ctstatus", ""
+	case s.Fib != nil && s.Fib.Result == "type" && slices.Contains(s.Fib.Flags, "daddr"):
+		return "fibtype", ""
+	}
+	return "", ""
+}
+
+func parseNftRule(exprs []map[string]json.RawMessage) fwRule {
+	var r fwRule
+	for _, expr := range exprs {
+		for kind, raw := range expr {
+			switch kind {
+			case "match":
+				var m nftMatch
+				var sel nftSelector
+				if json.Unmarshal(raw, &m) != nil || json.Unmarshal(m.Left, &sel) != nil {
+					r.conditional = true
+					continue
+				}
+				key, proto := sel.key()
+				negate := m.Op == "!="
+				values := nftValues(m.Right)
+				switch key {
+				case "iif":
+					r.addCondition(nftCondition(values, fwIsLoopback), negate)
+					continue
+				case "saddr":
+					r.addCondition(nftLoopbackSource(m.Right), negate)
+					continue
+				case "ctstatus":
+					// A connection straight to the host isn't NATed.
+					r.addCondition(nftCondition(values, func(v string) fwCondition {
+						if v == "dnat" || v == "snat" {
+							return fwCondFalse
+						}
+						return fwCondUnknown
+					}), negate)
+					continue
+				case "fibtype":
+					r.addCondition(nftCondition(values, fwLocalType), negate)
+					continue
+				case "ip6":
+					// Ports are checked for IPv4.
+					r.addCondition(fwCondFalse, negate)
+					continue
+				}
+				if key == "" || negate {
+					r.conditional = true
+					continue
+				}
+				switch key {
+				case "dport":
+					r.protocols = []string{proto}
+					for _, v := range values {
+						if pr, ok := parsePortRange(v, "-"); ok {
+							r.ports = append(r.ports, pr)
+						}
+					}
+				case "l4proto":
+					r.protocols = values
+				case "state":
+					r.states = values
+				}
+			case "vmap":
+				var vm struct {
+					Key  json.RawMessage `json:"key"`
+					Data struct {
+						Set [][2]json.RawMessage `json:"set"`
+					} `json:"data"`
+				}
+				var sel nftSelector
+				if json.Unmarshal(raw, &vm) != nil || json.Unmarshal(vm.Key, &sel) != nil {
+					r.conditional = true
+					continue
+				}
+				key, proto := sel.key()
+				if key != "dport" && key != "state" {
+					r.conditional = true
+					continue
+				}
+				if key == "dport" {
+					r.protocols = []string{proto}
+				}
+				r.vmapKey, r.vmap = key, map[string]fwVerdict{}
+				for _, e := range vm.Data.Set {
+					var verdict map[string]json.RawMessage
+					if json.Unmarshal(e[1], &verdict) != nil {
+						continue
+					}
+					v := fwContinue
+					if _, ok := verdict["accept"]; ok {
+						v = fwAccept
+					} else if _, ok := verdict["drop"]; ok {
+						v = fwDrop
+					} else if _, ok := verdict["reject"]; ok {
+						v = fwDrop
+					}
+					for _, k := range nftValues(e[0]) {
+						r.vmap[k] = v
+					}
+				}
+			case "accept":
+				r.verdict = fwAccept
+			case "drop", "reject":
+				r.verdict = fwDrop
+			case "return":
+				r.ret = true
+			case "jump", "goto":
+				var target struct {
+					Target string `json:"target"`
+				}
+				json.Unmarshal(raw, &target)
+				r.jump, r.gotoJump = target.Target, kind == "goto"
+			case "counter", "log", "comment":
+			default:
+				// limit, xt (an iptables-nft match) and the rest.
+				r.conditional = true
+			}
+		}
+	}
+	return r
+}
+
+// nftCondition reads a match against values, which holds if any of them
+// does.
+func nftCondition(values []string, cond func(string) fwCondition) fwCondition {
+	if len(values) == 0 {
+		return fwCondUnknown
+	}
+	c := fwCondFalse
+	for _, v := range values {
+		switch cond(v) {
+		case fwCondTrue:
+			return fwCondTrue
+		case fwCondUnknown:
+			c = fwCondUnknown
+		}
+	}
+	return c
+}
+
+// nftLoopbackSource reads a source address match, which nft gives as an
+// address or {"prefix": {"addr": ..., "len": ...}}.
+func nftLoopbackSource(raw json.RawMessage) fwCondition {
+	var p struct {
+		Prefix *struct {
+			Addr string `json:"addr"`
+			Len  int    `json:"len"`
+		} `json:"prefix"`
+	}
+	if json.Unmarshal(raw, &p) == nil && p.Prefix != nil {
+		return fwLoopbackSource(fmt.Sprintf("%s/%d", p.Prefix.Addr, p.Prefix.Len))
+	}
+	return nftCondition(nftValues(raw), fwLoopbackSource)
+}
+
+// nftValues flattens the right side of a match: a value, a list, a
+// {"set": [...]} or a {"range": [lo, hi]}, which becomes "lo-hi".
+func nftValues(raw json.RawMessage) []string {
+	var n json.Number
+	if json.Unmarshal(raw, &n) == nil {
+		return []string{n.String()}
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return []string{s}
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		var out []string
+		for _, v := range list {
+			out = append(out, nftValues(v)...)
+		}
+		return out
+	}
+	var obj struct {
+		Set   []json.RawMessage   `json:"set"`
+		Range *[2]json.RawMessage `json:"range"`
+	}
+	if json.Unmarshal(raw, &obj) == nil {
+		if obj.Range != nil {
+			lo, hi := nftValues(obj.Range[0]), nftValues(obj.Range[1])
+			if len(lo) == 1 && len(hi) == 1 {
+				return []string{lo[0] + "-" + hi[0]}
+			}
+		}
+		var out []string
+		for _, v := range obj.Set {
+			out = append(out, nftValues(v)...)
+		}
+		return out
+	}
+	return nil
+}
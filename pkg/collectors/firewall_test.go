@@ -0,0 +1,162 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFirewallPortVerdict(t *testing.T) {
+	tests := []struct {
+		file string
+		want map[string]fwVerdict
+	}{
+		{
+			// ufw with 22, 80 and 9100 from the LAN allowed, and 2222 rate
+			// limited. Its ufw-not-local chain returns for local addresses.
+			file: "ufw.iptables",
+			want: map[string]fwVerdict{
+				"22/tcp": fwAccept, "80/tcp": fwAccept, "443/tcp": fwDrop,
+				"9100/tcp": fwUnknown, "2222/tcp": fwAccept, "53/udp": fwDrop,
+			},
+		},
+		{
+			// The stock RHEL 7 iptables-services rules.
+			file: "rhel.iptables",
+			want: map[string]fwVerdict{"22/tcp": fwAccept, "80/tcp": fwDrop, "123/udp": fwDrop},
+		},
+		{
+			// firewalld's iptables backend with the public zone on eth0.
+			file: "firewalld.iptables",
+			want: map[string]fwVerdict{"22/tcp": fwAccept, "80/tcp": fwDrop, "443/tcp": fwAccept},
+		},
+		{
+			// firewalld's nftables backend, the same zone plus cockpit.
+			file: "firewalld.json",
+			want: map[string]fwVerdict{
+				"22/tcp": fwAccept, "80/tcp": fwDrop, "443/tcp": fwAccept,
+				"9090/tcp": fwAccept, "546/udp": fwDrop,
+			},
+		},
+		{
+			// Debian's nftables.conf with a few services allowed.
+			file: "debian.json",
+			want: map[string]fwVerdict{
+				"22/tcp": fwAccept, "80/tcp": fwAccept, "443/tcp": fwAccept,
+				"8443/tcp": fwDrop, "9100/tcp": fwUnknown, "8080/tcp": fwUnknown,
+				"60500/udp": fwAccept, "53/udp": fwDrop,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "firewall", tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tables []*fwTable
+			if filepath.Ext(tc.file) == ".json" {
+				if tables, err = parseNftRuleset(data); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				tables = parseIptablesSave(string(data), "ip")
+			}
+			for p, want := range tc.want {
+				port, proto, err := parseFirewallPort(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := firewallPortVerdict(tables, proto, port); got != want {
+					t.Errorf("%s: verdict = %d, want %d", p, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseIptablesRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		want fwRule
+	}{
+		{"-p tcp -m tcp --dport 22 -j ACCEPT", fwRule{protocols: []string{"tcp"}, ports: [][2]int{{22, 22}}, verdict: fwAccept}},
+		{"-p tcp -m multiport --dports 80,443,8000:8100 -j ACCEPT", fwRule{protocols: []string{"tcp"}, ports: [][2]int{{80, 80}, {443, 443}, {8000, 8100}}, verdict: fwAccept}},
+		{"-j REJECT --reject-with icmp-host-prohibited", fwRule{verdict: fwDrop}},
+		{"-p tcp -j REJECT --reject-with tcp-reset", fwRule{protocols: []string{"tcp"}, verdict: fwDrop}},
+		{"-m state --state RELATED,ESTABLISHED -j ACCEPT", fwRule{states: []string{"related", "established"}, verdict: fwAccept}},
+		{"-m addrtype --dst-type LOCAL -j RETURN", fwRule{ret: true}},
+		{"-m addrtype --dst-type BROADCAST -j ufw-skip-to-policy-input", fwRule{never: true, jump: "ufw-skip-to-policy-input"}},
+		{"-m addrtype ! --dst-type LOCAL -j DROP", fwRule{never: true, verdict: fwDrop}},
+		{"-i lo -j ACCEPT", fwRule{never: true, verdict: fwAccept}},
+		{"! -i lo -s 127.0.0.0/8 -j REJECT", fwRule{never: true, verdict: fwDrop}},
+		{"-i eth0 -g IN_public", fwRule{conditional: true, jump: "IN_public", gotoJump: true}},
+		{"-s 10.0.0.0/8 -p tcp -m tcp --dport 9100 -j ACCEPT", fwRule{conditional: true, protocols: []string{"tcp"}, ports: [][2]int{{9100, 9100}}, verdict: fwAccept}},
+		{"-p tcp -m tcp ! --dport 22 -j DROP", fwRule{protocols: []string{"tcp"}, conditional: true, verdict: fwDrop}},
+		{"-p tcp -m tcp --syn -m conntrack --ctstate NEW -j ACCEPT", fwRule{protocols: []string{"tcp"}, states: []string{"new"}, verdict: fwAccept}},
+		{`-m comment --comment "allow ssh -j DROP" -p tcp --dport 22 -j ACCEPT`, fwRule{protocols: []string{"tcp"}, ports: [][2]int{{22, 22}}, verdict: fwAccept}},
+		{"-m limit --limit 3/min --limit-burst 10 -j LOG --log-prefix \"[UFW BLOCK] \"", fwRule{conditional: true, jump: "LOG"}},
+		{"-p tcp --dport 2222 -m recent --set --name DEFAULT --rsource", fwRule{conditional: true, protocols: []string{"tcp"}, ports: [][2]int{{2222, 2222}}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.rule, func(t *testing.T) {
+			got := parseIptablesRule(splitIptablesArgs(tc.rule))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseIptablesRule() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseIptablesSave(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "firewall", "rhel.iptables"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := parseIptablesSave(string(data), "ip")
+	if len(tables) != 1 {
+		t.Fatalf("parseIptablesSave() = %d tables, want 1", len(tables))
+	}
+	tbl := tables[0]
+	if tbl.name != "filter" || !reflect.DeepEqual(tbl.hooks, []string{"INPUT"}) {
+		t.Errorf("table %s hooks = %v, want filter [INPUT]", tbl.name, tbl.hooks)
+	}
+	if want := []string{"INPUT", "FORWARD", "OUTPUT"}; !reflect.DeepEqual(tbl.order, want) {
+		t.Errorf("chains = %v, want %v", tbl.order, want)
+	}
+	if want := map[string]string{"INPUT": "accept", "FORWARD": "accept", "OUTPUT": "accept"}; !reflect.DeepEqual(tbl.policies, want) {
+		t.Errorf("policies = %v, want %v", tbl.policies, want)
+	}
+	if n := len(tbl.chains["INPUT"]); n != 5 {
+		t.Errorf("INPUT has %d rules, want 5", n)
+	}
+}
+
+func TestParseNftRuleset(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "firewall", "firewalld.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables, err := parseNftRuleset(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("parseNftRuleset() = %d tables, want 1", len(tables))
+	}
+	tbl := tables[0]
+	if tbl.family != "inet" || tbl.name != "firewalld" {
+		t.Errorf("table = %s %s, want inet firewalld", tbl.family, tbl.name)
+	}
+	// The nat and prerouting chains aren't on the input hook.
+	if want := []string{"filter_INPUT"}; !reflect.DeepEqual(tbl.hooks, want) {
+		t.Errorf("hooks = %v, want %v", tbl.hooks, want)
+	}
+	if n := len(tbl.order); n != 13 {
+		t.Errorf("%d chains, want 13", n)
+	}
+	if _, err := parseNftRuleset([]byte("Error: syntax error")); err == nil {
+		t.Error("parseNftRuleset() of nft's error output succeeded")
+	}
+}
@@ -0,0 +1,214 @@
+package collectors
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+const drmRoot = "/sys/class/drm"
+
+// nvidiaGPUFields are queried from nvidia-smi in this order. Memory is in
+// MiB and power in watts with nounits.
+var nvidiaGPUFields = []string{
+	"index", "uuid", "name", "utilization.gpu", "utilization.memory",
+	"memory.used", "memory.total", "temperature.gpu", "power.draw", "power.limit", "fan.speed",
+}
+
+// GPUCollector reports NVIDIA GPUs through nvidia-smi, which ships with the
+// driver and wraps NVML, and AMD GPUs through the amdgpu driver's sysfs
+// files. Hosts without either report nothing.
+type GPUCollector struct {
+	// NvidiaSMI is the nvidia-smi binary.
+	NvidiaSMI string `json:"nvidia_smi"`
+	// Processes adds the GPU memory used by each process, NVIDIA only.
+	Processes bool `json:"processes"`
+
+	none sync.Once
+}
+
+func NewGPUCollector(cfg config.CollectorConfig) (Collector, error) {
+	g := &GPUCollector{NvidiaSMI: "nvidia-smi", Processes: true}
+	if err := cfg.Decode(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *GPUCollector) Name() string {
+	return "gpu"
+}
+
+func (g *GPUCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+	found := false
+
+	if _, err := exec.LookPath(g.NvidiaSMI); err == nil {
+		found = true
+		if err := g.addNvidia(ctx, b); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if addAMD(b) {
+		found = true
+	}
+	if !found {
+		g.none.Do(func() { log.Info().Msg("No NVIDIA or AMD GPU found") })
+		return nil, nil
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func (g *GPUCollector) addNvidia(ctx context.Context, b *metric.Builder) error {
+	rows, err := g.query(ctx, "--query-gpu="+strings.Join(nvidiaGPUFields, ","))
+	if err != nil {
+		return err
+	}
+	byUUID := map[string]string{}
+	for _, row := range rows {
+		if len(row) != len(nvidiaGPUFields) {
+			continue
+		}
+		gpu := "nvidia" + row[0]
+		byUUID[row[1]] = gpu
+		labels := []string{"gpu", gpu}
+		b.Gauge("gpu.info", 1, "", "gpu", gpu, "vendor", "nvidia", "model", row[2], "uuid", row[1])
+		// Fields a GPU doesn't support read "[N/A]" or "[Not Supported]".
+		value := func(i int) (float64, bool) {
+			v, err := strconv.ParseFloat(row[i], 64)
+			return v, err == nil
+		}
+		if v, ok := value(3); ok {
+			b.Gauge("gpu.utilization_percent", v, "percent", labels...)
+		}
+		if v, ok := value(4); ok {
+			b.Gauge("gpu.memory_utilization_percent", v, "percent", labels...)
+		}
+		used, okUsed := value(5)
+		total, okTotal := value(6)
+		if okUsed && okTotal {
+			addGPUMemory(b, used*(1<<20), total*(1<<20), labels)
+		}
+		if v, ok := value(7); ok {
+			b.Gauge("gpu.temperature", v, "celsius", labels...)
+		}
+		if v, ok := value(8); ok {
+			b.Gauge("gpu.power_draw", v, "watts", labels...)
+		}
+		if v, ok := value(9); ok {
+			b.Gauge("gpu.power_limit", v, "watts", labels...)
+		}
+		if v, ok := value(10); ok {
+			b.Gauge("gpu.fan_speed_percent", v, "percent", labels...)
+		}
+	}
+
+	if !g.Processes {
+		return nil
+	}
+	rows, err = g.query(ctx, "--query-compute-apps=gpu_uuid,pid,process_name,used_memory")
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if len(row) != 4 {
+			continue
+		}
+		mib, err := strconv.ParseFloat(row[3], 64)
+		if err != nil {
+			continue
+		}
+		b.Gauge("gpu.process_memory", mib*(1<<20), "bytes",
+			"gpu", byUUID[row[0]], "pid", row[1], "process", filepath.Base(row[2]))
+	}
+	return nil
+}
+
+func (g *GPUCollector) query(ctx context.Context, query string) ([][]string, error) {
+	out, err := runCommand(ctx, g.NvidiaSMI, query, "--format=csv,noheader,nounits")
+	if err != nil {
+		// nvidia-smi reports errors such as a driver mismatch on stdout.
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	r := csv.NewReader(strings.NewReader(string(out)))
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parsing nvidia-smi output: %w", err)
+	}
+	return rows, nil
+}
+
+// addAMD reads the cards bound to amdgpu, reporting whether there were
+// any.
+func addAMD(b *metric.Builder) bool {
+	cards, _ := filepath.Glob(filepath.Join(drmRoot, "card[0-9]*"))
+	found := false
+	for _, card := range cards {
+		// Connectors such as card0-DP-1 share the prefix.
+		if strings.Contains(filepath.Base(card), "-") {
+			continue
+		}
+		dev := filepath.Join(card, "device")
+		if readSysfsString(filepath.Join(dev, "vendor")) != "0x1002" {
+			continue
+		}
+		found = true
+		gpu := filepath.Base(card)
+		labels := []string{"gpu", gpu}
+		model := readSysfsString(filepath.Join(dev, "product_name"))
+		b.Gauge("gpu.info", 1, "", "gpu", gpu, "vendor", "amd", "model", model, "uuid", readSysfsString(filepath.Join(dev, "unique_id")))
+		if v, err := readSysfsFloat(filepath.Join(dev, "gpu_busy_percent")); err == nil {
+			b.Gauge("gpu.utilization_percent", v, "percent", labels...)
+		}
+		used, errUsed := readSysfsFloat(filepath.Join(dev, "mem_info_vram_used"))
+		total, errTotal := readSysfsFloat(filepath.Join(dev, "mem_info_vram_total"))
+		if errUsed == nil && errTotal == nil {
+			addGPUMemory(b, used, total, labels)
+		}
+		hwmons, _ := filepath.Glob(filepath.Join(dev, "hwmon", "hwmon*"))
+		for _, hwmon := range hwmons {
+			// temp1 is the edge temperature, in millidegrees; power in
+			// microwatts.
+			if v, err := readSysfsFloat(filepath.Join(hwmon, "temp1_input")); err == nil {
+				b.Gauge("gpu.temperature", v/1000, "celsius", labels...)
+			}
+			power, err := readSysfsFloat(filepath.Join(hwmon, "power1_average"))
+			if err != nil {
+				power, err = readSysfsFloat(filepath.Join(hwmon, "power1_input"))
+			}
+			if err == nil {
+				b.Gauge("gpu.power_draw", power/1e6, "watts", labels...)
+			}
+			if v, err := readSysfsFloat(filepath.Join(hwmon, "power1_cap")); err == nil {
+				b.Gauge("gpu.power_limit", v/1e6, "watts", labels...)
+			}
+		}
+	}
+	return found
+}
+
+func addGPUMemory(b *metric.Builder, used, total float64, labels []string) {
+	b.Gauge("gpu.memory_used", used, "bytes", labels...)
+	b.Gauge("gpu.memory_total", total, "bytes", labels...)
+	if total > 0 {
+		b.Gauge("gpu.memory_used_percent", 100*used/total, "percent", labels...)
+	}
+}
@@ -0,0 +1,55 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"glass/pkg/metric"
+
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/load"
+)
+
+type HostCollector struct{}
+
+func (h *HostCollector) Name() string {
+	return "host"
+}
+
+func (h *HostCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	avg, err := load.AvgWithContext(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("getting load average: %w", err))
+	} else {
+		b.Gauge("host.load1", avg.Load1, "")
+		b.Gauge("host.load5", avg.Load5, "")
+		b.Gauge("host.load15", avg.Load15, "")
+	}
+
+	info, err := host.InfoWithContext(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("getting host info: %w", err))
+	} else {
+		b.Gauge("host.info", 1, "",
+			"hostname", info.Hostname,
+			"os", info.OS,
+			"platform", info.Platform,
+			"platform_family", info.PlatformFamily,
+			"platform_version", info.PlatformVersion,
+			"kernel_version", info.KernelVersion,
+			"kernel_arch", info.KernelArch,
+			"virtualization_system", info.VirtualizationSystem,
+			"virtualization_role", info.VirtualizationRole,
+			"host_id", info.HostID,
+		)
+		b.Gauge("host.uptime", float64(info.Uptime), "seconds")
+		b.Gauge("host.boot_time", float64(info.BootTime), "seconds")
+		b.Gauge("host.procs", float64(info.Procs), "")
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
@@ -0,0 +1,212 @@
+package collectors
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// maxProbeBody bounds how much of a response is read for content matching.
+const maxProbeBody = 1 << 20
+
+type HTTPTarget struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	// ExpectStatus lists acceptable status codes; any 2xx when empty.
+	ExpectStatus []int `json:"expect_status"`
+	// Match is a regular expression the body must match.
+	Match string `json:"match"`
+	// InsecureSkipVerify accepts invalid certificates. Validity is still
+	// reported, it just doesn't fail the probe.
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+	NoFollowRedirects  bool `json:"no_follow_redirects"`
+
+	match *regexp.Regexp
+}
+
+type HTTPProbeCollector struct {
+	Targets []HTTPTarget `json:"targets"`
+}
+
+func NewHTTPProbeCollector(cfg config.CollectorConfig) (Collector, error) {
+	h := &HTTPProbeCollector{}
+	if err := cfg.Decode(h); err != nil {
+		return nil, err
+	}
+	for i := range h.Targets {
+		t := &h.Targets[i]
+		if t.URL == "" {
+			return nil, errors.New("target url is required")
+		}
+		if t.Method == "" {
+			t.Method = http.MethodGet
+		}
+		if t.Match != "" {
+			re, err := regexp.Compile(t.Match)
+			if err != nil {
+				return nil, fmt.Errorf("invalid match for %s: %w", t.URL, err)
+			}
+			t.match = re
+		}
+	}
+	return h, nil
+}
+
+func (h *HTTPProbeCollector) Name() string {
+	return "http"
+}
+
+func (h *HTTPProbeCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		out  []metric.Metric
+		errs []error
+	)
+	for _, t := range h.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each probe keeps its own builder so that timings aren't
+			// skewed by waiting on the others.
+			b := metric.NewBuilder(time.Now())
+			err := probeHTTP(ctx, b, t)
+			mu.Lock()
+			defer mu.Unlock()
+			out = append(out, b.Metrics()...)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("probing %s: %w", t.URL, err))
+			}
+		}()
+	}
+	wg.Wait()
+	return out, errors.Join(errs...)
+}
+
+// probeHTTP fetches the target over a fresh connection so that every
+// phase is measured, and reports how long each took.
+func probeHTTP(ctx context.Context, b *metric.Builder, t HTTPTarget) error {
+	labels := []string{"url", t.URL}
+	var (
+		start                     = time.Now()
+		dnsStart, dnsDone         time.Time
+		connectStart, connectDone time.Time
+		tlsStart, tlsDone         time.Time
+		firstByte                 time.Time
+	)
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { connectDone = time.Now() },
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tlsDone = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	var body io.Reader
+	if t.Body != "" {
+		body = strings.NewReader(t.Body)
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), t.Method, t.URL, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range t.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			DisableKeepAlives: true,
+			// Verification happens below so that an invalid certificate
+			// is reported rather than hiding every other measurement.
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	if t.NoFollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		b.Gauge("http.probe_success", 0, "", labels...)
+		return err
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+	resp.Body.Close()
+	done := time.Now()
+	if err != nil {
+		b.Gauge("http.probe_success", 0, "", labels...)
+		return fmt.Errorf("reading body: %w", err)
+	}
+
+	phase := func(name string, from, to time.Time) {
+		if !from.IsZero() && !to.IsZero() {
+			b.Gauge("http.phase_duration", to.Sub(from).Seconds(), "seconds", "url", t.URL, "phase", name)
+		}
+	}
+	phase("dns", dnsStart, dnsDone)
+	phase("connect", connectStart, connectDone)
+	phase("tls", tlsStart, tlsDone)
+	phase("ttfb", start, firstByte)
+	phase("transfer", firstByte, done)
+	b.Gauge("http.duration", done.Sub(start).Seconds(), "seconds", labels...)
+	b.Gauge("http.status_code", float64(resp.StatusCode), "", labels...)
+	b.Gauge("http.content_length", float64(len(content)), "bytes", labels...)
+
+	var failures []string
+	if !statusOK(resp.StatusCode, t.ExpectStatus) {
+		failures = append(failures, "unexpected status "+strconv.Itoa(resp.StatusCode))
+	}
+	if t.match != nil {
+		matched := t.match.Match(content)
+		b.Gauge("http.content_match", boolValue(matched), "", labels...)
+		if !matched {
+			failures = append(failures, "body does not match "+t.match.String())
+		}
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		// After redirects the certificate is the last host's.
+		chain := resp.TLS.PeerCertificates
+		verr := verifyChain(chain, resp.Request.URL.Hostname())
+		b.Gauge("http.tls_valid", boolValue(verr == nil), "", labels...)
+		b.Gauge("http.cert_expiry_days", time.Until(chain[0].NotAfter).Hours()/24, "days", labels...)
+		if verr != nil && !t.InsecureSkipVerify {
+			failures = append(failures, "invalid certificate: "+verr.Error())
+		}
+	}
+
+	b.Gauge("http.probe_success", boolValue(len(failures) == 0), "", labels...)
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func statusOK(code int, expect []int) bool {
+	if len(expect) == 0 {
+		return code/100 == 2
+	}
+	return slices.Contains(expect, code)
+}
@@ -0,0 +1,195 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// ipmiUnits maps ipmitool's sensor units to metrics.
+var ipmiUnits = map[string]struct {
+	name string
+	unit string
+}{
+	"degrees C": {"ipmi.temperature", "celsius"},
+	"RPM":       {"ipmi.fan_speed", "rpm"},
+	"Volts":     {"ipmi.voltage", "volts"},
+	"Amps":      {"ipmi.current", "amps"},
+	"Watts":     {"ipmi.power", "watts"},
+}
+
+// ipmiChassisFaults maps `ipmitool chassis status` lines to the fault
+// label of ipmi.chassis_fault.
+var ipmiChassisFaults = []struct {
+	field string
+	fault string
+}{
+	{"Power Overload", "power_overload"},
+	{"Main Power Fault", "main_power"},
+	{"Power Control Fault", "power_control"},
+	{"Drive Fault", "drive"},
+	{"Cooling/Fan Fault", "cooling"},
+}
+
+// IPMICollector reports a server's BMC sensors, chassis state and system
+// event log through ipmitool. Locally it needs root and the ipmi_devintf
+// module; with Host set it queries a BMC over the network instead.
+type IPMICollector struct {
+	// Ipmitool is the ipmitool binary.
+	Ipmitool string `json:"ipmitool"`
+	// Host is a remote BMC, queried over Interface with Username and
+	// Password.
+	Host      string `json:"host"`
+	Interface string `json:"interface"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+}
+
+func NewIPMICollector(cfg config.CollectorConfig) (Collector, error) {
+	i := &IPMICollector{Ipmitool: "ipmitool", Interface: "lanplus"}
+	if err := cfg.Decode(i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (i *IPMICollector) Name() string {
+	return "ipmi"
+}
+
+func (i *IPMICollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	if rows, err := i.table(ctx, "sensor"); err != nil {
+		errs = append(errs, err)
+	} else {
+		addIPMISensors(b, rows)
+	}
+	if rows, err := i.table(ctx, "sdr", "type", "Power Supply"); err != nil {
+		errs = append(errs, err)
+	} else {
+		addIPMIPowerSupplies(b, rows)
+	}
+	if status, err := i.fields(ctx, "chassis", "status"); err != nil {
+		errs = append(errs, err)
+	} else {
+		b.Gauge("ipmi.chassis_power_on", boolValue(status["System Power"] == "on"), "")
+		if v, ok := status["Chassis Intrusion"]; ok {
+			b.Gauge("ipmi.chassis_intrusion", boolValue(v == "active"), "")
+		}
+		for _, f := range ipmiChassisFaults {
+			if v, ok := status[f.field]; ok {
+				b.Gauge("ipmi.chassis_fault", boolValue(v == "true"), "", "fault", f.fault)
+			}
+		}
+	}
+	if sel, err := i.fields(ctx, "sel", "info"); err != nil {
+		errs = append(errs, err)
+	} else {
+		if v, err := strconv.ParseFloat(sel["Entries"], 64); err == nil {
+			b.Gauge("ipmi.sel_entries", v, "")
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSuffix(sel["Percent Used"], "%"), 64); err == nil {
+			b.Gauge("ipmi.sel_used_percent", v, "percent")
+		}
+		if v, ok := sel["Overflow"]; ok {
+			b.Gauge("ipmi.sel_overflow", boolValue(v == "true"), "")
+		}
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// addIPMISensors reads `ipmitool sensor`, whose columns are name, reading,
+// unit, status and then the thresholds. Threshold sensors have a status of
+// ok, nc, cr or nr (non-critical, critical, non-recoverable), or ns when
+// there's no reading; discrete sensors have a hex state instead.
+func addIPMISensors(b *metric.Builder, rows [][]string) {
+	for _, row := range rows {
+		if len(row) < 4 {
+			continue
+		}
+		name, reading, unit, status := row[0], row[1], row[2], row[3]
+		m, ok := ipmiUnits[unit]
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(reading, 64)
+		if err != nil {
+			continue
+		}
+		b.Gauge(m.name, v, m.unit, "sensor", name)
+		switch status {
+		case "ok", "nc", "cr", "nr":
+			b.Gauge("ipmi.sensor_ok", boolValue(status == "ok"), "", "sensor", name)
+		}
+	}
+}
+
+// addIPMIPowerSupplies reads `ipmitool sdr type "Power Supply"`, whose
+// last column lists the asserted states, such as "Presence detected,
+// Power Supply AC lost".
+func addIPMIPowerSupplies(b *metric.Builder, rows [][]string) {
+	for _, row := range rows {
+		if len(row) < 5 || row[2] == "ns" {
+			continue
+		}
+		states := strings.ToLower(row[4])
+		failed := strings.Contains(states, "failure") || strings.Contains(states, "lost")
+		b.Gauge("ipmi.psu_ok", boolValue(!failed), "", "sensor", row[0])
+	}
+}
+
+// table runs ipmitool and splits its output into rows of "|"-separated
+// columns.
+func (i *IPMICollector) table(ctx context.Context, args ...string) ([][]string, error) {
+	out, err := i.ipmitool(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	for _, line := range strings.Split(string(out), "\n") {
+		if !strings.Contains(line, "|") {
+			continue
+		}
+		cols := strings.Split(line, "|")
+		for j := range cols {
+			cols[j] = strings.TrimSpace(cols[j])
+		}
+		rows = append(rows, cols)
+	}
+	return rows, nil
+}
+
+// fields runs ipmitool and reads its "Name : value" output.
+func (i *IPMICollector) fields(ctx context.Context, args ...string) (map[string]string, error) {
+	out, err := i.ipmitool(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return values, nil
+}
+
+func (i *IPMICollector) ipmitool(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, i.Ipmitool)
+	if i.Host != "" {
+		// -E reads the password from IPMI_PASSWORD, keeping it out of ps.
+		cmd.Args = append(cmd.Args, "-I", i.Interface, "-H", i.Host, "-U", i.Username, "-E")
+		cmd.Env = append(os.Environ(), "IPMI_PASSWORD="+i.Password)
+	}
+	cmd.Args = append(cmd.Args, args...)
+	return runCmd(cmd)
+}
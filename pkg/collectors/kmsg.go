@@ -0,0 +1,119 @@
+package collectors
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+// kmsgClasses are the kinds of kernel messages counted, and the pattern
+// each matches with the device, interface or process it is about as the
+// first group.
+var kmsgClasses = []struct {
+	class   string
+	pattern *regexp.Regexp
+}{
+	// "Out of memory: Killed process 1234 (mysqld) ..." and the same after
+	// "Memory cgroup out of memory:".
+	{"oom_kill", regexp.MustCompile(`Killed process \d+ \(([^)]*)\)`)},
+	// "I/O error, dev sda, sector ..." and "Buffer I/O error on dev sda1, ...".
+	{"io_error", regexp.MustCompile(`I/O error,? (?:on )?dev ([^ ,]+)`)},
+	{"fs_readonly", regexp.MustCompile(`\(([^)]+)\): (?:Remounting filesystem read-only|.*forced readonly|.*Shutting down filesystem)`)},
+	// "eth0: NIC Link is Down" and "eno1 NIC Link is Down".
+	{"link_down", regexp.MustCompile(`([^ :]+):? (?:NIC )?Link is Down`)},
+	{"segfault", regexp.MustCompile(`([^ ]+)\[\d+\]: segfault at`)},
+}
+
+// kmsgEvent is a kernel message that matched one of kmsgClasses.
+type kmsgEvent struct {
+	Time    time.Time
+	Class   string
+	Subject string
+	Message string
+}
+
+// KmsgCollector reads new kernel messages from /dev/kmsg each collection
+// and counts OOM kills, IO errors, filesystems going read-only, links going
+// down and segfaults. Matching messages are also logged as warnings.
+// Reading /dev/kmsg needs CAP_SYSLOG when kernel.dmesg_restrict is set.
+type KmsgCollector struct {
+	mu     sync.Mutex
+	fd     int
+	opened bool
+	boot   time.Time
+	counts map[string]float64
+}
+
+func (k *KmsgCollector) Name() string {
+	return "kmsg"
+}
+
+func (k *KmsgCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	events, err := k.read()
+	if !k.opened {
+		return nil, err
+	}
+	fresh := map[string]float64{}
+	for _, e := range events {
+		fresh[e.Class]++
+		k.counts[e.Class]++
+		log.Warn().Str("collector", "kmsg").Str("class", e.Class).Str("subject", e.Subject).
+			Time("kernel_time", e.Time).Msg(e.Message)
+	}
+
+	b := metric.NewBuilder(time.Now())
+	for _, c := range kmsgClasses {
+		b.Counter("kmsg.events", k.counts[c.class], "", "class", c.class)
+		b.Gauge("kmsg.new_events", fresh[c.class], "", "class", c.class)
+	}
+	return b.Metrics(), err
+}
+
+// read returns the classified messages logged since the last call. The
+// first call starts at the end of the ring buffer, so messages from before
+// glass started aren't counted.
+func (k *KmsgCollector) read() ([]kmsgEvent, error) {
+	if !k.opened {
+		fd, err := openKmsg(true)
+		if err != nil {
+			return nil, err
+		}
+		k.fd, k.opened, k.boot, k.counts = fd, true, bootTime(), map[string]float64{}
+	}
+	records, err := readKmsg(k.fd, k.boot)
+	var events []kmsgEvent
+	for _, r := range records {
+		for _, c := range kmsgClasses {
+			if m := c.pattern.FindStringSubmatch(r.Message); m != nil {
+				events = append(events, kmsgEvent{Time: r.Time, Class: c.class, Subject: m[1], Message: r.Message})
+				break
+			}
+		}
+	}
+	return events, err
+}
+
+// kmsgRecord is a message from the kernel ring buffer.
+type kmsgRecord struct {
+	Time    time.Time
+	Message string
+}
+
+// bootTime is when the host booted, from /proc/stat, or the zero time.
+func bootTime() time.Time {
+	if stat, err := readKeyValues("/proc/stat"); err == nil {
+		if btime, err := strconv.ParseInt(stat["btime"], 10, 64); err == nil {
+			return time.Unix(btime, 0)
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,69 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// openKmsg opens /dev/kmsg at the oldest record still in the ring buffer,
+// or after the newest with end set. Reads block without O_NONBLOCK, and
+// os.File would park on the poller instead of returning EAGAIN once the
+// buffer is drained, so the descriptor is used directly.
+func openKmsg(end bool) (int, error) {
+	fd, err := syscall.Open("/dev/kmsg", syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, fmt.Errorf("opening /dev/kmsg: %w", err)
+	}
+	if end {
+		if _, err := syscall.Seek(fd, 0, io.SeekEnd); err != nil {
+			syscall.Close(fd)
+			return -1, fmt.Errorf("seeking /dev/kmsg: %w", err)
+		}
+	}
+	return fd, nil
+}
+
+func closeKmsg(fd int) {
+	syscall.Close(fd)
+}
+
+// readKmsg reads the records from fd up to the newest. Each record looks
+// like "6,1234,5678901,-;message" followed by " KEY=value" lines, and the
+// third field is microseconds since boot.
+func readKmsg(fd int, boot time.Time) ([]kmsgRecord, error) {
+	var records []kmsgRecord
+	buf := make([]byte, 8192)
+	for {
+		// Each read returns one record.
+		n, err := syscall.Read(fd, buf)
+		switch {
+		case errors.Is(err, syscall.EAGAIN):
+			return records, nil
+		case errors.Is(err, syscall.EPIPE):
+			// Records were overwritten before they were read; the next read
+			// continues with the oldest one left.
+			continue
+		case errors.Is(err, syscall.EINTR):
+			continue
+		case err != nil:
+			return records, fmt.Errorf("reading /dev/kmsg: %w", err)
+		}
+		header, message, ok := strings.Cut(string(buf[:n]), ";")
+		if !ok {
+			continue
+		}
+		message, _, _ = strings.Cut(message, "\n")
+		r := kmsgRecord{Time: time.Now(), Message: message}
+		if f := strings.Split(header, ","); len(f) >= 3 && !boot.IsZero() {
+			if us, err := strconv.ParseInt(f[2], 10, 64); err == nil {
+				r.Time = boot.Add(time.Duration(us) * time.Microsecond)
+			}
+		}
+		records = append(records, r)
+	}
+}
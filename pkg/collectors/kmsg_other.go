@@ -0,0 +1,21 @@
+//go:build !linux
+
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// openKmsg fails, as /dev/kmsg is Linux's; on Windows the System event log
+// takes its place.
+func openKmsg(bool) (int, error) {
+	return -1, fmt.Errorf("opening the kernel log: %w", errors.ErrUnsupported)
+}
+
+func closeKmsg(int) {}
+
+func readKmsg(int, time.Time) ([]kmsgRecord, error) {
+	return nil, fmt.Errorf("reading the kernel log: %w", errors.ErrUnsupported)
+}
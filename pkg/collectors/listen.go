@@ -0,0 +1,135 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// Listener is a socket accepting connections or, for UDP, bound to a
+// port.
+type Listener struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     uint32 `json:"port"`
+	PID      int32  `json:"pid,omitempty"`
+	Process  string `json:"process,omitempty"`
+}
+
+// Exposed is whether the socket can be reached from other hosts: it is
+// bound to a wildcard or a non-loopback address.
+func (l Listener) Exposed() bool {
+	ip := net.ParseIP(l.Address)
+	return ip == nil || !ip.IsLoopback()
+}
+
+func (l Listener) key() string {
+	return fmt.Sprintf("%s %s %d", l.Protocol, l.Address, l.Port)
+}
+
+// Listening lists the TCP sockets in LISTEN and the bound UDP sockets,
+// sorted by port. Owners are named from names, or from /proc when names is
+// nil; sockets of other users' processes only have owners as root.
+func Listening(ctx context.Context, names map[int32]string) ([]Listener, error) {
+	conns, err := gnet.ConnectionsWithContext(ctx, "inet")
+	if err != nil {
+		return nil, fmt.Errorf("listing sockets: %w", err)
+	}
+	seen := map[Listener]bool{}
+	var out []Listener
+	for _, c := range conns {
+		var proto string
+		switch {
+		case c.Type == syscall.SOCK_STREAM && c.Status == "LISTEN":
+			proto = "tcp"
+		case c.Type == syscall.SOCK_DGRAM && c.Raddr.Port == 0:
+			proto = "udp"
+		default:
+			continue
+		}
+		if c.Family == syscall.AF_INET6 {
+			proto += "6"
+		}
+		l := Listener{Protocol: proto, Address: c.Laddr.IP, Port: c.Laddr.Port, PID: c.Pid}
+		if names != nil {
+			l.Process = names[c.Pid]
+		} else if c.Pid > 0 {
+			l.Process = readSysfsString(fmt.Sprintf("/proc/%d/comm", c.Pid))
+		}
+		if !seen[l] {
+			seen[l] = true
+			out = append(out, l)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Port != out[j].Port {
+			return out[i].Port < out[j].Port
+		}
+		if out[i].Protocol != out[j].Protocol {
+			return out[i].Protocol < out[j].Protocol
+		}
+		return out[i].Address < out[j].Address
+	})
+	return out, nil
+}
+
+// ListenCollector reports every listening socket with its owner and
+// whether it is exposed beyond loopback. Sockets that weren't there the
+// collection before are logged as warnings, since an unexpected listener
+// is often the first sign of a compromise or a misconfigured service.
+type ListenCollector struct {
+	mu   sync.Mutex
+	last map[string]bool
+}
+
+func (lc *ListenCollector) Name() string {
+	return "listen"
+}
+
+func (lc *ListenCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	ls, err := Listening(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	b := metric.NewBuilder(time.Now())
+	current := make(map[string]bool, len(ls))
+	fresh, freshExposed, exposed := 0, 0, 0
+	for _, l := range ls {
+		current[l.key()] = true
+		b.Gauge("listen.socket", 1, "",
+			"protocol", l.Protocol, "address", l.Address, "port", strconv.Itoa(int(l.Port)),
+			"process", l.Process, "exposed", strconv.FormatBool(l.Exposed()))
+		if l.Exposed() {
+			exposed++
+		}
+		// The first collection has nothing to compare with.
+		if lc.last != nil && !lc.last[l.key()] {
+			fresh++
+			if l.Exposed() {
+				freshExposed++
+			}
+			log.Warn().Str("collector", "listen").Str("protocol", l.Protocol).Str("address", l.Address).
+				Uint32("port", l.Port).Int32("pid", l.PID).Str("process", l.Process).
+				Bool("exposed", l.Exposed()).Msg("New listening socket")
+		}
+	}
+	lc.last = current
+	b.Gauge("listen.sockets", float64(len(ls)), "")
+	b.Gauge("listen.exposed_sockets", float64(exposed), "")
+	b.Gauge("listen.new_sockets", float64(fresh), "")
+	b.Gauge("listen.new_exposed_sockets", float64(freshExposed), "")
+	return b.Metrics(), nil
+}
@@ -0,0 +1,152 @@
+package collectors
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+// logFileState is a log's size when it was last seen.
+type logFileState struct {
+	size int64
+	ino  uint64
+	at   time.Time
+}
+
+// LogFilesCollector watches the logs matching Paths, globs like
+// /var/log/nginx/*.log, and reports each one's size, how fast it grew
+// since the last collection and how long ago it was last written, so a
+// log that debug logging or an error loop is filling is caught before the
+// disk is. Rotated and compressed archives don't match the default globs.
+type LogFilesCollector struct {
+	Paths []string `json:"paths"`
+	// MaxFiles bounds how many logs are reported individually, the largest
+	// first; totals cover all of them.
+	MaxFiles int `json:"max_files"`
+
+	truncated sync.Once
+
+	mu   sync.Mutex
+	last map[string]logFileState
+}
+
+func NewLogFilesCollector(cfg config.CollectorConfig) (Collector, error) {
+	l := &LogFilesCollector{
+		Paths:    []string{"/var/log/*.log", "/var/log/*/*.log", "/var/log/syslog", "/var/log/messages"},
+		MaxFiles: 100,
+	}
+	if err := cfg.Decode(l); err != nil {
+		return nil, err
+	}
+	for _, p := range l.Paths {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid log path %q: %w", p, err)
+		}
+	}
+	return l, nil
+}
+
+func (l *LogFilesCollector) Name() string {
+	return "logfiles"
+}
+
+func (l *LogFilesCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	type logFile struct {
+		path    string
+		size    int64
+		growth  float64
+		grew    bool
+		modTime time.Time
+	}
+	now := time.Now()
+	current := map[string]logFileState{}
+	var (
+		files []logFile
+		errs  []error
+	)
+	for _, pattern := range l.Paths {
+		paths, _ := filepath.Glob(pattern)
+		for _, path := range paths {
+			if _, ok := current[path]; ok {
+				continue
+			}
+			info, err := os.Stat(path)
+			if errors.Is(err, os.ErrNotExist) {
+				// Rotated away between the glob and the stat.
+				continue
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !info.Mode().IsRegular() {
+				continue
+			}
+			st, _ := statOf(info)
+			state := logFileState{size: info.Size(), ino: st.ino, at: now}
+			current[path] = state
+			f := logFile{path: path, size: info.Size(), modTime: info.ModTime()}
+			if prev, ok := l.last[path]; ok {
+				grown := state.size - prev.size
+				// A rotated or truncated log started over from nothing.
+				if state.ino != prev.ino || grown < 0 {
+					grown = state.size
+				}
+				if elapsed := now.Sub(prev.at).Seconds(); elapsed > 0 {
+					f.growth, f.grew = float64(grown)/elapsed, true
+				}
+			}
+			files = append(files, f)
+		}
+	}
+	l.last = current
+
+	b := metric.NewBuilder(now)
+	var (
+		total       int64
+		totalGrowth float64
+		grew        bool
+	)
+	for _, f := range files {
+		total += f.size
+		totalGrowth += f.growth
+		grew = grew || f.grew
+	}
+	b.Gauge("logfiles.files", float64(len(files)), "")
+	b.Gauge("logfiles.total_size", float64(total), "bytes")
+	if l.MaxFiles > 0 && len(files) > l.MaxFiles {
+		l.truncated.Do(func() {
+			log.Info().Int("files", len(files)).Int("max_files", l.MaxFiles).
+				Msg("More logs than max_files; reporting only the largest")
+		})
+		slices.SortFunc(files, func(x, y logFile) int { return cmp.Compare(y.size, x.size) })
+		files = files[:l.MaxFiles]
+	}
+	for _, f := range files {
+		labels := []string{"path", f.path}
+		b.Gauge("logfiles.size", float64(f.size), "bytes", labels...)
+		b.Gauge("logfiles.last_write_age", now.Sub(f.modTime).Seconds(), "seconds", labels...)
+		if f.grew {
+			b.Gauge("logfiles.growth", f.growth, "bytes/s", labels...)
+		}
+	}
+	// Growth needs a previous collection to compare with.
+	if grew {
+		b.Gauge("logfiles.total_growth", totalGrowth, "bytes/s")
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
@@ -0,0 +1,111 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxLogRead bounds how much of a log one collection reads. A denial storm
+// or brute-force attack can write gigabytes; past this the rest is skipped.
+const maxLogRead = 16 << 20
+
+// logTail reads the lines appended to a log since the last read,
+// starting over when it is rotated or truncated. The first read only finds
+// the end, unless fromStart is set and it returns what the log holds.
+type logTail struct {
+	path      string
+	fromStart bool
+	offset    int64
+	ino       uint64
+	started   bool
+}
+
+func (t *logTail) read() ([]string, error) {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", t.path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", t.path, err)
+	}
+	st, _ := statOf(info)
+	switch {
+	case !t.started && !t.fromStart:
+		t.started, t.offset, t.ino = true, info.Size(), st.ino
+		return nil, nil
+	case !t.started, st.ino != t.ino || info.Size() < t.offset:
+		t.started = true
+		t.offset, t.ino = 0, st.ino
+	}
+	if info.Size()-t.offset > maxLogRead {
+		t.offset = info.Size() - maxLogRead
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", t.path, err)
+	}
+
+	// A line still being written is left for the next read.
+	var lines []string
+	r := bufio.NewReader(io.LimitReader(f, info.Size()-t.offset))
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return lines, fmt.Errorf("reading %s: %w", t.path, err)
+		}
+		t.offset += int64(len(line))
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	return lines, nil
+}
+
+// journalTail reads the journal entries matching matches that were added
+// since the last read, following the journal's cursor once there is an
+// entry to take it from. The first read returns nothing unless since is
+// set, and then the entries from since on.
+type journalTail struct {
+	journalctl string
+	// output is journalctl's output format, e.g. "cat" for just the
+	// message or "short-unix" with the time and identifier.
+	output  string
+	matches []string
+	since   time.Time
+	cursor  string
+}
+
+func (t *journalTail) read(ctx context.Context) ([]string, error) {
+	if t.since.IsZero() {
+		t.since = time.Now()
+		return nil, nil
+	}
+	args := append([]string{"-q", "--no-pager", "-o", t.output, "--show-cursor"}, t.matches...)
+	if t.cursor == "" {
+		args = append(args, "--since", fmt.Sprintf("@%d", t.since.Unix()))
+	} else {
+		args = append(args, "--after-cursor", t.cursor)
+	}
+	out, err := runCommand(ctx, t.journalctl, args...)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if cursor, ok := strings.CutPrefix(line, "-- cursor: "); ok {
+			t.cursor = cursor
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,187 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+var (
+	// type=AVC msg=audit(1700000000.123:456): avc:  denied  { read } for
+	// pid=1234 comm="httpd" name="index.html" ... tclass=file permissive=0
+	selinuxDenialPattern = regexp.MustCompile(`avc:\s+denied\s.*\bcomm="([^"]*)"`)
+	// type=AVC msg=audit(...): apparmor="DENIED" operation="open"
+	// profile="/usr/sbin/mysqld" name="/data/x" pid=1234 comm="mysqld" ...
+	// Complain mode profiles log apparmor="ALLOWED" for the same.
+	apparmorDenialPattern = regexp.MustCompile(`apparmor="(?:DENIED|ALLOWED)".*\bcomm="([^"]*)"`)
+)
+
+// lsmDenial is what denials are counted by.
+type lsmDenial struct {
+	lsm, process string
+}
+
+// LSMCollector reports whether SELinux and AppArmor are enforcing, the
+// AppArmor profiles in complain mode, and the denials each logs, so an
+// application failing with EACCES despite correct file permissions can be
+// traced to its policy. Denials are read from the audit log as it grows,
+// or from the kernel log when auditd isn't running, and only those logged
+// since glass started are counted.
+type LSMCollector struct {
+	// AuditLog is auditd's log.
+	AuditLog string `json:"audit_log"`
+
+	mu      sync.Mutex
+	tail    logTail
+	kmsg    int
+	opened  bool
+	denials map[lsmDenial]float64
+}
+
+func NewLSMCollector(cfg config.CollectorConfig) (Collector, error) {
+	l := &LSMCollector{AuditLog: "/var/log/audit/audit.log"}
+	if err := cfg.Decode(l); err != nil {
+		return nil, err
+	}
+	l.tail.path = l.AuditLog
+	return l, nil
+}
+
+func (l *LSMCollector) Name() string {
+	return "lsm"
+}
+
+func (l *LSMCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	// selinuxfs is only mounted when SELinux is enabled.
+	selinux := "disabled"
+	switch readSysfsString("/sys/fs/selinux/enforce") {
+	case "1":
+		selinux = "enforcing"
+	case "0":
+		selinux = "permissive"
+	}
+	for _, mode := range []string{"enforcing", "permissive", "disabled"} {
+		b.Gauge("lsm.selinux_mode", boolValue(selinux == mode), "", "mode", mode)
+	}
+
+	apparmor := readSysfsString("/sys/module/apparmor/parameters/enabled") == "Y"
+	b.Gauge("lsm.apparmor_enabled", boolValue(apparmor), "")
+	if apparmor {
+		if err := addAppArmorProfiles(b); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if selinux != "disabled" || apparmor {
+		l.mu.Lock()
+		fresh, err := l.readDenials()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, d := range slices.SortedFunc(maps.Keys(l.denials), func(a, b lsmDenial) int {
+			return strings.Compare(a.lsm+" "+a.process, b.lsm+" "+b.process)
+		}) {
+			b.Counter("lsm.denials", l.denials[d], "", "lsm", d.lsm, "process", d.process)
+		}
+		for _, lsm := range []string{"selinux", "apparmor"} {
+			b.Gauge("lsm.new_denials", fresh[lsm], "", "lsm", lsm)
+		}
+		l.mu.Unlock()
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// addAppArmorProfiles counts loaded profiles by mode from
+// /sys/kernel/security/apparmor/profiles, lines like
+// "/usr/sbin/mysqld (enforce)", which only root can read. Profiles in
+// complain mode log violations without blocking them and are listed.
+func addAppArmorProfiles(b *metric.Builder) error {
+	data, err := os.ReadFile("/sys/kernel/security/apparmor/profiles")
+	if errors.Is(err, os.ErrPermission) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading AppArmor profiles: %w", err)
+	}
+	modes := map[string]float64{"enforce": 0, "complain": 0}
+	var complain []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		i := strings.LastIndex(line, " (")
+		if i < 0 || !strings.HasSuffix(line, ")") {
+			continue
+		}
+		name, mode := line[:i], line[i+2:len(line)-1]
+		modes[mode]++
+		if mode == "complain" {
+			complain = append(complain, name)
+		}
+	}
+	for _, mode := range slices.Sorted(maps.Keys(modes)) {
+		b.Gauge("lsm.apparmor_profiles", modes[mode], "", "mode", mode)
+	}
+	slices.Sort(complain)
+	for _, name := range complain {
+		b.Gauge("lsm.apparmor_complain_profile", 1, "", "profile", name)
+	}
+	return nil
+}
+
+// readDenials counts the denials logged since the last call, by LSM. The
+// audit log is used when it exists; everything else goes to the kernel
+// log.
+func (l *LSMCollector) readDenials() (map[string]float64, error) {
+	if l.denials == nil {
+		l.denials = map[lsmDenial]float64{}
+	}
+	var (
+		lines []string
+		err   error
+	)
+	if _, statErr := os.Stat(l.AuditLog); statErr == nil {
+		lines, err = l.tail.read()
+	} else {
+		if !l.opened {
+			fd, err := openKmsg(true)
+			if err != nil {
+				return nil, err
+			}
+			l.kmsg, l.opened = fd, true
+		}
+		var records []kmsgRecord
+		records, err = readKmsg(l.kmsg, time.Time{})
+		for _, r := range records {
+			lines = append(lines, r.Message)
+		}
+	}
+
+	fresh := map[string]float64{}
+	for _, line := range lines {
+		for _, p := range []struct {
+			lsm     string
+			pattern *regexp.Regexp
+		}{
+			{"selinux", selinuxDenialPattern},
+			{"apparmor", apparmorDenialPattern},
+		} {
+			if m := p.pattern.FindStringSubmatch(line); m != nil {
+				fresh[p.lsm]++
+				l.denials[lsmDenial{p.lsm, m[1]}]++
+				break
+			}
+		}
+	}
+	return fresh, err
+}
@@ -0,0 +1,122 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// LVMCollector reports volume groups, logical volumes and thin pools from
+// the lvm tools' JSON reports, which need LVM 2.02.158 or later and root.
+type LVMCollector struct {
+	// LVM is the lvm binary, run as "lvm vgs" and "lvm lvs".
+	LVM string `json:"lvm"`
+	// VolumeGroups limits the report to these volume groups.
+	VolumeGroups []string `json:"volume_groups"`
+}
+
+func NewLVMCollector(cfg config.CollectorConfig) (Collector, error) {
+	l := &LVMCollector{LVM: "lvm"}
+	if err := cfg.Decode(l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *LVMCollector) Name() string {
+	return "lvm"
+}
+
+// lvmReport is the shape of `--reportformat json`: every field is a
+// string, and sizes are bytes with --units b --nosuffix.
+type lvmReport struct {
+	Report []struct {
+		VG []map[string]string `json:"vg"`
+		LV []map[string]string `json:"lv"`
+	} `json:"report"`
+}
+
+func (l *LVMCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	vgs, err := l.report(ctx, "vgs", "vg_name,vg_size,vg_free,lv_count,pv_count")
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, vg := range vgs {
+		labels := []string{"vg", vg["vg_name"]}
+		size, free := lvmNumber(vg["vg_size"]), lvmNumber(vg["vg_free"])
+		b.Gauge("lvm.vg_size", size, "bytes", labels...)
+		b.Gauge("lvm.vg_free", free, "bytes", labels...)
+		if size > 0 {
+			b.Gauge("lvm.vg_free_percent", 100*free/size, "percent", labels...)
+		}
+		b.Gauge("lvm.vg_lvs", lvmNumber(vg["lv_count"]), "", labels...)
+		b.Gauge("lvm.vg_pvs", lvmNumber(vg["pv_count"]), "", labels...)
+	}
+
+	lvs, err := l.report(ctx, "lvs", "vg_name,lv_name,lv_size,lv_attr,segtype,pool_lv,data_percent,metadata_percent")
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, lv := range lvs {
+		vg, name, attr := lv["vg_name"], lv["lv_name"], lv["lv_attr"]
+		// Hidden volumes, such as a thin pool's [pool_tdata], are shown in
+		// brackets and are accounted for by the volume they belong to.
+		if strings.HasPrefix(name, "[") {
+			continue
+		}
+		if lv["segtype"] == "thin-pool" {
+			labels := []string{"vg", vg, "pool", name}
+			b.Gauge("lvm.thin_pool_size", lvmNumber(lv["lv_size"]), "bytes", labels...)
+			b.Gauge("lvm.thin_pool_data_percent", lvmNumber(lv["data_percent"]), "percent", labels...)
+			b.Gauge("lvm.thin_pool_metadata_percent", lvmNumber(lv["metadata_percent"]), "percent", labels...)
+			continue
+		}
+		labels := []string{"vg", vg, "lv", name, "type", lv["segtype"]}
+		if pool := lv["pool_lv"]; pool != "" {
+			labels = append(labels, "pool", pool)
+		}
+		b.Gauge("lvm.lv_size", lvmNumber(lv["lv_size"]), "bytes", labels...)
+		// The fifth lv_attr character is the state, "a" when active.
+		b.Gauge("lvm.lv_active", boolValue(len(attr) > 4 && attr[4] == 'a'), "", labels...)
+		// Thin volumes report how much of their size they have allocated,
+		// snapshots how full their copy-on-write space is.
+		if v := lv["data_percent"]; v != "" {
+			b.Gauge("lvm.lv_data_percent", lvmNumber(v), "percent", labels...)
+		}
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func (l *LVMCollector) report(ctx context.Context, command, fields string) ([]map[string]string, error) {
+	args := append([]string{command, "--reportformat", "json", "--units", "b", "--nosuffix", "-o", fields}, l.VolumeGroups...)
+	out, err := runCommand(ctx, l.LVM, args...)
+	if err != nil {
+		return nil, fmt.Errorf("lvm %s: %w", command, err)
+	}
+	var r lvmReport
+	if err := json.Unmarshal(out, &r); err != nil {
+		return nil, fmt.Errorf("parsing lvm %s output: %w", command, err)
+	}
+	var rows []map[string]string
+	for _, rep := range r.Report {
+		rows = append(rows, rep.VG...)
+		rows = append(rows, rep.LV...)
+	}
+	return rows, nil
+}
+
+// lvmNumber parses a report field, which is empty where it doesn't apply.
+func lvmNumber(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v
+}
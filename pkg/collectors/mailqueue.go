@@ -0,0 +1,244 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+// postfixQueues are Postfix's queues, reported so each has a series.
+var postfixQueues = []string{"maildrop", "incoming", "active", "deferred", "hold"}
+
+var (
+	// postqueue -p lists a message as its queue ID, marked * when active
+	// and ! when on hold, size, arrival time and sender, e.g.
+	//
+	//	3F2A21234AB*    1234 Mon Jun  4 12:00:00  sender@example.com
+	postqueueLine = regexp.MustCompile(`^([0-9A-Za-z]+)([*!]?)\s+(\d+)\s+(\w{3} \w{3} [ \d]\d \d\d:\d\d:\d\d)\s+(\S*)`)
+	// exim -bp lists a message as its age, size, ID and sender, e.g.
+	//
+	//	25m  2.9K 1hGH6o-0002Wx-Ck <sender@example.com> *** frozen ***
+	eximLine = regexp.MustCompile(`^\s*(\d+)([smhdw])\s+([\d.]+)([KMG]?)\s+(\S+)\s+<([^>]*)>(.*)$`)
+)
+
+// queuedMessage is one message in an MTA's queue.
+type queuedMessage struct {
+	queue   string
+	arrival time.Time
+	size    float64
+	sender  string
+}
+
+// MailQueueCollector reports the length of the Postfix or Exim mail queue,
+// per queue for Postfix and frozen messages for Exim, its size, the age of
+// the oldest message and the senders with the most messages queued. A
+// queue that keeps growing usually means a compromised site or account is
+// sending spam, or the relay configuration is broken.
+type MailQueueCollector struct {
+	// Postqueue and Exim are the MTAs' tools, tried in that order.
+	Postqueue string `json:"postqueue"`
+	Exim      string `json:"exim"`
+	// TopSenders is how many of the senders with the most messages queued
+	// to report; 0 reports none.
+	TopSenders int `json:"top_senders"`
+
+	noMTA sync.Once
+}
+
+func NewMailQueueCollector(cfg config.CollectorConfig) (Collector, error) {
+	m := &MailQueueCollector{Postqueue: "postqueue", Exim: "exim", TopSenders: 5}
+	if err := cfg.Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *MailQueueCollector) Name() string {
+	return "mailqueue"
+}
+
+func (m *MailQueueCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	mta, queues, msgs, err := m.query(ctx)
+	if mta == "" {
+		m.noMTA.Do(func() { log.Info().Msg("Neither postqueue nor exim found; not reporting the mail queue") })
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing the %s queue: %w", mta, err)
+	}
+	now := time.Now()
+	b := metric.NewBuilder(now)
+	counts := map[string]float64{}
+	senders := map[string]float64{}
+	var size float64
+	var oldest time.Time
+	for _, msg := range msgs {
+		counts[msg.queue]++
+		senders[msg.sender]++
+		size += msg.size
+		if !msg.arrival.IsZero() && (oldest.IsZero() || msg.arrival.Before(oldest)) {
+			oldest = msg.arrival
+		}
+	}
+	for _, q := range queues {
+		b.Gauge("mailqueue.messages", counts[q], "", "mta", mta, "queue", q)
+	}
+	b.Gauge("mailqueue.total", float64(len(msgs)), "", "mta", mta)
+	b.Gauge("mailqueue.size", size, "bytes", "mta", mta)
+	var age float64
+	if !oldest.IsZero() {
+		age = max(now.Sub(oldest).Seconds(), 0)
+	}
+	b.Gauge("mailqueue.oldest_age", age, "seconds", "mta", mta)
+
+	top := slices.SortedFunc(maps.Keys(senders), func(x, y string) int {
+		return cmp.Or(cmp.Compare(senders[y], senders[x]), strings.Compare(x, y))
+	})
+	for _, sender := range top[:min(m.TopSenders, len(top))] {
+		b.Gauge("mailqueue.sender_messages", senders[sender], "", "mta", mta, "sender", sender)
+	}
+	return b.Metrics(), nil
+}
+
+// query lists the Postfix queue, then Exim's. The MTA is empty when
+// neither tool is installed.
+func (m *MailQueueCollector) query(ctx context.Context) (string, []string, []queuedMessage, error) {
+	msgs, err := m.postfixQueue(ctx)
+	if !errors.Is(err, exec.ErrNotFound) {
+		return "postfix", postfixQueues, msgs, err
+	}
+	out, err := runCommand(ctx, m.Exim, "-bp")
+	if err == nil {
+		msgs, err := parseEximQueue(out, time.Now())
+		return "exim", []string{"queued", "frozen"}, msgs, err
+	}
+	if !errors.Is(err, exec.ErrNotFound) {
+		return "exim", nil, nil, err
+	}
+	return "", nil, nil, nil
+}
+
+// postfixQueue lists the queue with `postqueue -j`, or with `postqueue -p`
+// before Postfix 3.1 added -j.
+func (m *MailQueueCollector) postfixQueue(ctx context.Context) ([]queuedMessage, error) {
+	out, err := runCommand(ctx, m.Postqueue, "-j")
+	if err == nil {
+		return parsePostqueueJSON(out)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return nil, err
+	}
+	out, err = runCommand(ctx, m.Postqueue, "-p")
+	if err != nil {
+		return nil, err
+	}
+	return parsePostqueue(out, time.Now()), nil
+}
+
+// parsePostqueueJSON reads `postqueue -j`, a JSON object per message.
+func parsePostqueueJSON(out []byte) ([]queuedMessage, error) {
+	var msgs []queuedMessage
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var msg struct {
+			Queue   string  `json:"queue_name"`
+			Arrival int64   `json:"arrival_time"`
+			Size    float64 `json:"message_size"`
+			Sender  string  `json:"sender"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &msg); err != nil {
+			return nil, fmt.Errorf("parsing postqueue output: %w", err)
+		}
+		msgs = append(msgs, queuedMessage{
+			queue:   msg.Queue,
+			arrival: time.Unix(msg.Arrival, 0),
+			size:    msg.Size,
+			sender:  cmp.Or(msg.Sender, "<>"),
+		})
+	}
+	return msgs, sc.Err()
+}
+
+// parsePostqueue reads `postqueue -p`, whose arrival times have no year:
+// they're in the last year, as Postfix bounces messages after days.
+func parsePostqueue(out []byte, now time.Time) []queuedMessage {
+	var msgs []queuedMessage
+	for _, line := range strings.Split(string(out), "\n") {
+		f := postqueueLine.FindStringSubmatch(line)
+		if f == nil {
+			continue
+		}
+		msg := queuedMessage{queue: "deferred", sender: f[5]}
+		switch f[2] {
+		case "*":
+			msg.queue = "active"
+		case "!":
+			msg.queue = "hold"
+		}
+		msg.size, _ = strconv.ParseFloat(f[3], 64)
+		if t, err := time.ParseInLocation("Mon Jan _2 15:04:05", f[4], now.Location()); err == nil {
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			msg.arrival = t
+		}
+		if msg.sender == "" || msg.sender == "MAILER-DAEMON" {
+			msg.sender = "<>"
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// parseEximQueue reads `exim -bp`, which gives each message's age rather
+// than when it arrived.
+func parseEximQueue(out []byte, now time.Time) ([]queuedMessage, error) {
+	units := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	multipliers := map[string]float64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30}
+	var msgs []queuedMessage
+	for _, line := range strings.Split(string(out), "\n") {
+		f := eximLine.FindStringSubmatch(line)
+		if f == nil {
+			continue
+		}
+		age, _ := strconv.Atoi(f[1])
+		size, err := strconv.ParseFloat(f[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing exim -bp size %q: %w", f[3]+f[4], err)
+		}
+		msg := queuedMessage{
+			queue:   "queued",
+			arrival: now.Add(-time.Duration(age) * units[f[2]]),
+			size:    size * multipliers[f[4]],
+			sender:  cmp.Or(f[6], "<>"),
+		}
+		if strings.Contains(f[7], "*** frozen ***") {
+			msg.queue = "frozen"
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
@@ -0,0 +1,159 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/metric"
+)
+
+const mdstatPath = "/proc/mdstat"
+
+// MDRaidCollector reports Linux software RAID arrays from /proc/mdstat.
+type MDRaidCollector struct{}
+
+func (m *MDRaidCollector) Name() string {
+	return "mdraid"
+}
+
+// mdArray is one md device as /proc/mdstat describes it.
+type mdArray struct {
+	Name  string
+	State string // active, inactive, or active with a qualifier such as auto-read-only
+	Level string // raid1, raid5, linear, ...; empty for an inactive array
+	// Size is in 1 KiB blocks.
+	Size uint64
+	// Disks is the number of members the array should have and Active the
+	// number working; both are 0 for levels without redundancy, which
+	// mdstat doesn't report them for.
+	Disks, Active int
+	// Failed and Spare count members marked (F) and (S).
+	Failed, Spare int
+	Members       int
+	// SyncAction is resync, recover, check, repair or reshape while one is
+	// running or queued; Progress is its percentage and Finish the
+	// kernel's estimate of the time left.
+	SyncAction string
+	Progress   float64
+	Finish     time.Duration
+	SyncSpeed  float64 // KiB/s
+	Delayed    bool
+}
+
+var (
+	mdStatus   = regexp.MustCompile(`\[(\d+)/(\d+)\]`)
+	mdSync     = regexp.MustCompile(`(resync|recovery|check|repair|reshape)\s*=\s*([\d.]+)%.*?finish=([\d.]+)min(?:.*?speed=(\d+)K/sec)?`)
+	mdSyncWait = regexp.MustCompile(`(resync|recovery|check|repair|reshape)\s*=\s*(DELAYED|PENDING)`)
+)
+
+func (m *MDRaidCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	data, err := os.ReadFile(mdstatPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading mdstat: %w", err)
+	}
+	b := metric.NewBuilder(time.Now())
+	for _, a := range parseMdstat(data) {
+		labels := []string{"device", a.Name}
+		b.Gauge("mdraid.array_info", 1, "", "device", a.Name, "level", a.Level, "state", a.State)
+		b.Gauge("mdraid.active", boolValue(a.State != "inactive"), "", labels...)
+		b.Gauge("mdraid.size", float64(a.Size)*1024, "bytes", labels...)
+		b.Gauge("mdraid.members", float64(a.Members), "", labels...)
+		b.Gauge("mdraid.failed_disks", float64(a.Failed), "", labels...)
+		b.Gauge("mdraid.spare_disks", float64(a.Spare), "", labels...)
+		if a.Disks > 0 {
+			b.Gauge("mdraid.disks", float64(a.Disks), "", labels...)
+			b.Gauge("mdraid.active_disks", float64(a.Active), "", labels...)
+		}
+		// An array recovering onto a spare stays degraded until it is done.
+		b.Gauge("mdraid.degraded", boolValue(a.Active < a.Disks), "", labels...)
+		b.Gauge("mdraid.syncing", boolValue(a.SyncAction != "" && !a.Delayed), "", labels...)
+		if a.SyncAction != "" {
+			syncLabels := []string{"device", a.Name, "action", a.SyncAction}
+			b.Gauge("mdraid.sync_progress", a.Progress, "percent", syncLabels...)
+			if !a.Delayed {
+				b.Gauge("mdraid.sync_remaining", a.Finish.Seconds(), "seconds", syncLabels...)
+				b.Gauge("mdraid.sync_speed", a.SyncSpeed*1024, "bytes/s", syncLabels...)
+			}
+		}
+	}
+	return b.Metrics(), nil
+}
+
+// parseMdstat reads the arrays out of /proc/mdstat. Each starts with a
+// line such as "md0 : active raid1 sdb1[1] sda1[0](F)" followed by
+// indented lines with its size, member status and any sync in progress.
+func parseMdstat(data []byte) []mdArray {
+	var arrays []mdArray
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		name, rest, ok := strings.Cut(line, " : ")
+		if ok && strings.HasPrefix(name, "md") {
+			arrays = append(arrays, parseMdHeader(strings.TrimSpace(name), strings.Fields(rest)))
+			continue
+		}
+		if len(arrays) == 0 || strings.TrimLeft(line, " \t") == line {
+			continue
+		}
+		a := &arrays[len(arrays)-1]
+		if fields := strings.Fields(line); len(fields) > 1 && fields[1] == "blocks" {
+			a.Size, _ = strconv.ParseUint(fields[0], 10, 64)
+			if m := mdStatus.FindStringSubmatch(line); m != nil {
+				a.Disks, _ = strconv.Atoi(m[1])
+				a.Active, _ = strconv.Atoi(m[2])
+			}
+		}
+		if m := mdSync.FindStringSubmatch(line); m != nil {
+			a.SyncAction = mdSyncAction(m[1])
+			a.Progress, _ = strconv.ParseFloat(m[2], 64)
+			minutes, _ := strconv.ParseFloat(m[3], 64)
+			a.Finish = time.Duration(minutes * float64(time.Minute))
+			a.SyncSpeed, _ = strconv.ParseFloat(m[4], 64)
+		} else if m := mdSyncWait.FindStringSubmatch(line); m != nil {
+			a.SyncAction = mdSyncAction(m[1])
+			a.Delayed = true
+		}
+	}
+	return arrays
+}
+
+func parseMdHeader(name string, fields []string) mdArray {
+	a := mdArray{Name: name}
+	if len(fields) == 0 {
+		return a
+	}
+	a.State, fields = fields[0], fields[1:]
+	// "active (auto-read-only) raid1" and "active (read-only) raid1".
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "(") {
+		a.State += " " + strings.Trim(fields[0], "()")
+		fields = fields[1:]
+	}
+	if a.State != "inactive" && len(fields) > 0 {
+		a.Level, fields = fields[0], fields[1:]
+	}
+	for _, member := range fields {
+		a.Members++
+		switch {
+		case strings.HasSuffix(member, "(F)"):
+			a.Failed++
+		case strings.HasSuffix(member, "(S)"):
+			a.Spare++
+		}
+	}
+	return a
+}
+
+// mdSyncAction names the sync as the kernel's sync_action file does.
+func mdSyncAction(s string) string {
+	if s == "recovery" {
+		return "recover"
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mdMinutes converts a finish estimate as parseMdstat does.
+func mdMinutes(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
+
+func TestParseMdstat(t *testing.T) {
+	tests := []struct {
+		file string
+		want []mdArray
+	}{
+		{
+			file: "recovery.txt",
+			want: []mdArray{
+				{
+					Name: "md1", State: "active", Level: "raid5", Size: 5860267008,
+					Disks: 4, Active: 3, Members: 4,
+					SyncAction: "recover", Progress: 12.6, Finish: mdMinutes(145.3), SyncSpeed: 195742,
+				},
+				{Name: "md0", State: "active", Level: "raid1", Size: 1047552, Disks: 2, Active: 2, Members: 2},
+			},
+		},
+		{
+			file: "delayed.txt",
+			want: []mdArray{
+				{
+					Name: "md2", State: "active", Level: "raid1", Size: 976630464,
+					Disks: 2, Active: 2, Members: 2,
+					SyncAction: "check", Progress: 7.9, Finish: mdMinutes(99.6), SyncSpeed: 150400,
+				},
+				{
+					Name: "md3", State: "active", Level: "raid1", Size: 976630464,
+					Disks: 2, Active: 2, Members: 2, SyncAction: "resync", Delayed: true,
+				},
+				{
+					Name: "md4", State: "active auto-read-only", Level: "raid1", Size: 104320,
+					Disks: 2, Active: 2, Members: 2, SyncAction: "resync", Delayed: true,
+				},
+			},
+		},
+		{
+			file: "failed.txt",
+			want: []mdArray{
+				{Name: "md127", State: "inactive", Size: 3906764976, Members: 2, Spare: 2},
+				{Name: "md5", State: "active", Level: "raid1", Size: 2097088, Disks: 2, Active: 1, Members: 3, Failed: 1, Spare: 1},
+				{Name: "md6", State: "active", Level: "raid0", Size: 209584128, Members: 2},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "mdstat", tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := parseMdstat(data)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseMdstat() =\n%+v\nwant\n%+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseMdstatNoArrays(t *testing.T) {
+	data := []byte("Personalities : \nunused devices: <none>\n")
+	if got := parseMdstat(data); len(got) != 0 {
+		t.Errorf("parseMdstat() = %+v, want no arrays", got)
+	}
+}
+
+func TestParseMdHeader(t *testing.T) {
+	tests := []struct {
+		line string
+		want mdArray
+	}{
+		{"active raid1 sdb1[1] sda1[0]", mdArray{State: "active", Level: "raid1", Members: 2}},
+		{"active (auto-read-only) raid1 sdb1[1] sda1[0]", mdArray{State: "active auto-read-only", Level: "raid1", Members: 2}},
+		{"active (read-only) raid10 sdd[3] sdc[2] sdb[1] sda[0]", mdArray{State: "active read-only", Level: "raid10", Members: 4}},
+		{"active raid6 sde1[4](S) sdd1[3](F) sdc1[2] sdb1[1] sda1[0]", mdArray{State: "active", Level: "raid6", Members: 5, Failed: 1, Spare: 1}},
+		{"active linear sdb1[1] sda1[0]", mdArray{State: "active", Level: "linear", Members: 2}},
+		{"inactive sdc[1](S) sdb[0](S)", mdArray{State: "inactive", Members: 2, Spare: 2}},
+		{"inactive", mdArray{State: "inactive"}},
+		{"", mdArray{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.line, func(t *testing.T) {
+			tc.want.Name = "md0"
+			if got := parseMdHeader("md0", strings.Fields(tc.line)); got != tc.want {
+				t.Errorf("parseMdHeader(%q) = %+v, want %+v", tc.line, got, tc.want)
+			}
+		})
+	}
+}
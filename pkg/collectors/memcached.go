@@ -0,0 +1,175 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// memcachedFields maps stats fields to metrics.
+var memcachedFields = []struct {
+	field string
+	name  string
+	kind  metric.Kind
+	unit  string
+}{
+	{"uptime", "memcached.uptime", metric.Gauge, "seconds"},
+	{"curr_connections", "memcached.connections", metric.Gauge, ""},
+	{"total_connections", "memcached.connections_received", metric.Counter, ""},
+	{"rejected_connections", "memcached.rejected_connections", metric.Counter, ""},
+	{"listen_disabled_num", "memcached.listen_disabled", metric.Counter, ""},
+	{"threads", "memcached.threads", metric.Gauge, ""},
+	{"bytes", "memcached.bytes", metric.Gauge, "bytes"},
+	{"limit_maxbytes", "memcached.limit_bytes", metric.Gauge, "bytes"},
+	{"curr_items", "memcached.items", metric.Gauge, ""},
+	{"total_items", "memcached.items_stored", metric.Counter, ""},
+	{"cmd_get", "memcached.gets", metric.Counter, ""},
+	{"cmd_set", "memcached.sets", metric.Counter, ""},
+	{"get_hits", "memcached.get_hits", metric.Counter, ""},
+	{"get_misses", "memcached.get_misses", metric.Counter, ""},
+	{"get_expired", "memcached.get_expired", metric.Counter, ""},
+	{"evictions", "memcached.evictions", metric.Counter, ""},
+	{"reclaimed", "memcached.reclaimed", metric.Counter, ""},
+	{"bytes_read", "memcached.bytes_read", metric.Counter, "bytes"},
+	{"bytes_written", "memcached.bytes_written", metric.Counter, "bytes"},
+}
+
+type MemcachedInstance struct {
+	// Address is host:port or unix:///path/to/memcached.sock.
+	Address string `json:"address"`
+}
+
+type MemcachedCollector struct {
+	Instances []MemcachedInstance `json:"instances"`
+}
+
+func NewMemcachedCollector(cfg config.CollectorConfig) (Collector, error) {
+	m := &MemcachedCollector{Instances: []MemcachedInstance{{Address: "127.0.0.1:11211"}}}
+	if err := cfg.Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *MemcachedCollector) Name() string {
+	return "memcached"
+}
+
+func (m *MemcachedCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, inst := range m.Instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats, settings, err := memcachedStats(ctx, inst)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("getting stats from %s: %w", inst.Address, err))
+				return
+			}
+			addMemcachedStats(b, inst.Address, stats, settings)
+		}()
+	}
+	wg.Wait()
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func addMemcachedStats(b *metric.Builder, instance string, stats, settings map[string]string) {
+	labels := []string{"instance", instance}
+	num := func(m map[string]string, field string) (float64, bool) {
+		v, err := strconv.ParseFloat(m[field], 64)
+		return v, err == nil
+	}
+
+	b.Gauge("memcached.info", 1, "", "instance", instance, "version", stats["version"])
+	for _, f := range memcachedFields {
+		if v, ok := num(stats, f.field); ok {
+			b.Add(f.kind, f.name, v, f.unit, labels...)
+		}
+	}
+	if used, ok := num(stats, "bytes"); ok {
+		if limit, ok := num(stats, "limit_maxbytes"); ok && limit > 0 {
+			b.Gauge("memcached.used_memory_percent", 100*used/limit, "percent", labels...)
+		}
+	}
+	hits, _ := num(stats, "get_hits")
+	misses, _ := num(stats, "get_misses")
+	if hits+misses > 0 {
+		b.Gauge("memcached.hit_ratio", 100*hits/(hits+misses), "percent", labels...)
+	}
+	if limit, ok := num(settings, "maxconns"); ok && limit > 0 {
+		b.Gauge("memcached.max_connections", limit, "", labels...)
+		if conns, ok := num(stats, "curr_connections"); ok {
+			b.Gauge("memcached.connections_used_percent", 100*conns/limit, "percent", labels...)
+		}
+	}
+}
+
+// memcachedStats reads "stats" and "stats settings" over the text
+// protocol. Both reply with "STAT name value" lines ending with "END".
+func memcachedStats(ctx context.Context, inst MemcachedInstance) (map[string]string, map[string]string, error) {
+	network, addr := "tcp", inst.Address
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		network, addr = "unix", path
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+	}
+
+	r := bufio.NewReader(conn)
+	stats, err := memcachedCommand(conn, r, "stats")
+	if err != nil {
+		return nil, nil, err
+	}
+	// Only the connection limit comes from the settings, which proxies
+	// like mcrouter don't have.
+	settings, _ := memcachedCommand(conn, r, "stats settings")
+	return stats, settings, nil
+}
+
+func memcachedCommand(w io.Writer, r *bufio.Reader, command string) (map[string]string, error) {
+	if _, err := io.WriteString(w, command+"\r\n"); err != nil {
+		return nil, err
+	}
+	stats := make(map[string]string)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "END" {
+			return stats, nil
+		}
+		if line == "ERROR" || strings.HasPrefix(line, "CLIENT_ERROR") || strings.HasPrefix(line, "SERVER_ERROR") {
+			return nil, errors.New(line)
+		}
+		if f := strings.Fields(line); len(f) == 3 && f[0] == "STAT" {
+			stats[f[1]] = f[2]
+		}
+	}
+}
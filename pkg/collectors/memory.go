@@ -1,18 +1,55 @@
 package collectors
 
 import (
-	"github.com/rs/zerolog/log"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"glass/pkg/metric"
+
 	"github.com/shirou/gopsutil/v4/mem"
 )
 
 type MemoryCollector struct{}
 
-func (m *MemoryCollector) Collector() error {
-	vmstat, err := mem.VirtualMemory()
+func (m *MemoryCollector) Name() string {
+	return "mem"
+}
+
+func (m *MemoryCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	vmstat, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("getting memory info: %w", err))
+	} else {
+		b.Gauge("mem.total", float64(vmstat.Total), "bytes")
+		b.Gauge("mem.available", float64(vmstat.Available), "bytes")
+		b.Gauge("mem.used", float64(vmstat.Used), "bytes")
+		b.Gauge("mem.free", float64(vmstat.Free), "bytes")
+		b.Gauge("mem.used_percent", vmstat.UsedPercent, "percent")
+		if vmstat.Total > 0 {
+			b.Gauge("mem.available_percent", 100*float64(vmstat.Available)/float64(vmstat.Total), "percent")
+		}
+		if vmstat.CommitLimit > 0 {
+			b.Gauge("mem.committed", float64(vmstat.CommittedAS), "bytes")
+			b.Gauge("mem.commit_limit", float64(vmstat.CommitLimit), "bytes")
+			b.Gauge("mem.committed_percent", 100*float64(vmstat.CommittedAS)/float64(vmstat.CommitLimit), "percent")
+		}
+	}
+
+	swap, err := mem.SwapMemoryWithContext(ctx)
 	if err != nil {
-		log.Err(err).Msg("Error getting memory info")
+		errs = append(errs, fmt.Errorf("getting swap info: %w", err))
+	} else {
+		b.Gauge("mem.swap_total", float64(swap.Total), "bytes")
+		b.Gauge("mem.swap_used", float64(swap.Used), "bytes")
+		b.Gauge("mem.swap_free", float64(swap.Free), "bytes")
+		b.Gauge("mem.swap_used_percent", swap.UsedPercent, "percent")
+		b.Counter("mem.swap_in", float64(swap.Sin), "bytes")
+		b.Counter("mem.swap_out", float64(swap.Sout), "bytes")
 	}
-	log.Info().Uint64("total", vmstat.Total).Uint64("available", vmstat.Available).Uint64("used", vmstat.Used).Uint64("free", vmstat.Free).Float64("used-percent", vmstat.UsedPercent).Msg("")
-	//log.Info().Msg("Memory collector")
-	return nil
+	return b.Metrics(), errors.Join(errs...)
 }
@@ -0,0 +1,233 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// mongoFields maps serverStatus fields, as paths, to metrics.
+var mongoFields = []struct {
+	path []string
+	name string
+	kind metric.Kind
+	unit string
+}{
+	{[]string{"uptime"}, "mongodb.uptime", metric.Gauge, "seconds"},
+	{[]string{"connections", "current"}, "mongodb.connections", metric.Gauge, ""},
+	{[]string{"connections", "available"}, "mongodb.connections_available", metric.Gauge, ""},
+	{[]string{"connections", "active"}, "mongodb.connections_active", metric.Gauge, ""},
+	{[]string{"connections", "totalCreated"}, "mongodb.connections_created", metric.Counter, ""},
+	{[]string{"network", "bytesIn"}, "mongodb.network_received", metric.Counter, "bytes"},
+	{[]string{"network", "bytesOut"}, "mongodb.network_sent", metric.Counter, "bytes"},
+	{[]string{"wiredTiger", "cache", "bytes currently in the cache"}, "mongodb.cache_used", metric.Gauge, "bytes"},
+	{[]string{"wiredTiger", "cache", "maximum bytes configured"}, "mongodb.cache_max", metric.Gauge, "bytes"},
+	{[]string{"wiredTiger", "cache", "tracked dirty bytes in the cache"}, "mongodb.cache_dirty", metric.Gauge, "bytes"},
+	{[]string{"wiredTiger", "cache", "pages evicted by application threads"}, "mongodb.cache_app_evictions", metric.Counter, ""},
+}
+
+// mongoOps are the opcounters, reported for client and replicated
+// operations.
+var mongoOps = []string{"insert", "query", "update", "delete", "getmore", "command"}
+
+type MongoDBInstance struct {
+	// Address is host:port or unix:///tmp/mongodb-27017.sock.
+	Address  string `json:"address"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// AuthSource is the database the user is defined in, admin by default.
+	AuthSource string `json:"auth_source"`
+}
+
+// MongoDBCollector reports serverStatus for each instance: connections,
+// operations, WiredTiger cache usage, lock queues and tickets, and, for
+// replica set members, each member's health and replication lag from
+// replSetGetStatus. The user needs the clusterMonitor role. Connections
+// are plain TCP or Unix sockets, authenticated with SCRAM-SHA-256.
+type MongoDBCollector struct {
+	Instances []MongoDBInstance `json:"instances"`
+}
+
+func NewMongoDBCollector(cfg config.CollectorConfig) (Collector, error) {
+	m := &MongoDBCollector{Instances: []MongoDBInstance{{Address: "127.0.0.1:27017"}}}
+	if err := cfg.Decode(m); err != nil {
+		return nil, err
+	}
+	for i := range m.Instances {
+		if m.Instances[i].AuthSource == "" {
+			m.Instances[i].AuthSource = "admin"
+		}
+	}
+	return m, nil
+}
+
+func (m *MongoDBCollector) Name() string {
+	return "mongodb"
+}
+
+func (m *MongoDBCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, inst := range m.Instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, repl, err := mongoStatus(ctx, inst)
+			mu.Lock()
+			defer mu.Unlock()
+			if status != nil {
+				addMongoStatus(b, inst.Address, status, repl)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("getting server status from %s: %w", inst.Address, err))
+			}
+		}()
+	}
+	wg.Wait()
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// mongoStatus runs serverStatus and, on replica set members,
+// replSetGetStatus, whose reply is nil otherwise.
+func mongoStatus(ctx context.Context, inst MongoDBInstance) (map[string]any, map[string]any, error) {
+	conn, err := dialMongo(ctx, inst.Address)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+	if inst.Username != "" {
+		if err := conn.authenticate(inst.AuthSource, inst.Username, inst.Password); err != nil {
+			return nil, nil, fmt.Errorf("authenticating: %w", err)
+		}
+	}
+	status, err := conn.command("admin", bsonD{{"serverStatus", int32(1)}})
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, ok := mongoPath(status, "repl", "setName").(string); !ok {
+		return status, nil, nil
+	}
+	repl, err := conn.command("admin", bsonD{{"replSetGetStatus", int32(1)}})
+	return status, repl, err
+}
+
+// mongoPath looks up a nested field, or returns nil.
+func mongoPath(doc map[string]any, path ...string) any {
+	var v any = doc
+	for _, key := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+func addMongoStatus(b *metric.Builder, instance string, status, repl map[string]any) {
+	labels := []string{"instance", instance}
+	num := func(path ...string) (float64, bool) {
+		return bsonNumber(mongoPath(status, path...))
+	}
+
+	version, _ := status["version"].(string)
+	role := "standalone"
+	if _, ok := mongoPath(status, "repl", "setName").(string); ok {
+		role = "secondary"
+		// isWritablePrimary replaced ismaster in 5.0.
+		for _, field := range []string{"isWritablePrimary", "ismaster"} {
+			if primary, _ := mongoPath(status, "repl", field).(bool); primary {
+				role = "primary"
+			}
+		}
+	}
+	b.Gauge("mongodb.info", 1, "", "instance", instance, "version", version, "role", role)
+
+	for _, f := range mongoFields {
+		if v, ok := num(f.path...); ok {
+			b.Add(f.kind, f.name, v, f.unit, labels...)
+		}
+	}
+	if used, ok := num("wiredTiger", "cache", "bytes currently in the cache"); ok {
+		if limit, ok := num("wiredTiger", "cache", "maximum bytes configured"); ok && limit > 0 {
+			b.Gauge("mongodb.cache_used_percent", 100*used/limit, "percent", labels...)
+		}
+	}
+	if current, ok := num("connections", "current"); ok {
+		if available, ok := num("connections", "available"); ok && current+available > 0 {
+			b.Gauge("mongodb.connections_used_percent", 100*current/(current+available), "percent", labels...)
+		}
+	}
+	// Resident and virtual memory are in MiB.
+	if v, ok := num("mem", "resident"); ok {
+		b.Gauge("mongodb.memory_resident", v*(1<<20), "bytes", labels...)
+	}
+	if v, ok := num("mem", "virtual"); ok {
+		b.Gauge("mongodb.memory_virtual", v*(1<<20), "bytes", labels...)
+	}
+	for _, op := range mongoOps {
+		if v, ok := num("opcounters", op); ok {
+			b.Counter("mongodb.operations", v, "", "instance", instance, "op", op)
+		}
+		if v, ok := num("opcountersRepl", op); ok {
+			b.Counter("mongodb.replicated_operations", v, "", "instance", instance, "op", op)
+		}
+	}
+	for _, kind := range []string{"readers", "writers"} {
+		if v, ok := num("globalLock", "currentQueue", kind); ok {
+			b.Gauge("mongodb.lock_queue", v, "", "instance", instance, "type", kind)
+		}
+		if v, ok := num("globalLock", "activeClients", kind); ok {
+			b.Gauge("mongodb.active_clients", v, "", "instance", instance, "type", kind)
+		}
+	}
+	// Read and write tickets limit concurrent storage engine operations;
+	// none available means operations queue.
+	for _, kind := range []string{"read", "write"} {
+		if v, ok := num("wiredTiger", "concurrentTransactions", kind, "available"); ok {
+			b.Gauge("mongodb.tickets_available", v, "", "instance", instance, "type", kind)
+		}
+	}
+
+	if repl != nil {
+		addMongoReplication(b, instance, repl)
+	}
+}
+
+// addMongoReplication reports each replica set member's health and, for
+// secondaries, how far their last applied operation is behind the
+// primary's.
+func addMongoReplication(b *metric.Builder, instance string, repl map[string]any) {
+	set, _ := repl["set"].(string)
+	members, _ := repl["members"].([]any)
+	var primary time.Time
+	for _, m := range members {
+		member, _ := m.(map[string]any)
+		if state, _ := member["stateStr"].(string); state == "PRIMARY" {
+			primary, _ = member["optimeDate"].(time.Time)
+		}
+	}
+	b.Gauge("mongodb.replica_set_has_primary", boolValue(!primary.IsZero()), "", "instance", instance, "set", set)
+	for _, m := range members {
+		member, _ := m.(map[string]any)
+		name, _ := member["name"].(string)
+		state, _ := member["stateStr"].(string)
+		l := []string{"instance", instance, "set", set, "member", name}
+		if health, ok := bsonNumber(member["health"]); ok {
+			b.Gauge("mongodb.member_healthy", health, "", l...)
+		}
+		b.Gauge("mongodb.member_state", 1, "", append(l, "state", state)...)
+		if optime, ok := member["optimeDate"].(time.Time); ok && state == "SECONDARY" && !primary.IsZero() {
+			b.Gauge("mongodb.replication_lag", max(primary.Sub(optime).Seconds(), 0), "seconds", l...)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package collectors
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// opMsg is the OP_MSG opcode of the MongoDB wire protocol, the only one
+// servers since 3.6 need for commands.
+const opMsg = 2013
+
+// maxMongoMessage bounds a reply; serverStatus is tens of kilobytes.
+const maxMongoMessage = 48 << 20
+
+// bsonD is an ordered BSON document, as commands need their name first.
+type bsonD []bsonE
+
+type bsonE struct {
+	Key   string
+	Value any
+}
+
+// mongoConn runs commands on a MongoDB server.
+type mongoConn struct {
+	conn net.Conn
+	id   int32
+}
+
+// dialMongo connects to address, host:port or unix:///path/to/mongodb.sock.
+func dialMongo(ctx context.Context, address string) (*mongoConn, error) {
+	network, addr := "tcp", address
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		network, addr = "unix", path
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+	}
+	return &mongoConn{conn: conn}, nil
+}
+
+func (c *mongoConn) Close() error {
+	return c.conn.Close()
+}
+
+// command runs cmd against db and returns the reply, or the server's
+// error when the command failed.
+func (c *mongoConn) command(db string, cmd bsonD) (map[string]any, error) {
+	body, err := appendBSON(nil, append(cmd, bsonE{"$db", db}))
+	if err != nil {
+		return nil, err
+	}
+	c.id++
+	// Header, then no flags and a single body section.
+	msg := make([]byte, 16, 16+5+len(body))
+	binary.LittleEndian.PutUint32(msg[4:], uint32(c.id))
+	binary.LittleEndian.PutUint32(msg[12:], opMsg)
+	msg = append(msg, 0, 0, 0, 0, 0)
+	msg = append(msg, body...)
+	binary.LittleEndian.PutUint32(msg[0:], uint32(len(msg)))
+	if _, err := c.conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	var header [16]byte
+	if _, err := io.ReadFull(c.conn, header[:]); err != nil {
+		return nil, err
+	}
+	length := int(binary.LittleEndian.Uint32(header[0:]))
+	if length < 21 || length > maxMongoMessage {
+		return nil, fmt.Errorf("invalid reply length %d", length)
+	}
+	if op := binary.LittleEndian.Uint32(header[12:]); op != opMsg {
+		return nil, fmt.Errorf("unexpected reply opcode %d", op)
+	}
+	reply := make([]byte, length-16)
+	if _, err := io.ReadFull(c.conn, reply); err != nil {
+		return nil, err
+	}
+	// Skip the flags; a single-document reply has only a body section.
+	if reply[4] != 0 {
+		return nil, fmt.Errorf("unexpected reply section kind %d", reply[4])
+	}
+	doc, _, err := readBSON(reply[5:])
+	if err != nil {
+		return nil, fmt.Errorf("parsing reply: %w", err)
+	}
+	if ok, _ := bsonNumber(doc["ok"]); ok != 1 {
+		msg, _ := doc["errmsg"].(string)
+		return nil, fmt.Errorf("%s failed: %s", cmd[0].Key, msg)
+	}
+	return doc, nil
+}
+
+// authenticate logs in with SCRAM-SHA-256 (RFC 7677), the default since
+// MongoDB 4.0, against the user's authentication database.
+func (c *mongoConn) authenticate(db, username, password string) error {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	user := strings.NewReplacer("=", "=3D", ",", "=2C").Replace(username)
+	clientNonce := base64.StdEncoding.EncodeToString(nonce)
+	clientFirst := "n=" + user + ",r=" + clientNonce
+	reply, err := c.command(db, bsonD{
+		{"saslStart", int32(1)},
+		{"mechanism", "SCRAM-SHA-256"},
+		{"payload", []byte("n,," + clientFirst)},
+		{"options", bsonD{{"skipEmptyExchange", true}}},
+	})
+	if err != nil {
+		return err
+	}
+	serverFirst, _ := reply["payload"].([]byte)
+	fields := scramFields(string(serverFirst))
+	salt, err := base64.StdEncoding.DecodeString(fields["s"])
+	if err != nil {
+		return fmt.Errorf("invalid SCRAM salt: %w", err)
+	}
+	iterations, err := strconv.Atoi(fields["i"])
+	if err != nil || iterations <= 0 {
+		return fmt.Errorf("invalid SCRAM iteration count %q", fields["i"])
+	}
+	if !strings.HasPrefix(fields["r"], clientNonce) {
+		return errors.New("server nonce doesn't extend the client's")
+	}
+
+	salted := pbkdf2SHA256([]byte(password), salt, iterations)
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	withoutProof := "c=biws,r=" + fields["r"]
+	authMessage := clientFirst + "," + string(serverFirst) + "," + withoutProof
+	proof := hmacSHA256(storedKey[:], authMessage)
+	for i := range proof {
+		proof[i] ^= clientKey[i]
+	}
+	reply, err = c.command(db, bsonD{
+		{"saslContinue", int32(1)},
+		{"conversationId", reply["conversationId"]},
+		{"payload", []byte(withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof))},
+	})
+	if err != nil {
+		return err
+	}
+	serverFinal, _ := reply["payload"].([]byte)
+	signature := hmacSHA256(hmacSHA256(salted, "Server Key"), authMessage)
+	if scramFields(string(serverFinal))["v"] != base64.StdEncoding.EncodeToString(signature) {
+		return errors.New("server signature doesn't match")
+	}
+	// Servers before 4.4 ignore skipEmptyExchange and want one more round.
+	for done, _ := reply["done"].(bool); !done; done, _ = reply["done"].(bool) {
+		reply, err = c.command(db, bsonD{
+			{"saslContinue", int32(1)},
+			{"conversationId", reply["conversationId"]},
+			{"payload", []byte{}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scramFields splits a SCRAM message like "r=...,s=...,i=4096".
+func scramFields(msg string) map[string]string {
+	fields := map[string]string{}
+	for _, attr := range strings.Split(msg, ",") {
+		if k, v, ok := strings.Cut(attr, "="); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+// pbkdf2SHA256 derives a single-block PBKDF2 key (RFC 8018), which is all
+// SCRAM-SHA-256 needs.
+func pbkdf2SHA256(password, salt []byte, iterations int) []byte {
+	h := hmac.New(sha256.New, password)
+	h.Write(salt)
+	h.Write([]byte{0, 0, 0, 1})
+	u := h.Sum(nil)
+	key := bytes.Clone(u)
+	for range iterations - 1 {
+		h.Reset()
+		h.Write(u)
+		u = h.Sum(u[:0])
+		for i := range key {
+			key[i] ^= u[i]
+		}
+	}
+	return key
+}
+
+// appendBSON encodes doc, supporting the types commands are built from.
+func appendBSON(buf []byte, doc bsonD) ([]byte, error) {
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0)
+	for _, e := range doc {
+		var err error
+		switch v := e.Value.(type) {
+		case string:
+			buf = appendBSONKey(buf, 0x02, e.Key)
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(len(v)+1))
+			buf = append(append(buf, v...), 0)
+		case bsonD:
+			buf = appendBSONKey(buf, 0x03, e.Key)
+			if buf, err = appendBSON(buf, v); err != nil {
+				return nil, err
+			}
+		case []byte:
+			buf = appendBSONKey(buf, 0x05, e.Key)
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(len(v)))
+			buf = append(append(buf, 0), v...)
+		case bool:
+			buf = appendBSONKey(buf, 0x08, e.Key)
+			if v {
+				buf = append(buf, 1)
+			} else {
+				buf = append(buf, 0)
+			}
+		case int32:
+			buf = appendBSONKey(buf, 0x10, e.Key)
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(v))
+		case int64:
+			buf = appendBSONKey(buf, 0x12, e.Key)
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(v))
+		default:
+			return nil, fmt.Errorf("can't encode %T as BSON", e.Value)
+		}
+	}
+	buf = append(buf, 0)
+	binary.LittleEndian.PutUint32(buf[start:], uint32(len(buf)-start))
+	return buf, nil
+}
+
+func appendBSONKey(buf []byte, kind byte, key string) []byte {
+	return append(append(append(buf, kind), key...), 0)
+}
+
+// readBSON decodes a document at the start of data into a map, with
+// arrays as slices, and returns its length. Numbers keep their BSON type:
+// float64, int32 or int64; bsonNumber reads any of them.
+func readBSON(data []byte) (map[string]any, int, error) {
+	if len(data) < 5 {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	length := int(binary.LittleEndian.Uint32(data))
+	if length < 5 || length > len(data) || data[length-1] != 0 {
+		return nil, 0, fmt.Errorf("invalid document length %d", length)
+	}
+	doc := map[string]any{}
+	p := data[4 : length-1]
+	for len(p) > 0 {
+		kind := p[0]
+		end := bytes.IndexByte(p[1:], 0)
+		if end < 0 {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		key := string(p[1 : 1+end])
+		p = p[2+end:]
+		v, n, err := readBSONValue(kind, p)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", key, err)
+		}
+		doc[key] = v
+		p = p[n:]
+	}
+	return doc, length, nil
+}
+
+func readBSONValue(kind byte, p []byte) (any, int, error) {
+	need := func(n int) error {
+		if len(p) < n {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+	switch kind {
+	case 0x01: // double
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(p)), 8, nil
+	case 0x02, 0x0D, 0x0E: // string, JavaScript, symbol
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		n := int(binary.LittleEndian.Uint32(p))
+		if n < 1 || len(p) < 4+n {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		return string(p[4 : 4+n-1]), 4 + n, nil
+	case 0x03, 0x04: // document, array
+		doc, n, err := readBSON(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		if kind == 0x03 {
+			return doc, n, nil
+		}
+		arr := make([]any, len(doc))
+		for k, v := range doc {
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(arr) {
+				return nil, 0, fmt.Errorf("invalid array index %q", k)
+			}
+			arr[i] = v
+		}
+		return arr, n, nil
+	case 0x05: // binary
+		if err := need(5); err != nil {
+			return nil, 0, err
+		}
+		n := int(binary.LittleEndian.Uint32(p))
+		if n < 0 || len(p) < 5+n {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		return bytes.Clone(p[5 : 5+n]), 5 + n, nil
+	case 0x06, 0x0A, 0x7F, 0xFF: // undefined, null, max key, min key
+		return nil, 0, nil
+	case 0x07: // ObjectId
+		return nil, 12, need(12)
+	case 0x08: // bool
+		if err := need(1); err != nil {
+			return nil, 0, err
+		}
+		return p[0] != 0, 1, nil
+	case 0x09: // UTC datetime, in milliseconds
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return time.UnixMilli(int64(binary.LittleEndian.Uint64(p))), 8, nil
+	case 0x0B: // regular expression: pattern and options
+		n := 0
+		for range 2 {
+			end := bytes.IndexByte(p[n:], 0)
+			if end < 0 {
+				return nil, 0, io.ErrUnexpectedEOF
+			}
+			n += end + 1
+		}
+		return nil, n, nil
+	case 0x0F: // JavaScript with scope
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		n := int(binary.LittleEndian.Uint32(p))
+		return nil, n, need(n)
+	case 0x10: // int32
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		return int32(binary.LittleEndian.Uint32(p)), 4, nil
+	case 0x11: // timestamp: increment, then seconds
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return time.Unix(int64(binary.LittleEndian.Uint32(p[4:])), 0), 8, nil
+	case 0x12: // int64
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return int64(binary.LittleEndian.Uint64(p)), 8, nil
+	case 0x13: // decimal128
+		return nil, 16, need(16)
+	default:
+		return nil, 0, fmt.Errorf("unknown BSON type 0x%02x", kind)
+	}
+}
+
+// bsonNumber reads a number of any BSON numeric type.
+func bsonNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,252 @@
+package collectors
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mongoHelloReply is the body of a replica set member's reply to hello,
+// trimmed to a field of each type the decoder handles.
+const mongoHelloReply = "170100000869735772697461626c655072696d617279000103746f706f6c6f67" +
+	"7956657273696f6e002d0000000770726f63657373496400652a1f8e9d3c4b00" +
+	"1234567812636f756e7465720006000000000000000004686f73747300270000" +
+	"000230000a0000006462313a3237303137000231000a0000006462323a323730" +
+	"31370000027365744e616d65000400000072733000106d617842736f6e4f626a" +
+	"65637453697a650000000001096c6f63616c54696d65007b9529e29901000003" +
+	"6c61737457726974650029000000036f7054696d65001c000000117473000100" +
+	"0000161fee681274000300000000000000000008726561644f6e6c7900000a65" +
+	"6c656374696f6e496400016f6b00000000000000f03f00"
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestAppendBSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     bsonD
+		want    string
+		wantErr string
+	}{
+		{
+			name: "hello",
+			doc:  bsonD{{"hello", int32(1)}, {"$db", "admin"}},
+			want: "1f000000" + "1068656c6c6f0001000000" + "022464620006000000" + "61646d696e00" + "00",
+		},
+		{
+			name: "nested",
+			doc:  bsonD{{"options", bsonD{{"skipEmptyExchange", true}}}, {"n", int64(-1)}, {"p", []byte("n,,")}},
+			want: "3d000000" + "036f7074696f6e7300" + "1900000008736b6970456d70747945786368616e6765000100" +
+				"126e00ffffffffffffffff" + "0570000300000000" + "6e2c2c" + "00",
+		},
+		{name: "empty", doc: bsonD{}, want: "0500000000"},
+		{name: "unsupported", doc: bsonD{{"ratio", 0.5}}, wantErr: "can't encode float64 as BSON"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := appendBSON(nil, tc.doc)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("appendBSON() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("appendBSON() error = %v", err)
+			}
+			if hex.EncodeToString(got) != tc.want {
+				t.Errorf("appendBSON() = %x, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadBSON(t *testing.T) {
+	data := mustHex(t, mongoHelloReply)
+	doc, n, err := readBSON(append(data, "trailing"...))
+	if err != nil {
+		t.Fatalf("readBSON() error = %v", err)
+	}
+	if n != len(data) {
+		t.Errorf("readBSON() length = %d, want %d", n, len(data))
+	}
+	want := map[string]any{
+		"isWritablePrimary": true,
+		"topologyVersion":   map[string]any{"processId": nil, "counter": int64(6)},
+		"hosts":             []any{"db1:27017", "db2:27017"},
+		"setName":           "rs0",
+		"maxBsonObjectSize": int32(16777216),
+		"localTime":         time.UnixMilli(1760436000123),
+		"lastWrite":         map[string]any{"opTime": map[string]any{"ts": time.Unix(1760435990, 0), "t": int64(3)}},
+		"readOnly":          false,
+		"electionId":        nil,
+		"ok":                1.0,
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("readBSON() =\n%v\nwant\n%v", doc, want)
+	}
+}
+
+func TestReadBSONErrors(t *testing.T) {
+	tests := []struct {
+		name, data, wantErr string
+	}{
+		{"short", "0500", "unexpected EOF"},
+		{"length past end", "0a00000000", "invalid document length 10"},
+		{"no terminator", "0500000001", "invalid document length 5"},
+		{"unterminated key", "0800000010616200", "unexpected EOF"},
+		{"truncated int32", "0a000000106100010000", "a: unexpected EOF"},
+		{"string past end", "0d0000000273000a000000610000", "s: unexpected EOF"},
+		{"unknown type", "0800000020610000", "a: unknown BSON type 0x20"},
+		{"bad array index", "1100000004610009000000087800010000", "a: invalid array index \"x\""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := readBSON(mustHex(t, tc.data))
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("readBSON() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBSONRoundTrip(t *testing.T) {
+	doc := bsonD{
+		{"saslContinue", int32(1)},
+		{"conversationId", int64(1 << 40)},
+		{"payload", []byte("c=biws,r=abc")},
+		{"options", bsonD{{"skipEmptyExchange", true}, {"mechanism", "SCRAM-SHA-256"}}},
+		{"done", false},
+	}
+	data, err := appendBSON(nil, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := readBSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"saslContinue":   int32(1),
+		"conversationId": int64(1 << 40),
+		"payload":        []byte("c=biws,r=abc"),
+		"options":        map[string]any{"skipEmptyExchange": true, "mechanism": "SCRAM-SHA-256"},
+		"done":           false,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %v, want %v", got, want)
+	}
+}
+
+// mongoReply frames body as the OP_MSG reply to request id.
+func mongoReply(opcode uint32, id uint32, body []byte) []byte {
+	msg := make([]byte, 16, 21+len(body))
+	binary.LittleEndian.PutUint32(msg[0:], uint32(21+len(body)))
+	binary.LittleEndian.PutUint32(msg[4:], id+100)
+	binary.LittleEndian.PutUint32(msg[8:], id)
+	binary.LittleEndian.PutUint32(msg[12:], opcode)
+	msg = append(msg, 0, 0, 0, 0, 0)
+	return append(msg, body...)
+}
+
+func TestMongoCommand(t *testing.T) {
+	hello := mustHex(t, mongoHelloReply)
+	failed, err := appendBSON(nil, bsonD{{"ok", int32(0)}, {"errmsg", "no such command: 'hullo'"}, {"code", int32(59)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		reply   func(id uint32) []byte
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "ok",
+			reply: func(id uint32) []byte { return mongoReply(opMsg, id, hello) },
+			want:  "rs0",
+		},
+		{
+			name:    "command failed",
+			reply:   func(id uint32) []byte { return mongoReply(opMsg, id, failed) },
+			wantErr: "hello failed: no such command: 'hullo'",
+		},
+		{
+			name:    "legacy reply",
+			reply:   func(id uint32) []byte { return mongoReply(1, id, failed) },
+			wantErr: "unexpected reply opcode 1",
+		},
+		{
+			name: "document sequence",
+			reply: func(id uint32) []byte {
+				msg := mongoReply(opMsg, id, failed)
+				msg[20] = 1
+				return msg
+			},
+			wantErr: "unexpected reply section kind 1",
+		},
+		{
+			name: "invalid length",
+			reply: func(id uint32) []byte {
+				msg := mongoReply(opMsg, id, nil)[:16]
+				binary.LittleEndian.PutUint32(msg[0:], 16)
+				return msg
+			},
+			wantErr: "invalid reply length 16",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			requests := make(chan map[string]any, 1)
+			go func() {
+				defer server.Close()
+				defer close(requests)
+				var header [16]byte
+				if _, err := io.ReadFull(server, header[:]); err != nil {
+					return
+				}
+				body := make([]byte, binary.LittleEndian.Uint32(header[0:])-16)
+				if _, err := io.ReadFull(server, body); err != nil {
+					return
+				}
+				if op := binary.LittleEndian.Uint32(header[12:]); op != opMsg || body[4] != 0 {
+					return
+				}
+				doc, _, _ := readBSON(body[5:])
+				requests <- doc
+				server.Write(tc.reply(binary.LittleEndian.Uint32(header[4:])))
+			}()
+
+			c := &mongoConn{conn: client}
+			doc, err := c.command("admin", bsonD{{"hello", int32(1)}})
+			if got, want := <-requests, map[string]any{"hello": int32(1), "$db": "admin"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("request = %v, want %v", got, want)
+			}
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("command() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("command() error = %v", err)
+			}
+			if doc["setName"] != tc.want {
+				t.Errorf("command() setName = %v, want %s", doc["setName"], tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package collectors
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlStatus maps SHOW GLOBAL STATUS variables to metrics. Counters get
+// per-second rates from the rate engine, e.g. mysql.questions_per_sec.
+var mysqlStatus = []struct {
+	variable string
+	name     string
+	kind     metric.Kind
+	unit     string
+}{
+	{"Threads_connected", "mysql.threads_connected", metric.Gauge, ""},
+	{"Threads_running", "mysql.threads_running", metric.Gauge, ""},
+	{"Max_used_connections", "mysql.max_used_connections", metric.Gauge, ""},
+	{"Aborted_connects", "mysql.aborted_connects", metric.Counter, ""},
+	{"Connections", "mysql.connections", metric.Counter, ""},
+	{"Questions", "mysql.questions", metric.Counter, ""},
+	{"Queries", "mysql.queries", metric.Counter, ""},
+	{"Slow_queries", "mysql.slow_queries", metric.Counter, ""},
+	{"Bytes_received", "mysql.bytes_received", metric.Counter, "bytes"},
+	{"Bytes_sent", "mysql.bytes_sent", metric.Counter, "bytes"},
+	{"Created_tmp_disk_tables", "mysql.tmp_disk_tables", metric.Counter, ""},
+	{"Table_locks_waited", "mysql.table_locks_waited", metric.Counter, ""},
+	{"Uptime", "mysql.uptime", metric.Gauge, "seconds"},
+	{"Innodb_buffer_pool_pages_total", "mysql.innodb_buffer_pool_pages_total", metric.Gauge, ""},
+	{"Innodb_buffer_pool_pages_free", "mysql.innodb_buffer_pool_pages_free", metric.Gauge, ""},
+	{"Innodb_buffer_pool_pages_data", "mysql.innodb_buffer_pool_pages_data", metric.Gauge, ""},
+	{"Innodb_buffer_pool_pages_dirty", "mysql.innodb_buffer_pool_pages_dirty", metric.Gauge, ""},
+	{"Innodb_buffer_pool_bytes_data", "mysql.innodb_buffer_pool_bytes_data", metric.Gauge, "bytes"},
+	{"Innodb_buffer_pool_read_requests", "mysql.innodb_buffer_pool_read_requests", metric.Counter, ""},
+	{"Innodb_buffer_pool_reads", "mysql.innodb_buffer_pool_reads", metric.Counter, ""},
+	{"Innodb_row_lock_waits", "mysql.innodb_row_lock_waits", metric.Counter, ""},
+}
+
+type MySQLCollector struct {
+	// DSN is a go-sql-driver/mysql data source name, e.g.
+	// "glass:secret@tcp(127.0.0.1:3306)/".
+	DSN string `json:"dsn"`
+
+	db *sql.DB
+}
+
+func NewMySQLCollector(cfg config.CollectorConfig) (Collector, error) {
+	m := &MySQLCollector{DSN: "root@unix(/var/run/mysqld/mysqld.sock)/"}
+	if err := cfg.Decode(m); err != nil {
+		return nil, err
+	}
+	dsn, err := mysql.ParseDSN(m.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dsn: %w", err)
+	}
+	if dsn.Timeout == 0 {
+		dsn.Timeout = 5 * time.Second
+	}
+	connector, err := mysql.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	m.db = sql.OpenDB(connector)
+	m.db.SetMaxOpenConns(1)
+	m.db.SetConnMaxIdleTime(time.Minute)
+	return m, nil
+}
+
+func (m *MySQLCollector) Name() string {
+	return "mysql"
+}
+
+func (m *MySQLCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	status, err := m.variables(ctx, "SHOW GLOBAL STATUS")
+	if err != nil {
+		return nil, fmt.Errorf("getting status: %w", err)
+	}
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	for _, s := range mysqlStatus {
+		if v, ok := parseMySQLNumber(status[s.variable]); ok {
+			b.Add(s.kind, s.name, v, s.unit)
+		}
+	}
+	if total, ok := parseMySQLNumber(status["Innodb_buffer_pool_pages_total"]); ok && total > 0 {
+		free, _ := parseMySQLNumber(status["Innodb_buffer_pool_pages_free"])
+		b.Gauge("mysql.innodb_buffer_pool_used_percent", 100*(total-free)/total, "percent")
+	}
+
+	vars, err := m.variables(ctx, "SHOW GLOBAL VARIABLES WHERE Variable_name IN ('max_connections', 'version')")
+	if err != nil {
+		errs = append(errs, fmt.Errorf("getting variables: %w", err))
+	} else {
+		b.Gauge("mysql.info", 1, "", "version", vars["version"])
+		if max, ok := parseMySQLNumber(vars["max_connections"]); ok && max > 0 {
+			b.Gauge("mysql.max_connections", max, "")
+			connected, _ := parseMySQLNumber(status["Threads_connected"])
+			b.Gauge("mysql.connections_used_percent", 100*connected/max, "percent")
+		}
+	}
+
+	if err := m.addReplication(ctx, b); err != nil {
+		errs = append(errs, fmt.Errorf("getting replication status: %w", err))
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// variables runs a two-column SHOW statement and returns it as a map.
+func (m *MySQLCollector) variables(ctx context.Context, query string) (map[string]string, error) {
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]string)
+	for rows.Next() {
+		var k, v sql.NullString
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, err
+		}
+		out[k.String] = v.String
+	}
+	return out, rows.Err()
+}
+
+// addReplication reports lag and thread state when the server is a
+// replica. MySQL 8.0.22 renamed SHOW SLAVE STATUS and its columns; both
+// spellings are tried.
+func (m *MySQLCollector) addReplication(ctx context.Context, b *metric.Builder) error {
+	row, err := m.queryRow(ctx, "SHOW REPLICA STATUS")
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1064 {
+		row, err = m.queryRow(ctx, "SHOW SLAVE STATUS")
+	}
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1227 {
+		// Without REPLICATION CLIENT there's nothing to report, which is
+		// the common case on servers that aren't replicas anyway.
+		return nil
+	}
+	if err != nil || row == nil {
+		return err
+	}
+
+	pick := func(names ...string) string {
+		for _, n := range names {
+			if v, ok := row[n]; ok {
+				return v
+			}
+		}
+		return ""
+	}
+	b.Gauge("mysql.replication_io_running", boolValue(pick("Replica_IO_Running", "Slave_IO_Running") == "Yes"), "")
+	b.Gauge("mysql.replication_sql_running", boolValue(pick("Replica_SQL_Running", "Slave_SQL_Running") == "Yes"), "")
+	// Lag is NULL while replication is stopped; leave it out rather than
+	// reporting zero.
+	if lag, ok := parseMySQLNumber(pick("Seconds_Behind_Source", "Seconds_Behind_Master")); ok {
+		b.Gauge("mysql.replication_lag", lag, "seconds")
+	}
+	return nil
+}
+
+// queryRow returns the first row of query keyed by column name, or nil if
+// there are no rows.
+func (m *MySQLCollector) queryRow(ctx context.Context, query string) (map[string]string, error) {
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	values := make([]sql.NullString, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	row := make(map[string]string, len(cols))
+	for i, c := range cols {
+		if values[i].Valid {
+			row[c] = values[i].String
+		}
+	}
+	return row, nil
+}
+
+func parseMySQLNumber(s string) (float64, bool) {
+	switch strings.ToUpper(s) {
+	case "":
+		return 0, false
+	case "ON", "YES":
+		return 1, true
+	case "OFF", "NO":
+		return 0, true
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
@@ -1,23 +1,114 @@
 package collectors
 
 import (
-	"github.com/rs/zerolog/log"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
 	"github.com/shirou/gopsutil/v4/net"
 )
 
-type NetworkCollector struct{}
+// defaultExcludeInterfaces skips loopback and the per-container veth pairs
+// that would otherwise swamp the output on Docker hosts.
+var defaultExcludeInterfaces = []string{"lo", "veth*"}
+
+type NetworkCollector struct {
+	// Interfaces restricts the collector to NICs matching these globs.
+	// ExcludeInterfaces defaults to lo and veth* only when Interfaces is
+	// empty, so an explicit list is never filtered behind the user's back.
+	Interfaces        []string `json:"interfaces"`
+	ExcludeInterfaces []string `json:"exclude_interfaces"`
+	// TCPDetails reports every TCP connection individually, capped at
+	// TCPDetailsLimit since busy servers have tens of thousands.
+	TCPDetails      bool `json:"tcp_details"`
+	TCPDetailsLimit int  `json:"tcp_details_limit"`
+}
 
-func (n *NetworkCollector) Collector() error {
-	connections, _ := net.Connections("tcp")
-	netstat, err := net.IOCounters(false)
+func NewNetworkCollector(cfg config.CollectorConfig) (Collector, error) {
+	n := &NetworkCollector{TCPDetailsLimit: 100}
+	if err := cfg.Decode(n); err != nil {
+		return nil, err
+	}
+	if n.ExcludeInterfaces == nil && len(n.Interfaces) == 0 {
+		n.ExcludeInterfaces = defaultExcludeInterfaces
+	}
+	if err := validatePatterns(n.Interfaces, n.ExcludeInterfaces); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *NetworkCollector) Name() string {
+	return "net"
+}
+
+func (n *NetworkCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	netstat, err := net.IOCountersWithContext(ctx, true)
 	if err != nil {
-		log.Err(err).Msg("Error getting network info")
+		errs = append(errs, fmt.Errorf("getting network info: %w", err))
 	}
 	for _, stat := range netstat {
-		log.Info().Str("name", stat.Name).Uint64("bytes-sent", stat.BytesSent).Uint64("bytes-received", stat.BytesRecv).Uint64("packets-sent", stat.PacketsSent).Uint64("packets-received", stat.PacketsRecv).Msg("")
+		if !n.wanted(stat.Name) {
+			continue
+		}
+		labels := []string{"interface", stat.Name}
+		b.Counter("net.bytes_sent", float64(stat.BytesSent), "bytes", labels...)
+		b.Counter("net.bytes_recv", float64(stat.BytesRecv), "bytes", labels...)
+		b.Counter("net.packets_sent", float64(stat.PacketsSent), "packets", labels...)
+		b.Counter("net.packets_recv", float64(stat.PacketsRecv), "packets", labels...)
+		b.Counter("net.err_in", float64(stat.Errin), "packets", labels...)
+		b.Counter("net.err_out", float64(stat.Errout), "packets", labels...)
+		b.Counter("net.drop_in", float64(stat.Dropin), "packets", labels...)
+		b.Counter("net.drop_out", float64(stat.Dropout), "packets", labels...)
 	}
-	for _, connection := range connections {
-		log.Info().Interface("connection", connection).Msg("")
+
+	ifaces, err := net.InterfacesWithContext(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("getting network interfaces: %w", err))
+	}
+	for _, iface := range ifaces {
+		if !n.wanted(iface.Name) {
+			continue
+		}
+		n.addInterface(b, iface)
+	}
+
+	if err := n.addTCP(ctx, b); err != nil {
+		errs = append(errs, err)
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func (n *NetworkCollector) wanted(name string) bool {
+	return filterMatch(name, n.Interfaces, n.ExcludeInterfaces)
+}
+
+func (n *NetworkCollector) addInterface(b *metric.Builder, iface net.InterfaceStat) {
+	labels := []string{"interface", iface.Name}
+	b.Gauge("net.info", 1, "", "interface", iface.Name, "mac", iface.HardwareAddr)
+	b.Gauge("net.mtu", float64(iface.MTU), "bytes", labels...)
+
+	// operstate distinguishes an administratively up interface without
+	// carrier from one that can pass traffic; fall back to the flags where
+	// sysfs isn't available.
+	sysfs := filepath.Join("/sys/class/net", iface.Name)
+	up := slices.Contains(iface.Flags, "up")
+	if state := readSysfsString(filepath.Join(sysfs, "operstate")); state != "" && state != "unknown" {
+		up = state == "up"
+	}
+	b.Gauge("net.up", boolValue(up), "", labels...)
+
+	// speed is in Mbit/s, and -1 or unreadable for virtual and down links.
+	if mbps, err := readSysfsFloat(filepath.Join(sysfs, "speed")); err == nil && mbps > 0 {
+		b.Gauge("net.speed", mbps*1e6/8, "bytes/s", labels...)
 	}
-	return nil
 }
@@ -0,0 +1,241 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+const (
+	nfsClientStatsPath = "/proc/net/rpc/nfs"
+	nfsServerStatsPath = "/proc/net/rpc/nfsd"
+	mountstatsPath     = "/proc/self/mountstats"
+)
+
+// NFSCollector reports NFS client and server RPC statistics and, for each
+// NFS mount, per-operation counts, retransmissions and round-trip times.
+type NFSCollector struct {
+	// Mountpoints limits the per-mount statistics to these mount points.
+	Mountpoints []string `json:"mountpoints"`
+
+	mu   sync.Mutex
+	last map[nfsOpKey]nfsOpStats
+}
+
+func NewNFSCollector(cfg config.CollectorConfig) (Collector, error) {
+	n := &NFSCollector{}
+	if err := cfg.Decode(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *NFSCollector) Name() string {
+	return "nfs"
+}
+
+type nfsOpKey struct {
+	mountpoint, op string
+}
+
+// nfsOpStats is one line of a mount's per-op statistics: the operations
+// done, the requests sent for them, which is more when the client had to
+// retransmit, and the milliseconds spent on them.
+type nfsOpStats struct {
+	Ops, Transmissions, Timeouts float64
+	RTT, Execute                 float64
+}
+
+type nfsMount struct {
+	Export, Mountpoint string
+	ReadBytes          float64
+	WriteBytes         float64
+	Ops                map[string]nfsOpStats
+}
+
+func (n *NFSCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+	found := false
+
+	if data, err := os.ReadFile(nfsClientStatsPath); err == nil {
+		found = true
+		addNFSRPCStats(b, "nfs.client", data)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("reading NFS client statistics: %w", err))
+	}
+	if data, err := os.ReadFile(nfsServerStatsPath); err == nil {
+		found = true
+		addNFSRPCStats(b, "nfs.server", data)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("reading NFS server statistics: %w", err))
+	}
+
+	data, err := os.ReadFile(mountstatsPath)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("reading mountstats: %w", err))
+	}
+	mounts := parseMountstats(data)
+	if len(n.Mountpoints) > 0 {
+		mounts = slices.DeleteFunc(mounts, func(m nfsMount) bool { return !slices.Contains(n.Mountpoints, m.Mountpoint) })
+	}
+	n.addMounts(b, mounts)
+
+	if !found && len(mounts) == 0 && len(errs) == 0 {
+		return nil, errors.New("neither the NFS client nor the NFS server is loaded")
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// addNFSRPCStats reads /proc/net/rpc/nfs or nfsd. The "rpc" line starts
+// with calls, then retransmissions on the client and bad calls on the
+// server; each "procN" line is a count of operations followed by the
+// number of each NFSvN operation done.
+func addNFSRPCStats(b *metric.Builder, prefix string, data []byte) {
+	server := prefix == "nfs.server"
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		num := func(i int) float64 {
+			if i >= len(f) {
+				return 0
+			}
+			v, _ := strconv.ParseFloat(f[i], 64)
+			return v
+		}
+		switch {
+		case f[0] == "rpc":
+			b.Counter(prefix+"_rpc_calls", num(1), "")
+			if server {
+				b.Counter(prefix+"_rpc_bad_calls", num(2), "")
+			} else {
+				b.Counter(prefix+"_rpc_retransmissions", num(2), "")
+			}
+		case strings.HasPrefix(f[0], "proc") && f[0] != "proc4ops":
+			version := strings.TrimPrefix(f[0], "proc")
+			var total float64
+			for i := 2; i < len(f); i++ {
+				total += num(i)
+			}
+			b.Counter(prefix+"_ops", total, "", "version", version)
+		case server && f[0] == "th":
+			b.Gauge(prefix+"_threads", num(1), "")
+		case server && f[0] == "io":
+			b.Counter(prefix+"_read_bytes", num(1), "bytes")
+			b.Counter(prefix+"_write_bytes", num(2), "bytes")
+		case server && f[0] == "rc":
+			b.Counter(prefix+"_reply_cache_hits", num(1), "")
+			b.Counter(prefix+"_reply_cache_misses", num(2), "")
+		}
+	}
+}
+
+// parseMountstats reads the NFS mounts out of /proc/self/mountstats. Each
+// starts with "device srv:/export mounted on /mnt with fstype nfs4 ..." and
+// lists, after "per-op statistics", lines such as
+//
+//	READ: 1200 1203 0 180000 98304000 25 4100 4300 0
+//
+// with the operations, transmissions, timeouts, bytes sent and received,
+// and milliseconds queued, in flight and in total.
+func parseMountstats(data []byte) []nfsMount {
+	var (
+		mounts []nfsMount
+		cur    *nfsMount
+		perOp  bool
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		if len(f) == 0 {
+			continue
+		}
+		if f[0] == "device" {
+			cur, perOp = nil, false
+			if len(f) >= 8 && f[2] == "mounted" && f[6] == "fstype" && strings.HasPrefix(f[7], "nfs") {
+				mounts = append(mounts, nfsMount{
+					Export:     f[1],
+					Mountpoint: strings.ReplaceAll(f[4], `\040`, " "),
+					Ops:        map[string]nfsOpStats{},
+				})
+				cur = &mounts[len(mounts)-1]
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		num := func(i int) float64 {
+			if i >= len(f) {
+				return 0
+			}
+			v, _ := strconv.ParseFloat(f[i], 64)
+			return v
+		}
+		switch {
+		case f[0] == "bytes:":
+			// normal, direct and server reads and writes, then pages.
+			cur.ReadBytes, cur.WriteBytes = num(5), num(6)
+		case f[0] == "per-op":
+			perOp = true
+		case perOp && strings.HasSuffix(f[0], ":") && len(f) >= 9:
+			cur.Ops[strings.TrimSuffix(f[0], ":")] = nfsOpStats{
+				Ops:           num(1),
+				Transmissions: num(2),
+				Timeouts:      num(3),
+				RTT:           num(7),
+				Execute:       num(8),
+			}
+		}
+	}
+	return mounts
+}
+
+// addMounts reports each mount's operations. Round-trip and execution
+// times are averaged over the operations done since the previous
+// collection, or since the mount on the first one.
+func (n *NFSCollector) addMounts(b *metric.Builder, mounts []nfsMount) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	last := map[nfsOpKey]nfsOpStats{}
+	for _, m := range mounts {
+		labels := []string{"mountpoint", m.Mountpoint, "export", m.Export}
+		b.Counter("nfs.mount_read_bytes", m.ReadBytes, "bytes", labels...)
+		b.Counter("nfs.mount_write_bytes", m.WriteBytes, "bytes", labels...)
+		for op, s := range m.Ops {
+			// Most of the dozens of operations are never used on a mount.
+			if s.Ops == 0 {
+				continue
+			}
+			key := nfsOpKey{m.Mountpoint, op}
+			last[key] = s
+			opLabels := append(slices.Clip(labels), "op", op)
+			b.Counter("nfs.mount_ops", s.Ops, "", opLabels...)
+			b.Counter("nfs.mount_retransmissions", max(s.Transmissions-s.Ops, 0), "", opLabels...)
+			b.Counter("nfs.mount_timeouts", s.Timeouts, "", opLabels...)
+
+			prev := n.last[key]
+			ops := s.Ops - prev.Ops
+			if ops <= 0 || s.RTT < prev.RTT {
+				// Nothing done in between, or the mount was remounted.
+				continue
+			}
+			b.Gauge("nfs.mount_rtt", (s.RTT-prev.RTT)/ops/1000, "seconds", opLabels...)
+			b.Gauge("nfs.mount_execute_time", (s.Execute-prev.Execute)/ops/1000, "seconds", opLabels...)
+		}
+	}
+	n.last = last
+}
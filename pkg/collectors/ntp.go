@@ -0,0 +1,174 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+// clockSync is what chronyd or ntpd says about synchronization. Offset is
+// positive when the system clock is behind the reference.
+type clockSync struct {
+	source                    string
+	stratum                   float64
+	offset, jitter            float64
+	rootDelay, rootDispersion float64
+	synced                    bool
+}
+
+// NTPCollector reports whether the clock is synchronized and how far off
+// it is: the kernel's view from adjtimex(2), which every time daemon
+// including systemd-timesyncd keeps up to date, and the offset, jitter,
+// stratum and source from chronyd or ntpd when either is running. A drifting
+// clock breaks TLS validation, token expiry and database replication long
+// before anyone notices the time is wrong.
+type NTPCollector struct {
+	// Chronyc and Ntpq are the query tools, tried in that order.
+	Chronyc string `json:"chronyc"`
+	Ntpq    string `json:"ntpq"`
+
+	noDaemon sync.Once
+}
+
+func NewNTPCollector(cfg config.CollectorConfig) (Collector, error) {
+	n := &NTPCollector{Chronyc: "chronyc", Ntpq: "ntpq"}
+	if err := cfg.Decode(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *NTPCollector) Name() string {
+	return "ntp"
+}
+
+func (n *NTPCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	if err := addKernelClock(b); err != nil {
+		errs = append(errs, err)
+	}
+
+	daemon, s, err := n.query(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if daemon == "" {
+		n.noDaemon.Do(func() {
+			log.Info().Msg("Neither chronyc nor ntpq found; reporting only the kernel's clock state")
+		})
+	}
+	if s != nil {
+		b.Gauge("ntp.synced", boolValue(s.synced), "")
+		b.Gauge("ntp.source_info", 1, "", "daemon", daemon, "source", s.source)
+		b.Gauge("ntp.stratum", s.stratum, "")
+		b.Gauge("ntp.offset", s.offset, "seconds")
+		b.Gauge("ntp.abs_offset", math.Abs(s.offset), "seconds")
+		b.Gauge("ntp.jitter", s.jitter, "seconds")
+		b.Gauge("ntp.root_delay", s.rootDelay, "seconds")
+		b.Gauge("ntp.root_dispersion", s.rootDispersion, "seconds")
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// query asks chronyd, then ntpd. The daemon is empty when neither tool is
+// installed.
+func (n *NTPCollector) query(ctx context.Context) (string, *clockSync, error) {
+	out, err := runCommand(ctx, n.Chronyc, "-c", "tracking")
+	if err == nil {
+		s, err := parseChronyTracking(string(out))
+		return "chrony", s, err
+	}
+	if !errors.Is(err, exec.ErrNotFound) {
+		return "chrony", nil, err
+	}
+	out, err = runCommand(ctx, n.Ntpq, "-c", "rv")
+	if err == nil {
+		s, err := parseNtpqVariables(string(out))
+		return "ntpd", s, err
+	}
+	if !errors.Is(err, exec.ErrNotFound) {
+		return "ntpd", nil, err
+	}
+	return "", nil, nil
+}
+
+// parseChronyTracking reads `chronyc -c tracking`: reference ID, source
+// name, stratum, reference time, system time correction, last offset, RMS
+// offset, frequency, residual frequency, skew, root delay, root dispersion,
+// update interval and leap status, e.g.
+//
+//	A9FEA97B,169.254.169.123,4,1700000000.1,0.000001234,-0.0000004,0.000012,-7.5,0.001,0.02,0.000345,0.000123,64.2,Normal
+func parseChronyTracking(out string) (*clockSync, error) {
+	f := strings.Split(strings.TrimSpace(out), ",")
+	if len(f) < 14 {
+		return nil, fmt.Errorf("parsing chronyc tracking: unexpected output %q", strings.TrimSpace(out))
+	}
+	num := func(s string) float64 {
+		v, _ := strconv.ParseFloat(s, 64)
+		return v
+	}
+	return &clockSync{
+		source:         f[1],
+		stratum:        num(f[2]),
+		offset:         num(f[4]),
+		jitter:         num(f[6]),
+		rootDelay:      num(f[10]),
+		rootDispersion: num(f[11]),
+		// Stratum 0 with an unsynchronised leap status is what chronyd
+		// reports before it has picked a source.
+		synced: f[13] != "Not synchronised" && num(f[2]) > 0,
+	}, nil
+}
+
+// parseNtpqVariables reads the system variables from `ntpq -c rv`, comma
+// separated name=value pairs over several lines with times in
+// milliseconds, e.g.
+//
+//	associd=0 status=0615 leap_none, sync_ntp, 1 event, clock_sync,
+//	version="ntpd 4.2.8p15", leap=00, stratum=2, refid=192.0.2.1,
+//	rootdelay=1.234, rootdisp=5.678, offset=0.123, sys_jitter=0.045
+func parseNtpqVariables(out string) (*clockSync, error) {
+	vars := map[string]string{}
+	for _, kv := range strings.Split(strings.ReplaceAll(out, "\n", ","), ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(kv), "="); ok {
+			vars[k] = strings.Trim(v, `"`)
+		}
+	}
+	if _, ok := vars["stratum"]; !ok {
+		return nil, fmt.Errorf("parsing ntpq: no system variables in %q", strings.TrimSpace(out))
+	}
+	ms := func(k string) float64 {
+		v, _ := strconv.ParseFloat(vars[k], 64)
+		return v / 1000
+	}
+	stratum, _ := strconv.ParseFloat(vars["stratum"], 64)
+	s := &clockSync{
+		source:         vars["refid"],
+		stratum:        stratum,
+		offset:         ms("offset"),
+		jitter:         ms("sys_jitter"),
+		rootDelay:      ms("rootdelay"),
+		rootDispersion: ms("rootdisp"),
+		// Leap indicator 11 is the alarm condition: not synchronized.
+		// Stratum 16 is unsynchronized too.
+		synced: vars["leap"] != "11" && vars["leap"] != "3" && stratum < 16,
+	}
+	// ntpd before 4.2.8 calls it jitter.
+	if _, ok := vars["sys_jitter"]; !ok {
+		s.jitter = ms("jitter")
+	}
+	return s, nil
+}
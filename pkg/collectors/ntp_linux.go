@@ -0,0 +1,36 @@
+package collectors
+
+import (
+	"fmt"
+	"syscall"
+
+	"glass/pkg/metric"
+)
+
+// Kernel clock status bits and states from linux/timex.h.
+const (
+	staUnsync    = 0x0040
+	staNano      = 0x2000
+	timeStateErr = 5
+)
+
+// addKernelClock reports the kernel's view of the clock from adjtimex,
+// which the NTP daemons and systemd-timesyncd keep up to date.
+func addKernelClock(b *metric.Builder) error {
+	var tx syscall.Timex
+	state, err := syscall.Adjtimex(&tx)
+	if err != nil {
+		return fmt.Errorf("reading kernel clock state: %w", err)
+	}
+	offset := float64(tx.Offset) / 1e6
+	if tx.Status&staNano != 0 {
+		offset = float64(tx.Offset) / 1e9
+	}
+	b.Gauge("ntp.kernel_synced", boolValue(tx.Status&staUnsync == 0 && state != timeStateErr), "")
+	b.Gauge("ntp.kernel_offset", offset, "seconds")
+	b.Gauge("ntp.kernel_max_error", float64(tx.Maxerror)/1e6, "seconds")
+	b.Gauge("ntp.kernel_estimated_error", float64(tx.Esterror)/1e6, "seconds")
+	// freq is in ppm with a 16-bit fractional part.
+	b.Gauge("ntp.frequency_ppm", float64(tx.Freq)/65536, "")
+	return nil
+}
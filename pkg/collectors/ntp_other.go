@@ -0,0 +1,15 @@
+//go:build !linux
+
+package collectors
+
+import (
+	"errors"
+	"fmt"
+
+	"glass/pkg/metric"
+)
+
+// addKernelClock fails, as adjtimex is Linux's.
+func addKernelClock(*metric.Builder) error {
+	return fmt.Errorf("reading kernel clock state: %w", errors.ErrUnsupported)
+}
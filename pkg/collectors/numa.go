@@ -0,0 +1,166 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/metric"
+)
+
+const (
+	hugepagesRoot = "/sys/kernel/mm/hugepages"
+	thpRoot       = "/sys/kernel/mm/transparent_hugepage"
+	numaNodeRoot  = "/sys/devices/system/node"
+)
+
+// numastatFields maps a node's numastat counters to metrics. A miss is a
+// page allocated on this node that was meant for another, a foreign page
+// one meant for this node that went elsewhere.
+var numastatFields = []struct {
+	field string
+	name  string
+}{
+	{"numa_hit", "numa.node_hits"},
+	{"numa_miss", "numa.node_misses"},
+	{"numa_foreign", "numa.node_foreign"},
+	{"interleave_hit", "numa.node_interleave_hits"},
+	{"local_node", "numa.node_local_allocations"},
+	{"other_node", "numa.node_other_allocations"},
+}
+
+// NUMACollector reports hugepage pools, transparent hugepage settings and
+// memory per NUMA node, from sysfs.
+type NUMACollector struct{}
+
+func (n *NUMACollector) Name() string {
+	return "numa"
+}
+
+func (n *NUMACollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	// hugepages-2048kB and hugepages-1048576kB hold the pools for 2 MiB and
+	// 1 GiB pages.
+	pools, _ := filepath.Glob(filepath.Join(hugepagesRoot, "hugepages-*"))
+	for _, dir := range pools {
+		addHugepages(b, dir)
+	}
+
+	if enabled := readSysfsString(filepath.Join(thpRoot, "enabled")); enabled != "" {
+		b.Gauge("numa.transparent_hugepage_info", 1, "",
+			"enabled", sysfsChoice(enabled),
+			"defrag", sysfsChoice(readSysfsString(filepath.Join(thpRoot, "defrag"))),
+		)
+	}
+	if meminfo, err := readKeyValues("/proc/meminfo"); err == nil {
+		if kb, err := strconv.ParseFloat(strings.TrimSuffix(meminfo["AnonHugePages:"], " kB"), 64); err == nil {
+			b.Gauge("numa.anon_hugepages", kb*1024, "bytes")
+		}
+	}
+
+	nodes, _ := filepath.Glob(filepath.Join(numaNodeRoot, "node[0-9]*"))
+	if len(nodes) == 0 && len(pools) == 0 {
+		return nil, errors.New("no hugepage or NUMA information in sysfs")
+	}
+	for _, dir := range nodes {
+		if err := addNUMANode(b, dir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func addHugepages(b *metric.Builder, dir string, labels ...string) {
+	size := strings.TrimPrefix(filepath.Base(dir), "hugepages-")
+	labels = append(slices.Clip(labels), "size", size)
+	read := func(file string) (float64, bool) {
+		v, err := readSysfsFloat(filepath.Join(dir, file))
+		return v, err == nil
+	}
+	total, ok := read("nr_hugepages")
+	if !ok {
+		return
+	}
+	prefix := "numa.hugepages"
+	if len(labels) > 2 {
+		prefix = "numa.node_hugepages"
+	}
+	b.Gauge(prefix+"_total", total, "", labels...)
+	if free, ok := read("free_hugepages"); ok {
+		b.Gauge(prefix+"_free", free, "", labels...)
+	}
+	if surplus, ok := read("surplus_hugepages"); ok {
+		b.Gauge(prefix+"_surplus", surplus, "", labels...)
+	}
+	// Reservations are only kept for the whole pool, not per node.
+	if reserved, ok := read("resv_hugepages"); ok {
+		b.Gauge(prefix+"_reserved", reserved, "", labels...)
+	}
+}
+
+// addNUMANode reads a node's meminfo, whose lines look like
+// "Node 0 MemTotal:  6158152 kB", its numastat and its hugepage pools.
+func addNUMANode(b *metric.Builder, dir string) error {
+	node := strings.TrimPrefix(filepath.Base(dir), "node")
+	labels := []string{"node", node}
+
+	data, err := os.ReadFile(filepath.Join(dir, "meminfo"))
+	if err != nil {
+		return fmt.Errorf("reading node %s meminfo: %w", node, err)
+	}
+	meminfo := map[string]float64{}
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 4 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(f[3], 64); err == nil {
+			if len(f) > 4 && f[4] == "kB" {
+				v *= 1024
+			}
+			meminfo[strings.TrimSuffix(f[2], ":")] = v
+		}
+	}
+	total, free := meminfo["MemTotal"], meminfo["MemFree"]
+	b.Gauge("numa.node_memory_total", total, "bytes", labels...)
+	b.Gauge("numa.node_memory_free", free, "bytes", labels...)
+	b.Gauge("numa.node_memory_used", total-free, "bytes", labels...)
+	if total > 0 {
+		b.Gauge("numa.node_memory_used_percent", 100*(total-free)/total, "percent", labels...)
+	}
+	b.Gauge("numa.node_file_pages", meminfo["FilePages"], "bytes", labels...)
+	b.Gauge("numa.node_anon_pages", meminfo["AnonPages"], "bytes", labels...)
+
+	if stat, err := readKeyValues(filepath.Join(dir, "numastat")); err == nil {
+		for _, f := range numastatFields {
+			if v, err := strconv.ParseFloat(stat[f.field], 64); err == nil {
+				b.Counter(f.name, v, "", labels...)
+			}
+		}
+	}
+
+	pools, _ := filepath.Glob(filepath.Join(dir, "hugepages", "hugepages-*"))
+	for _, pool := range pools {
+		addHugepages(b, pool, labels...)
+	}
+	return nil
+}
+
+// sysfsChoice picks the selected value out of a sysfs setting such as
+// "always [madvise] never".
+func sysfsChoice(s string) string {
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		if j := strings.IndexByte(s[i:], ']'); j > 0 {
+			return s[i+1 : i+j]
+		}
+	}
+	return s
+}
@@ -0,0 +1,161 @@
+package collectors
+
+import (
+	"context"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// "mysqld invoked oom-killer: gfp_mask=0xcc0(GFP_KERNEL), order=0, ..."
+	oomInvokedPattern = regexp.MustCompile(`^(.*) invoked oom-killer:`)
+	// "Out of memory: Killed process 1234 (mysqld) total-vm:1024kB,
+	// anon-rss:512kB, file-rss:4kB, shmem-rss:0kB, UID:999 ...", after
+	// "Memory cgroup out of memory:" when a cgroup hit its limit. Kernels
+	// before 4.5 leave out shmem-rss, and older ones UID too.
+	oomKilledPattern = regexp.MustCompile(`Killed process (\d+) \(([^)]*)\)(?: total-vm:\d+kB, anon-rss:(\d+)kB, file-rss:(\d+)kB(?:, shmem-rss:(\d+)kB)?(?:, UID:(\d+))?)?`)
+)
+
+// OOMKill is a process the kernel's OOM killer killed.
+type OOMKill struct {
+	Time    time.Time `json:"time"`
+	PID     int       `json:"pid"`
+	Process string    `json:"process"`
+	UID     string    `json:"uid,omitempty"`
+	// RSS is the resident memory the process had, in bytes.
+	RSS float64 `json:"rss_bytes"`
+	// Cgroup is the killed process's memory cgroup.
+	Cgroup string `json:"cgroup,omitempty"`
+	// Constraint is what ran out: "none" for the whole system, "memcg" for
+	// a cgroup's limit, or "cpuset" or "memory_policy".
+	Constraint string `json:"constraint,omitempty"`
+	// Trigger is the process whose allocation invoked the OOM killer.
+	Trigger string `json:"trigger,omitempty"`
+}
+
+// oomParser pieces kills together from the messages the OOM killer logs:
+// which process invoked it, an "oom-kill:" summary on 4.19 and later, then
+// the kill itself.
+type oomParser struct {
+	trigger string
+	summary map[string]string
+}
+
+func (p *oomParser) add(r kmsgRecord) (OOMKill, bool) {
+	if m := oomInvokedPattern.FindStringSubmatch(r.Message); m != nil {
+		p.trigger, p.summary = m[1], nil
+		return OOMKill{}, false
+	}
+	// oom-kill:constraint=CONSTRAINT_MEMCG,...,task_memcg=/docker/abc,task=mysqld,pid=1234,uid=999
+	if rest, ok := strings.CutPrefix(r.Message, "oom-kill:"); ok {
+		p.summary = map[string]string{}
+		for _, kv := range strings.Split(rest, ",") {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				p.summary[k] = v
+			}
+		}
+		return OOMKill{}, false
+	}
+	m := oomKilledPattern.FindStringSubmatch(r.Message)
+	if m == nil {
+		return OOMKill{}, false
+	}
+	pid, _ := strconv.Atoi(m[1])
+	kill := OOMKill{Time: r.Time, PID: pid, Process: m[2], UID: m[6], Trigger: p.trigger}
+	for _, kb := range m[3:6] {
+		if v, err := strconv.ParseFloat(kb, 64); err == nil {
+			kill.RSS += v * 1024
+		}
+	}
+	if p.summary != nil && p.summary["pid"] == m[1] {
+		kill.Cgroup = p.summary["task_memcg"]
+		kill.Constraint = strings.ToLower(strings.TrimPrefix(p.summary["constraint"], "CONSTRAINT_"))
+		if kill.UID == "" {
+			kill.UID = p.summary["uid"]
+		}
+	}
+	p.trigger, p.summary = "", nil
+	return kill, true
+}
+
+// RecentOOMKills returns the OOM kills still in the kernel ring buffer,
+// oldest first, including those from before glass started.
+func RecentOOMKills() ([]OOMKill, error) {
+	fd, err := openKmsg(false)
+	if err != nil {
+		return nil, err
+	}
+	defer closeKmsg(fd)
+	records, err := readKmsg(fd, bootTime())
+	var (
+		p     oomParser
+		kills []OOMKill
+	)
+	for _, r := range records {
+		if kill, ok := p.add(r); ok {
+			kills = append(kills, kill)
+		}
+	}
+	return kills, err
+}
+
+// OOMCollector counts OOM kills by process name as the kernel logs them,
+// and logs each with the process's memory use and cgroup. The cgroup
+// collector counts kills per cgroup from memory.events, and
+// /api/v1/oom lists recent ones.
+type OOMCollector struct {
+	mu     sync.Mutex
+	fd     int
+	opened bool
+	boot   time.Time
+	parser oomParser
+	kills  map[string]float64
+}
+
+func (o *OOMCollector) Name() string {
+	return "oom"
+}
+
+func (o *OOMCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.opened {
+		// Kills from before glass started are in RecentOOMKills but aren't
+		// counted.
+		fd, err := openKmsg(true)
+		if err != nil {
+			return nil, err
+		}
+		o.fd, o.opened, o.boot, o.kills = fd, true, bootTime(), map[string]float64{}
+	}
+	records, err := readKmsg(o.fd, o.boot)
+	fresh := 0
+	for _, r := range records {
+		kill, ok := o.parser.add(r)
+		if !ok {
+			continue
+		}
+		fresh++
+		o.kills[kill.Process]++
+		log.Warn().Str("collector", "oom").Int("pid", kill.PID).Str("process", kill.Process).
+			Float64("rss_bytes", kill.RSS).Str("cgroup", kill.Cgroup).Str("constraint", kill.Constraint).
+			Str("trigger", kill.Trigger).Time("kernel_time", kill.Time).Msg("OOM killer killed a process")
+	}
+
+	b := metric.NewBuilder(time.Now())
+	b.Gauge("oom.new_kills", float64(fresh), "")
+	for _, process := range slices.Sorted(maps.Keys(o.kills)) {
+		b.Counter("oom.kills", o.kills[process], "", "process", process)
+	}
+	return b.Metrics(), err
+}
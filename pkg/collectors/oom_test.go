@@ -0,0 +1,76 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOOMParser(t *testing.T) {
+	base := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		file string
+		want []OOMKill
+	}{
+		{
+			file: "memcg.txt",
+			want: []OOMKill{{
+				Time: base.Add(6 * time.Second), PID: 2210, Process: "mysqld", UID: "999",
+				RSS: (1012344 + 12340) * 1024, Cgroup: "/system.slice/docker-3f2a.scope", Constraint: "memcg", Trigger: "mysqld",
+			}},
+		},
+		{
+			file: "global.txt",
+			want: []OOMKill{
+				{
+					Time: base.Add(2 * time.Second), PID: 31337, Process: "php-fpm", UID: "33",
+					RSS: (402112 + 2048) * 1024, Cgroup: "/system.slice/php8.1-fpm.service", Constraint: "none", Trigger: "php-fpm",
+				},
+				{
+					Time: base.Add(5 * time.Second), PID: 4400, Process: "node", UID: "1000",
+					RSS: (600000 + 1000) * 1024, Cgroup: "/user.slice/user-1000.slice", Constraint: "none", Trigger: "node",
+				},
+			},
+		},
+		{
+			// Before 4.19 there's no oom-kill: summary, so no cgroup, and
+			// before 4.5 no shmem-rss.
+			file: "legacy.txt",
+			want: []OOMKill{
+				{Time: base.Add(2 * time.Second), PID: 4321, Process: "java", RSS: (3456789 + 12) * 1024, Trigger: "java"},
+				{Time: base.Add(5 * time.Second), PID: 999, Process: "httpd", RSS: (20480 + 1024) * 1024, Trigger: "httpd"},
+			},
+		},
+		{
+			// A summary only describes the kill of the process it names.
+			file: "mismatch.txt",
+			want: []OOMKill{
+				{Time: base.Add(1 * time.Second), PID: 701, Process: "worker", UID: "0", RSS: 4096 * 1024},
+				{Time: base.Add(2 * time.Second), PID: 702, Process: "worker", UID: "0", RSS: 2048 * 1024},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "oom", tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var (
+				p   oomParser
+				got []OOMKill
+			)
+			for i, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+				if kill, ok := p.add(kmsgRecord{Time: base.Add(time.Duration(i) * time.Second), Message: line}); ok {
+					got = append(got, kill)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("kills =\n%+v\nwant\n%+v", got, tc.want)
+			}
+		})
+	}
+}
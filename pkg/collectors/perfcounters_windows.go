@@ -0,0 +1,81 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"glass/pkg/metric"
+
+	"github.com/yusufpapurcu/wmi"
+)
+
+type win32PerfSystem struct {
+	ProcessorQueueLength uint32
+	Processes            uint32
+	Threads              uint32
+}
+
+type win32PerfMemory struct {
+	CommittedBytes             uint64
+	CommitLimit                uint64
+	PercentCommittedBytesInUse uint32
+}
+
+type win32PerfDisk struct {
+	Name                   string
+	CurrentDiskQueueLength uint32
+	PercentIdleTime        uint64
+}
+
+// PerfCountersCollector reads the performance counters that say a Windows
+// host is saturated, from WMI's formatted counter classes: the processor
+// queue, threads waiting for a CPU; committed bytes against the commit
+// limit, past which allocations fail; and the requests queued on each
+// physical disk and how busy it is.
+type PerfCountersCollector struct{}
+
+func (p *PerfCountersCollector) Name() string {
+	return "perfcounters"
+}
+
+func (p *PerfCountersCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	var system []win32PerfSystem
+	if err := wmi.Query("SELECT ProcessorQueueLength, Processes, Threads FROM Win32_PerfFormattedData_PerfOS_System", &system); err != nil {
+		errs = append(errs, fmt.Errorf("reading system counters: %w", err))
+	}
+	for _, s := range system {
+		b.Gauge("perfcounters.processor_queue_length", float64(s.ProcessorQueueLength), "")
+		b.Gauge("perfcounters.processes", float64(s.Processes), "")
+		b.Gauge("perfcounters.threads", float64(s.Threads), "")
+	}
+
+	var memory []win32PerfMemory
+	if err := wmi.Query("SELECT CommittedBytes, CommitLimit, PercentCommittedBytesInUse FROM Win32_PerfFormattedData_PerfOS_Memory", &memory); err != nil {
+		errs = append(errs, fmt.Errorf("reading memory counters: %w", err))
+	}
+	for _, m := range memory {
+		b.Gauge("perfcounters.committed_bytes", float64(m.CommittedBytes), "bytes")
+		b.Gauge("perfcounters.commit_limit", float64(m.CommitLimit), "bytes")
+		b.Gauge("perfcounters.committed_percent", float64(m.PercentCommittedBytesInUse), "percent")
+	}
+
+	var disks []win32PerfDisk
+	if err := wmi.Query("SELECT Name, CurrentDiskQueueLength, PercentIdleTime FROM Win32_PerfFormattedData_PerfDisk_PhysicalDisk", &disks); err != nil {
+		errs = append(errs, fmt.Errorf("reading disk counters: %w", err))
+	}
+	for _, d := range disks {
+		// Names are the disk number and its drive letters, like "0 C: D:".
+		if d.Name == "_Total" {
+			continue
+		}
+		labels := []string{"disk", d.Name}
+		b.Gauge("perfcounters.disk_queue_length", float64(d.CurrentDiskQueueLength), "", labels...)
+		b.Gauge("perfcounters.disk_busy_percent", 100-min(float64(d.PercentIdleTime), 100), "percent", labels...)
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
@@ -0,0 +1,155 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+type PHPFPMCollector struct {
+	// URLs lists one status page per pool. http(s):// URLs are fetched
+	// through the web server; tcp://host:port/status and
+	// unix:///path/to.sock;/status talk FastCGI to PHP-FPM directly.
+	URLs []string `json:"urls"`
+
+	client *http.Client
+}
+
+// phpfpmStatus is the JSON form of the pm.status_path page.
+type phpfpmStatus struct {
+	Pool               string `json:"pool"`
+	ProcessManager     string `json:"process manager"`
+	StartSince         int64  `json:"start since"`
+	AcceptedConn       int64  `json:"accepted conn"`
+	ListenQueue        int64  `json:"listen queue"`
+	MaxListenQueue     int64  `json:"max listen queue"`
+	ListenQueueLen     int64  `json:"listen queue len"`
+	IdleProcesses      int64  `json:"idle processes"`
+	ActiveProcesses    int64  `json:"active processes"`
+	TotalProcesses     int64  `json:"total processes"`
+	MaxActiveProcesses int64  `json:"max active processes"`
+	MaxChildrenReached int64  `json:"max children reached"`
+	SlowRequests       int64  `json:"slow requests"`
+}
+
+func NewPHPFPMCollector(cfg config.CollectorConfig) (Collector, error) {
+	p := &PHPFPMCollector{URLs: []string{"tcp://127.0.0.1:9000/status"}}
+	if err := cfg.Decode(p); err != nil {
+		return nil, err
+	}
+	for _, u := range p.URLs {
+		if _, err := url.Parse(u); err != nil {
+			return nil, fmt.Errorf("invalid url %q: %w", u, err)
+		}
+	}
+	p.client = &http.Client{}
+	return p, nil
+}
+
+func (p *PHPFPMCollector) Name() string {
+	return "phpfpm"
+}
+
+func (p *PHPFPMCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, u := range p.URLs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, err := p.status(ctx, u)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("getting status from %s: %w", u, err))
+				return
+			}
+			addPHPFPMStatus(b, status)
+		}()
+	}
+	wg.Wait()
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func addPHPFPMStatus(b *metric.Builder, s phpfpmStatus) {
+	labels := []string{"pool", s.Pool}
+	b.Gauge("phpfpm.active_processes", float64(s.ActiveProcesses), "", labels...)
+	b.Gauge("phpfpm.idle_processes", float64(s.IdleProcesses), "", labels...)
+	b.Gauge("phpfpm.total_processes", float64(s.TotalProcesses), "", labels...)
+	b.Gauge("phpfpm.max_active_processes", float64(s.MaxActiveProcesses), "", labels...)
+	b.Gauge("phpfpm.listen_queue", float64(s.ListenQueue), "", labels...)
+	b.Gauge("phpfpm.max_listen_queue", float64(s.MaxListenQueue), "", labels...)
+	b.Gauge("phpfpm.listen_queue_len", float64(s.ListenQueueLen), "", labels...)
+	b.Counter("phpfpm.accepted_connections", float64(s.AcceptedConn), "", labels...)
+	b.Counter("phpfpm.max_children_reached", float64(s.MaxChildrenReached), "", labels...)
+	b.Counter("phpfpm.slow_requests", float64(s.SlowRequests), "", labels...)
+	b.Gauge("phpfpm.uptime", float64(s.StartSince), "seconds", labels...)
+}
+
+func (p *PHPFPMCollector) status(ctx context.Context, rawURL string) (phpfpmStatus, error) {
+	var status phpfpmStatus
+	body, err := p.fetch(ctx, rawURL)
+	if err != nil {
+		return status, err
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return status, fmt.Errorf("decoding status: %w", err)
+	}
+	return status, nil
+}
+
+func (p *PHPFPMCollector) fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		q := u.Query()
+		q.Set("json", "")
+		u.RawQuery = q.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("status page returned %s", resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	case "tcp":
+		script := u.Path
+		if script == "" {
+			script = "/status"
+		}
+		return fcgiGet(ctx, "tcp", u.Host, script, "json")
+	case "unix":
+		// The socket path and status path are separated by ';' as in
+		// php-fpm's own tools, since both are paths.
+		socket, script, _ := strings.Cut(u.Path, ";")
+		if script == "" {
+			script = "/status"
+		}
+		return fcgiGet(ctx, "unix", socket, script, "json")
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+}
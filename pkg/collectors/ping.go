@@ -0,0 +1,241 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+type PingCollector struct {
+	// Targets are host names or addresses.
+	Targets []string `json:"targets"`
+	// Count is the number of echo requests sent to each target per cycle,
+	// PingInterval apart. The collector's own interval and timeout are
+	// how often it runs and how long a cycle may take.
+	Count        int             `json:"count"`
+	PingInterval config.Duration `json:"ping_interval"`
+	// ReplyTimeout is how long to wait for each reply.
+	ReplyTimeout config.Duration `json:"reply_timeout"`
+	// Mode is "icmp" for raw sockets (root or CAP_NET_RAW), "udp" for
+	// unprivileged ICMP datagram sockets (net.ipv4.ping_group_range), or
+	// "auto" to try raw sockets first.
+	Mode string `json:"mode"`
+}
+
+func NewPingCollector(cfg config.CollectorConfig) (Collector, error) {
+	p := &PingCollector{
+		Count:        5,
+		PingInterval: config.Duration(200 * time.Millisecond),
+		ReplyTimeout: config.Duration(time.Second),
+		Mode:         "auto",
+	}
+	if err := cfg.Decode(p); err != nil {
+		return nil, err
+	}
+	if p.Count < 1 {
+		return nil, fmt.Errorf("count must be at least 1, got %d", p.Count)
+	}
+	switch p.Mode {
+	case "auto", "icmp", "udp":
+	default:
+		return nil, fmt.Errorf("unknown mode %q", p.Mode)
+	}
+	return p, nil
+}
+
+func (p *PingCollector) Name() string {
+	return "ping"
+}
+
+func (p *PingCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, target := range p.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rtts, sent, err := p.ping(ctx, target)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("pinging %s: %w", target, err))
+				return
+			}
+			addPingStats(b, target, rtts, sent)
+		}()
+	}
+	wg.Wait()
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func addPingStats(b *metric.Builder, target string, rtts []time.Duration, sent int) {
+	labels := []string{"target", target}
+	b.Gauge("ping.packets_sent", float64(sent), "packets", labels...)
+	b.Gauge("ping.packets_received", float64(len(rtts)), "packets", labels...)
+	b.Gauge("ping.packet_loss", 100*float64(sent-len(rtts))/float64(sent), "percent", labels...)
+	b.Gauge("ping.up", boolValue(len(rtts) > 0), "", labels...)
+	if len(rtts) == 0 {
+		return
+	}
+
+	lo, hi := rtts[0], rtts[0]
+	var sum float64
+	for _, d := range rtts {
+		lo, hi = min(lo, d), max(hi, d)
+		sum += d.Seconds()
+	}
+	avg := sum / float64(len(rtts))
+	var sq float64
+	for _, d := range rtts {
+		sq += (d.Seconds() - avg) * (d.Seconds() - avg)
+	}
+	b.Gauge("ping.rtt_min", lo.Seconds(), "seconds", labels...)
+	b.Gauge("ping.rtt_avg", avg, "seconds", labels...)
+	b.Gauge("ping.rtt_max", hi.Seconds(), "seconds", labels...)
+	b.Gauge("ping.rtt_stddev", math.Sqrt(sq/float64(len(rtts))), "seconds", labels...)
+}
+
+// pingFamily holds what differs between ICMPv4 and ICMPv6.
+type pingFamily struct {
+	raw, udp string
+	proto    int
+	request  icmp.Type
+	reply    icmp.Type
+}
+
+var (
+	pingIPv4 = pingFamily{"ip4:icmp", "udp4", 1, ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply}
+	pingIPv6 = pingFamily{"ip6:ipv6-icmp", "udp6", 58, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply}
+)
+
+// ping sends Count echo requests to target and returns the round-trip
+// time of each one answered.
+func (p *PingCollector) ping(ctx context.Context, target string) ([]time.Duration, int, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, target)
+	if err != nil {
+		return nil, 0, err
+	}
+	ip := addrs[0].IP
+	family := pingIPv6
+	if ip.To4() != nil {
+		family = pingIPv4
+	}
+
+	conn, dgram, err := p.listen(family)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	var dst net.Addr = &net.IPAddr{IP: ip}
+	if dgram {
+		dst = &net.UDPAddr{IP: ip}
+	}
+
+	// The kernel picks the ID of datagram sockets and only delivers their
+	// own replies; raw sockets see every reply so the ID tells them apart.
+	id := rand.IntN(0xffff)
+	payload := []byte("glass ping")
+	var rtts []time.Duration
+	sent := 0
+	for seq := 0; seq < p.Count; seq++ {
+		if seq > 0 {
+			select {
+			case <-ctx.Done():
+				return rtts, sent, nil
+			case <-time.After(time.Duration(p.PingInterval)):
+			}
+		}
+		msg := icmp.Message{
+			Type: family.request,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: payload},
+		}
+		wb, err := msg.Marshal(nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		start := time.Now()
+		if _, err := conn.WriteTo(wb, dst); err != nil {
+			return nil, 0, err
+		}
+		sent++
+		if awaitEcho(ctx, conn, family, ip, id, seq, dgram, start.Add(time.Duration(p.ReplyTimeout))) {
+			rtts = append(rtts, time.Since(start))
+		}
+	}
+	return rtts, sent, nil
+}
+
+// awaitEcho reads until the reply to seq arrives or the deadline passes.
+func awaitEcho(ctx context.Context, conn *icmp.PacketConn, family pingFamily, ip net.IP, id, seq int, dgram bool, deadline time.Time) bool {
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return false
+		}
+		msg, err := icmp.ParseMessage(family.proto, buf[:n])
+		if err != nil || msg.Type != family.reply {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq || (!dgram && echo.ID != id) {
+			continue
+		}
+		if !peerIP(peer).Equal(ip) {
+			continue
+		}
+		return true
+	}
+}
+
+func peerIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+// listen opens an ICMP socket for the configured mode. It reports whether
+// the socket is a datagram one, which changes how addresses are given.
+func (p *PingCollector) listen(family pingFamily) (*icmp.PacketConn, bool, error) {
+	if p.Mode != "udp" {
+		conn, err := icmp.ListenPacket(family.raw, "")
+		if err == nil {
+			return conn, false, nil
+		}
+		if p.Mode == "icmp" || !errors.Is(err, os.ErrPermission) {
+			return nil, false, err
+		}
+		log.Debug().Err(err).Msg("Raw ICMP sockets unavailable, falling back to unprivileged ping")
+	}
+	conn, err := icmp.ListenPacket(family.udp, "")
+	if err != nil {
+		return nil, false, fmt.Errorf("opening unprivileged ICMP socket (check net.ipv4.ping_group_range): %w", err)
+	}
+	return conn, true, nil
+}
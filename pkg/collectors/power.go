@@ -0,0 +1,327 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+const powerSupplyRoot = "/sys/class/power_supply"
+
+// upsFields maps NUT variables to metrics. apcupsd's status is translated
+// into the same variables.
+var upsFields = []struct {
+	field string
+	name  string
+	unit  string
+}{
+	{"battery.charge", "power.ups_charge", "percent"},
+	{"ups.load", "power.ups_load", "percent"},
+	{"battery.runtime", "power.ups_runtime", "seconds"},
+	{"input.voltage", "power.ups_input_voltage", "volts"},
+	{"output.voltage", "power.ups_output_voltage", "volts"},
+	{"battery.voltage", "power.ups_battery_voltage", "volts"},
+	{"ups.realpower", "power.ups_power", "watts"},
+}
+
+// apcupsdFields maps apcupsd status fields to NUT variables, with the
+// factor that converts apcupsd's units.
+var apcupsdFields = map[string]struct {
+	field string
+	scale float64
+}{
+	"BCHARGE":  {"battery.charge", 1},
+	"LOADPCT":  {"ups.load", 1},
+	"TIMELEFT": {"battery.runtime", 60},
+	"LINEV":    {"input.voltage", 1},
+	"OUTPUTV":  {"output.voltage", 1},
+	"BATTV":    {"battery.voltage", 1},
+}
+
+// apcupsdStatus maps apcupsd's STATUS words to NUT's ups.status flags.
+var apcupsdStatus = map[string]string{
+	"ONLINE":      "OL",
+	"ONBATT":      "OB",
+	"LOWBATT":     "LB",
+	"REPLACEBATT": "RB",
+	"OVERLOAD":    "OVER",
+	"COMMLOST":    "OFF",
+}
+
+// PowerCollector reports UPSes through the NUT and apcupsd network
+// servers, and laptop batteries and mains adapters from sysfs.
+type PowerCollector struct {
+	// NUT lists upsd addresses; every UPS each one serves is reported.
+	NUT []string `json:"nut"`
+	// Apcupsd lists apcupsd network information server addresses.
+	Apcupsd []string `json:"apcupsd"`
+}
+
+func NewPowerCollector(cfg config.CollectorConfig) (Collector, error) {
+	p := &PowerCollector{}
+	if err := cfg.Decode(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *PowerCollector) Name() string {
+	return "power"
+}
+
+func (p *PowerCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	for _, addr := range p.NUT {
+		upses, err := nutStatus(ctx, addr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("querying NUT at %s: %w", addr, err))
+		}
+		for _, name := range slices.Sorted(maps.Keys(upses)) {
+			addUPS(b, name, upses[name])
+		}
+	}
+	for _, addr := range p.Apcupsd {
+		vars, err := apcupsdStatusVars(ctx, addr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("querying apcupsd at %s: %w", addr, err))
+			continue
+		}
+		name := vars["ups.name"]
+		if name == "" {
+			name = addr
+		}
+		addUPS(b, name, vars)
+	}
+
+	supplies, _ := filepath.Glob(filepath.Join(powerSupplyRoot, "*"))
+	for _, dir := range supplies {
+		addPowerSupply(b, dir)
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// addUPS reports a UPS from its NUT variables. ups.status is a list of
+// flags such as "OL CHRG" or "OB LB".
+func addUPS(b *metric.Builder, name string, vars map[string]string) {
+	labels := []string{"ups", name}
+	status := strings.Fields(vars["ups.status"])
+	b.Gauge("power.ups_info", 1, "", "ups", name, "model", vars["ups.model"], "status", vars["ups.status"])
+	b.Gauge("power.ups_on_battery", boolValue(slices.Contains(status, "OB")), "", labels...)
+	b.Gauge("power.ups_low_battery", boolValue(slices.Contains(status, "LB")), "", labels...)
+	b.Gauge("power.ups_replace_battery", boolValue(slices.Contains(status, "RB")), "", labels...)
+	b.Gauge("power.ups_overload", boolValue(slices.Contains(status, "OVER")), "", labels...)
+	for _, f := range upsFields {
+		if v, err := strconv.ParseFloat(vars[f.field], 64); err == nil {
+			b.Gauge(f.name, v, f.unit, labels...)
+		}
+	}
+}
+
+// nutStatus lists the UPSes upsd serves and their variables, using the
+// network protocol's LIST UPS and LIST VAR commands.
+func nutStatus(ctx context.Context, addr string) (map[string]map[string]string, error) {
+	conn, err := dialPower(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	lines, err := nutList(conn, r, "UPS")
+	if err != nil {
+		return nil, err
+	}
+	upses := map[string]map[string]string{}
+	for _, line := range lines {
+		// UPS <name> "<description>"
+		name, _, _ := strings.Cut(line, " ")
+		vars := map[string]string{}
+		varLines, err := nutList(conn, r, "VAR "+name)
+		if err != nil {
+			return upses, fmt.Errorf("listing variables of %s: %w", name, err)
+		}
+		// VAR <ups> <variable> "<value>"
+		for _, l := range varLines {
+			f := strings.SplitN(l, " ", 3)
+			if len(f) == 3 {
+				vars[f[1]] = strings.Trim(f[2], `"`)
+			}
+		}
+		if vars["ups.model"] == "" {
+			vars["ups.model"] = vars["device.model"]
+		}
+		upses[name] = vars
+	}
+	return upses, nil
+}
+
+// nutList sends LIST <what> and returns the lines between BEGIN and END,
+// without their leading type word.
+func nutList(w io.Writer, r *bufio.Reader, what string) ([]string, error) {
+	if _, err := fmt.Fprintf(w, "LIST %s\n", what); err != nil {
+		return nil, err
+	}
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "ERR "):
+			return nil, errors.New(strings.TrimPrefix(line, "ERR "))
+		case strings.HasPrefix(line, "BEGIN LIST "):
+		case strings.HasPrefix(line, "END LIST "):
+			return lines, nil
+		default:
+			if _, rest, ok := strings.Cut(line, " "); ok {
+				lines = append(lines, rest)
+			}
+		}
+	}
+}
+
+// apcupsdStatusVars sends the status command to apcupsd's network
+// information server and translates the reply into NUT variables. Messages
+// in both directions are prefixed with a two-byte length, and the reply
+// ends with an empty one.
+func apcupsdStatusVars(ctx context.Context, addr string) (map[string]string, error) {
+	conn, err := dialPower(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	cmd := "status"
+	msg := binary.BigEndian.AppendUint16(nil, uint16(len(cmd)))
+	if _, err := conn.Write(append(msg, cmd...)); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	vars := map[string]string{}
+	for {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return vars, nil
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		// Lines look like "BCHARGE  : 100.0 Percent".
+		k, v, ok := strings.Cut(string(buf), ":")
+		if !ok {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		switch k {
+		case "UPSNAME":
+			vars["ups.name"] = v
+		case "MODEL":
+			vars["ups.model"] = v
+		case "STATUS":
+			var flags []string
+			for _, word := range strings.Fields(v) {
+				if flag, ok := apcupsdStatus[word]; ok {
+					flags = append(flags, flag)
+				}
+			}
+			vars["ups.status"] = strings.Join(flags, " ")
+		default:
+			f, ok := apcupsdFields[k]
+			if !ok {
+				continue
+			}
+			num, _, _ := strings.Cut(v, " ")
+			if x, err := strconv.ParseFloat(num, 64); err == nil {
+				vars[f.field] = strconv.FormatFloat(x*f.scale, 'f', -1, 64)
+			}
+		}
+	}
+}
+
+func dialPower(ctx context.Context, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+	}
+	return conn, nil
+}
+
+// addPowerSupply reports a battery or mains adapter. Batteries give energy
+// in µWh and power in µW, or charge in µAh and current in µA; the ratios
+// are the same either way.
+func addPowerSupply(b *metric.Builder, dir string) {
+	name := filepath.Base(dir)
+	read := func(file string) (float64, bool) {
+		v, err := readSysfsFloat(filepath.Join(dir, file))
+		return v, err == nil
+	}
+	switch readSysfsString(filepath.Join(dir, "type")) {
+	case "Mains":
+		if online, ok := read("online"); ok {
+			b.Gauge("power.ac_online", online, "", "supply", name)
+		}
+	case "Battery":
+		// Peripherals such as wireless mice report batteries too.
+		if readSysfsString(filepath.Join(dir, "scope")) == "Device" {
+			return
+		}
+		labels := []string{"battery", name}
+		status := readSysfsString(filepath.Join(dir, "status"))
+		b.Gauge("power.battery_info", 1, "", "battery", name,
+			"model", readSysfsString(filepath.Join(dir, "model_name")), "status", status)
+		b.Gauge("power.battery_discharging", boolValue(status == "Discharging"), "", labels...)
+		if v, ok := read("capacity"); ok {
+			b.Gauge("power.battery_charge", v, "percent", labels...)
+		}
+		if v, ok := read("cycle_count"); ok {
+			b.Gauge("power.battery_cycles", v, "", labels...)
+		}
+
+		now, okNow := read("energy_now")
+		full, okFull := read("energy_full")
+		design, okDesign := read("energy_full_design")
+		rate, okRate := read("power_now")
+		if !okNow {
+			now, okNow = read("charge_now")
+			full, okFull = read("charge_full")
+			design, okDesign = read("charge_full_design")
+			rate, okRate = read("current_now")
+		}
+		if okFull && okDesign && design > 0 {
+			b.Gauge("power.battery_health", 100*full/design, "percent", labels...)
+		}
+		if status == "Discharging" && okNow && okRate && rate > 0 {
+			b.Gauge("power.battery_runtime", 3600*now/rate, "seconds", labels...)
+		}
+		if v, ok := read("power_now"); ok {
+			b.Gauge("power.battery_power", v/1e6, "watts", labels...)
+		}
+	}
+}
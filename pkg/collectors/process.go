@@ -0,0 +1,235 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// ProcessInfo is a point-in-time view of one process.
+type ProcessInfo struct {
+	PID           int32   `json:"pid"`
+	Name          string  `json:"name"`
+	User          string  `json:"user"`
+	Cmdline       string  `json:"cmdline"`
+	State         string  `json:"state"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryPercent float64 `json:"memory_percent"`
+	RSS           uint64  `json:"rss"`
+	VMS           uint64  `json:"vms"`
+	FDs           int32   `json:"fds"`
+	Threads       int32   `json:"threads"`
+	ReadBytes     uint64  `json:"read_bytes"`
+	WriteBytes    uint64  `json:"write_bytes"`
+	// ReadRate and WriteRate are bytes per second since the previous
+	// sample, or over the process lifetime on the first.
+	ReadRate   float64   `json:"read_bytes_per_second"`
+	WriteRate  float64   `json:"write_bytes_per_second"`
+	CreateTime time.Time `json:"create_time"`
+	Age        float64   `json:"age_seconds"`
+}
+
+// ProcessSortKeys are the orders SortProcesses accepts.
+var ProcessSortKeys = []string{"cpu", "memory", "io", "fds"}
+
+// ProcessSampler keeps gopsutil handles between runs so CPU percentages and
+// IO rates are computed over the time since the previous sample rather than
+// since the process started. It is safe for concurrent use.
+type ProcessSampler struct {
+	mu    sync.Mutex
+	procs map[int32]*sampledProcess
+}
+
+type sampledProcess struct {
+	p           *process.Process
+	read, write uint64
+	at          time.Time
+}
+
+func (s *ProcessSampler) Sample(ctx context.Context) ([]ProcessInfo, error) {
+	pids, err := process.PidsWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing processes: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.procs == nil {
+		s.procs = make(map[int32]*sampledProcess)
+	}
+
+	seen := make(map[int32]*sampledProcess, len(pids))
+	infos := make([]ProcessInfo, 0, len(pids))
+	for _, pid := range pids {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		sp, known := s.procs[pid]
+		if !known {
+			p, err := process.NewProcessWithContext(ctx, pid)
+			if err != nil {
+				continue
+			}
+			sp = &sampledProcess{p: p}
+		}
+		info, ok := inspectProcess(ctx, sp, known)
+		if !ok {
+			continue
+		}
+		seen[pid] = sp
+		infos = append(infos, info)
+	}
+	s.procs = seen
+	return infos, nil
+}
+
+// inspectProcess gathers what it can about sp and remembers its IO
+// counters for the next sample. Fields the caller isn't allowed to read
+// (e.g. FDs of other users' processes) are left zero.
+func inspectProcess(ctx context.Context, sp *sampledProcess, known bool) (ProcessInfo, bool) {
+	p := sp.p
+	name, err := p.NameWithContext(ctx)
+	if err != nil {
+		return ProcessInfo{}, false
+	}
+	now := time.Now()
+	info := ProcessInfo{PID: p.Pid, Name: name}
+	if known {
+		info.CPUPercent, _ = p.PercentWithContext(ctx, 0)
+	} else {
+		// Prime the sampler and fall back to the lifetime average.
+		p.PercentWithContext(ctx, 0)
+		info.CPUPercent, _ = p.CPUPercentWithContext(ctx)
+	}
+	info.User, _ = p.UsernameWithContext(ctx)
+	info.Cmdline, _ = p.CmdlineWithContext(ctx)
+	if status, err := p.StatusWithContext(ctx); err == nil && len(status) > 0 {
+		info.State = status[0]
+	}
+	if mp, err := p.MemoryPercentWithContext(ctx); err == nil {
+		info.MemoryPercent = float64(mp)
+	}
+	if mem, err := p.MemoryInfoWithContext(ctx); err == nil {
+		info.RSS, info.VMS = mem.RSS, mem.VMS
+	}
+	info.FDs, _ = p.NumFDsWithContext(ctx)
+	info.Threads, _ = p.NumThreadsWithContext(ctx)
+	if ms, err := p.CreateTimeWithContext(ctx); err == nil {
+		info.CreateTime = time.UnixMilli(ms)
+		info.Age = now.Sub(info.CreateTime).Seconds()
+	}
+	if io, err := p.IOCountersWithContext(ctx); err == nil {
+		info.ReadBytes, info.WriteBytes = io.ReadBytes, io.WriteBytes
+		since, read, write := info.Age, uint64(0), uint64(0)
+		if known && !sp.at.IsZero() {
+			since, read, write = now.Sub(sp.at).Seconds(), sp.read, sp.write
+		}
+		if since > 0 && io.ReadBytes >= read && io.WriteBytes >= write {
+			info.ReadRate = float64(io.ReadBytes-read) / since
+			info.WriteRate = float64(io.WriteBytes-write) / since
+		}
+		sp.read, sp.write, sp.at = io.ReadBytes, io.WriteBytes, now
+	}
+	return info, true
+}
+
+// SortProcesses orders infos descending by one of ProcessSortKeys.
+func SortProcesses(infos []ProcessInfo, by string) {
+	less := func(a, b ProcessInfo) bool { return a.CPUPercent > b.CPUPercent }
+	switch by {
+	case "memory":
+		less = func(a, b ProcessInfo) bool { return a.RSS > b.RSS }
+	case "io":
+		less = func(a, b ProcessInfo) bool { return a.ReadRate+a.WriteRate > b.ReadRate+b.WriteRate }
+	case "fds":
+		less = func(a, b ProcessInfo) bool { return a.FDs > b.FDs }
+	}
+	sort.SliceStable(infos, func(i, j int) bool { return less(infos[i], infos[j]) })
+}
+
+// TopProcesses sorts infos as SortProcesses does and returns the first n,
+// or all of them when n is zero.
+func TopProcesses(infos []ProcessInfo, by string, n int) []ProcessInfo {
+	SortProcesses(infos, by)
+	if n > 0 && len(infos) > n {
+		infos = infos[:n]
+	}
+	return infos
+}
+
+type ProcessCollector struct {
+	// Pattern is a regular expression matched against the process name.
+	Pattern string `json:"name"`
+	// Users restricts reporting to processes owned by these users.
+	Users []string `json:"users"`
+	// Top limits reporting to the N heaviest processes by SortBy.
+	Top    int    `json:"top"`
+	SortBy string `json:"sort_by"`
+
+	pattern *regexp.Regexp
+	sampler ProcessSampler
+}
+
+func NewProcessCollector(cfg config.CollectorConfig) (Collector, error) {
+	p := &ProcessCollector{Top: 10, SortBy: "cpu"}
+	if err := cfg.Decode(p); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(ProcessSortKeys, p.SortBy) {
+		return nil, fmt.Errorf("invalid sort_by %q: must be one of %s", p.SortBy, strings.Join(ProcessSortKeys, ", "))
+	}
+	if p.Pattern != "" {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name pattern: %w", err)
+		}
+		p.pattern = re
+	}
+	return p, nil
+}
+
+func (p *ProcessCollector) Name() string {
+	return "proc"
+}
+
+func (p *ProcessCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	infos, err := p.sampler.Sample(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b := metric.NewBuilder(time.Now())
+	b.Gauge("proc.total", float64(len(infos)), "")
+
+	matched := infos[:0]
+	for _, info := range infos {
+		if p.pattern != nil && !p.pattern.MatchString(info.Name) {
+			continue
+		}
+		if len(p.Users) > 0 && !slices.Contains(p.Users, info.User) {
+			continue
+		}
+		matched = append(matched, info)
+	}
+	for _, info := range TopProcesses(matched, p.SortBy, p.Top) {
+		labels := []string{"pid", strconv.Itoa(int(info.PID)), "name", info.Name, "user", info.User}
+		b.Gauge("proc.info", 1, "", append(labels, "state", info.State)...)
+		b.Gauge("proc.cpu_percent", info.CPUPercent, "percent", labels...)
+		b.Gauge("proc.memory_percent", info.MemoryPercent, "percent", labels...)
+		b.Gauge("proc.memory_rss", float64(info.RSS), "bytes", labels...)
+		b.Gauge("proc.memory_vms", float64(info.VMS), "bytes", labels...)
+		b.Gauge("proc.open_fds", float64(info.FDs), "", labels...)
+		b.Gauge("proc.threads", float64(info.Threads), "", labels...)
+	}
+	return b.Metrics(), nil
+}
@@ -0,0 +1,90 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+const pressureRoot = "/proc/pressure"
+
+// psiResources are the files under /proc/pressure; irq appeared in Linux
+// 6.1 and the others in 4.20.
+var psiResources = []string{"cpu", "memory", "io", "irq"}
+
+// PSICollector reports Pressure Stall Information: the share of time tasks
+// were stalled waiting for CPU, memory or IO.
+type PSICollector struct {
+	missing sync.Once
+}
+
+func (p *PSICollector) Name() string {
+	return "psi"
+}
+
+func (p *PSICollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	if _, err := os.Stat(pressureRoot); errors.Is(err, os.ErrNotExist) {
+		// Kernels before 4.20, or built without CONFIG_PSI or booted with
+		// psi=0, have nothing to report; that isn't worth an error every
+		// collection.
+		p.missing.Do(func() {
+			log.Info().Msg("Pressure stall information is not available on this kernel")
+		})
+		return nil, nil
+	}
+
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+	for _, resource := range psiResources {
+		data, err := os.ReadFile(filepath.Join(pressureRoot, resource))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading %s pressure: %w", resource, err))
+			continue
+		}
+		addPressure(b, resource, data)
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// addPressure reads lines such as
+//
+//	some avg10=1.53 avg60=0.87 avg300=0.29 total=4678165
+//
+// where "some" is the share of time at least one task was stalled and
+// "full" the share all non-idle tasks were, averaged over 10s, 60s and
+// 300s, and total is the stall time in microseconds.
+func addPressure(b *metric.Builder, resource string, data []byte) {
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		labels := []string{"resource", resource, "kind", fields[0]}
+		for _, f := range fields[1:] {
+			k, v, _ := strings.Cut(f, "=")
+			value, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			switch k {
+			case "avg10", "avg60", "avg300":
+				b.Gauge("psi."+k, value, "percent", labels...)
+			case "total":
+				b.Counter("psi.stall_time", value/1e6, "seconds", labels...)
+			}
+		}
+	}
+}
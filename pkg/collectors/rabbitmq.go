@@ -0,0 +1,207 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// rabbitmqMessageStats are the message rates RabbitMQ keeps as totals.
+type rabbitmqMessageStats struct {
+	Publish    *float64 `json:"publish"`
+	DeliverGet *float64 `json:"deliver_get"`
+	Ack        *float64 `json:"ack"`
+	Redeliver  *float64 `json:"redeliver"`
+}
+
+type rabbitmqOverview struct {
+	ClusterName     string `json:"cluster_name"`
+	RabbitMQVersion string `json:"rabbitmq_version"`
+	ObjectTotals    struct {
+		Queues      float64 `json:"queues"`
+		Connections float64 `json:"connections"`
+		Channels    float64 `json:"channels"`
+		Consumers   float64 `json:"consumers"`
+	} `json:"object_totals"`
+	QueueTotals struct {
+		Messages        float64 `json:"messages"`
+		MessagesReady   float64 `json:"messages_ready"`
+		MessagesUnacked float64 `json:"messages_unacknowledged"`
+	} `json:"queue_totals"`
+	MessageStats rabbitmqMessageStats `json:"message_stats"`
+}
+
+type rabbitmqNode struct {
+	Name          string  `json:"name"`
+	Running       bool    `json:"running"`
+	MemUsed       float64 `json:"mem_used"`
+	MemLimit      float64 `json:"mem_limit"`
+	MemAlarm      bool    `json:"mem_alarm"`
+	DiskFree      float64 `json:"disk_free"`
+	DiskFreeLimit float64 `json:"disk_free_limit"`
+	DiskFreeAlarm bool    `json:"disk_free_alarm"`
+	FDUsed        float64 `json:"fd_used"`
+	FDTotal       float64 `json:"fd_total"`
+	ProcUsed      float64 `json:"proc_used"`
+	ProcTotal     float64 `json:"proc_total"`
+}
+
+type rabbitmqQueue struct {
+	Name            string               `json:"name"`
+	VHost           string               `json:"vhost"`
+	State           string               `json:"state"`
+	Messages        float64              `json:"messages"`
+	MessagesReady   float64              `json:"messages_ready"`
+	MessagesUnacked float64              `json:"messages_unacknowledged"`
+	Consumers       float64              `json:"consumers"`
+	MessageStats    rabbitmqMessageStats `json:"message_stats"`
+}
+
+// RabbitMQCollector reads the management plugin's HTTP API: message and
+// connection totals, each node's memory, disk and file descriptor use
+// against the limits that raise alarms and block publishers, and each
+// queue's depth, unacknowledged messages and consumers. Queues are
+// filtered by name with globs, as there can be thousands. The user needs
+// the monitoring tag.
+type RabbitMQCollector struct {
+	URL           string   `json:"url"`
+	Username      string   `json:"username"`
+	Password      string   `json:"password"`
+	IncludeQueues []string `json:"include_queues"`
+	ExcludeQueues []string `json:"exclude_queues"`
+
+	client *http.Client
+}
+
+func NewRabbitMQCollector(cfg config.CollectorConfig) (Collector, error) {
+	r := &RabbitMQCollector{URL: "http://127.0.0.1:15672", Username: "guest", Password: "guest"}
+	if err := cfg.Decode(r); err != nil {
+		return nil, err
+	}
+	if err := validatePatterns(r.IncludeQueues, r.ExcludeQueues); err != nil {
+		return nil, err
+	}
+	r.URL = strings.TrimSuffix(r.URL, "/")
+	r.client = &http.Client{}
+	return r, nil
+}
+
+func (r *RabbitMQCollector) Name() string {
+	return "rabbitmq"
+}
+
+func (r *RabbitMQCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	var overview rabbitmqOverview
+	if err := r.get(ctx, "/api/overview", &overview); err != nil {
+		return nil, fmt.Errorf("getting overview: %w", err)
+	}
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+	cluster := overview.ClusterName
+	labels := []string{"cluster", cluster}
+	b.Gauge("rabbitmq.info", 1, "", "cluster", cluster, "version", overview.RabbitMQVersion)
+	b.Gauge("rabbitmq.queues", overview.ObjectTotals.Queues, "", labels...)
+	b.Gauge("rabbitmq.connections", overview.ObjectTotals.Connections, "", labels...)
+	b.Gauge("rabbitmq.channels", overview.ObjectTotals.Channels, "", labels...)
+	b.Gauge("rabbitmq.consumers", overview.ObjectTotals.Consumers, "", labels...)
+	b.Gauge("rabbitmq.messages", overview.QueueTotals.Messages, "", labels...)
+	b.Gauge("rabbitmq.messages_ready", overview.QueueTotals.MessagesReady, "", labels...)
+	b.Gauge("rabbitmq.messages_unacked", overview.QueueTotals.MessagesUnacked, "", labels...)
+	addRabbitMQMessageStats(b, "rabbitmq.", overview.MessageStats, labels)
+
+	var nodes []rabbitmqNode
+	if err := r.get(ctx, "/api/nodes", &nodes); err != nil {
+		errs = append(errs, fmt.Errorf("getting nodes: %w", err))
+	}
+	for _, n := range nodes {
+		l := []string{"cluster", cluster, "node", n.Name}
+		b.Gauge("rabbitmq.node_running", boolValue(n.Running), "", l...)
+		// A stopped node reports only its name.
+		if !n.Running {
+			continue
+		}
+		b.Gauge("rabbitmq.node_memory_used", n.MemUsed, "bytes", l...)
+		b.Gauge("rabbitmq.node_memory_limit", n.MemLimit, "bytes", l...)
+		if n.MemLimit > 0 {
+			b.Gauge("rabbitmq.node_memory_used_percent", 100*n.MemUsed/n.MemLimit, "percent", l...)
+		}
+		b.Gauge("rabbitmq.node_memory_alarm", boolValue(n.MemAlarm), "", l...)
+		b.Gauge("rabbitmq.node_disk_free", n.DiskFree, "bytes", l...)
+		b.Gauge("rabbitmq.node_disk_free_limit", n.DiskFreeLimit, "bytes", l...)
+		b.Gauge("rabbitmq.node_disk_alarm", boolValue(n.DiskFreeAlarm), "", l...)
+		b.Gauge("rabbitmq.node_fds_used", n.FDUsed, "", l...)
+		b.Gauge("rabbitmq.node_fds_limit", n.FDTotal, "", l...)
+		b.Gauge("rabbitmq.node_processes_used", n.ProcUsed, "", l...)
+		b.Gauge("rabbitmq.node_processes_limit", n.ProcTotal, "", l...)
+	}
+
+	// Only the columns reported, rather than every queue's full details.
+	columns := "name,vhost,state,messages,messages_ready,messages_unacknowledged,consumers," +
+		"message_stats.publish,message_stats.deliver_get,message_stats.ack,message_stats.redeliver"
+	var queues []rabbitmqQueue
+	if err := r.get(ctx, "/api/queues?columns="+url.QueryEscape(columns), &queues); err != nil {
+		errs = append(errs, fmt.Errorf("getting queues: %w", err))
+	}
+	for _, q := range queues {
+		if !filterMatch(q.Name, r.IncludeQueues, r.ExcludeQueues) {
+			continue
+		}
+		l := []string{"cluster", cluster, "vhost", q.VHost, "queue", q.Name}
+		// Flow is running, throttled by credit flow; the others are down,
+		// crashed, stopped and, for quorum queues, minority.
+		if q.State != "" {
+			b.Gauge("rabbitmq.queue_up", boolValue(q.State == "running" || q.State == "idle" || q.State == "flow"), "", l...)
+		}
+		b.Gauge("rabbitmq.queue_messages", q.Messages, "", l...)
+		b.Gauge("rabbitmq.queue_messages_ready", q.MessagesReady, "", l...)
+		b.Gauge("rabbitmq.queue_messages_unacked", q.MessagesUnacked, "", l...)
+		b.Gauge("rabbitmq.queue_consumers", q.Consumers, "", l...)
+		addRabbitMQMessageStats(b, "rabbitmq.queue_", q.MessageStats, l)
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// addRabbitMQMessageStats reports the totals RabbitMQ has, which it leaves
+// out until the first message.
+func addRabbitMQMessageStats(b *metric.Builder, prefix string, s rabbitmqMessageStats, labels []string) {
+	for _, stat := range []struct {
+		name  string
+		value *float64
+	}{
+		{"published", s.Publish},
+		{"delivered", s.DeliverGet},
+		{"acked", s.Ack},
+		{"redelivered", s.Redeliver},
+	} {
+		if stat.value != nil {
+			b.Counter(prefix+stat.name, *stat.value, "", labels...)
+		}
+	}
+}
+
+func (r *RabbitMQCollector) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(r.Username, r.Password)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
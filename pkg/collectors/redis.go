@@ -0,0 +1,262 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// redisFields maps INFO fields to metrics.
+var redisFields = []struct {
+	field string
+	name  string
+	kind  metric.Kind
+	unit  string
+}{
+	{"uptime_in_seconds", "redis.uptime", metric.Gauge, "seconds"},
+	{"connected_clients", "redis.connected_clients", metric.Gauge, ""},
+	{"blocked_clients", "redis.blocked_clients", metric.Gauge, ""},
+	{"used_memory", "redis.used_memory", metric.Gauge, "bytes"},
+	{"used_memory_rss", "redis.used_memory_rss", metric.Gauge, "bytes"},
+	{"used_memory_peak", "redis.used_memory_peak", metric.Gauge, "bytes"},
+	{"maxmemory", "redis.maxmemory", metric.Gauge, "bytes"},
+	{"mem_fragmentation_ratio", "redis.mem_fragmentation_ratio", metric.Gauge, ""},
+	{"total_connections_received", "redis.connections_received", metric.Counter, ""},
+	{"rejected_connections", "redis.rejected_connections", metric.Counter, ""},
+	{"total_commands_processed", "redis.commands_processed", metric.Counter, ""},
+	{"keyspace_hits", "redis.keyspace_hits", metric.Counter, ""},
+	{"keyspace_misses", "redis.keyspace_misses", metric.Counter, ""},
+	{"evicted_keys", "redis.evicted_keys", metric.Counter, ""},
+	{"expired_keys", "redis.expired_keys", metric.Counter, ""},
+	{"connected_slaves", "redis.connected_replicas", metric.Gauge, ""},
+	{"master_last_io_seconds_ago", "redis.master_last_io", metric.Gauge, "seconds"},
+	{"rdb_changes_since_last_save", "redis.rdb_changes_since_last_save", metric.Gauge, ""},
+}
+
+type RedisInstance struct {
+	// Address is host:port or unix:///path/to/redis.sock.
+	Address  string `json:"address"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type RedisCollector struct {
+	Instances []RedisInstance `json:"instances"`
+}
+
+func NewRedisCollector(cfg config.CollectorConfig) (Collector, error) {
+	r := &RedisCollector{Instances: []RedisInstance{{Address: "127.0.0.1:6379"}}}
+	if err := cfg.Decode(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RedisCollector) Name() string {
+	return "redis"
+}
+
+func (r *RedisCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, inst := range r.Instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info, err := redisInfo(ctx, inst)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("getting INFO from %s: %w", inst.Address, err))
+				return
+			}
+			addRedisInfo(b, inst.Address, info)
+		}()
+	}
+	wg.Wait()
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func addRedisInfo(b *metric.Builder, instance string, info map[string]string) {
+	labels := []string{"instance", instance}
+	num := func(field string) (float64, bool) {
+		v, err := strconv.ParseFloat(info[field], 64)
+		return v, err == nil
+	}
+
+	b.Gauge("redis.info", 1, "", "instance", instance, "version", info["redis_version"], "role", info["role"])
+	for _, f := range redisFields {
+		if v, ok := num(f.field); ok {
+			b.Add(f.kind, f.name, v, f.unit, labels...)
+		}
+	}
+	if used, ok := num("used_memory"); ok {
+		if limit, ok := num("maxmemory"); ok && limit > 0 {
+			b.Gauge("redis.used_memory_percent", 100*used/limit, "percent", labels...)
+		}
+	}
+	hits, _ := num("keyspace_hits")
+	misses, _ := num("keyspace_misses")
+	if hits+misses > 0 {
+		b.Gauge("redis.hit_ratio", 100*hits/(hits+misses), "percent", labels...)
+	}
+
+	// Replication: a replica reports its link to the master, a master
+	// reports how far each replica is behind.
+	if status, ok := info["master_link_status"]; ok {
+		b.Gauge("redis.master_link_up", boolValue(status == "up"), "", labels...)
+		master, _ := num("master_repl_offset")
+		replica, _ := num("slave_repl_offset")
+		b.Gauge("redis.replication_lag", max(master-replica, 0), "bytes", labels...)
+	}
+	for k, v := range info {
+		if !strings.HasPrefix(k, "slave") || strings.Contains(k, "_") {
+			continue
+		}
+		fields := redisKV(v)
+		if lag, err := strconv.ParseFloat(fields["lag"], 64); err == nil {
+			b.Gauge("redis.replica_lag", lag, "seconds", "instance", instance, "replica", fields["ip"]+":"+fields["port"])
+		}
+	}
+
+	// Persistence.
+	if last, ok := num("rdb_last_save_time"); ok && last > 0 {
+		b.Gauge("redis.rdb_last_save_age", float64(time.Now().Unix())-last, "seconds", labels...)
+	}
+	if status, ok := info["rdb_last_bgsave_status"]; ok {
+		b.Gauge("redis.rdb_last_bgsave_ok", boolValue(status == "ok"), "", labels...)
+	}
+	if info["aof_enabled"] == "1" {
+		b.Gauge("redis.aof_last_write_ok", boolValue(info["aof_last_write_status"] == "ok"), "", labels...)
+	}
+
+	// Keyspace lines look like db0:keys=12,expires=3,avg_ttl=0.
+	for k, v := range info {
+		if !strings.HasPrefix(k, "db") {
+			continue
+		}
+		if _, err := strconv.Atoi(k[2:]); err != nil {
+			continue
+		}
+		fields := redisKV(v)
+		if keys, err := strconv.ParseFloat(fields["keys"], 64); err == nil {
+			b.Gauge("redis.keys", keys, "", "instance", instance, "db", k)
+		}
+		if expires, err := strconv.ParseFloat(fields["expires"], 64); err == nil {
+			b.Gauge("redis.expires", expires, "", "instance", instance, "db", k)
+		}
+	}
+}
+
+// redisKV splits a "k=v,k2=v2" INFO value.
+func redisKV(s string) map[string]string {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if k, v, ok := strings.Cut(pair, "="); ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func redisInfo(ctx context.Context, inst RedisInstance) (map[string]string, error) {
+	network, addr := "tcp", inst.Address
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		network, addr = "unix", path
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+	}
+
+	r := bufio.NewReader(conn)
+	if inst.Password != "" {
+		args := []string{"AUTH", inst.Password}
+		if inst.Username != "" {
+			args = []string{"AUTH", inst.Username, inst.Password}
+		}
+		if _, err := redisCommand(conn, r, args...); err != nil {
+			return nil, fmt.Errorf("authenticating: %w", err)
+		}
+	}
+	reply, err := redisCommand(conn, r, "INFO", "all")
+	if err != nil {
+		return nil, err
+	}
+
+	info := make(map[string]string)
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			info[k] = v
+		}
+	}
+	return info, nil
+}
+
+// redisCommand sends a command in RESP and reads a simple, error or bulk
+// string reply, which covers AUTH and INFO.
+func redisCommand(w io.Writer, r *bufio.Reader, args ...string) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return "", err
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return "", errors.New(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid bulk length %q", line[1:])
+		}
+		if n < 0 {
+			return "", nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	default:
+		return "", fmt.Errorf("unexpected reply %q", line)
+	}
+}
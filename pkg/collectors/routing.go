@@ -0,0 +1,219 @@
+package collectors
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+// neighborStates names the NUD_* states of a neighbor entry.
+var neighborStates = []struct {
+	bit  uint16
+	name string
+}{
+	{0x01, "incomplete"},
+	{0x02, "reachable"},
+	{0x04, "stale"},
+	{0x08, "delay"},
+	{0x10, "probe"},
+	{0x20, "failed"},
+	{0x40, "noarp"},
+	{0x80, "permanent"},
+}
+
+// Route flags from linux/route.h and ipv6_route.h.
+const (
+	rtfGateway = 0x0002
+	rtfReject  = 0x0200
+	rtfLocal   = 0x80000000
+)
+
+// defaultRoute is a family's default route.
+type defaultRoute struct {
+	gateway, iface string
+}
+
+// RoutingCollector reports the ARP and NDP neighbor tables against their
+// garbage collection limits, and the routing table's size and default
+// routes. When a neighbor table passes gc_thresh3 the kernel refuses new
+// entries and logs "neighbor table overflow"; a changed default gateway
+// is logged as a warning.
+type RoutingCollector struct {
+	mu       sync.Mutex
+	gateways map[string]defaultRoute
+	changes  map[string]float64
+}
+
+func (r *RoutingCollector) Name() string {
+	return "routing"
+}
+
+func (r *RoutingCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	if err := addNeighbors(b); err != nil {
+		errs = append(errs, err)
+	}
+
+	tables := map[string]*routeTable{}
+	var err error
+	if tables["ipv4"], err = ipv4Routes(); err != nil {
+		errs = append(errs, err)
+	}
+	tables["ipv6"] = ipv6Routes()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gateways == nil {
+		r.gateways, r.changes = map[string]defaultRoute{}, map[string]float64{"ipv4": 0, "ipv6": 0}
+	}
+	for _, family := range []string{"ipv4", "ipv6"} {
+		t := tables[family]
+		if t == nil {
+			continue
+		}
+		labels := []string{"family", family}
+		b.Gauge("routing.routes", float64(t.count), "", labels...)
+		b.Gauge("routing.default_route", boolValue(len(t.defaults) > 0), "", labels...)
+		// With several default routes the kernel uses the first, the one
+		// with the lowest metric.
+		var current defaultRoute
+		if len(t.defaults) > 0 {
+			current = t.defaults[0]
+			b.Gauge("routing.default_gateway_info", 1, "", "family", family, "gateway", current.gateway, "interface", current.iface)
+		}
+		if last, ok := r.gateways[family]; ok && last != current {
+			r.changes[family]++
+			log.Warn().Str("collector", "routing").Str("family", family).
+				Str("from", last.gateway).Str("to", current.gateway).Str("interface", current.iface).
+				Msg("Default gateway changed")
+		}
+		r.gateways[family] = current
+		b.Counter("routing.gateway_changes", r.changes[family], "", labels...)
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// neighbor is an ARP or NDP entry: its family, ipv4 or ipv6, and its
+// NUD_* state bits.
+type neighbor struct {
+	family string
+	state  uint16
+}
+
+// addNeighbors counts neighbor entries by family and state.
+func addNeighbors(b *metric.Builder) error {
+	neighbors, err := listNeighbors()
+	if err != nil {
+		return err
+	}
+	type key struct{ family, state string }
+	counts := map[key]float64{}
+	entries := map[string]float64{"ipv4": 0, "ipv6": 0}
+	for _, n := range neighbors {
+		for _, s := range neighborStates {
+			if n.state&s.bit != 0 {
+				counts[key{n.family, s.name}]++
+			}
+		}
+		// Permanent and noarp entries aren't garbage collected and don't
+		// count towards the thresholds.
+		if n.state&0xc0 == 0 {
+			entries[n.family]++
+		}
+	}
+
+	for _, family := range []string{"ipv4", "ipv6"} {
+		labels := []string{"family", family}
+		for _, s := range neighborStates {
+			b.Gauge("routing.neighbors", counts[key{family, s.name}], "", "family", family, "state", s.name)
+		}
+		b.Gauge("routing.neighbor_entries", entries[family], "", labels...)
+		dir := "/proc/sys/net/" + family + "/neigh/default/"
+		for _, level := range []string{"1", "2", "3"} {
+			if v, err := readSysfsFloat(dir + "gc_thresh" + level); err == nil {
+				b.Gauge("routing.neighbor_gc_thresh", v, "", "family", family, "level", level)
+				if level == "3" && v > 0 {
+					b.Gauge("routing.neighbor_used_percent", 100*entries[family]/v, "percent", labels...)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// routeTable is the size and default routes of a family's routing table.
+type routeTable struct {
+	count    int
+	defaults []defaultRoute
+}
+
+// ipv4Routes reads /proc/net/route, whose addresses are little-endian hex
+// and which lists the main table only.
+func ipv4Routes() (*routeTable, error) {
+	lines := readLines("/proc/net/route")
+	if len(lines) == 0 {
+		return nil, errors.New("reading /proc/net/route: no routing table")
+	}
+	t := &routeTable{}
+	for _, line := range lines[1:] {
+		f := strings.Fields(line)
+		if len(f) < 8 {
+			continue
+		}
+		t.count++
+		flags, _ := strconv.ParseUint(f[3], 16, 32)
+		if f[1] != "00000000" || f[7] != "00000000" || flags&rtfReject != 0 {
+			continue
+		}
+		gw := ""
+		if raw, err := hex.DecodeString(f[2]); err == nil && len(raw) == 4 && flags&rtfGateway != 0 {
+			gw = net.IPv4(raw[3], raw[2], raw[1], raw[0]).String()
+		}
+		t.defaults = append(t.defaults, defaultRoute{gateway: gw, iface: f[0]})
+	}
+	return t, nil
+}
+
+// ipv6Routes reads /proc/net/ipv6_route: destination, prefix length,
+// source, source prefix length, next hop, metric, refcount, use, flags
+// and device. Local and multicast routes, which `ip -6 route` keeps in the
+// local table, and unreachable ones are left out. It is nil when the kernel was booted
+// with ipv6.disable=1.
+func ipv6Routes() *routeTable {
+	lines := readLines("/proc/net/ipv6_route")
+	if lines == nil {
+		return nil
+	}
+	t := &routeTable{}
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) < 10 {
+			continue
+		}
+		flags, _ := strconv.ParseUint(f[8], 16, 32)
+		if flags&(rtfLocal|rtfReject) != 0 || strings.HasPrefix(f[0], "ff") {
+			continue
+		}
+		t.count++
+		if f[1] != "00" || strings.Trim(f[0], "0") != "" {
+			continue
+		}
+		gw := ""
+		if raw, err := hex.DecodeString(f[4]); err == nil && len(raw) == 16 && flags&rtfGateway != 0 {
+			gw = net.IP(raw).String()
+		}
+		t.defaults = append(t.defaults, defaultRoute{gateway: gw, iface: f[9]})
+	}
+	return t
+}
@@ -0,0 +1,39 @@
+package collectors
+
+import (
+	"encoding/binary"
+	"fmt"
+	"syscall"
+)
+
+// listNeighbors dumps the neighbor table over rtnetlink, which unlike
+// /proc/net/arp covers IPv6 and reports states.
+func listNeighbors() ([]neighbor, error) {
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETNEIGH, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, fmt.Errorf("listing neighbors: %w", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, fmt.Errorf("parsing neighbors: %w", err)
+	}
+	var neighbors []neighbor
+	for _, m := range msgs {
+		// struct ndmsg: family, 3 bytes of padding, ifindex, state, flags
+		// and type.
+		if m.Header.Type != syscall.RTM_NEWNEIGH || len(m.Data) < 12 {
+			continue
+		}
+		var family string
+		switch m.Data[0] {
+		case syscall.AF_INET:
+			family = "ipv4"
+		case syscall.AF_INET6:
+			family = "ipv6"
+		default:
+			continue
+		}
+		neighbors = append(neighbors, neighbor{family, binary.NativeEndian.Uint16(m.Data[8:10])})
+	}
+	return neighbors, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package collectors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// listNeighbors fails, as rtnetlink is Linux's.
+func listNeighbors() ([]neighbor, error) {
+	return nil, fmt.Errorf("listing neighbors: %w", errors.ErrUnsupported)
+}
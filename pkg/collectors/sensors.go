@@ -0,0 +1,109 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/metric"
+
+	"github.com/shirou/gopsutil/v4/sensors"
+)
+
+const hwmonRoot = "/sys/class/hwmon"
+
+type SensorsCollector struct{}
+
+func (s *SensorsCollector) Name() string {
+	return "sensors"
+}
+
+func (s *SensorsCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+
+	temps, err := sensors.TemperaturesWithContext(ctx)
+	var warnings *sensors.Warnings
+	if err != nil && !errors.As(err, &warnings) {
+		return nil, fmt.Errorf("reading temperatures: %w", err)
+	}
+	for _, t := range temps {
+		labels := []string{"sensor", t.SensorKey, "kind", sensorKind(t.SensorKey)}
+		b.Gauge("sensors.temperature", t.Temperature, "celsius", labels...)
+		if t.High > 0 {
+			b.Gauge("sensors.temperature_high", t.High, "celsius", labels...)
+		}
+		if t.Critical > 0 {
+			b.Gauge("sensors.temperature_critical", t.Critical, "celsius", labels...)
+			b.Gauge("sensors.over_critical", boolValue(t.Temperature >= t.Critical), "", labels...)
+		}
+	}
+
+	addFans(b)
+	return b.Metrics(), nil
+}
+
+// sensorKind groups hwmon sensor keys such as "coretemp_package_id_0",
+// "coretemp_core_3", "k10temp_tctl" or "nvme_composite".
+func sensorKind(key string) string {
+	key = strings.ToLower(key)
+	switch {
+	case strings.Contains(key, "package") || strings.HasPrefix(key, "k10temp") || strings.HasPrefix(key, "zenpower"):
+		return "cpu_package"
+	case strings.Contains(key, "core"):
+		return "cpu_core"
+	case strings.HasPrefix(key, "nvme"):
+		return "nvme"
+	default:
+		return "other"
+	}
+}
+
+// addFans reads fan speeds from hwmon, which gopsutil doesn't expose.
+func addFans(b *metric.Builder) {
+	inputs, _ := filepath.Glob(filepath.Join(hwmonRoot, "hwmon*", "fan*_input"))
+	for _, input := range inputs {
+		rpm, err := readSysfsFloat(input)
+		if err != nil {
+			continue
+		}
+		dir := filepath.Dir(input)
+		base := strings.TrimSuffix(filepath.Base(input), "_input")
+		fan := base
+		if label := readSysfsString(filepath.Join(dir, base+"_label")); label != "" {
+			fan = label
+		}
+		labels := []string{"chip", readSysfsString(filepath.Join(dir, "name")), "fan", fan}
+		b.Gauge("sensors.fan_speed", rpm, "rpm", labels...)
+		if minRPM, err := readSysfsFloat(filepath.Join(dir, base+"_min")); err == nil && minRPM > 0 {
+			b.Gauge("sensors.fan_below_min", boolValue(rpm < minRPM), "", labels...)
+		}
+	}
+}
+
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readSysfsFloat(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+// SessionsCollector reports the login sessions in utmp, what `who` lists:
+// each session's user, terminal and source address with its login time,
+// and how many there are. Sessions that weren't there the collection
+// before are logged as warnings, so an unexpected login shows up next to
+// the metrics without a separate audit pipeline.
+type SessionsCollector struct {
+	missing sync.Once
+
+	mu   sync.Mutex
+	last map[host.UserStat]bool
+}
+
+func (s *SessionsCollector) Name() string {
+	return "sessions"
+}
+
+func (s *SessionsCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	users, err := host.UsersWithContext(ctx)
+	if errors.Is(err, os.ErrNotExist) {
+		// Containers, and distributions that dropped utmp for logind.
+		s.missing.Do(func() { log.Info().Msg("No utmp; not reporting login sessions") })
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting login sessions: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := metric.NewBuilder(time.Now())
+	current := make(map[host.UserStat]bool, len(users))
+	names := map[string]bool{}
+	remote, fresh := 0, 0
+	for _, u := range users {
+		if current[u] {
+			continue
+		}
+		current[u] = true
+		names[u.User] = true
+		if u.Host != "" {
+			remote++
+		}
+		b.Gauge("sessions.login_time", float64(u.Started), "seconds",
+			"user", u.User, "tty", u.Terminal, "source", u.Host)
+		// The first collection has nothing to compare with.
+		if s.last != nil && !s.last[u] {
+			fresh++
+			log.Warn().Str("collector", "sessions").Str("user", u.User).Str("tty", u.Terminal).
+				Str("source", u.Host).Time("login_time", time.Unix(int64(u.Started), 0)).
+				Msg("New login session")
+		}
+	}
+	s.last = current
+	b.Gauge("sessions.sessions", float64(len(current)), "")
+	b.Gauge("sessions.remote_sessions", float64(remote), "")
+	b.Gauge("sessions.users", float64(len(names)), "")
+	b.Gauge("sessions.new_sessions", float64(fresh), "")
+	return b.Metrics(), nil
+}
@@ -0,0 +1,264 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// SmartCollector reads drive health from smartctl's JSON output, which
+// needs smartmontools 7.0 or later and root.
+type SmartCollector struct {
+	// Smartctl is the smartctl binary.
+	Smartctl string `json:"smartctl"`
+	// Devices are the drives to query, such as /dev/sda or /dev/nvme0. When
+	// empty, those found by `smartctl --scan` are.
+	Devices []string `json:"devices"`
+	// SkipStandby leaves drives that are spun down alone rather than
+	// waking them up to read them.
+	SkipStandby bool `json:"skip_standby"`
+}
+
+func NewSmartCollector(cfg config.CollectorConfig) (Collector, error) {
+	s := &SmartCollector{Smartctl: "smartctl", SkipStandby: true}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SmartCollector) Name() string {
+	return "smart"
+}
+
+// smartDevice is a drive as --scan reports it; the type is passed back to
+// smartctl so it talks to the drive the way the scan found it.
+type smartDevice struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type smartAttribute struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Value  int    `json:"value"`
+	Thresh int    `json:"thresh"`
+	Raw    struct {
+		Value float64 `json:"value"`
+	} `json:"raw"`
+}
+
+// smartReport is the part of `smartctl --json --all` glass reads.
+type smartReport struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	Device struct {
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName       string `json:"model_name"`
+	SerialNumber    string `json:"serial_number"`
+	FirmwareVersion string `json:"firmware_version"`
+	SmartStatus     *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature *struct {
+		Current float64 `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime *struct {
+		Hours float64 `json:"hours"`
+	} `json:"power_on_time"`
+	ATA *struct {
+		Table []smartAttribute `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMe *struct {
+		CriticalWarning   int     `json:"critical_warning"`
+		AvailableSpare    float64 `json:"available_spare"`
+		AvailableSpareMin float64 `json:"available_spare_threshold"`
+		PercentageUsed    float64 `json:"percentage_used"`
+		MediaErrors       float64 `json:"media_errors"`
+		UnsafeShutdowns   float64 `json:"unsafe_shutdowns"`
+		ErrorLogEntries   float64 `json:"num_err_log_entries"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// smartSectors maps the ATA attributes that count bad sectors to metrics.
+var smartSectors = map[int]string{
+	5:   "smart.reallocated_sectors",
+	197: "smart.pending_sectors",
+	198: "smart.uncorrectable_sectors",
+}
+
+// smartWear lists ATA attributes whose normalized value is the life left
+// in an SSD, from 100 down, in the order they are trusted. Vendors use
+// different ones.
+var smartWear = []int{
+	231, // SSD_Life_Left
+	233, // Media_Wearout_Indicator
+	177, // Wear_Leveling_Count
+	202, // Percent_Lifetime_Remain
+}
+
+func (s *SmartCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	devices, err := s.devices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b := metric.NewBuilder(time.Now())
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, d := range devices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := s.query(ctx, d)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("reading SMART data of %s: %w", d.Name, err))
+				return
+			}
+			if r != nil {
+				addSmartReport(b, d.Name, r)
+			}
+		}()
+	}
+	wg.Wait()
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func (s *SmartCollector) devices(ctx context.Context) ([]smartDevice, error) {
+	if len(s.Devices) > 0 {
+		devices := make([]smartDevice, len(s.Devices))
+		for i, name := range s.Devices {
+			devices[i] = smartDevice{Name: name}
+		}
+		return devices, nil
+	}
+	out, err := runCommand(ctx, s.Smartctl, "--json", "--scan")
+	if err != nil {
+		return nil, err
+	}
+	var scan struct {
+		Devices []smartDevice `json:"devices"`
+	}
+	if err := json.Unmarshal(out, &scan); err != nil {
+		return nil, fmt.Errorf("parsing smartctl --scan: %w", err)
+	}
+	return scan.Devices, nil
+}
+
+// query returns nil, and no error, for a drive skipped in standby.
+func (s *SmartCollector) query(ctx context.Context, d smartDevice) (*smartReport, error) {
+	args := []string{"--json", "--all"}
+	if s.SkipStandby {
+		// Exit with 0x80 rather than spin the drive up; no other status
+		// sets that bit alone.
+		args = append(args, "--nocheck=standby,128")
+	}
+	if d.Type != "" {
+		args = append(args, "--device="+d.Type)
+	}
+	// smartctl exits non-zero for a failing drive too, so the status is
+	// read from the JSON when there is any.
+	out, err := runCommand(ctx, s.Smartctl, append(args, d.Name)...)
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, err
+	}
+	var r smartReport
+	if jsonErr := json.Unmarshal(out, &r); jsonErr != nil {
+		if exitErr != nil && exitErr.ExitCode() == 128 && s.SkipStandby {
+			return nil, nil
+		}
+		if exitErr != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("parsing smartctl output: %w", jsonErr)
+	}
+	// The exit status is a bit mask: bits 0 and 1 mean smartctl couldn't
+	// read the drive, the higher ones report what it found there.
+	status := r.Smartctl.ExitStatus
+	if exitErr != nil {
+		status = exitErr.ExitCode()
+	}
+	if s.SkipStandby && status == 128 {
+		return nil, nil
+	}
+	if status&0x03 != 0 {
+		for _, m := range r.Smartctl.Messages {
+			if m.Severity == "error" {
+				return nil, errors.New(m.String)
+			}
+		}
+		return nil, fmt.Errorf("smartctl exited with status %d", status)
+	}
+	return &r, nil
+}
+
+func addSmartReport(b *metric.Builder, device string, r *smartReport) {
+	labels := []string{"device", device}
+	b.Gauge("smart.device_info", 1, "",
+		"device", device,
+		"model", r.ModelName,
+		"serial", r.SerialNumber,
+		"firmware", r.FirmwareVersion,
+		"protocol", strings.ToLower(r.Device.Protocol),
+	)
+	if r.SmartStatus != nil {
+		b.Gauge("smart.health_passed", boolValue(r.SmartStatus.Passed), "", labels...)
+	}
+	if r.Temperature != nil {
+		b.Gauge("smart.temperature", r.Temperature.Current, "celsius", labels...)
+	}
+	if r.PowerOnTime != nil {
+		b.Counter("smart.power_on_hours", r.PowerOnTime.Hours, "hours", labels...)
+	}
+
+	if r.ATA != nil {
+		failing := 0
+		byID := make(map[int]smartAttribute, len(r.ATA.Table))
+		for _, a := range r.ATA.Table {
+			byID[a.ID] = a
+			// A threshold of 0 means the attribute can't fail.
+			if a.Thresh > 0 && a.Value <= a.Thresh {
+				failing++
+			}
+			if name, ok := smartSectors[a.ID]; ok {
+				b.Gauge(name, a.Raw.Value, "", labels...)
+			}
+		}
+		b.Gauge("smart.attributes_failing", float64(failing), "", labels...)
+		for _, id := range smartWear {
+			if a, ok := byID[id]; ok {
+				b.Gauge("smart.wear_percent", float64(100-min(a.Value, 100)), "percent", labels...)
+				break
+			}
+		}
+	}
+
+	if n := r.NVMe; n != nil {
+		b.Gauge("smart.wear_percent", n.PercentageUsed, "percent", labels...)
+		b.Gauge("smart.nvme_critical_warning", float64(n.CriticalWarning), "", labels...)
+		b.Gauge("smart.nvme_available_spare", n.AvailableSpare, "percent", labels...)
+		b.Gauge("smart.nvme_available_spare_threshold", n.AvailableSpareMin, "percent", labels...)
+		b.Counter("smart.nvme_media_errors", n.MediaErrors, "", labels...)
+		b.Counter("smart.nvme_unsafe_shutdowns", n.UnsafeShutdowns, "", labels...)
+		b.Counter("smart.nvme_error_log_entries", n.ErrorLogEntries, "", labels...)
+	}
+}
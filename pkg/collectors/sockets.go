@@ -0,0 +1,210 @@
+package collectors
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// sockstatFields maps the "TCP:" and "UDP:" lines of /proc/net/sockstat to
+// gauges. Memory is in pages.
+var sockstatFields = []struct {
+	protocol string
+	field    string
+	name     string
+}{
+	{"TCP", "inuse", "sockets.tcp_in_use"},
+	{"TCP", "orphan", "sockets.tcp_orphans"},
+	{"TCP", "tw", "sockets.tcp_time_wait"},
+	{"TCP", "alloc", "sockets.tcp_allocated"},
+	{"TCP", "mem", "sockets.tcp_memory"},
+	{"UDP", "inuse", "sockets.udp_in_use"},
+	{"UDP", "mem", "sockets.udp_memory"},
+}
+
+// SocketsCollector reports how close the host is to running out of sockets:
+// ephemeral ports, orphaned and TIME_WAIT sockets against their limits, and
+// socket buffer memory against tcp_mem. A proxy opening many connections to
+// one upstream runs out of ports to it first, since each connection needs
+// a distinct local port per remote address and port, so the busiest
+// remotes are reported individually.
+type SocketsCollector struct {
+	// Remotes is how many of the remotes with the most connections from
+	// ephemeral ports to report.
+	Remotes int `json:"remotes"`
+}
+
+func NewSocketsCollector(cfg config.CollectorConfig) (Collector, error) {
+	s := &SocketsCollector{Remotes: 10}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SocketsCollector) Name() string {
+	return "sockets"
+}
+
+func (s *SocketsCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	sockstat, err := readSockstat()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if v, ok := sockstat["sockets"]["used"]; ok {
+		b.Gauge("sockets.used", v, "")
+	}
+	pageSize := float64(os.Getpagesize())
+	for _, f := range sockstatFields {
+		v, ok := sockstat[f.protocol][f.field]
+		if !ok {
+			continue
+		}
+		if f.field == "mem" {
+			b.Gauge(f.name, v*pageSize, "bytes")
+		} else {
+			b.Gauge(f.name, v, "")
+		}
+	}
+
+	// Past tcp_max_orphans the kernel resets orphaned connections, and past
+	// tcp_max_tw_buckets it destroys TIME_WAIT sockets early, both logging
+	// "too many orphaned sockets" or "time wait bucket table overflow".
+	limit := func(file, field, name string) {
+		max, err := readSysfsFloat("/proc/sys/net/ipv4/" + file)
+		if err != nil {
+			return
+		}
+		b.Gauge("sockets."+name+"_limit", max, "")
+		if v, ok := sockstat["TCP"][field]; ok && max > 0 {
+			b.Gauge("sockets."+name+"_used_percent", 100*v/max, "percent")
+		}
+	}
+	limit("tcp_max_orphans", "orphan", "tcp_orphans")
+	limit("tcp_max_tw_buckets", "tw", "tcp_time_wait")
+	// tcp_mem is three page counts: below the first there's no pressure,
+	// above the second the kernel shrinks buffers, and at the third it
+	// refuses to allocate.
+	if f := strings.Fields(readSysfsString("/proc/sys/net/ipv4/tcp_mem")); len(f) == 3 {
+		pressure, _ := strconv.ParseFloat(f[1], 64)
+		max, _ := strconv.ParseFloat(f[2], 64)
+		b.Gauge("sockets.tcp_memory_pressure", pressure*pageSize, "bytes")
+		b.Gauge("sockets.tcp_memory_limit", max*pageSize, "bytes")
+		if v, ok := sockstat["TCP"]["mem"]; ok && max > 0 {
+			b.Gauge("sockets.tcp_memory_used_percent", 100*v/max, "percent")
+		}
+	}
+
+	if err := s.addEphemeral(ctx, b); err != nil {
+		errs = append(errs, err)
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// addEphemeral reports the TCP sockets using ports from
+// ip_local_port_range, which are outgoing connections and their TIME_WAIT
+// remains.
+func (s *SocketsCollector) addEphemeral(ctx context.Context, b *metric.Builder) error {
+	f := strings.Fields(readSysfsString("/proc/sys/net/ipv4/ip_local_port_range"))
+	if len(f) != 2 {
+		return errors.New("reading ip_local_port_range: malformed")
+	}
+	low, err := strconv.ParseUint(f[0], 10, 16)
+	var high uint64
+	if err == nil {
+		high, err = strconv.ParseUint(f[1], 10, 16)
+	}
+	if err != nil || high < low {
+		return fmt.Errorf("reading ip_local_port_range: %q", strings.Join(f, " "))
+	}
+	size := float64(high - low + 1)
+
+	conns, err := tcpConnections(ctx)
+	if err != nil {
+		return fmt.Errorf("getting TCP connections: %w", err)
+	}
+	type remote struct {
+		conns, timeWait float64
+	}
+	ports := map[uint32]bool{}
+	remotes := map[string]*remote{}
+	for _, c := range conns {
+		if c.state == "LISTEN" || c.localPort < uint32(low) || c.localPort > uint32(high) {
+			continue
+		}
+		ports[c.localPort] = true
+		addr := net.JoinHostPort(c.remoteIP, strconv.Itoa(int(c.remotePort)))
+		r := remotes[addr]
+		if r == nil {
+			r = &remote{}
+			remotes[addr] = r
+		}
+		r.conns++
+		if c.state == "TIME_WAIT" {
+			r.timeWait++
+		}
+	}
+
+	b.Gauge("sockets.ephemeral_ports", size, "")
+	b.Gauge("sockets.ephemeral_ports_used", float64(len(ports)), "")
+	b.Gauge("sockets.ephemeral_used_percent", 100*float64(len(ports))/size, "percent")
+
+	// Busiest first, then by address so ties report the same remotes every
+	// collection.
+	addrs := make([]string, 0, len(remotes))
+	for addr := range remotes {
+		addrs = append(addrs, addr)
+	}
+	slices.SortFunc(addrs, func(a, b string) int {
+		return cmp.Or(cmp.Compare(remotes[b].conns, remotes[a].conns), strings.Compare(a, b))
+	})
+	busiest := 0.0
+	if len(addrs) > 0 {
+		busiest = remotes[addrs[0]].conns
+	}
+	b.Gauge("sockets.ephemeral_busiest_remote_percent", 100*busiest/size, "percent")
+	for _, addr := range addrs[:min(len(addrs), s.Remotes)] {
+		r := remotes[addr]
+		b.Gauge("sockets.remote_connections", r.conns, "", "remote", addr)
+		b.Gauge("sockets.remote_time_wait", r.timeWait, "", "remote", addr)
+	}
+	return nil
+}
+
+// readSockstat reads /proc/net/sockstat, lines like
+// "TCP: inuse 5 orphan 0 tw 2 alloc 7 mem 1", by protocol and field.
+func readSockstat() (map[string]map[string]float64, error) {
+	data, err := os.ReadFile("/proc/net/sockstat")
+	if err != nil {
+		return nil, fmt.Errorf("reading /proc/net/sockstat: %w", err)
+	}
+	out := map[string]map[string]float64{}
+	for _, line := range strings.Split(string(data), "\n") {
+		protocol, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		f := strings.Fields(rest)
+		values := map[string]float64{}
+		for i := 0; i+1 < len(f); i += 2 {
+			if v, err := strconv.ParseFloat(f[i+1], 64); err == nil {
+				values[f[i]] = v
+			}
+		}
+		out[protocol] = values
+	}
+	return out, nil
+}
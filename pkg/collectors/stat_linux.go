@@ -0,0 +1,22 @@
+package collectors
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileStat is what a file's metadata says beyond fs.FileInfo.
+type fileStat struct {
+	dev, ino, nlink uint64
+	// allocated is the space the file takes on disk, less than its size
+	// when sparse.
+	allocated float64
+}
+
+func statOf(info fs.FileInfo) (fileStat, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileStat{}, false
+	}
+	return fileStat{uint64(st.Dev), st.Ino, uint64(st.Nlink), float64(st.Blocks) * 512}, true
+}
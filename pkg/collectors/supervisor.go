@@ -0,0 +1,304 @@
+package collectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+// managedProcess is a program run by supervisord or an app run by PM2.
+type managedProcess struct {
+	manager, group, name, state string
+	running                     bool
+	// started is when the process last started, zero if it never has.
+	started time.Time
+	// restarts is -1 when the manager doesn't count them.
+	restarts float64
+}
+
+// SupervisorCollector reports the programs supervisord runs, from its
+// XML-RPC interface, and the apps PM2 runs, from `pm2 jlist`: whether each
+// is running, its state, uptime and restarts. Many app servers run their
+// workers this way rather than as systemd units. supervisord doesn't count
+// restarts, so for its programs they are the starts seen since glass
+// started.
+type SupervisorCollector struct {
+	// URL is supervisord's unix_http_server socket, as unix:///path, or its
+	// inet_http_server, as http://127.0.0.1:9001. By default the socket
+	// where Debian or Red Hat put it, if either exists.
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	PM2      string `json:"pm2"`
+	// PM2Homes are the PM2_HOME directories of the PM2 daemons to query,
+	// one per user running PM2, usually ~user/.pm2. PM2 isn't queried when
+	// there are none.
+	PM2Homes []string `json:"pm2_homes"`
+
+	noSupervisor sync.Once
+	client       *http.Client
+	endpoint     string
+
+	mu     sync.Mutex
+	starts map[string]time.Time
+	counts map[string]float64
+}
+
+func NewSupervisorCollector(cfg config.CollectorConfig) (Collector, error) {
+	s := &SupervisorCollector{PM2: "pm2"}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	if s.URL == "" {
+		for _, path := range []string{"/var/run/supervisor.sock", "/var/run/supervisor/supervisor.sock"} {
+			if _, err := os.Stat(path); err == nil {
+				s.URL = "unix://" + path
+				break
+			}
+		}
+	}
+	s.client = &http.Client{}
+	s.endpoint = strings.TrimSuffix(s.URL, "/") + "/RPC2"
+	if path, ok := strings.CutPrefix(s.URL, "unix://"); ok {
+		s.client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+		s.endpoint = "http://supervisor/RPC2"
+	}
+	return s, nil
+}
+
+func (s *SupervisorCollector) Name() string {
+	return "supervisor"
+}
+
+func (s *SupervisorCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	var (
+		procs []managedProcess
+		errs  []error
+	)
+	if s.URL == "" {
+		s.noSupervisor.Do(func() { log.Info().Msg("No supervisord socket; not reporting supervisord programs") })
+	} else {
+		p, err := s.supervisorProcesses(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("getting supervisord programs: %w", err))
+		}
+		procs = append(procs, p...)
+	}
+	for _, home := range s.PM2Homes {
+		p, err := s.pm2Processes(ctx, home)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("getting PM2 apps of %s: %w", home, err))
+		}
+		procs = append(procs, p...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.starts == nil {
+		s.starts, s.counts = map[string]time.Time{}, map[string]float64{}
+	}
+	now := time.Now()
+	b := metric.NewBuilder(now)
+	notRunning := map[string]float64{}
+	for _, p := range procs {
+		labels := []string{"manager", p.manager, "group", p.group, "process", p.name}
+		b.Gauge("supervisor.process_running", boolValue(p.running), "", labels...)
+		b.Gauge("supervisor.process_info", 1, "", append(labels, "state", p.state)...)
+		if p.running && !p.started.IsZero() {
+			b.Gauge("supervisor.process_uptime", now.Sub(p.started).Seconds(), "seconds", labels...)
+		}
+		restarts := p.restarts
+		if restarts < 0 {
+			// A start time that moved since the last collection is a start.
+			key := p.manager + "/" + p.group + "/" + p.name
+			if last, ok := s.starts[key]; ok && !p.started.IsZero() && !p.started.Equal(last) {
+				s.counts[key]++
+			}
+			if !p.started.IsZero() {
+				s.starts[key] = p.started
+			}
+			restarts = s.counts[key]
+		}
+		b.Counter("supervisor.process_restarts", restarts, "", labels...)
+		if !p.running {
+			notRunning[p.manager]++
+		}
+	}
+	if s.URL != "" {
+		b.Gauge("supervisor.not_running", notRunning["supervisord"], "", "manager", "supervisord")
+	}
+	if len(s.PM2Homes) > 0 {
+		b.Gauge("supervisor.not_running", notRunning["pm2"], "", "manager", "pm2")
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// supervisorProcesses calls supervisor.getAllProcessInfo, which returns a
+// struct per process with its group, name, state and, as Unix times, when
+// it last started and the server's current time.
+func (s *SupervisorCollector) supervisorProcesses(ctx context.Context) ([]managedProcess, error) {
+	body := `<?xml version="1.0"?><methodCall><methodName>supervisor.getAllProcessInfo</methodName><params/></methodCall>`
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	if s.Username != "" {
+		req.SetBasicAuth(s.Username, s.Password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var reply struct {
+		Params []xmlrpcValue `xml:"params>param>value"`
+		Fault  *xmlrpcValue  `xml:"fault>value"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("parsing reply: %w", err)
+	}
+	if reply.Fault != nil {
+		fault := reply.Fault.members()
+		return nil, fmt.Errorf("fault %s: %s", fault["faultCode"].text(), fault["faultString"].text())
+	}
+	if len(reply.Params) == 0 || reply.Params[0].Array == nil {
+		return nil, errors.New("reply isn't an array")
+	}
+	var procs []managedProcess
+	for _, v := range reply.Params[0].Array.Data {
+		m := v.members()
+		state := m["statename"].text()
+		p := managedProcess{
+			manager:  "supervisord",
+			group:    m["group"].text(),
+			name:     m["name"].text(),
+			state:    state,
+			running:  state == "RUNNING",
+			restarts: -1,
+		}
+		if start, err := strconv.ParseInt(m["start"].text(), 10, 64); err == nil && start > 0 {
+			p.started = time.Unix(start, 0)
+		}
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
+
+// pm2Processes lists the apps of the PM2 daemon whose home is home, which
+// pm2 jlist prints as JSON with their state and restarts, and when they
+// last started in milliseconds.
+func (s *SupervisorCollector) pm2Processes(ctx context.Context, home string) ([]managedProcess, error) {
+	// pm2 starts a daemon when none is running, so only ask running ones.
+	if _, err := os.Stat(filepath.Join(home, "rpc.sock")); err != nil {
+		return nil, fmt.Errorf("PM2 isn't running: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, s.PM2, "jlist")
+	cmd.Env = append(os.Environ(), "PM2_HOME="+home)
+	out, err := runCmd(cmd)
+	if err != nil {
+		return nil, err
+	}
+	// pm2 prints notices, like "[PM2] In-memory PM2 is out-of-date", on
+	// lines before the list.
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("[")) && !bytes.HasPrefix(line, []byte("[PM2")) {
+			out = line
+		}
+	}
+	var apps []struct {
+		Name string `json:"name"`
+		ID   int    `json:"pm_id"`
+		Env  struct {
+			Status      string  `json:"status"`
+			Restarts    float64 `json:"restart_time"`
+			StartedAtMs float64 `json:"pm_uptime"`
+		} `json:"pm2_env"`
+	}
+	if err := json.Unmarshal(out, &apps); err != nil {
+		return nil, fmt.Errorf("parsing pm2 jlist: %w", err)
+	}
+	procs := make([]managedProcess, 0, len(apps))
+	for _, a := range apps {
+		p := managedProcess{
+			manager:  "pm2",
+			group:    a.Name,
+			name:     strconv.Itoa(a.ID),
+			state:    a.Env.Status,
+			running:  a.Env.Status == "online",
+			restarts: a.Env.Restarts,
+		}
+		if a.Env.StartedAtMs > 0 {
+			p.started = time.UnixMilli(int64(a.Env.StartedAtMs))
+		}
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
+
+// xmlrpcValue is an XML-RPC value of any type. A value without a type
+// element is a string.
+type xmlrpcValue struct {
+	Chardata string  `xml:",chardata"`
+	String   *string `xml:"string"`
+	Int      *string `xml:"int"`
+	I4       *string `xml:"i4"`
+	Boolean  *string `xml:"boolean"`
+	Double   *string `xml:"double"`
+	Array    *struct {
+		Data []xmlrpcValue `xml:"data>value"`
+	} `xml:"array"`
+	Struct *struct {
+		Members []struct {
+			Name  string      `xml:"name"`
+			Value xmlrpcValue `xml:"value"`
+		} `xml:"member"`
+	} `xml:"struct"`
+}
+
+// text returns a scalar value as text.
+func (v xmlrpcValue) text() string {
+	for _, s := range []*string{v.String, v.Int, v.I4, v.Boolean, v.Double} {
+		if s != nil {
+			return strings.TrimSpace(*s)
+		}
+	}
+	return strings.TrimSpace(v.Chardata)
+}
+
+func (v xmlrpcValue) members() map[string]xmlrpcValue {
+	m := map[string]xmlrpcValue{}
+	if v.Struct != nil {
+		for _, member := range v.Struct.Members {
+			m[member.Name] = member.Value
+		}
+	}
+	return m
+}
@@ -0,0 +1,205 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/rs/zerolog/log"
+)
+
+type SystemdCollector struct {
+	// Units is the watch list. Names without a suffix are taken to be
+	// services, so "nginx" means nginx.service.
+	Units []string `json:"units"`
+	// Failed reports every failed unit, watched or not.
+	Failed bool `json:"failed"`
+}
+
+type unitState struct {
+	Name        string
+	LoadState   string
+	ActiveState string
+	SubState    string
+	// Restarts is -1 when the unit isn't a service or systemd is too old to
+	// count restarts.
+	Restarts int
+}
+
+func NewSystemdCollector(cfg config.CollectorConfig) (Collector, error) {
+	s := &SystemdCollector{Failed: true}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	for i, u := range s.Units {
+		if !strings.Contains(u, ".") {
+			s.Units[i] = u + ".service"
+		}
+	}
+	return s, nil
+}
+
+func (s *SystemdCollector) Name() string {
+	return "systemd"
+}
+
+func (s *SystemdCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	units, failed, err := s.queryDBus(ctx)
+	if err != nil {
+		log.Debug().Err(err).Msg("systemd D-Bus unavailable, falling back to systemctl")
+		var fallbackErr error
+		units, failed, fallbackErr = s.querySystemctl(ctx)
+		if fallbackErr != nil {
+			return nil, errors.Join(err, fallbackErr)
+		}
+	}
+
+	b := metric.NewBuilder(time.Now())
+	for _, u := range units {
+		b.Gauge("systemd.unit_active", boolValue(u.ActiveState == "active"), "", "unit", u.Name)
+		b.Gauge("systemd.unit_info", 1, "",
+			"unit", u.Name,
+			"load_state", u.LoadState,
+			"active_state", u.ActiveState,
+			"sub_state", u.SubState,
+		)
+		if u.Restarts >= 0 {
+			b.Counter("systemd.unit_restarts", float64(u.Restarts), "", "unit", u.Name)
+		}
+	}
+	if s.Failed {
+		b.Gauge("systemd.failed_units", float64(len(failed)), "")
+		for _, name := range failed {
+			b.Gauge("systemd.unit_failed", 1, "", "unit", name)
+		}
+	}
+	return b.Metrics(), nil
+}
+
+func (s *SystemdCollector) queryDBus(ctx context.Context) ([]unitState, []string, error) {
+	conn, err := dbus.NewWithContext(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to systemd: %w", err)
+	}
+	defer conn.Close()
+
+	var units []unitState
+	if len(s.Units) > 0 {
+		statuses, err := conn.ListUnitsByNamesContext(ctx, s.Units)
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing units: %w", err)
+		}
+		for _, st := range statuses {
+			u := unitState{
+				Name:        st.Name,
+				LoadState:   st.LoadState,
+				ActiveState: st.ActiveState,
+				SubState:    st.SubState,
+				Restarts:    -1,
+			}
+			if strings.HasSuffix(st.Name, ".service") && st.LoadState == "loaded" {
+				if p, err := conn.GetUnitTypePropertyContext(ctx, st.Name, "Service", "NRestarts"); err == nil {
+					if n, ok := p.Value.Value().(uint32); ok {
+						u.Restarts = int(n)
+					}
+				}
+			}
+			units = append(units, u)
+		}
+	}
+
+	var failed []string
+	if s.Failed {
+		statuses, err := conn.ListUnitsFilteredContext(ctx, []string{"failed"})
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing failed units: %w", err)
+		}
+		for _, st := range statuses {
+			failed = append(failed, st.Name)
+		}
+	}
+	return units, failed, nil
+}
+
+func (s *SystemdCollector) querySystemctl(ctx context.Context) ([]unitState, []string, error) {
+	var units []unitState
+	if len(s.Units) > 0 {
+		args := append([]string{"show", "--property=Id,LoadState,ActiveState,SubState,NRestarts"}, s.Units...)
+		out, err := systemctl(ctx, args...)
+		if err != nil {
+			return nil, nil, err
+		}
+		units = parseSystemctlShow(out)
+	}
+
+	var failed []string
+	if s.Failed {
+		out, err := systemctl(ctx, "list-units", "--state=failed", "--all", "--plain", "--no-legend", "--no-pager")
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			if fields := strings.Fields(line); len(fields) > 0 {
+				failed = append(failed, fields[0])
+			}
+		}
+	}
+	return units, failed, nil
+}
+
+func systemctl(ctx context.Context, args ...string) ([]byte, error) {
+	out, err := runCommand(ctx, "systemctl", args...)
+	if err != nil {
+		return nil, fmt.Errorf("systemctl %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// parseSystemctlShow reads the blank-line separated key=value blocks that
+// `systemctl show` prints, one per unit.
+func parseSystemctlShow(out []byte) []unitState {
+	var (
+		units []unitState
+		cur   = unitState{Restarts: -1}
+	)
+	flush := func() {
+		if cur.Name != "" {
+			units = append(units, cur)
+		}
+		cur = unitState{Restarts: -1}
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		k, v, _ := strings.Cut(line, "=")
+		switch k {
+		case "Id":
+			cur.Name = v
+		case "LoadState":
+			cur.LoadState = v
+		case "ActiveState":
+			cur.ActiveState = v
+		case "SubState":
+			cur.SubState = v
+		case "NRestarts":
+			if n, err := strconv.Atoi(v); err == nil {
+				cur.Restarts = n
+			}
+		}
+	}
+	flush()
+	return units
+}
@@ -0,0 +1,187 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	"glass/pkg/metric"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// tcpStates lists every state so each one is reported, zero or not, and
+// series don't appear and disappear between samples.
+var tcpStates = []string{
+	"ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT",
+	"CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING",
+}
+
+// tcpConn is the part of a socket the summaries need.
+type tcpConn struct {
+	state      string
+	localPort  uint32
+	remoteIP   string
+	remotePort uint32
+}
+
+// addTCP reports connection counts by state, and for each listening port
+// the number of connections to it. With TCPDetails set it also lists
+// individual connections, up to TCPDetailsLimit of them.
+func (n *NetworkCollector) addTCP(ctx context.Context, b *metric.Builder) error {
+	conns, err := tcpConnections(ctx)
+	if err != nil {
+		return fmt.Errorf("getting TCP connections: %w", err)
+	}
+
+	states := make(map[string]int, len(tcpStates))
+	listening := make(map[uint32]bool)
+	for _, c := range conns {
+		states[c.state]++
+		if c.state == "LISTEN" {
+			listening[c.localPort] = true
+		}
+	}
+	for _, state := range tcpStates {
+		b.Gauge("net.tcp_connections", float64(states[state]), "", "state", strings.ToLower(state))
+	}
+
+	perPort := make(map[uint32]int, len(listening))
+	for port := range listening {
+		perPort[port] = 0
+	}
+	for _, c := range conns {
+		if c.state != "LISTEN" && listening[c.localPort] {
+			perPort[c.localPort]++
+		}
+	}
+	for port, count := range perPort {
+		b.Gauge("net.tcp_listen_connections", float64(count), "", "port", strconv.FormatUint(uint64(port), 10))
+	}
+
+	if n.TCPDetails {
+		return n.addTCPDetails(ctx, b)
+	}
+	return nil
+}
+
+func (n *NetworkCollector) addTCPDetails(ctx context.Context, b *metric.Builder) error {
+	conns, err := net.ConnectionsWithContext(ctx, "tcp")
+	if err != nil {
+		return fmt.Errorf("getting TCP connection details: %w", err)
+	}
+	if len(conns) > n.TCPDetailsLimit {
+		conns = conns[:n.TCPDetailsLimit]
+	}
+	for _, c := range conns {
+		b.Gauge("net.tcp_connection", 1, "",
+			"local", fmt.Sprintf("%s:%d", c.Laddr.IP, c.Laddr.Port),
+			"remote", fmt.Sprintf("%s:%d", c.Raddr.IP, c.Raddr.Port),
+			"state", strings.ToLower(c.Status),
+			"pid", strconv.Itoa(int(c.Pid)),
+		)
+	}
+	return nil
+}
+
+// tcpConnections reads the kernel socket tables directly when it can:
+// gopsutil maps every socket to its process by walking /proc/*/fd, which
+// is far more work than a summary needs.
+func tcpConnections(ctx context.Context) ([]tcpConn, error) {
+	var (
+		conns []tcpConn
+		found bool
+	)
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		table, err := readTCPTable(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, table...)
+		found = true
+	}
+	if found {
+		return conns, nil
+	}
+
+	stats, err := net.ConnectionsWithoutUidsWithContext(ctx, "tcp")
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stats {
+		conns = append(conns, tcpConn{state: s.Status, localPort: s.Laddr.Port, remoteIP: s.Raddr.IP, remotePort: s.Raddr.Port})
+	}
+	return conns, nil
+}
+
+// procTCPStates maps the hex state column of /proc/net/tcp.
+var procTCPStates = map[string]string{
+	"01": "ESTABLISHED", "02": "SYN_SENT", "03": "SYN_RECV", "04": "FIN_WAIT1",
+	"05": "FIN_WAIT2", "06": "TIME_WAIT", "07": "CLOSE", "08": "CLOSE_WAIT",
+	"09": "LAST_ACK", "0A": "LISTEN", "0B": "CLOSING",
+}
+
+func readTCPTable(path string) ([]tcpConn, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var conns []tcpConn
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// sl local_address rem_address st ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		_, port, err := parseProcAddr(fields[1])
+		if err != nil {
+			continue
+		}
+		remoteIP, remotePort, err := parseProcAddr(fields[2])
+		if err != nil {
+			continue
+		}
+		state, ok := procTCPStates[fields[3]]
+		if !ok {
+			continue
+		}
+		conns = append(conns, tcpConn{state: state, localPort: port, remoteIP: remoteIP, remotePort: remotePort})
+	}
+	return conns, scanner.Err()
+}
+
+// parseProcAddr parses an address from /proc/net/tcp, like "0100007F:0050"
+// for 127.0.0.1:80: the address is hex 32-bit words in host byte order, the
+// port hex in network order.
+func parseProcAddr(s string) (string, uint32, error) {
+	ipHex, portHex, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("malformed address %q", s)
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return "", 0, err
+	}
+	raw, err := hex.DecodeString(ipHex)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return "", 0, fmt.Errorf("malformed address %q", s)
+	}
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(raw[i:], binary.NativeEndian.Uint32(raw[i:]))
+	}
+	addr, _ := netip.AddrFromSlice(raw)
+	return addr.Unmap().String(), uint32(port), nil
+}
@@ -0,0 +1,130 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+)
+
+// tcpFields maps the Tcp section of /proc/net/snmp and the TcpExt section
+// of /proc/net/netstat to metrics. All are counters except CurrEstab.
+var tcpFields = []struct {
+	section string
+	field   string
+	name    string
+}{
+	{"Tcp", "ActiveOpens", "tcp.active_opens"},
+	{"Tcp", "PassiveOpens", "tcp.passive_opens"},
+	{"Tcp", "AttemptFails", "tcp.attempt_fails"},
+	{"Tcp", "EstabResets", "tcp.established_resets"},
+	{"Tcp", "InSegs", "tcp.segments_in"},
+	{"Tcp", "OutSegs", "tcp.segments_out"},
+	{"Tcp", "RetransSegs", "tcp.retransmits"},
+	{"Tcp", "InErrs", "tcp.in_errors"},
+	{"Tcp", "InCsumErrors", "tcp.checksum_errors"},
+	{"Tcp", "OutRsts", "tcp.resets_sent"},
+	// A listen overflow is a completed handshake dropped because the
+	// application's accept queue was full; ListenDrops counts those and
+	// every other SYN dropped at a listener.
+	{"TcpExt", "ListenOverflows", "tcp.listen_overflows"},
+	{"TcpExt", "ListenDrops", "tcp.listen_drops"},
+	// SYN cookies are sent when the SYN backlog is full, which is a flood
+	// or a backlog too small for the connection rate.
+	{"TcpExt", "SyncookiesSent", "tcp.syncookies_sent"},
+	{"TcpExt", "SyncookiesRecv", "tcp.syncookies_recv"},
+	{"TcpExt", "SyncookiesFailed", "tcp.syncookies_failed"},
+	{"TcpExt", "TCPReqQFullDrop", "tcp.syn_backlog_drops"},
+	{"TcpExt", "TCPSynRetrans", "tcp.syn_retransmits"},
+	{"TcpExt", "TCPTimeouts", "tcp.timeouts"},
+	{"TcpExt", "TCPFastRetrans", "tcp.fast_retransmits"},
+	{"TcpExt", "TCPLostRetransmit", "tcp.lost_retransmits"},
+	{"TcpExt", "TCPBacklogDrop", "tcp.backlog_drops"},
+	{"TcpExt", "TCPAbortOnData", "tcp.aborts_on_data"},
+	{"TcpExt", "TCPAbortOnClose", "tcp.aborts_on_close"},
+	{"TcpExt", "TCPAbortOnMemory", "tcp.aborts_on_memory"},
+	{"TcpExt", "TCPAbortOnTimeout", "tcp.aborts_on_timeout"},
+}
+
+// TCPCollector reports the kernel's TCP counters: retransmissions, resets,
+// accept queue overflows and SYN cookies, which show a slow site's cause
+// is in the network or the listen backlog rather than the application.
+// Outputs get the counters per second; retransmit_percent is the share of
+// segments sent since the previous collection that were retransmissions.
+type TCPCollector struct {
+	mu                   sync.Mutex
+	lastOut, lastRetrans float64
+}
+
+func (t *TCPCollector) Name() string {
+	return "tcp"
+}
+
+func (t *TCPCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	values := map[string]map[string]float64{}
+	for _, path := range []string{"/proc/net/snmp", "/proc/net/netstat"} {
+		if err := readNetstat(path, values); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, f := range tcpFields {
+		if v, ok := values[f.section][f.field]; ok {
+			b.Counter(f.name, v, "")
+		}
+	}
+	if v, ok := values["Tcp"]["CurrEstab"]; ok {
+		b.Gauge("tcp.established", v, "")
+	}
+
+	out, okOut := values["Tcp"]["OutSegs"]
+	retrans, okRetrans := values["Tcp"]["RetransSegs"]
+	if okOut && okRetrans {
+		t.mu.Lock()
+		// Skip the first collection, and a counter going backwards when
+		// the network namespace was recreated.
+		if t.lastOut > 0 && out > t.lastOut && retrans >= t.lastRetrans {
+			b.Gauge("tcp.retransmit_percent", 100*(retrans-t.lastRetrans)/(out-t.lastOut), "percent")
+		}
+		t.lastOut, t.lastRetrans = out, retrans
+		t.mu.Unlock()
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// readNetstat reads /proc/net/snmp or /proc/net/netstat into values by
+// section. Each section is a line of field names followed by a line of
+// values, both prefixed with the section name, like "Tcp: ActiveOpens ..."
+// and "Tcp: 42 ...".
+func readNetstat(path string, values map[string]map[string]float64) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i+1 < len(lines); i += 2 {
+		names, nums := strings.Fields(lines[i]), strings.Fields(lines[i+1])
+		if len(names) == 0 || len(names) != len(nums) || names[0] != nums[0] {
+			return fmt.Errorf("parsing %s: malformed line %d", path, i+1)
+		}
+		section := strings.TrimSuffix(names[0], ":")
+		if values[section] == nil {
+			values[section] = map[string]float64{}
+		}
+		for j := 1; j < len(names); j++ {
+			// MaxConn is -1 for no limit, so parse everything as signed.
+			if v, err := strconv.ParseFloat(nums[j], 64); err == nil {
+				values[section][names[j]] = v
+			}
+		}
+	}
+	return nil
+}
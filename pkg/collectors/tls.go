@@ -0,0 +1,157 @@
+package collectors
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+type TLSCollector struct {
+	// Endpoints are host:port pairs to connect to. The host is also sent as
+	// SNI and checked against the certificate.
+	Endpoints []string `json:"endpoints"`
+	// Files are PEM certificates on disk, leaf first as servers load them.
+	Files []string `json:"files"`
+}
+
+func NewTLSCollector(cfg config.CollectorConfig) (Collector, error) {
+	t := &TLSCollector{}
+	if err := cfg.Decode(t); err != nil {
+		return nil, err
+	}
+	for _, e := range t.Endpoints {
+		if _, _, err := net.SplitHostPort(e); err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: %w", e, err)
+		}
+	}
+	return t, nil
+}
+
+func (t *TLSCollector) Name() string {
+	return "tls"
+}
+
+func (t *TLSCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	probe := func(target string, fn func() (*tls.ConnectionState, []*x509.Certificate, string, error)) {
+		defer wg.Done()
+		state, chain, host, err := fn()
+		mu.Lock()
+		defer mu.Unlock()
+		b.Gauge("tls.probe_success", boolValue(err == nil), "", "target", target)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("checking %s: %w", target, err))
+			return
+		}
+		addCertificate(b, target, host, state, chain)
+	}
+	for _, e := range t.Endpoints {
+		wg.Add(1)
+		go probe(e, func() (*tls.ConnectionState, []*x509.Certificate, string, error) {
+			host, _, _ := net.SplitHostPort(e)
+			state, err := dialTLS(ctx, e, host)
+			if err != nil {
+				return nil, nil, "", err
+			}
+			return state, state.PeerCertificates, host, nil
+		})
+	}
+	for _, f := range t.Files {
+		wg.Add(1)
+		go probe(f, func() (*tls.ConnectionState, []*x509.Certificate, string, error) {
+			chain, err := readCertificates(f)
+			return nil, chain, "", err
+		})
+	}
+	wg.Wait()
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func addCertificate(b *metric.Builder, target, host string, state *tls.ConnectionState, chain []*x509.Certificate) {
+	leaf := chain[0]
+	labels := []string{"target", target}
+	b.Gauge("tls.cert_expiry_days", time.Until(leaf.NotAfter).Hours()/24, "days", labels...)
+	b.Gauge("tls.cert_not_after", float64(leaf.NotAfter.Unix()), "seconds", labels...)
+	b.Gauge("tls.chain_valid", boolValue(verifyChain(chain, host) == nil), "", labels...)
+
+	info := []string{
+		"target", target,
+		"subject", leaf.Subject.CommonName,
+		"issuer", leaf.Issuer.CommonName,
+	}
+	if state != nil {
+		info = append(info,
+			"version", tls.VersionName(state.Version),
+			"cipher", tls.CipherSuiteName(state.CipherSuite),
+		)
+	}
+	b.Gauge("tls.info", 1, "", info...)
+}
+
+// dialTLS completes a handshake without verifying, so that expiry and
+// protocol are reported even for broken chains; validity is checked
+// separately.
+func dialTLS(ctx context.Context, addr, serverName string) (*tls.ConnectionState, error) {
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: serverName, InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil, errors.New("no certificate presented")
+	}
+	return &state, nil
+}
+
+func verifyChain(chain []*x509.Certificate, host string) error {
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates})
+	return err
+}
+
+func readCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return chain, nil
+}
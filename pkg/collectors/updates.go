@@ -0,0 +1,233 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+// packageManagers are tried in order when none is configured, with the
+// files whose modification time is when their metadata was last refreshed.
+var packageManagers = []struct {
+	name   string
+	stamps []string
+}{
+	{"apt-get", []string{"/var/lib/apt/periodic/update-success-stamp", "/var/lib/apt/lists"}},
+	{"dnf", []string{"/var/cache/dnf/last_makecache", "/var/cache/dnf"}},
+	{"yum", []string{"/var/cache/yum"}},
+}
+
+// UpdatesCollector reports the host's patch posture: package updates and
+// security updates available, whether a newer kernel is installed than
+// the one running, and whether a reboot is required. It only reads the
+// package manager's cached metadata and never refreshes it, so counts
+// are as current as the last apt-get update or dnf makecache, reported as
+// metadata_age. Resolving updates takes seconds; collect every hour or so.
+type UpdatesCollector struct {
+	// Manager is apt-get, dnf or yum; the first installed one by default.
+	Manager string `json:"manager"`
+
+	noManager sync.Once
+}
+
+func NewUpdatesCollector(cfg config.CollectorConfig) (Collector, error) {
+	u := &UpdatesCollector{}
+	if err := cfg.Decode(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (u *UpdatesCollector) Name() string {
+	return "updates"
+}
+
+func (u *UpdatesCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	manager := u.Manager
+	if manager == "" {
+		for _, m := range packageManagers {
+			if _, err := exec.LookPath(m.name); err == nil {
+				manager = m.name
+				break
+			}
+		}
+	}
+	if manager == "" {
+		u.noManager.Do(func() { log.Info().Msg("No apt-get, dnf or yum; not reporting package updates") })
+	} else {
+		var (
+			pending, security int
+			err               error
+		)
+		switch manager {
+		case "apt-get":
+			pending, security, err = aptUpdates(ctx)
+		case "dnf", "yum":
+			pending, security, err = rpmUpdates(ctx, manager)
+		default:
+			err = fmt.Errorf("unknown package manager %q", manager)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			b.Gauge("updates.pending", float64(pending), "", "manager", manager)
+			b.Gauge("updates.security", float64(security), "", "manager", manager)
+		}
+		for _, m := range packageManagers {
+			if m.name != manager {
+				continue
+			}
+			for _, stamp := range m.stamps {
+				if info, err := os.Stat(stamp); err == nil {
+					b.Gauge("updates.metadata_age", time.Since(info.ModTime()).Seconds(), "seconds", "manager", manager)
+					break
+				}
+			}
+		}
+	}
+
+	running := readSysfsString("/proc/sys/kernel/osrelease")
+	latest := latestInstalledKernel()
+	outdated := latest != "" && latest != running
+	if latest != "" {
+		b.Gauge("updates.kernel_info", 1, "", "running", running, "latest", latest)
+		b.Gauge("updates.kernel_outdated", boolValue(outdated), "")
+	}
+
+	reboot := outdated
+	// Debian's update-notifier and unattended-upgrades leave this behind
+	// when an upgraded package needs a reboot.
+	for _, path := range []string{"/run/reboot-required", "/var/run/reboot-required"} {
+		if _, err := os.Stat(path); err == nil {
+			reboot = true
+		}
+	}
+	// needs-restarting from dnf-utils or yum-utils exits 1 when core
+	// libraries or the kernel were updated since boot.
+	if !reboot && (manager == "dnf" || manager == "yum") {
+		if path, err := exec.LookPath("needs-restarting"); err == nil {
+			var exitErr *exec.ExitError
+			if err := exec.CommandContext(ctx, path, "-r").Run(); errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+				reboot = true
+			}
+		}
+	}
+	b.Gauge("updates.reboot_required", boolValue(reboot), "")
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// aptUpdates simulates an upgrade, which prints a line per package like
+// "Inst openssl [3.0.2-0ubuntu1.10] (3.0.2-0ubuntu1.12
+// Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security [amd64])". The
+// update is a security one when it comes from a security archive.
+func aptUpdates(ctx context.Context) (int, int, error) {
+	cmd := exec.CommandContext(ctx, "apt-get", "-s", "-o", "Debug::NoLocking=1", "upgrade")
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := updatesOutput(cmd)
+	if err != nil {
+		return 0, 0, err
+	}
+	pending, security := 0, 0
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Inst ") {
+			continue
+		}
+		pending++
+		if strings.Contains(strings.ToLower(line), "-security") {
+			security++
+		}
+	}
+	return pending, security, scanner.Err()
+}
+
+// rpmUpdates lists updates from the cache with check-update, then only
+// those fixing security advisories. check-update exits 100 when there are
+// updates, and prints "name.arch version repo" lines, with packages that
+// obsolete others listed again under "Obsoleting Packages".
+func rpmUpdates(ctx context.Context, manager string) (int, int, error) {
+	count := func(args ...string) (int, error) {
+		cmd := exec.CommandContext(ctx, manager, append([]string{"-q", "--cacheonly"}, args...)...)
+		cmd.Env = append(os.Environ(), "LC_ALL=C")
+		out, err := updatesOutput(cmd)
+		if err != nil {
+			return 0, err
+		}
+		n := 0
+		for _, line := range strings.Split(string(out), "\n") {
+			if strings.HasPrefix(line, "Obsoleting") {
+				break
+			}
+			if f := strings.Fields(line); len(f) == 3 && strings.Contains(f[0], ".") && !strings.HasPrefix(line, " ") {
+				n++
+			}
+		}
+		return n, nil
+	}
+	pending, err := count("check-update")
+	if err != nil {
+		return 0, 0, err
+	}
+	security, err := count("--security", "check-update")
+	if err != nil {
+		return 0, 0, err
+	}
+	return pending, security, nil
+}
+
+func updatesOutput(cmd *exec.Cmd) ([]byte, error) {
+	out, err := runCmd(cmd)
+	var exitErr *exec.ExitError
+	// check-update's "updates available".
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 100 && filepath.Base(cmd.Path) != "apt-get" {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// latestInstalledKernel is the version of the newest kernel image in /boot,
+// where both Debian and Red Hat put them as vmlinuz-VERSION, or empty when
+// there are none, as in most containers. Packages keep their build time as
+// the image's modification time, which orders kernels without having to
+// compare distribution version strings.
+func latestInstalledKernel() string {
+	images, _ := filepath.Glob("/boot/vmlinuz-*")
+	var (
+		latest string
+		newest time.Time
+	)
+	for _, image := range images {
+		// Red Hat's rescue image is a copy of whichever kernel was first.
+		if strings.Contains(image, "rescue") {
+			continue
+		}
+		info, err := os.Stat(image)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(newest) {
+			latest, newest = strings.TrimPrefix(filepath.Base(image), "vmlinuz-"), info.ModTime()
+		}
+	}
+	return latest
+}
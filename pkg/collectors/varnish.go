@@ -0,0 +1,213 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+// varnishFields maps MAIN counters to metrics.
+var varnishFields = []struct {
+	field string
+	name  string
+	kind  metric.Kind
+	unit  string
+}{
+	{"MAIN.uptime", "varnish.uptime", metric.Gauge, "seconds"},
+	{"MAIN.sess_conn", "varnish.sessions", metric.Counter, ""},
+	{"MAIN.sess_dropped", "varnish.sessions_dropped", metric.Counter, ""},
+	{"MAIN.sess_fail", "varnish.sessions_failed", metric.Counter, ""},
+	{"MAIN.sess_queued", "varnish.sessions_queued", metric.Counter, ""},
+	{"MAIN.client_req", "varnish.requests", metric.Counter, ""},
+	{"MAIN.cache_hit", "varnish.cache_hits", metric.Counter, ""},
+	{"MAIN.cache_hitpass", "varnish.cache_hitpass", metric.Counter, ""},
+	{"MAIN.cache_miss", "varnish.cache_misses", metric.Counter, ""},
+	{"MAIN.backend_conn", "varnish.backend_connections", metric.Counter, ""},
+	{"MAIN.backend_reuse", "varnish.backend_reuses", metric.Counter, ""},
+	{"MAIN.backend_unhealthy", "varnish.backend_unhealthy", metric.Counter, ""},
+	{"MAIN.backend_busy", "varnish.backend_busy", metric.Counter, ""},
+	{"MAIN.backend_fail", "varnish.backend_failures", metric.Counter, ""},
+	{"MAIN.fetch_failed", "varnish.fetch_failures", metric.Counter, ""},
+	{"MAIN.threads", "varnish.threads", metric.Gauge, ""},
+	{"MAIN.threads_limited", "varnish.threads_limited", metric.Counter, ""},
+	{"MAIN.threads_failed", "varnish.threads_failed", metric.Counter, ""},
+	{"MAIN.thread_queue_len", "varnish.thread_queue_length", metric.Gauge, ""},
+	{"MAIN.n_object", "varnish.objects", metric.Gauge, ""},
+	{"MAIN.n_lru_nuked", "varnish.lru_nuked", metric.Counter, ""},
+	{"MAIN.n_expired", "varnish.expired", metric.Counter, ""},
+}
+
+// VarnishCollector reads varnishd's counters with `varnishstat -j`: hit
+// ratio, backend failures, worker threads and dropped sessions, per
+// backend health and request counts, and storage use. varnishstat reads
+// varnishd's shared memory, so glass needs to be in the varnish group.
+type VarnishCollector struct {
+	Varnishstat string `json:"varnishstat"`
+	// Instances are the varnishd instance names, as given to -n, when more
+	// than the default one runs.
+	Instances []string `json:"instances"`
+
+	missing sync.Once
+}
+
+func NewVarnishCollector(cfg config.CollectorConfig) (Collector, error) {
+	v := &VarnishCollector{Varnishstat: "varnishstat"}
+	if err := cfg.Decode(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *VarnishCollector) Name() string {
+	return "varnish"
+}
+
+func (v *VarnishCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	instances := v.Instances
+	if len(instances) == 0 {
+		instances = []string{""}
+	}
+	b := metric.NewBuilder(time.Now())
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, name := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counters, err := v.counters(ctx, name)
+			mu.Lock()
+			defer mu.Unlock()
+			if errors.Is(err, exec.ErrNotFound) {
+				v.missing.Do(func() { log.Info().Msg("varnishstat not found; not reporting Varnish") })
+				return
+			}
+			if name == "" {
+				name = "default"
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("reading Varnish counters of %s: %w", name, err))
+				return
+			}
+			addVarnishCounters(b, name, counters)
+		}()
+	}
+	wg.Wait()
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func addVarnishCounters(b *metric.Builder, instance string, counters map[string]float64) {
+	labels := []string{"instance", instance}
+	for _, f := range varnishFields {
+		if v, ok := counters[f.field]; ok {
+			b.Add(f.kind, f.name, v, f.unit, labels...)
+		}
+	}
+	hits, misses := counters["MAIN.cache_hit"], counters["MAIN.cache_miss"]
+	if hits+misses > 0 {
+		b.Gauge("varnish.hit_ratio", 100*hits/(hits+misses), "percent", labels...)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(counters)) {
+		v := counters[name]
+		switch {
+		// Backends are VBE.<vcl>.<backend>.<counter>. Backends of VCLs
+		// that were replaced but not discarded are reported too, under
+		// their own VCL.
+		case strings.HasPrefix(name, "VBE."):
+			rest := strings.TrimPrefix(name, "VBE.")
+			i, j := strings.Index(rest, "."), strings.LastIndex(rest, ".")
+			if i < 0 || i == j {
+				continue
+			}
+			l := []string{"instance", instance, "vcl", rest[:i], "backend", rest[i+1 : j]}
+			switch rest[j+1:] {
+			case "happy":
+				b.Gauge("varnish.backend_healthy", v, "", l...)
+			case "req":
+				b.Counter("varnish.backend_requests", v, "", l...)
+			case "conn":
+				b.Gauge("varnish.backend_open_connections", v, "", l...)
+			}
+		// Storage is SMA.<name>.<counter> for malloc and SMF for file.
+		case strings.HasPrefix(name, "SMA.") || strings.HasPrefix(name, "SMF."):
+			storage, counter, ok := strings.Cut(name[4:], ".")
+			if !ok {
+				continue
+			}
+			l := []string{"instance", instance, "storage", storage}
+			switch counter {
+			case "g_bytes":
+				b.Gauge("varnish.storage_used", v, "bytes", l...)
+				if free, ok := counters[name[:4]+storage+".g_space"]; ok && v+free > 0 {
+					b.Gauge("varnish.storage_used_percent", 100*v/(v+free), "percent", l...)
+				}
+			case "g_space":
+				b.Gauge("varnish.storage_free", v, "bytes", l...)
+			case "c_fail":
+				b.Counter("varnish.storage_allocation_failures", v, "", l...)
+			}
+		}
+	}
+}
+
+// counters runs varnishstat -j, which since Varnish 6.5 nests the counters
+// under "counters" and before that lists them at the top level next to the
+// timestamp.
+func (v *VarnishCollector) counters(ctx context.Context, instance string) (map[string]float64, error) {
+	args := []string{"-j"}
+	if instance != "" {
+		args = append(args, "-n", instance)
+	}
+	out, err := runCommand(ctx, v.Varnishstat, args...)
+	if err != nil {
+		return nil, err
+	}
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(out, &top); err != nil {
+		return nil, fmt.Errorf("parsing varnishstat output: %w", err)
+	}
+	if nested, ok := top["counters"]; ok {
+		top = nil
+		if err := json.Unmarshal(nested, &top); err != nil {
+			return nil, fmt.Errorf("parsing varnishstat output: %w", err)
+		}
+	}
+	counters := make(map[string]float64, len(top))
+	for name, raw := range top {
+		var c struct {
+			Value *json.Number `json:"value"`
+		}
+		// The timestamp and version aren't counters.
+		if json.Unmarshal(raw, &c) != nil || c.Value == nil {
+			continue
+		}
+		// A backend's happy is a bitmap of its recent health probes, the
+		// newest in the lowest bit, too wide for a float64 to keep it.
+		if strings.HasSuffix(name, ".happy") {
+			if bits, err := strconv.ParseUint(c.Value.String(), 10, 64); err == nil {
+				counters[name] = float64(bits & 1)
+			}
+			continue
+		}
+		if v, err := c.Value.Float64(); err == nil {
+			counters[name] = v
+		}
+	}
+	return counters, nil
+}
@@ -0,0 +1,100 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/metric"
+)
+
+// vmstatFields maps /proc/vmstat fields to metrics. pgpgin and pgpgout
+// count KiB, the swap fields pages.
+var vmstatFields = []struct {
+	field string
+	name  string
+	unit  string
+}{
+	{"pgpgin", "vmstat.paged_in", "bytes"},
+	{"pgpgout", "vmstat.paged_out", "bytes"},
+	{"pswpin", "vmstat.swapped_in", "bytes"},
+	{"pswpout", "vmstat.swapped_out", "bytes"},
+	{"pgfault", "vmstat.page_faults", ""},
+	{"pgmajfault", "vmstat.major_page_faults", ""},
+	{"oom_kill", "vmstat.oom_kills", ""},
+}
+
+// VMStatCollector reports kernel activity counters the way vmstat does:
+// context switches, interrupts, forks, paging and swapping. They are
+// counters, so outputs also get them per second.
+type VMStatCollector struct{}
+
+func (v *VMStatCollector) Name() string {
+	return "vmstat"
+}
+
+func (v *VMStatCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	stat, err := readKeyValues("/proc/stat")
+	if err != nil {
+		errs = append(errs, fmt.Errorf("reading /proc/stat: %w", err))
+	}
+	counter := func(values map[string]string, field, name, unit string, scale float64) {
+		if n, err := strconv.ParseFloat(values[field], 64); err == nil {
+			b.Counter(name, n*scale, unit)
+		}
+	}
+	gauge := func(values map[string]string, field, name string) {
+		if n, err := strconv.ParseFloat(values[field], 64); err == nil {
+			b.Gauge(name, n, "")
+		}
+	}
+	counter(stat, "ctxt", "vmstat.context_switches", "", 1)
+	// The intr line lists the total and then a count per interrupt.
+	if fields := strings.Fields(stat["intr"]); len(fields) > 0 {
+		if n, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			b.Counter("vmstat.interrupts", n, "")
+		}
+	}
+	counter(stat, "processes", "vmstat.forks", "", 1)
+	gauge(stat, "procs_running", "vmstat.procs_running")
+	gauge(stat, "procs_blocked", "vmstat.procs_blocked")
+
+	vmstat, err := readKeyValues("/proc/vmstat")
+	if err != nil {
+		errs = append(errs, fmt.Errorf("reading /proc/vmstat: %w", err))
+	}
+	pageSize := float64(os.Getpagesize())
+	for _, f := range vmstatFields {
+		scale := pageSize
+		if f.unit == "" {
+			scale = 1
+		} else if strings.HasPrefix(f.field, "pgpg") {
+			scale = 1024
+		}
+		counter(vmstat, f.field, f.name, f.unit, scale)
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+// readKeyValues reads a file of "key value..." lines into a map from each
+// key to the rest of its line.
+func readKeyValues(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if k, v, ok := strings.Cut(line, " "); ok {
+			values[k] = strings.TrimSpace(v)
+		}
+	}
+	return values, nil
+}
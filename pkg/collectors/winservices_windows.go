@@ -0,0 +1,98 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/yusufpapurcu/wmi"
+)
+
+// Win32 error codes a stopped service can have exited with.
+const (
+	errorSuccess             = 0
+	errorServiceNeverStarted = 1077
+	errorServiceSpecific     = 1066
+)
+
+type win32Service struct {
+	Name                    string
+	State                   string
+	StartMode               string
+	ExitCode                uint32
+	ServiceSpecificExitCode uint32
+}
+
+// WinServicesCollector reports the state of watched Windows services from
+// WMI's Win32_Service, and the automatic services that stopped with an
+// error, as systemd's failed units are reported on Linux. Automatic
+// services that stop cleanly, such as trigger-started ones, or that
+// haven't been started yet aren't failed.
+type WinServicesCollector struct {
+	// Services is the watch list, by service name rather than display
+	// name, e.g. "W3SVC" or "MSSQLSERVER".
+	Services []string `json:"services"`
+	// Failed reports every failed automatic service, watched or not.
+	Failed bool `json:"failed"`
+}
+
+func NewWinServicesCollector(cfg config.CollectorConfig) (Collector, error) {
+	w := &WinServicesCollector{Failed: true}
+	if err := cfg.Decode(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *WinServicesCollector) Name() string {
+	return "winservices"
+}
+
+func (w *WinServicesCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	var services []win32Service
+	if err := wmi.Query("SELECT Name, State, StartMode, ExitCode, ServiceSpecificExitCode FROM Win32_Service", &services); err != nil {
+		return nil, fmt.Errorf("listing services: %w", err)
+	}
+	b := metric.NewBuilder(time.Now())
+	var failed float64
+	seen := map[string]bool{}
+	for _, s := range services {
+		// Service names are case-insensitive.
+		i := slices.IndexFunc(w.Services, func(name string) bool { return strings.EqualFold(name, s.Name) })
+		if i >= 0 {
+			seen[w.Services[i]] = true
+			addWinService(b, w.Services[i], s.State, s.StartMode)
+		}
+		if !w.Failed || s.StartMode != "Auto" || s.State != "Stopped" ||
+			s.ExitCode == errorSuccess || s.ExitCode == errorServiceNeverStarted {
+			continue
+		}
+		code := s.ExitCode
+		if code == errorServiceSpecific {
+			code = s.ServiceSpecificExitCode
+		}
+		failed++
+		b.Gauge("winservices.service_failed", 1, "", "service", s.Name, "exit_code", strconv.FormatUint(uint64(code), 10))
+	}
+	// A watched service that isn't installed is as down as a stopped one.
+	for _, name := range w.Services {
+		if !seen[name] {
+			addWinService(b, name, "NotFound", "")
+		}
+	}
+	if w.Failed {
+		b.Gauge("winservices.failed_services", failed, "")
+	}
+	return b.Metrics(), nil
+}
+
+func addWinService(b *metric.Builder, name, state, startMode string) {
+	b.Gauge("winservices.service_running", boolValue(state == "Running"), "", "service", name)
+	b.Gauge("winservices.service_info", 1, "", "service", name, "state", state, "start_mode", startMode)
+}
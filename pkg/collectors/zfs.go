@@ -0,0 +1,202 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+const arcstatsPath = "/proc/spl/kstat/zfs/arcstats"
+
+// ZFSCollector reports pool health, capacity and scrubs from the zpool
+// command and ARC statistics from the kernel module.
+type ZFSCollector struct {
+	// Zpool is the zpool binary.
+	Zpool string `json:"zpool"`
+	// Pools limits the report to these pools.
+	Pools []string `json:"pools"`
+}
+
+func NewZFSCollector(cfg config.CollectorConfig) (Collector, error) {
+	z := &ZFSCollector{Zpool: "zpool"}
+	if err := cfg.Decode(z); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+func (z *ZFSCollector) Name() string {
+	return "zfs"
+}
+
+// zfsScan is the last or current scrub or resilver of a pool, from the
+// "scan:" line of zpool status.
+type zfsScan struct {
+	Function   string // scrub or resilver; empty if none ever ran
+	InProgress bool
+	Progress   float64
+	Errors     float64
+	// Finished is when the last completed scan ended.
+	Finished time.Time
+}
+
+var (
+	zfsScanDone     = regexp.MustCompile(`^(scrub repaired|resilvered) .* with (\d+) errors on (.+)$`)
+	zfsScanRunning  = regexp.MustCompile(`^(scrub|resilver) in progress since`)
+	zfsScanProgress = regexp.MustCompile(`([\d.]+)% done`)
+)
+
+func (z *ZFSCollector) Collect(ctx context.Context) ([]metric.Metric, error) {
+	b := metric.NewBuilder(time.Now())
+	var errs []error
+
+	out, err := z.zpool(ctx, "list", "-Hp", "-o", "name,health,size,allocated,free,fragmentation,capacity")
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) != 7 {
+			continue
+		}
+		labels := []string{"pool", f[0]}
+		b.Gauge("zfs.pool_info", 1, "", "pool", f[0], "health", f[1])
+		b.Gauge("zfs.pool_healthy", boolValue(f[1] == "ONLINE"), "", labels...)
+		b.Gauge("zfs.pool_size", zfsNumber(f[2]), "bytes", labels...)
+		b.Gauge("zfs.pool_allocated", zfsNumber(f[3]), "bytes", labels...)
+		b.Gauge("zfs.pool_free", zfsNumber(f[4]), "bytes", labels...)
+		// Fragmentation is "-" for pools too old to track it.
+		if frag, err := strconv.ParseFloat(strings.TrimSuffix(f[5], "%"), 64); err == nil {
+			b.Gauge("zfs.pool_fragmentation_percent", frag, "percent", labels...)
+		}
+		b.Gauge("zfs.pool_capacity_percent", zfsNumber(f[6]), "percent", labels...)
+	}
+
+	out, err = z.zpool(ctx, "status", "-p")
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for pool, scan := range parseZpoolStatus(out) {
+		labels := []string{"pool", pool}
+		b.Gauge("zfs.scrub_in_progress", boolValue(scan.InProgress && scan.Function == "scrub"), "", labels...)
+		b.Gauge("zfs.resilver_in_progress", boolValue(scan.InProgress && scan.Function == "resilver"), "", labels...)
+		if scan.InProgress {
+			b.Gauge("zfs.scan_progress", scan.Progress, "percent", "pool", pool, "function", scan.Function)
+		}
+		if !scan.Finished.IsZero() {
+			labels := []string{"pool", pool, "function", scan.Function}
+			b.Gauge("zfs.last_scan_age", time.Since(scan.Finished).Seconds(), "seconds", labels...)
+			b.Gauge("zfs.last_scan_errors", scan.Errors, "", labels...)
+		}
+	}
+
+	if err := addARCStats(b); err != nil {
+		errs = append(errs, err)
+	}
+	return b.Metrics(), errors.Join(errs...)
+}
+
+func (z *ZFSCollector) zpool(ctx context.Context, args ...string) ([]byte, error) {
+	out, err := runCommand(ctx, z.Zpool, append(args, z.Pools...)...)
+	if err != nil {
+		return nil, fmt.Errorf("zpool %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// parseZpoolStatus reads the scan line of each pool in zpool status, e.g.
+//
+//	 pool: tank
+//	state: ONLINE
+//	 scan: scrub repaired 0B in 00:10:32 with 0 errors on Sun Oct 11 00:34:33 2026
+func parseZpoolStatus(out []byte) map[string]zfsScan {
+	scans := map[string]zfsScan{}
+	var pool string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, _ := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		switch key {
+		case "pool":
+			pool = value
+			scans[pool] = zfsScan{}
+		case "scan":
+			if pool == "" {
+				continue
+			}
+			var scan zfsScan
+			if m := zfsScanDone.FindStringSubmatch(value); m != nil {
+				scan.Function = "scrub"
+				if m[1] == "resilvered" {
+					scan.Function = "resilver"
+				}
+				scan.Errors, _ = strconv.ParseFloat(m[2], 64)
+				scan.Finished, _ = time.ParseInLocation("Mon Jan _2 15:04:05 2006", m[3], time.Local)
+			} else if m := zfsScanRunning.FindStringSubmatch(value); m != nil {
+				scan.Function = m[1]
+				scan.InProgress = true
+			}
+			scans[pool] = scan
+		default:
+			// The progress of a running scan is on a continuation line:
+			// "1.23T scanned at 512M/s, ... 45.67% done, 01:02:03 to go".
+			if s := scans[pool]; s.InProgress {
+				if m := zfsScanProgress.FindStringSubmatch(line); m != nil {
+					s.Progress, _ = strconv.ParseFloat(m[1], 64)
+					scans[pool] = s
+				}
+			}
+		}
+	}
+	return scans
+}
+
+// addARCStats reads the ARC kstats, lines of "name type data" after two
+// header lines. A host without the module loaded has none, which isn't an
+// error while zpool works.
+func addARCStats(b *metric.Builder) error {
+	data, err := os.ReadFile(arcstatsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading arcstats: %w", err)
+	}
+	stats := map[string]float64{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if f := strings.Fields(line); len(f) == 3 {
+			if v, err := strconv.ParseFloat(f[2], 64); err == nil {
+				stats[f[0]] = v
+			}
+		}
+	}
+	b.Gauge("zfs.arc_size", stats["size"], "bytes")
+	b.Gauge("zfs.arc_target_size", stats["c"], "bytes")
+	b.Gauge("zfs.arc_max_size", stats["c_max"], "bytes")
+	b.Counter("zfs.arc_hits", stats["hits"], "")
+	b.Counter("zfs.arc_misses", stats["misses"], "")
+	if total := stats["hits"] + stats["misses"]; total > 0 {
+		b.Gauge("zfs.arc_hit_ratio", 100*stats["hits"]/total, "percent")
+	}
+	if l2 := stats["l2_hits"] + stats["l2_misses"]; l2 > 0 {
+		b.Gauge("zfs.l2arc_size", stats["l2_size"], "bytes")
+		b.Gauge("zfs.l2arc_hit_ratio", 100*stats["l2_hits"]/l2, "percent")
+	}
+	return nil
+}
+
+func zfsNumber(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	return v
+}
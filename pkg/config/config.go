@@ -0,0 +1,329 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	LogLevel string   `json:"log_level"`
+	Interval Duration `json:"interval"`
+	Jitter   Duration `json:"jitter"`
+	// Workers caps how many collectors run at once; zero means no limit.
+	Workers    int                        `json:"workers"`
+	Collectors map[string]CollectorConfig `json:"collectors"`
+	// Tags are added as labels to every metric, for example env: prod.
+	// GLASS_TAG_<NAME> environment variables add to and override them.
+	Tags  map[string]string `json:"tags"`
+	Cloud CloudConfig       `json:"cloud"`
+	// Outputs all receive every collection. run and daemon log metrics
+	// when there are none; serve has its HTTP API either way.
+	Outputs []OutputConfig `json:"outputs"`
+	Alerts  AlertsConfig   `json:"alerts"`
+	Server  ServerConfig   `json:"server"`
+	// Aggregator applies to glass aggregator, which otherwise uses Server
+	// and History.
+	Aggregator AggregatorConfig `json:"aggregator"`
+	History    HistoryConfig    `json:"history"`
+	Storage    StorageConfig    `json:"storage"`
+}
+
+// ServerConfig configures the HTTP server run by serve.
+type ServerConfig struct {
+	Listen string `json:"listen"`
+	// GRPCListen turns on the gRPC API on this address. It shares TLS and
+	// auth with the HTTP API.
+	GRPCListen string     `json:"grpc_listen"`
+	TLS        TLSConfig  `json:"tls"`
+	Auth       AuthConfig `json:"auth"`
+}
+
+// AuthConfig protects the HTTP API with bearer tokens, basic auth or both.
+// With neither configured the API is open.
+type AuthConfig struct {
+	Tokens []string `json:"tokens"`
+	// TokensFile holds one token per line.
+	TokensFile string `json:"tokens_file"`
+	// Users maps user names to passwords for basic auth.
+	Users map[string]string `json:"users"`
+	// UsersFile holds one user:password per line.
+	UsersFile string `json:"users_file"`
+	// Exempt lists paths served without credentials; a trailing slash
+	// makes an entry a prefix. It defaults to /healthz and /readyz.
+	Exempt []string `json:"exempt"`
+}
+
+// TLSConfig turns on TLS when CertFile and KeyFile are set. The files are
+// read again when they change, so certificates can rotate without a
+// restart.
+type TLSConfig struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// ClientCAFile turns on client certificate verification (mTLS).
+	ClientCAFile string `json:"client_ca_file"`
+	// ClientAuth is "require", the default with a ClientCAFile, or
+	// "request", which only verifies certificates that clients offer.
+	ClientAuth string `json:"client_auth"`
+	// MinVersion is "1.2" (the default) or "1.3".
+	MinVersion string `json:"min_version"`
+}
+
+// Enabled reports whether TLS is configured.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// AggregatorConfig controls how long glass aggregator remembers hosts that
+// stop pushing.
+type AggregatorConfig struct {
+	// StaleAfter drops a silent host's metrics from /metrics.
+	StaleAfter Duration `json:"stale_after"`
+	// ForgetAfter drops a silent host altogether.
+	ForgetAfter Duration `json:"forget_after"`
+}
+
+// HistoryConfig sizes the in-memory history kept by serve.
+type HistoryConfig struct {
+	Retention  Duration `json:"retention"`
+	Resolution Duration `json:"resolution"`
+}
+
+// CloudConfig turns on tagging metrics with the cloud instance's provider,
+// ID, type, region and zone, read from the metadata service at startup.
+type CloudConfig struct {
+	Enabled bool `json:"enabled"`
+	// Providers limits which metadata services are asked; empty means all.
+	Providers []string `json:"providers"`
+	Timeout   Duration `json:"timeout"`
+}
+
+// StorageConfig enables the on-disk store when Path is set.
+type StorageConfig struct {
+	Path      string   `json:"path"`
+	Retention Duration `json:"retention"`
+	// Samples older than DownsampleAfter are averaged into buckets of
+	// DownsampleResolution.
+	DownsampleAfter      Duration `json:"downsample_after"`
+	DownsampleResolution Duration `json:"downsample_resolution"`
+	FlushInterval        Duration `json:"flush_interval"`
+	CompactInterval      Duration `json:"compact_interval"`
+}
+
+// CollectorConfig holds the common settings for a collector. Every other
+// key is kept in Options and decoded by the collector itself.
+type CollectorConfig struct {
+	Enabled  *bool
+	Interval Duration
+	Timeout  Duration
+	Options  map[string]any
+}
+
+// OutputConfig selects a sink by Type; the remaining keys are sink options.
+type OutputConfig struct {
+	Type    string
+	Options map[string]any
+}
+
+type AlertsConfig struct {
+	Rules []AlertRule `json:"rules"`
+	// RepeatInterval re-sends notifications for alerts that stay firing.
+	// Zero notifies only on state changes.
+	RepeatInterval Duration         `json:"repeat_interval"`
+	Notifiers      []NotifierConfig `json:"notifiers"`
+}
+
+// AlertRule is a threshold such as "mem.used_percent > 90 for 5m".
+type AlertRule struct {
+	Name        string            `json:"name"`
+	Expr        string            `json:"expr"`
+	For         Duration          `json:"for"`
+	Severity    string            `json:"severity"`
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// NotifierConfig selects an alert notifier by Type; the remaining keys are
+// notifier options.
+type NotifierConfig struct {
+	Type    string
+	Options map[string]any
+}
+
+func Default() *Config {
+	return &Config{
+		LogLevel: "info",
+		History: HistoryConfig{
+			Retention:  Duration(time.Hour),
+			Resolution: Duration(10 * time.Second),
+		},
+		Cloud: CloudConfig{Timeout: Duration(2 * time.Second)},
+		Aggregator: AggregatorConfig{
+			StaleAfter:  Duration(5 * time.Minute),
+			ForgetAfter: Duration(24 * time.Hour),
+		},
+		Storage: StorageConfig{
+			Retention:            Duration(7 * 24 * time.Hour),
+			DownsampleAfter:      Duration(24 * time.Hour),
+			DownsampleResolution: Duration(5 * time.Minute),
+			FlushInterval:        Duration(time.Minute),
+			CompactInterval:      Duration(time.Hour),
+		},
+	}
+}
+
+// Load reads a YAML or TOML file, picking the format by extension.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	case ".yaml", ".yml", "":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	cfg := Default()
+	if err := decode(raw, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Collector returns the configuration for the named collector, or the zero
+// value when the file doesn't mention it.
+func (c *Config) Collector(name string) CollectorConfig {
+	return c.Collectors[name]
+}
+
+// IsEnabled reports whether the collector should run, falling back to
+// def when the config doesn't say.
+func (c CollectorConfig) IsEnabled(def bool) bool {
+	if c.Enabled == nil {
+		return def
+	}
+	return *c.Enabled
+}
+
+// Decode unpacks the collector-specific options into v.
+func (c CollectorConfig) Decode(v any) error {
+	return decode(c.Options, v)
+}
+
+func (c *CollectorConfig) UnmarshalJSON(data []byte) error {
+	var common struct {
+		Enabled  *bool    `json:"enabled"`
+		Interval Duration `json:"interval"`
+		Timeout  Duration `json:"timeout"`
+	}
+	if err := json.Unmarshal(data, &common); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &c.Options); err != nil {
+		return err
+	}
+	delete(c.Options, "enabled")
+	delete(c.Options, "interval")
+	delete(c.Options, "timeout")
+	c.Enabled, c.Interval, c.Timeout = common.Enabled, common.Interval, common.Timeout
+	return nil
+}
+
+func (c CollectorConfig) MarshalJSON() ([]byte, error) {
+	out := make(map[string]any, len(c.Options)+3)
+	for k, v := range c.Options {
+		out[k] = v
+	}
+	if c.Enabled != nil {
+		out["enabled"] = *c.Enabled
+	}
+	if c.Interval != 0 {
+		out["interval"] = c.Interval
+	}
+	if c.Timeout != 0 {
+		out["timeout"] = c.Timeout
+	}
+	return json.Marshal(out)
+}
+
+// Decode unpacks the sink-specific options into v.
+func (o OutputConfig) Decode(v any) error {
+	return decode(o.Options, v)
+}
+
+func (o *OutputConfig) UnmarshalJSON(data []byte) error {
+	return unmarshalTyped(data, &o.Type, &o.Options)
+}
+
+func (o OutputConfig) MarshalJSON() ([]byte, error) {
+	return marshalTyped(o.Type, o.Options)
+}
+
+// Decode unpacks the notifier-specific options into v.
+func (n NotifierConfig) Decode(v any) error {
+	return decode(n.Options, v)
+}
+
+func (n *NotifierConfig) UnmarshalJSON(data []byte) error {
+	return unmarshalTyped(data, &n.Type, &n.Options)
+}
+
+func (n NotifierConfig) MarshalJSON() ([]byte, error) {
+	return marshalTyped(n.Type, n.Options)
+}
+
+// unmarshalTyped splits an object with a "type" key into the type and the
+// remaining options.
+func unmarshalTyped(data []byte, typ *string, options *map[string]any) error {
+	var common struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &common); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, options); err != nil {
+		return err
+	}
+	delete(*options, "type")
+	*typ = common.Type
+	return nil
+}
+
+// marshalTyped is the reverse of unmarshalTyped.
+func marshalTyped(typ string, options map[string]any) ([]byte, error) {
+	out := make(map[string]any, len(options)+1)
+	for k, v := range options {
+		out[k] = v
+	}
+	out["type"] = typ
+	return json.Marshal(out)
+}
+
+// decode converts a generic map (as produced by the YAML and TOML parsers)
+// into a typed struct by round-tripping through JSON, so that a single set of
+// json tags serves both file formats.
+func decode(in any, out any) error {
+	if in == nil {
+		return nil
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,82 @@
+package glassv1
+
+import (
+	"time"
+
+	"glass/pkg/metric"
+	"glass/pkg/store"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FromSnapshot converts a collection to its message.
+func FromSnapshot(s store.Snapshot) *Snapshot {
+	pb := &Snapshot{
+		Collector: s.Collector,
+		Timestamp: Timestamp(s.Timestamp),
+		Metrics:   make([]*Metric, 0, len(s.Metrics)),
+	}
+	for _, m := range s.Metrics {
+		pb.Metrics = append(pb.Metrics, &Metric{
+			Name:      m.Name,
+			Kind:      fromKind(m.Kind),
+			Value:     m.Value,
+			Unit:      m.Unit,
+			Labels:    m.Labels,
+			Timestamp: Timestamp(m.Timestamp),
+		})
+	}
+	return pb
+}
+
+// ToSnapshot converts the message back to a collection.
+func (s *Snapshot) ToSnapshot() store.Snapshot {
+	out := store.Snapshot{
+		Collector: s.GetCollector(),
+		Timestamp: asTime(s.GetTimestamp()),
+		Metrics:   make([]metric.Metric, 0, len(s.GetMetrics())),
+	}
+	for _, m := range s.GetMetrics() {
+		out.Metrics = append(out.Metrics, metric.Metric{
+			Name:      m.GetName(),
+			Kind:      toKind(m.GetKind()),
+			Value:     m.GetValue(),
+			Unit:      m.GetUnit(),
+			Labels:    m.GetLabels(),
+			Timestamp: asTime(m.GetTimestamp()),
+		})
+	}
+	return out
+}
+
+func fromKind(k metric.Kind) Kind {
+	switch k {
+	case metric.Gauge:
+		return Kind_KIND_GAUGE
+	case metric.Counter:
+		return Kind_KIND_COUNTER
+	}
+	return Kind_KIND_UNSPECIFIED
+}
+
+func toKind(k Kind) metric.Kind {
+	if k == Kind_KIND_COUNTER {
+		return metric.Counter
+	}
+	return metric.Gauge
+}
+
+// Timestamp leaves unset times unset rather than sending the epoch.
+func Timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func asTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
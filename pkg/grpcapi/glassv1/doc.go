@@ -0,0 +1,5 @@
+// Package glassv1 holds the messages and service of glass's gRPC API,
+// generated from glass.proto.
+package glassv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative glass.proto
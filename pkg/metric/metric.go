@@ -0,0 +1,82 @@
+package metric
+
+import (
+	"sort"
+	"time"
+)
+
+// Kind tells exporters whether a metric is a point-in-time value or a
+// monotonically increasing counter.
+type Kind string
+
+const (
+	Gauge   Kind = "gauge"
+	Counter Kind = "counter"
+)
+
+// Metric is a single collected sample.
+type Metric struct {
+	Name      string            `json:"name"`
+	Kind      Kind              `json:"kind"`
+	Value     float64           `json:"value"`
+	Unit      string            `json:"unit,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// LabelKeys returns the metric's label names in sorted order.
+func (m Metric) LabelKeys() []string {
+	keys := make([]string, 0, len(m.Labels))
+	for k := range m.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Builder accumulates metrics that share a collection timestamp.
+type Builder struct {
+	timestamp time.Time
+	metrics   []Metric
+}
+
+func NewBuilder(timestamp time.Time) *Builder {
+	return &Builder{timestamp: timestamp}
+}
+
+// Gauge appends a gauge. Labels are given as alternating key/value pairs.
+func (b *Builder) Gauge(name string, value float64, unit string, labels ...string) {
+	b.Add(Gauge, name, value, unit, labels...)
+}
+
+// Counter appends a counter. Labels are given as alternating key/value pairs.
+func (b *Builder) Counter(name string, value float64, unit string, labels ...string) {
+	b.Add(Counter, name, value, unit, labels...)
+}
+
+func (b *Builder) Add(kind Kind, name string, value float64, unit string, labels ...string) {
+	b.metrics = append(b.metrics, Metric{
+		Name:      name,
+		Kind:      kind,
+		Value:     value,
+		Unit:      unit,
+		Labels:    Labels(labels...),
+		Timestamp: b.timestamp,
+	})
+}
+
+func (b *Builder) Metrics() []Metric {
+	return b.metrics
+}
+
+// Labels builds a label map from alternating key/value pairs.
+func Labels(kv ...string) map[string]string {
+	if len(kv) < 2 {
+		return nil
+	}
+	labels := make(map[string]string, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		labels[kv[i]] = kv[i+1]
+	}
+	return labels
+}
@@ -0,0 +1,125 @@
+package metric
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Selector picks metrics out of a collection. It accepts either a plain
+// name ("mem.used_percent"), a name with an instance segment matched against
+// any label value ("disk./.used_percent", "net.eth0.bytes_recv"), or a name
+// with explicit labels (`disk.used_percent{mountpoint="/"}`).
+type Selector struct {
+	Name     string
+	Instance string
+	Labels   map[string]string
+
+	// short is Name with the instance segment removed, e.g. "disk.used_percent".
+	short string
+}
+
+func ParseSelector(s string) (Selector, error) {
+	s = strings.TrimSpace(s)
+	sel := Selector{Name: s}
+	if i := strings.IndexByte(s, '{'); i >= 0 {
+		if !strings.HasSuffix(s, "}") {
+			return Selector{}, fmt.Errorf("invalid selector %q: unterminated label list", s)
+		}
+		sel.Name = s[:i]
+		labels, err := parseLabelList(s[i+1 : len(s)-1])
+		if err != nil {
+			return Selector{}, fmt.Errorf("invalid selector %q: %w", s, err)
+		}
+		sel.Labels = labels
+	}
+	if sel.Name == "" {
+		return Selector{}, fmt.Errorf("invalid selector %q: missing metric name", s)
+	}
+	first, last := strings.IndexByte(sel.Name, '.'), strings.LastIndexByte(sel.Name, '.')
+	if first >= 0 && last > first {
+		sel.Instance = sel.Name[first+1 : last]
+		sel.short = sel.Name[:first] + sel.Name[last:]
+	}
+	return sel, nil
+}
+
+func parseLabelList(s string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("label %q is not key=value", pair)
+		}
+		labels[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"'`)
+	}
+	return labels, nil
+}
+
+// Match reports whether m is selected.
+func (s Selector) Match(m Metric) bool {
+	for k, v := range s.Labels {
+		if m.Labels[k] != v {
+			return false
+		}
+	}
+	if m.Name == s.Name {
+		return true
+	}
+	if s.short == "" || m.Name != s.short {
+		return false
+	}
+	for _, v := range m.Labels {
+		if v == s.Instance {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the metrics matched by s.
+func (s Selector) Select(metrics []Metric) []Metric {
+	var out []Metric
+	for _, m := range metrics {
+		if s.Match(m) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (s Selector) String() string {
+	if len(s.Labels) == 0 {
+		return s.Name
+	}
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%q", k, s.Labels[k])
+	}
+	return s.Name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// SeriesKey identifies a metric's time series: its name plus sorted labels.
+func (m Metric) SeriesKey() string {
+	if len(m.Labels) == 0 {
+		return m.Name
+	}
+	var sb strings.Builder
+	sb.WriteString(m.Name)
+	for _, k := range m.LabelKeys() {
+		sb.WriteByte(',')
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(m.Labels[k])
+	}
+	return sb.String()
+}
@@ -0,0 +1,208 @@
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"glass/pkg/aggregator"
+	"glass/pkg/config"
+	"glass/pkg/grpcapi/glassv1"
+	"glass/pkg/metric"
+	"glass/pkg/retry"
+	"glass/pkg/store"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AggregatorSink pushes collections to a glass aggregator, over HTTP for
+// http:// and https:// URLs and over gRPC for grpc:// and grpcs:// ones.
+// Collections are queued while the aggregator can't be reached; BatchSize
+// and MaxBuffer count collector runs, not metrics.
+type AggregatorSink struct {
+	URL string `json:"url"`
+	// Host identifies this agent to the aggregator. It defaults to the
+	// hostname.
+	Host  string `json:"host"`
+	Token string `json:"token"`
+	// Compression is gzip (the default) or none.
+	Compression string `json:"compression"`
+
+	CAFile             string `json:"ca_file"`
+	CertFile           string `json:"cert_file"`
+	KeyFile            string `json:"key_file"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+
+	FlushInterval config.Duration `json:"flush_interval"`
+	BatchSize     int             `json:"batch_size"`
+	MaxBuffer     int             `json:"max_buffer"`
+	Retries       int             `json:"retries"`
+	Timeout       config.Duration `json:"timeout"`
+
+	client  *http.Client
+	conn    *grpc.ClientConn
+	batcher *batcher[store.Snapshot]
+}
+
+func NewAggregatorSink(cfg config.OutputConfig) (*AggregatorSink, error) {
+	s := &AggregatorSink{
+		Compression:   "gzip",
+		FlushInterval: config.Duration(10 * time.Second),
+		BatchSize:     100,
+		MaxBuffer:     10000,
+		Retries:       3,
+		Timeout:       config.Duration(10 * time.Second),
+	}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	if s.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if s.Compression != "gzip" && s.Compression != "none" {
+		return nil, fmt.Errorf("invalid compression %q: must be gzip or none", s.Compression)
+	}
+	if s.Host == "" {
+		if s.Host, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("getting hostname: %w", err)
+		}
+	}
+	tlsCfg, err := tlsClientConfig(s.CAFile, s.CertFile, s.KeyFile, s.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	var send func(context.Context, []store.Snapshot) error
+	switch u.Scheme {
+	case "http", "https":
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v1/push"
+		endpoint := u.String()
+		s.client = &http.Client{Timeout: s.Timeout.Duration(), Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsCfg,
+		}}
+		send = func(ctx context.Context, batch []store.Snapshot) error {
+			return s.sendHTTP(ctx, endpoint, batch)
+		}
+	case "grpc", "grpcs":
+		creds := insecure.NewCredentials()
+		if u.Scheme == "grpcs" {
+			creds = credentials.NewTLS(tlsCfg)
+		}
+		if s.conn, err = grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds)); err != nil {
+			return nil, fmt.Errorf("connecting to %s: %w", u.Host, err)
+		}
+		send = s.sendGRPC
+	default:
+		return nil, fmt.Errorf("unsupported url scheme %q: must be http, https, grpc or grpcs", u.Scheme)
+	}
+	s.batcher = newBatcher("aggregator", s.FlushInterval.Duration(), s.BatchSize, s.MaxBuffer, s.Retries, send)
+	return s, nil
+}
+
+func (s *AggregatorSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	s.batcher.add([]store.Snapshot{{Collector: collector, Timestamp: time.Now(), Metrics: metrics}})
+	return nil
+}
+
+func (s *AggregatorSink) bufferStats() Stats {
+	return s.batcher.bufferStats()
+}
+
+func (s *AggregatorSink) Close() error {
+	err := s.batcher.close(s.Timeout.Duration())
+	if s.conn != nil {
+		err = errors.Join(err, s.conn.Close())
+	}
+	return err
+}
+
+func (s *AggregatorSink) sendHTTP(ctx context.Context, endpoint string, batch []store.Snapshot) error {
+	var body bytes.Buffer
+	w := io.Writer(&body)
+	var gz *gzip.Writer
+	if s.Compression == "gzip" {
+		gz = gzip.NewWriter(&body)
+		w = gz
+	}
+	if err := json.NewEncoder(w).Encode(aggregator.Push{Host: s.Host, Collections: batch}); err != nil {
+		return retry.Permanent(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return retry.Permanent(err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if gz != nil {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("aggregator returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return retry.Permanent(err)
+}
+
+func (s *AggregatorSink) sendGRPC(ctx context.Context, batch []store.Snapshot) error {
+	req := &glassv1.PushRequest{Host: s.Host, Collections: make([]*glassv1.Snapshot, 0, len(batch))}
+	for _, c := range batch {
+		req.Collections = append(req.Collections, glassv1.FromSnapshot(c))
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout.Duration())
+	defer cancel()
+	if s.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+s.Token)
+	}
+	var opts []grpc.CallOption
+	if s.Compression == "gzip" {
+		opts = append(opts, grpc.UseCompressor(grpcgzip.Name))
+	}
+	_, err := glassv1.NewAggregatorClient(s.conn).Push(ctx, req, opts...)
+	if err == nil {
+		return nil
+	}
+	code := status.Code(err)
+	err = fmt.Errorf("aggregator returned %w", err)
+	switch code {
+	case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied, codes.Unimplemented:
+		return retry.Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"glass/pkg/metric"
+)
+
+// collectionSchema is the Avro schema of one collector run, matching the
+// JSON messages field for field.
+const collectionSchema = `{
+  "type": "record", "name": "Collection", "namespace": "glass",
+  "fields": [
+    {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "host", "type": "string"},
+    {"name": "collector", "type": "string"},
+    {"name": "metrics", "type": {"type": "array", "items": {
+      "type": "record", "name": "Metric",
+      "fields": [
+        {"name": "name", "type": "string"},
+        {"name": "kind", "type": "string"},
+        {"name": "value", "type": "double"},
+        {"name": "unit", "type": "string"},
+        {"name": "labels", "type": {"type": "map", "values": "string"}},
+        {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+      ]
+    }}}
+  ]
+}`
+
+// encodeAvroCollection writes a collection in Avro binary encoding, framed
+// as the Confluent wire format: a zero byte and the big-endian schema ID.
+func encodeAvroCollection(w *bytes.Buffer, schemaID int32, ts time.Time, host, collector string, metrics []metric.Metric) {
+	w.WriteByte(0)
+	binary.Write(w, binary.BigEndian, schemaID)
+	avroLong(w, ts.UnixMilli())
+	avroString(w, host)
+	avroString(w, collector)
+	if len(metrics) > 0 {
+		avroLong(w, int64(len(metrics)))
+		for _, m := range metrics {
+			avroString(w, m.Name)
+			avroString(w, string(m.Kind))
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(m.Value))
+			w.Write(b[:])
+			avroString(w, m.Unit)
+			if len(m.Labels) > 0 {
+				avroLong(w, int64(len(m.Labels)))
+				for _, k := range m.LabelKeys() {
+					avroString(w, k)
+					avroString(w, m.Labels[k])
+				}
+			}
+			avroLong(w, 0)
+			avroLong(w, m.Timestamp.UnixMilli())
+		}
+	}
+	avroLong(w, 0)
+}
+
+func avroLong(w *bytes.Buffer, v int64) {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func avroString(w *bytes.Buffer, s string) {
+	avroLong(w, int64(len(s)))
+	w.WriteString(s)
+}
+
+// registerSchema registers schema under subject with a Confluent schema
+// registry and returns its ID. Registering an existing schema returns the
+// existing ID.
+func registerSchema(ctx context.Context, client *http.Client, registry, subject, schema string) (int32, error) {
+	body, err := json.Marshal(map[string]string{"schema": schema})
+	if err != nil {
+		return 0, err
+	}
+	endpoint := strings.TrimSuffix(registry, "/") + "/subjects/" + url.PathEscape(subject) + "/versions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("schema registry returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	var out struct {
+		ID int32 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("decoding schema registry response: %w", err)
+	}
+	return out.ID, nil
+}
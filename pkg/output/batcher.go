@@ -0,0 +1,160 @@
+package output
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"glass/pkg/retry"
+
+	"github.com/rs/zerolog/log"
+)
+
+// batcher buffers metrics, or whatever else a push-style sink sends, and
+// hands them to send in batches, either every interval or as soon as a full
+// batch is waiting. Sizes and counts are in items.
+type batcher[T any] struct {
+	name      string
+	interval  time.Duration
+	batchSize int
+	maxBuffer int
+	policy    retry.Policy
+	send      func(ctx context.Context, batch []T) error
+
+	mu      sync.Mutex
+	pending []T
+	stats   Stats
+	full    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newBatcher[T any](name string, interval time.Duration, batchSize, maxBuffer, retries int, send func(context.Context, []T) error) *batcher[T] {
+	b := &batcher[T]{
+		name:      name,
+		interval:  interval,
+		batchSize: max(batchSize, 1),
+		maxBuffer: maxBuffer,
+		policy:    retry.Policy{Attempts: retries + 1, Base: time.Second, Max: 30 * time.Second},
+		send:      send,
+		full:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+func (b *batcher[T]) add(items []T) {
+	b.mu.Lock()
+	b.pending = append(b.pending, items...)
+	if over := len(b.pending) - b.maxBuffer; b.maxBuffer > 0 && over > 0 {
+		// Drop the oldest samples rather than grow without bound while the
+		// destination is unreachable.
+		b.pending = b.pending[over:]
+		b.stats.Dropped += uint64(over)
+		log.Warn().Str("output", b.name).Int("dropped", over).Msg("Output buffer full, dropping oldest metrics")
+	}
+	n := len(b.pending)
+	b.mu.Unlock()
+
+	if n >= b.batchSize {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// bufferStats reports what is waiting to be sent and how sending has gone.
+func (b *batcher[T]) bufferStats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := b.stats
+	st.Queued = len(b.pending)
+	return st
+}
+
+// close stops the flush loop and makes a final attempt to deliver whatever
+// is still buffered.
+func (b *batcher[T]) close(timeout time.Duration) error {
+	close(b.done)
+	<-b.stopped
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return b.flush(ctx)
+}
+
+func (b *batcher[T]) loop() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-b.done
+		cancel()
+	}()
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+		case <-b.full:
+		}
+		if err := b.flush(ctx); err != nil {
+			log.Err(err).Str("output", b.name).Msg("Error flushing output")
+		}
+	}
+}
+
+// flush sends everything pending in batches. Batches that fail after all
+// retries are put back at the front of the queue for the next flush, unless
+// the destination rejected them outright.
+func (b *batcher[T]) flush(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		n := min(len(b.pending), b.batchSize)
+		batch := b.pending[:n:n]
+		b.pending = b.pending[n:]
+		b.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+
+		err := retry.Do(ctx, b.policy, func() error {
+			return b.send(ctx, batch)
+		})
+		if retry.IsPermanent(err) {
+			log.Err(err).Str("output", b.name).Int("metrics", n).Msg("Output rejected batch, dropping it")
+			b.mu.Lock()
+			b.stats.Dropped += uint64(n)
+			b.record(err)
+			b.mu.Unlock()
+			continue
+		}
+		b.mu.Lock()
+		b.record(err)
+		if err != nil {
+			b.pending = append(batch, b.pending...)
+		}
+		b.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// record notes the outcome of sending a batch. b.mu must be held.
+func (b *batcher[T]) record(err error) {
+	b.stats.Writes++
+	if err != nil {
+		b.stats.Errors++
+		b.stats.ConsecutiveErrors++
+		b.stats.LastError = err.Error()
+		return
+	}
+	b.stats.ConsecutiveErrors = 0
+	b.stats.LastError = ""
+	b.stats.LastSuccess = time.Now()
+}
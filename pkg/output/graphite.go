@@ -0,0 +1,142 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// GraphiteSink writes metrics over TCP in Graphite's plaintext protocol.
+// The connection is re-established on failure, with metrics buffered until
+// it is back.
+type GraphiteSink struct {
+	Address string `json:"address"`
+	// Prefix is prepended to every path; "{host}" is replaced by the host
+	// name with dots turned into underscores.
+	Prefix string `json:"prefix"`
+	// Tags sends labels as Graphite 1.1 tags (path;key=value) rather than
+	// appending their values to the path.
+	Tags bool `json:"tags"`
+
+	FlushInterval config.Duration `json:"flush_interval"`
+	BatchSize     int             `json:"batch_size"`
+	MaxBuffer     int             `json:"max_buffer"`
+	Retries       int             `json:"retries"`
+	Timeout       config.Duration `json:"timeout"`
+
+	batcher *batcher[metric.Metric]
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func NewGraphiteSink(cfg config.OutputConfig) (*GraphiteSink, error) {
+	s := &GraphiteSink{
+		Address:       "127.0.0.1:2003",
+		Prefix:        "servers.{host}.",
+		FlushInterval: config.Duration(10 * time.Second),
+		BatchSize:     5000,
+		MaxBuffer:     100000,
+		Retries:       3,
+		Timeout:       config.Duration(10 * time.Second),
+	}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	if s.Address == "" {
+		return nil, errors.New("address is required")
+	}
+	host, _ := os.Hostname()
+	s.Prefix = strings.ReplaceAll(s.Prefix, "{host}", pathSegment(host))
+	s.batcher = newBatcher("graphite", s.FlushInterval.Duration(), s.BatchSize, s.MaxBuffer, s.Retries, s.send)
+	return s, nil
+}
+
+func (s *GraphiteSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	s.batcher.add(metrics)
+	return nil
+}
+
+func (s *GraphiteSink) bufferStats() Stats {
+	return s.batcher.bufferStats()
+}
+
+func (s *GraphiteSink) Close() error {
+	err := s.batcher.close(s.Timeout.Duration())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+func (s *GraphiteSink) send(ctx context.Context, batch []metric.Metric) error {
+	var buf bytes.Buffer
+	for _, m := range batch {
+		buf.WriteString(s.path(m))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(m.Value, 'f', -1, 64))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(m.Timestamp.Unix(), 10))
+		buf.WriteByte('\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: s.Timeout.Duration()}
+		conn, err := dialer.DialContext(ctx, "tcp", s.Address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.Timeout.Duration()))
+	if _, err := s.conn.Write(buf.Bytes()); err != nil {
+		// Part of the batch may have been written; resending it duplicates
+		// a few points, which Graphite overwrites anyway.
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (s *GraphiteSink) path(m metric.Metric) string {
+	var sb strings.Builder
+	sb.WriteString(s.Prefix)
+	sb.WriteString(graphiteName(m.Name))
+	for _, k := range m.LabelKeys() {
+		v := m.Labels[k]
+		if v == "" {
+			continue
+		}
+		if s.Tags {
+			sb.WriteByte(';')
+			sb.WriteString(graphiteTag(k))
+			sb.WriteByte('=')
+			sb.WriteString(graphiteTag(v))
+		} else {
+			sb.WriteByte('.')
+			sb.WriteString(pathSegment(v))
+		}
+	}
+	return sb.String()
+}
+
+var (
+	graphiteName = strings.NewReplacer(" ", "_", "\n", "_", ";", "_").Replace
+	// Tag values can't contain ';' or '~' or start with a space.
+	graphiteTag = strings.NewReplacer(" ", "_", "\n", "_", ";", "_", "~", "_", "=", "_").Replace
+)
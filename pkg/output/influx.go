@@ -0,0 +1,145 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+	"glass/pkg/retry"
+)
+
+// InfluxSink batches metrics and pushes them to InfluxDB as line protocol.
+type InfluxSink struct {
+	URL     string `json:"url"`
+	Version int    `json:"version"`
+	// v1
+	Database        string `json:"database"`
+	RetentionPolicy string `json:"retention_policy"`
+	Username        string `json:"username"`
+	Password        string `json:"password"`
+	// v2
+	Org    string `json:"org"`
+	Bucket string `json:"bucket"`
+	Token  string `json:"token"`
+
+	FlushInterval config.Duration `json:"flush_interval"`
+	BatchSize     int             `json:"batch_size"`
+	MaxBuffer     int             `json:"max_buffer"`
+	Retries       int             `json:"retries"`
+	Timeout       config.Duration `json:"timeout"`
+
+	endpoint string
+	client   *http.Client
+	batcher  *batcher[metric.Metric]
+}
+
+func NewInfluxSink(cfg config.OutputConfig) (*InfluxSink, error) {
+	s := &InfluxSink{
+		Version:       2,
+		FlushInterval: config.Duration(10 * time.Second),
+		BatchSize:     5000,
+		MaxBuffer:     100000,
+		Retries:       3,
+		Timeout:       config.Duration(10 * time.Second),
+	}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	endpoint, err := s.writeURL()
+	if err != nil {
+		return nil, err
+	}
+	s.endpoint = endpoint
+	s.client = &http.Client{Timeout: s.Timeout.Duration()}
+	s.batcher = newBatcher("influxdb", s.FlushInterval.Duration(), s.BatchSize, s.MaxBuffer, s.Retries, s.send)
+	return s, nil
+}
+
+func (s *InfluxSink) writeURL() (string, error) {
+	if s.URL == "" {
+		return "", errors.New("url is required")
+	}
+	base, err := url.Parse(strings.TrimSuffix(s.URL, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	q := url.Values{"precision": {"ns"}}
+	switch s.Version {
+	case 1:
+		if s.Database == "" {
+			return "", errors.New("database is required for InfluxDB v1")
+		}
+		base.Path += "/write"
+		q.Set("db", s.Database)
+		if s.RetentionPolicy != "" {
+			q.Set("rp", s.RetentionPolicy)
+		}
+	case 2:
+		if s.Bucket == "" {
+			return "", errors.New("bucket is required for InfluxDB v2")
+		}
+		base.Path += "/api/v2/write"
+		q.Set("bucket", s.Bucket)
+		if s.Org != "" {
+			q.Set("org", s.Org)
+		}
+	default:
+		return "", fmt.Errorf("unsupported InfluxDB version %d", s.Version)
+	}
+	base.RawQuery = q.Encode()
+	return base.String(), nil
+}
+
+func (s *InfluxSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	s.batcher.add(metrics)
+	return nil
+}
+
+func (s *InfluxSink) bufferStats() Stats {
+	return s.batcher.bufferStats()
+}
+
+func (s *InfluxSink) Close() error {
+	return s.batcher.close(s.Timeout.Duration())
+}
+
+func (s *InfluxSink) send(ctx context.Context, batch []metric.Metric) error {
+	var body bytes.Buffer
+	if err := EncodeLineProtocol(&body, batch); err != nil {
+		return retry.Permanent(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	switch {
+	case s.Token != "":
+		req.Header.Set("Authorization", "Token "+s.Token)
+	case s.Username != "":
+		req.SetBasicAuth(s.Username, s.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("InfluxDB returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return retry.Permanent(err)
+}
@@ -0,0 +1,67 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+	"glass/pkg/syslog"
+
+	"github.com/coreos/go-systemd/v22/journal"
+)
+
+// JournaldSink writes every metric to the systemd journal as its own entry,
+// with the metric in GLASS_* fields so it can be matched with journalctl,
+// e.g. journalctl GLASS_METRIC=cpu.usage.
+type JournaldSink struct {
+	Severity   string `json:"severity"`
+	Identifier string `json:"identifier"`
+
+	priority journal.Priority
+}
+
+func NewJournaldSink(cfg config.OutputConfig) (*JournaldSink, error) {
+	s := &JournaldSink{Severity: "info", Identifier: "glass"}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	sev, ok := syslog.ParseSeverity(s.Severity)
+	if !ok {
+		return nil, fmt.Errorf("unknown severity %q", s.Severity)
+	}
+	// Journal priorities are the syslog severities.
+	s.priority = journal.Priority(sev)
+	if !journal.Enabled() {
+		return nil, errors.New("the systemd journal is not available")
+	}
+	return s, nil
+}
+
+func (s *JournaldSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	for _, m := range metrics {
+		fields := map[string]string{
+			"SYSLOG_IDENTIFIER": s.Identifier,
+			"GLASS_COLLECTOR":   collector,
+			"GLASS_METRIC":      m.Name,
+			"GLASS_KIND":        string(m.Kind),
+			"GLASS_VALUE":       strconv.FormatFloat(m.Value, 'f', -1, 64),
+		}
+		if m.Unit != "" {
+			fields["GLASS_UNIT"] = m.Unit
+		}
+		for k, v := range m.Labels {
+			fields["GLASS_LABEL_"+syslog.JournalField(k)] = v
+		}
+		if err := journal.Send(metricText(m), s.priority, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *JournaldSink) Close() error {
+	return nil
+}
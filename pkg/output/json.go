@@ -0,0 +1,87 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// JSONSink writes metrics as JSON. In one-shot mode every write is buffered
+// and a single document keyed by collector name is emitted on Close; in
+// stream mode each collector run is written immediately as one NDJSON line.
+type JSONSink struct {
+	Path   string `json:"path"`
+	Stream bool   `json:"stream"`
+
+	mu        sync.Mutex
+	w         io.Writer
+	closer    io.Closer
+	collected map[string][]metric.Metric
+}
+
+type jsonDocument struct {
+	Timestamp  time.Time                  `json:"timestamp"`
+	Collectors map[string][]metric.Metric `json:"collectors"`
+}
+
+type jsonLine struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Collector string          `json:"collector"`
+	Metrics   []metric.Metric `json:"metrics"`
+}
+
+func NewJSONSink(cfg config.OutputConfig, stream bool) (*JSONSink, error) {
+	j := &JSONSink{Stream: stream}
+	if err := cfg.Decode(j); err != nil {
+		return nil, err
+	}
+	j.w = os.Stdout
+	if j.Path != "" && j.Path != "-" {
+		f, err := os.OpenFile(j.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening JSON output: %w", err)
+		}
+		j.w, j.closer = f, f
+	}
+	j.collected = make(map[string][]metric.Metric)
+	return j, nil
+}
+
+func (j *JSONSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.Stream {
+		j.collected[collector] = append(j.collected[collector], metrics...)
+		return nil
+	}
+	return json.NewEncoder(j.w).Encode(jsonLine{
+		Timestamp: time.Now(),
+		Collector: collector,
+		Metrics:   metrics,
+	})
+}
+
+func (j *JSONSink) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var err error
+	if !j.Stream {
+		enc := json.NewEncoder(j.w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(jsonDocument{Timestamp: time.Now(), Collectors: j.collected})
+		j.collected = make(map[string][]metric.Metric)
+	}
+	if j.closer != nil {
+		if cerr := j.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
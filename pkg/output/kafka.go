@@ -0,0 +1,174 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaSink publishes each collector run as one message, keyed by host name
+// so that a host's metrics stay in order on a single partition.
+type KafkaSink struct {
+	Brokers []string `json:"brokers"`
+	Topic   string   `json:"topic"`
+	// Format is "json" or "avro". Avro messages use the Confluent wire
+	// format, with the schema registered at SchemaRegistry.
+	Format         string `json:"format"`
+	SchemaRegistry string `json:"schema_registry"`
+	// Compression is none, gzip, snappy, lz4 or zstd.
+	Compression string `json:"compression"`
+	// Acks is all, one or none.
+	Acks         string          `json:"acks"`
+	BatchSize    int             `json:"batch_size"`
+	BatchTimeout config.Duration `json:"batch_timeout"`
+	Retries      int             `json:"retries"`
+	Timeout      config.Duration `json:"timeout"`
+
+	host   string
+	writer *kafka.Writer
+	client *http.Client
+
+	mu       sync.Mutex
+	schemaID int32
+}
+
+// collectionMessage is the JSON form of a collector run for sinks whose
+// messages leave the host, so each carries the host name.
+type collectionMessage struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Host      string          `json:"host"`
+	Collector string          `json:"collector"`
+	Metrics   []metric.Metric `json:"metrics"`
+}
+
+func NewKafkaSink(cfg config.OutputConfig) (*KafkaSink, error) {
+	s := &KafkaSink{
+		Topic:        "glass",
+		Format:       "json",
+		Compression:  "none",
+		Acks:         "all",
+		BatchSize:    100,
+		BatchTimeout: config.Duration(time.Second),
+		Retries:      3,
+		Timeout:      config.Duration(10 * time.Second),
+	}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	if len(s.Brokers) == 0 {
+		return nil, errors.New("brokers is required")
+	}
+	if s.Topic == "" {
+		return nil, errors.New("topic is required")
+	}
+	switch s.Format {
+	case "json":
+	case "avro":
+		if s.SchemaRegistry == "" {
+			return nil, errors.New("schema_registry is required for avro")
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q", s.Format)
+	}
+	var codec kafka.Compression
+	switch s.Compression {
+	case "none", "":
+	case "gzip":
+		codec = kafka.Gzip
+	case "snappy":
+		codec = kafka.Snappy
+	case "lz4":
+		codec = kafka.Lz4
+	case "zstd":
+		codec = kafka.Zstd
+	default:
+		return nil, fmt.Errorf("unknown compression %q", s.Compression)
+	}
+	var acks kafka.RequiredAcks
+	switch s.Acks {
+	case "all":
+		acks = kafka.RequireAll
+	case "one":
+		acks = kafka.RequireOne
+	case "none":
+		acks = kafka.RequireNone
+	default:
+		return nil, fmt.Errorf("unknown acks %q", s.Acks)
+	}
+
+	s.host, _ = os.Hostname()
+	s.client = &http.Client{Timeout: s.Timeout.Duration()}
+	s.writer = &kafka.Writer{
+		Addr:         kafka.TCP(s.Brokers...),
+		Topic:        s.Topic,
+		Balancer:     &kafka.Hash{},
+		MaxAttempts:  s.Retries + 1,
+		BatchSize:    s.BatchSize,
+		BatchTimeout: s.BatchTimeout.Duration(),
+		WriteTimeout: s.Timeout.Duration(),
+		RequiredAcks: acks,
+		Compression:  codec,
+		// Writes return straight away so a slow broker never holds up
+		// collection; failures are reported once retries run out.
+		Async: true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				log.Err(err).Int("messages", len(messages)).Msg("Error publishing to Kafka, dropping messages")
+			}
+		},
+	}
+	return s, nil
+}
+
+func (s *KafkaSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	now := time.Now()
+	var value []byte
+	if s.Format == "avro" {
+		id, err := s.schema(ctx)
+		if err != nil {
+			return fmt.Errorf("registering Avro schema: %w", err)
+		}
+		var buf bytes.Buffer
+		encodeAvroCollection(&buf, id, now, s.host, collector, metrics)
+		value = buf.Bytes()
+	} else {
+		var err error
+		value, err = json.Marshal(collectionMessage{Timestamp: now, Host: s.host, Collector: collector, Metrics: metrics})
+		if err != nil {
+			return err
+		}
+	}
+	return s.writer.WriteMessages(ctx, kafka.Message{Key: []byte(s.host), Value: value, Time: now})
+}
+
+// schema returns the registered schema ID, registering it on first use so
+// that an unreachable registry doesn't stop glass from starting.
+func (s *KafkaSink) schema(ctx context.Context) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.schemaID != 0 {
+		return s.schemaID, nil
+	}
+	id, err := registerSchema(ctx, s.client, s.SchemaRegistry, s.Topic+"-value", collectionSchema)
+	if err != nil {
+		return 0, err
+	}
+	s.schemaID = id
+	return id, nil
+}
+
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,92 @@
+package output
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"glass/pkg/metric"
+)
+
+// EncodeLineProtocol writes metrics as InfluxDB line protocol. The part of
+// the metric name before the first dot becomes the measurement and the rest
+// the field key, so "mem.used_percent" is written as measurement "mem" with
+// field "used_percent". Metrics sharing a measurement, label set and
+// timestamp are folded into a single line.
+func EncodeLineProtocol(w io.Writer, metrics []metric.Metric) error {
+	type point struct {
+		series    string
+		timestamp int64
+		fields    []string
+	}
+	var points []*point
+	index := make(map[string]*point)
+	for _, m := range metrics {
+		measurement, field, ok := strings.Cut(m.Name, ".")
+		if !ok {
+			field = "value"
+		}
+		var series strings.Builder
+		series.WriteString(lpMeasurementEscaper.Replace(measurement))
+		for _, k := range m.LabelKeys() {
+			if m.Labels[k] == "" {
+				continue
+			}
+			series.WriteByte(',')
+			series.WriteString(lpTagEscaper.Replace(k))
+			series.WriteByte('=')
+			series.WriteString(lpTagEscaper.Replace(m.Labels[k]))
+		}
+		ts := m.Timestamp.UnixNano()
+		key := series.String() + " " + strconv.FormatInt(ts, 10)
+		p, ok := index[key]
+		if !ok {
+			p = &point{series: series.String(), timestamp: ts}
+			index[key] = p
+			points = append(points, p)
+		}
+		p.fields = append(p.fields, lpTagEscaper.Replace(field)+"="+strconv.FormatFloat(m.Value, 'f', -1, 64))
+	}
+
+	var sb strings.Builder
+	for _, p := range points {
+		sort.Strings(p.fields)
+		sb.WriteString(p.series)
+		sb.WriteByte(' ')
+		sb.WriteString(strings.Join(p.fields, ","))
+		sb.WriteByte(' ')
+		sb.WriteString(strconv.FormatInt(p.timestamp, 10))
+		sb.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+var (
+	lpMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	lpTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+)
+
+// LineProtocolSink writes each collection to w as line protocol as soon as
+// it arrives, for programs reading glass's output, such as Telegraf.
+type LineProtocolSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLineProtocolSink(w io.Writer) *LineProtocolSink {
+	return &LineProtocolSink{w: w}
+}
+
+func (s *LineProtocolSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return EncodeLineProtocol(s.w, metrics)
+}
+
+func (s *LineProtocolSink) Close() error {
+	return nil
+}
@@ -0,0 +1,41 @@
+package output
+
+import (
+	"context"
+
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// LogSink writes every metric as a zerolog line.
+type LogSink struct {
+	logger zerolog.Logger
+}
+
+func NewLogSink() *LogSink {
+	return &LogSink{logger: log.Logger}
+}
+
+func (l *LogSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	for _, m := range metrics {
+		event := l.logger.Info().Str("collector", collector).Str("metric", m.Name).Float64("value", m.Value)
+		if m.Unit != "" {
+			event = event.Str("unit", m.Unit)
+		}
+		if len(m.Labels) > 0 {
+			labels := zerolog.Dict()
+			for _, k := range m.LabelKeys() {
+				labels = labels.Str(k, m.Labels[k])
+			}
+			event = event.Dict("labels", labels)
+		}
+		event.Msg("")
+	}
+	return nil
+}
+
+func (l *LogSink) Close() error {
+	return nil
+}
@@ -0,0 +1,172 @@
+package output
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rs/zerolog/log"
+)
+
+// MQTTSink publishes each collector run as a JSON message on a per-host,
+// per-collector topic. The client reconnects on its own; messages with QoS 1
+// or 2 are held in memory until they can be delivered.
+type MQTTSink struct {
+	// Broker is a URL such as tcp://host:1883, ssl://host:8883 or
+	// ws://host:80/mqtt.
+	Broker   string `json:"broker"`
+	ClientID string `json:"client_id"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Topic may contain {host} and {collector}.
+	Topic  string `json:"topic"`
+	QoS    byte   `json:"qos"`
+	Retain bool   `json:"retain"`
+	// StatusTopic gets a retained "online" on connect and, as the last
+	// will, "offline" when glass drops off. Empty disables both.
+	StatusTopic string `json:"status_topic"`
+
+	CAFile             string `json:"ca_file"`
+	CertFile           string `json:"cert_file"`
+	KeyFile            string `json:"key_file"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+
+	Timeout config.Duration `json:"timeout"`
+
+	host   string
+	client mqtt.Client
+}
+
+func NewMQTTSink(cfg config.OutputConfig) (*MQTTSink, error) {
+	s := &MQTTSink{
+		Broker:      "tcp://127.0.0.1:1883",
+		Topic:       "glass/{host}/{collector}",
+		QoS:         1,
+		StatusTopic: "glass/{host}/status",
+		Timeout:     config.Duration(10 * time.Second),
+	}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	if s.Broker == "" {
+		return nil, errors.New("broker is required")
+	}
+	if s.Topic == "" {
+		return nil, errors.New("topic is required")
+	}
+	if s.QoS > 2 {
+		return nil, fmt.Errorf("invalid qos %d: must be 0, 1 or 2", s.QoS)
+	}
+	s.host, _ = os.Hostname()
+	if s.ClientID == "" {
+		s.ClientID = "glass-" + s.host
+	}
+	tlsConfig, err := tlsClientConfig(s.CAFile, s.CertFile, s.KeyFile, s.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(s.Broker).
+		SetClientID(s.ClientID).
+		SetUsername(s.Username).
+		SetPassword(s.Password).
+		SetTLSConfig(tlsConfig).
+		SetConnectTimeout(s.Timeout.Duration()).
+		SetWriteTimeout(s.Timeout.Duration()).
+		SetAutoReconnect(true).
+		// Keep trying in the background rather than failing startup when
+		// the broker is down.
+		SetConnectRetry(true).
+		SetOnConnectHandler(s.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Warn().Err(err).Str("broker", s.Broker).Msg("Lost connection to MQTT broker")
+		})
+	if s.StatusTopic != "" {
+		s.StatusTopic = s.expand(s.StatusTopic, "")
+		opts.SetWill(s.StatusTopic, "offline", s.QoS, true)
+	}
+	s.client = mqtt.NewClient(opts)
+	// The first connection starts a clean session, which discards anything
+	// published before it, so give it a chance to come up.
+	if token := s.client.Connect(); !token.WaitTimeout(s.Timeout.Duration()) {
+		log.Warn().Str("broker", s.Broker).Msg("MQTT broker not reachable yet, still trying")
+	}
+	return s, nil
+}
+
+func (s *MQTTSink) onConnect(c mqtt.Client) {
+	log.Info().Str("broker", s.Broker).Msg("Connected to MQTT broker")
+	if s.StatusTopic != "" {
+		c.Publish(s.StatusTopic, s.QoS, true, "online")
+	}
+}
+
+func (s *MQTTSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	payload, err := json.Marshal(collectionMessage{Timestamp: time.Now(), Host: s.host, Collector: collector, Metrics: metrics})
+	if err != nil {
+		return err
+	}
+	token := s.client.Publish(s.expand(s.Topic, collector), s.QoS, s.Retain, payload)
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(s.Timeout.Duration()):
+		// QoS 1 and 2 messages stay queued and go out on reconnect.
+		return errors.New("timed out publishing to MQTT")
+	}
+}
+
+func (s *MQTTSink) Close() error {
+	// A clean disconnect doesn't trigger the last will, so say goodbye
+	// explicitly.
+	if s.StatusTopic != "" && s.client.IsConnectionOpen() {
+		s.client.Publish(s.StatusTopic, s.QoS, true, "offline").WaitTimeout(s.Timeout.Duration())
+	}
+	s.client.Disconnect(uint(s.Timeout.Duration().Milliseconds()))
+	return nil
+}
+
+// expand fills in a topic template. MQTT wildcards and separators in the
+// values would change the topic's shape, so they are replaced.
+func (s *MQTTSink) expand(topic, collector string) string {
+	return strings.NewReplacer("{host}", mqttLevel(s.host), "{collector}", mqttLevel(collector)).Replace(topic)
+}
+
+var mqttLevel = strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace
+
+// tlsClientConfig builds the client side of a TLS connection from PEM
+// files. All of them are optional.
+func tlsClientConfig(caFile, certFile, keyFile string, insecure bool) (*tls.Config, error) {
+	c := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
@@ -0,0 +1,271 @@
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+
+	"glass/pkg/cloud"
+	"glass/pkg/config"
+	"glass/pkg/metric"
+	"glass/pkg/retry"
+)
+
+// OTLPSink pushes metrics to an OpenTelemetry collector over OTLP/HTTP
+// using the JSON encoding. The resource describes the host, with the
+// cloud.* and host.id attributes of the OpenTelemetry conventions when the
+// cloud instance is known.
+type OTLPSink struct {
+	Endpoint           string            `json:"endpoint"`
+	Headers            map[string]string `json:"headers"`
+	ResourceAttributes map[string]string `json:"resource_attributes"`
+	Gzip               bool              `json:"gzip"`
+
+	FlushInterval config.Duration `json:"flush_interval"`
+	BatchSize     int             `json:"batch_size"`
+	MaxBuffer     int             `json:"max_buffer"`
+	Retries       int             `json:"retries"`
+	Timeout       config.Duration `json:"timeout"`
+
+	resource otlpResource
+	start    time.Time
+	client   *http.Client
+	batcher  *batcher[metric.Metric]
+}
+
+func NewOTLPSink(cfg config.OutputConfig, md *cloud.Metadata) (*OTLPSink, error) {
+	s := &OTLPSink{
+		Endpoint:      "http://localhost:4318/v1/metrics",
+		FlushInterval: config.Duration(10 * time.Second),
+		BatchSize:     5000,
+		MaxBuffer:     100000,
+		Retries:       3,
+		Timeout:       config.Duration(10 * time.Second),
+	}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	if s.Endpoint == "" {
+		return nil, errors.New("endpoint is required")
+	}
+
+	attrs := map[string]string{
+		"service.name": "glass",
+		"os.type":      runtime.GOOS,
+		"host.arch":    runtime.GOARCH,
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		attrs["host.name"] = hostname
+	}
+	if md != nil {
+		for k, v := range map[string]string{
+			"cloud.provider":          md.Provider,
+			"cloud.region":            md.Region,
+			"cloud.availability_zone": md.Zone,
+			"host.id":                 md.InstanceID,
+			"host.type":               md.InstanceType,
+		} {
+			if v != "" {
+				attrs[k] = v
+			}
+		}
+	}
+	for k, v := range s.ResourceAttributes {
+		attrs[k] = v
+	}
+	s.resource = otlpResource{Attributes: otlpAttributes(attrs)}
+	s.start = time.Now()
+	s.client = &http.Client{Timeout: s.Timeout.Duration()}
+	s.batcher = newBatcher("otlp", s.FlushInterval.Duration(), s.BatchSize, s.MaxBuffer, s.Retries, s.send)
+	return s, nil
+}
+
+func (s *OTLPSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	s.batcher.add(metrics)
+	return nil
+}
+
+func (s *OTLPSink) bufferStats() Stats {
+	return s.batcher.bufferStats()
+}
+
+func (s *OTLPSink) Close() error {
+	return s.batcher.close(s.Timeout.Duration())
+}
+
+func (s *OTLPSink) send(ctx context.Context, batch []metric.Metric) error {
+	payload, err := json.Marshal(s.request(batch))
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	var body io.Reader = bytes.NewReader(payload)
+	if s.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(payload)
+		zw.Close()
+		body = &buf
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Endpoint, body)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("OTLP endpoint returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return err
+	}
+	return retry.Permanent(err)
+}
+
+// request groups the batch into one OTLP metric per name.
+func (s *OTLPSink) request(batch []metric.Metric) otlpRequest {
+	var metrics []*otlpMetric
+	byName := make(map[string]*otlpMetric)
+	start := strconv.FormatInt(s.start.UnixNano(), 10)
+	for _, m := range batch {
+		om, ok := byName[m.Name]
+		if !ok {
+			om = &otlpMetric{Name: m.Name, Unit: otlpUnit(m.Unit)}
+			if m.Kind == metric.Counter {
+				om.Sum = &otlpSum{AggregationTemporality: 2, IsMonotonic: true}
+			} else {
+				om.Gauge = &otlpGauge{}
+			}
+			byName[m.Name] = om
+			metrics = append(metrics, om)
+		}
+		dp := otlpDataPoint{
+			Attributes:   otlpAttributes(m.Labels),
+			TimeUnixNano: strconv.FormatInt(m.Timestamp.UnixNano(), 10),
+			AsDouble:     m.Value,
+		}
+		if om.Sum != nil {
+			dp.StartTimeUnixNano = start
+			om.Sum.DataPoints = append(om.Sum.DataPoints, dp)
+		} else {
+			om.Gauge.DataPoints = append(om.Gauge.DataPoints, dp)
+		}
+	}
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: s.resource,
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: "glass"},
+			Metrics: metrics,
+		}},
+	}}}
+}
+
+var otlpUnits = map[string]string{
+	"bytes":   "By",
+	"seconds": "s",
+	"percent": "%",
+	"celsius": "Cel",
+	"watts":   "W",
+	"volts":   "V",
+	"amps":    "A",
+	"bytes/s": "By/s",
+}
+
+func otlpUnit(unit string) string {
+	if u, ok := otlpUnits[unit]; ok {
+		return u
+	}
+	return unit
+}
+
+func otlpAttributes(labels map[string]string) []otlpKeyValue {
+	if len(labels) == 0 {
+		return nil
+	}
+	kvs := make([]otlpKeyValue, 0, len(labels))
+	for k, v := range labels {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpAnyValue{StringValue: v}})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs
+}
+
+// The types below mirror the protobuf JSON mapping of
+// opentelemetry.proto.collector.metrics.v1.ExportMetricsServiceRequest.
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope     `json:"scope"`
+	Metrics []*otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetric struct {
+	Name  string     `json:"name"`
+	Unit  string     `json:"unit,omitempty"`
+	Gauge *otlpGauge `json:"gauge,omitempty"`
+	Sum   *otlpSum   `json:"sum,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	IsMonotonic            bool            `json:"isMonotonic"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	AsDouble          float64        `json:"asDouble"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"glass/pkg/cloud"
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// Sink receives the metrics produced by a single collector run. Close
+// flushes anything still buffered.
+type Sink interface {
+	Write(ctx context.Context, collector string, metrics []metric.Metric) error
+	Close() error
+}
+
+// New builds the sinks described by the config. stream is set when glass
+// runs continuously rather than collecting once, in which case each sink
+// gets a Queue of its own, sized by its "buffer" option. md is the cloud
+// instance, if one was detected, for outputs that describe the host.
+func New(cfgs []config.OutputConfig, stream bool, md *cloud.Metadata) (Sink, error) {
+	var sinks Multi
+	seen := map[string]int{}
+	for _, cfg := range cfgs {
+		common := struct {
+			Buffer int `json:"buffer"`
+		}{Buffer: 100}
+		if err := cfg.Decode(&common); err != nil {
+			return nil, fmt.Errorf("configuring %s output: %w", cfg.Type, err)
+		}
+		var (
+			sink Sink
+			err  error
+		)
+		switch cfg.Type {
+		case "log":
+			sink = NewLogSink()
+		case "json":
+			sink, err = NewJSONSink(cfg, stream)
+		case "influxdb":
+			sink, err = NewInfluxSink(cfg)
+		case "otlp":
+			sink, err = NewOTLPSink(cfg, md)
+		case "statsd":
+			sink, err = NewStatsDSink(cfg)
+		case "graphite":
+			sink, err = NewGraphiteSink(cfg)
+		case "kafka":
+			sink, err = NewKafkaSink(cfg)
+		case "mqtt":
+			sink, err = NewMQTTSink(cfg)
+		case "syslog":
+			sink, err = NewSyslogSink(cfg)
+		case "journald":
+			sink, err = NewJournaldSink(cfg)
+		case "aggregator":
+			sink, err = NewAggregatorSink(cfg)
+		case "zabbix":
+			sink, err = NewZabbixSink(cfg)
+		default:
+			return nil, fmt.Errorf("unknown output type %q", cfg.Type)
+		}
+		if err != nil {
+			sinks.Close()
+			return nil, fmt.Errorf("configuring %s output: %w", cfg.Type, err)
+		}
+		if stream {
+			// Outputs are named by type, numbered when one type is used twice.
+			name := cfg.Type
+			if seen[cfg.Type]++; seen[cfg.Type] > 1 {
+				name += "-" + strconv.Itoa(seen[cfg.Type])
+			}
+			sink = NewQueue(name, sink, common.Buffer)
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 1 {
+		return sinks[0], nil
+	}
+	return sinks, nil
+}
+
+// Multi writes to every sink in turn.
+type Multi []Sink
+
+func (m Multi) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Write(ctx, collector, metrics); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m Multi) Close() error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,158 @@
+package output
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Queue decouples a sink from collection: writes are buffered and handed to
+// the sink by a goroutine of its own, so a slow or failing sink neither
+// blocks the scheduler nor holds up the other outputs.
+type Queue struct {
+	name   string
+	sink   Sink
+	writes chan queuedWrite
+	done   chan struct{}
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+type queuedWrite struct {
+	collector string
+	metrics   []metric.Metric
+}
+
+// Stats reports on an output's buffering and delivery.
+type Stats struct {
+	Name string `json:"name"`
+	// Queued counts the metrics waiting to be delivered, including any the
+	// sink buffers itself.
+	Queued int `json:"queued"`
+	// Dropped counts the metrics discarded because a buffer was full or the
+	// destination rejected them.
+	Dropped           uint64    `json:"dropped"`
+	Writes            uint64    `json:"writes"`
+	Errors            uint64    `json:"errors"`
+	ConsecutiveErrors int       `json:"consecutive_errors"`
+	LastError         string    `json:"last_error,omitempty"`
+	LastSuccess       time.Time `json:"last_success"`
+}
+
+// bufferedSink is implemented by sinks that batch metrics themselves, so
+// that a write succeeding says nothing about delivery.
+type bufferedSink interface {
+	bufferStats() Stats
+}
+
+// NewQueue buffers up to size collector runs for sink. When the buffer is
+// full the oldest run is dropped.
+func NewQueue(name string, sink Sink, size int) *Queue {
+	q := &Queue{
+		name:   name,
+		sink:   sink,
+		writes: make(chan queuedWrite, max(size, 1)),
+		done:   make(chan struct{}),
+		stats:  Stats{Name: name},
+	}
+	go q.loop()
+	return q
+}
+
+func (q *Queue) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	w := queuedWrite{collector: collector, metrics: metrics}
+	q.mu.Lock()
+	q.stats.Queued += len(metrics)
+	q.mu.Unlock()
+	for {
+		select {
+		case q.writes <- w:
+			return nil
+		default:
+		}
+		select {
+		case old := <-q.writes:
+			q.mu.Lock()
+			q.stats.Queued -= len(old.metrics)
+			q.stats.Dropped += uint64(len(old.metrics))
+			q.mu.Unlock()
+			log.Warn().Str("output", q.name).Str("collector", old.collector).Int("dropped", len(old.metrics)).
+				Msg("Output queue full, dropping oldest metrics")
+		default:
+		}
+	}
+}
+
+func (q *Queue) loop() {
+	defer close(q.done)
+	for w := range q.writes {
+		err := q.sink.Write(context.Background(), w.collector, w.metrics)
+		q.mu.Lock()
+		q.stats.Queued -= len(w.metrics)
+		q.stats.Writes++
+		if err != nil {
+			q.stats.Errors++
+			q.stats.ConsecutiveErrors++
+			q.stats.LastError = err.Error()
+		} else {
+			q.stats.ConsecutiveErrors = 0
+			q.stats.LastError = ""
+			q.stats.LastSuccess = time.Now()
+		}
+		q.mu.Unlock()
+		if err != nil {
+			log.Err(err).Str("output", q.name).Str("collector", w.collector).Msg("Error writing metrics")
+		}
+	}
+}
+
+// Stats returns the queue's counters. For a sink that batches, buffering
+// is the sum of both and delivery is the sink's own.
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	st := q.stats
+	q.mu.Unlock()
+	if b, ok := q.sink.(bufferedSink); ok {
+		bs := b.bufferStats()
+		bs.Name = st.Name
+		bs.Queued += st.Queued
+		bs.Dropped += st.Dropped
+		return bs
+	}
+	return st
+}
+
+// Close delivers what is still queued, giving up after a while on a sink
+// that has stopped accepting writes, then closes the sink.
+func (q *Queue) Close() error {
+	close(q.writes)
+	select {
+	case <-q.done:
+	case <-time.After(30 * time.Second):
+		log.Warn().Str("output", q.name).Int("pending", len(q.writes)).Msg("Timed out draining output queue")
+	}
+	return q.sink.Close()
+}
+
+// SinkStats gathers the Stats of every queued output behind s, looking
+// through Multi and through wrappers that have an Unwrap method.
+func SinkStats(s Sink) []Stats {
+	switch s := s.(type) {
+	case Multi:
+		var stats []Stats
+		for _, sink := range s {
+			stats = append(stats, SinkStats(sink)...)
+		}
+		return stats
+	case *Queue:
+		return []Stats{s.Stats()}
+	case interface{ Unwrap() Sink }:
+		return SinkStats(s.Unwrap())
+	}
+	return nil
+}
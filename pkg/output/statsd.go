@@ -0,0 +1,165 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+)
+
+// StatsDSink sends metrics as StatsD over UDP. Gauges are sent as gauges;
+// counters, which glass reports as running totals, are sent as the increase
+// since the previous sample.
+type StatsDSink struct {
+	Address string `json:"address"`
+	Prefix  string `json:"prefix"`
+	// Format is "dogstatsd", which sends labels as tags, or "statsd", which
+	// has no tags and appends label values to the metric name instead.
+	Format        string          `json:"format"`
+	MaxPacketSize int             `json:"max_packet_size"`
+	FlushInterval config.Duration `json:"flush_interval"`
+	MaxBuffer     int             `json:"max_buffer"`
+
+	conn    net.Conn
+	batcher *batcher[metric.Metric]
+
+	mu   sync.Mutex
+	last map[string]float64
+}
+
+func NewStatsDSink(cfg config.OutputConfig) (*StatsDSink, error) {
+	s := &StatsDSink{
+		Address: "127.0.0.1:8125",
+		Format:  "dogstatsd",
+		// Fits in a single Ethernet frame, as DogStatsD recommends.
+		MaxPacketSize: 1432,
+		FlushInterval: config.Duration(10 * time.Second),
+		MaxBuffer:     100000,
+		last:          make(map[string]float64),
+	}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	if s.Format != "dogstatsd" && s.Format != "statsd" {
+		return nil, fmt.Errorf("unknown format %q", s.Format)
+	}
+	if s.MaxPacketSize < 64 {
+		return nil, fmt.Errorf("max_packet_size %d is too small", s.MaxPacketSize)
+	}
+	conn, err := net.Dial("udp", s.Address)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	// UDP sends don't fail in ways that retrying would fix.
+	s.batcher = newBatcher("statsd", s.FlushInterval.Duration(), s.MaxBuffer, s.MaxBuffer, 0, s.send)
+	return s, nil
+}
+
+func (s *StatsDSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	out := make([]metric.Metric, 0, len(metrics))
+	s.mu.Lock()
+	for _, m := range metrics {
+		if m.Kind == metric.Counter {
+			key := m.SeriesKey()
+			prev, seen := s.last[key]
+			s.last[key] = m.Value
+			// The first sample only sets the baseline, and a drop means the
+			// counter was reset.
+			if !seen || m.Value < prev {
+				continue
+			}
+			m.Value -= prev
+		}
+		out = append(out, m)
+	}
+	s.mu.Unlock()
+	s.batcher.add(out)
+	return nil
+}
+
+func (s *StatsDSink) bufferStats() Stats {
+	return s.batcher.bufferStats()
+}
+
+func (s *StatsDSink) Close() error {
+	err := s.batcher.close(5 * time.Second)
+	s.conn.Close()
+	return err
+}
+
+func (s *StatsDSink) send(ctx context.Context, batch []metric.Metric) error {
+	var packet bytes.Buffer
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := s.conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+	for _, m := range batch {
+		for _, line := range s.lines(m) {
+			if packet.Len() > 0 && packet.Len()+1+len(line) > s.MaxPacketSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			if packet.Len() > 0 {
+				packet.WriteByte('\n')
+			}
+			packet.WriteString(line)
+		}
+	}
+	return flush()
+}
+
+// lines renders m as StatsD lines. It is usually one line, but negative
+// gauges need two since StatsD reads a leading sign as a relative change.
+func (s *StatsDSink) lines(m metric.Metric) []string {
+	name := s.Prefix + statsdName(m.Name)
+	var tags string
+	if s.Format == "statsd" {
+		for _, k := range m.LabelKeys() {
+			if v := m.Labels[k]; v != "" {
+				name += "." + pathSegment(v)
+			}
+		}
+	} else if len(m.Labels) > 0 {
+		pairs := make([]string, 0, len(m.Labels))
+		for _, k := range m.LabelKeys() {
+			pairs = append(pairs, statsdTag(k)+":"+statsdTagValue(m.Labels[k]))
+		}
+		tags = "|#" + strings.Join(pairs, ",")
+	}
+
+	value := strconv.FormatFloat(m.Value, 'f', -1, 64)
+	if m.Kind == metric.Counter {
+		return []string{name + ":" + value + "|c" + tags}
+	}
+	line := name + ":" + value + "|g" + tags
+	if m.Value < 0 {
+		return []string{name + ":0|g" + tags, line}
+	}
+	return []string{line}
+}
+
+// statsdName replaces the characters StatsD uses as separators.
+var statsdName = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", " ", "_", "\n", "_", "/", "_").Replace
+
+// pathSegment also replaces dots, so that a label value such as an
+// address stays a single segment of the name.
+var pathSegment = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", " ", "_", "\n", "_", "/", "_", ".", "_").Replace
+
+var statsdTag = strings.NewReplacer(":", "_", "|", "_", ",", "_", "#", "_", "\n", "_").Replace
+
+// statsdTagValue keeps colons, since only the first one in a tag separates
+// the key from the value.
+var statsdTagValue = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_").Replace
@@ -0,0 +1,112 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+	"glass/pkg/syslog"
+)
+
+// SyslogSink writes every metric as an RFC 5424 message, with the metric in
+// a metric@32473 structured data element and its labels in labels@32473.
+type SyslogSink struct {
+	// Network is udp, tcp, unix or unixgram; empty means the local daemon
+	// on /dev/log.
+	Network  string `json:"network"`
+	Address  string `json:"address"`
+	Facility string `json:"facility"`
+	Severity string `json:"severity"`
+	AppName  string `json:"app_name"`
+
+	severity syslog.Severity
+	writer   *syslog.Writer
+}
+
+func NewSyslogSink(cfg config.OutputConfig) (*SyslogSink, error) {
+	s := &SyslogSink{Facility: "daemon", Severity: "info", AppName: "glass"}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	sev, ok := syslog.ParseSeverity(s.Severity)
+	if !ok {
+		return nil, fmt.Errorf("unknown severity %q", s.Severity)
+	}
+	s.severity = sev
+	w, err := syslog.Dial(s.Network, s.Address, s.Facility, s.AppName)
+	if err != nil {
+		return nil, err
+	}
+	s.writer = w
+	return s, nil
+}
+
+func (s *SyslogSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	var errs []error
+	for _, m := range metrics {
+		params := []syslog.Param{
+			{Name: "collector", Value: collector},
+			{Name: "name", Value: m.Name},
+			{Name: "kind", Value: string(m.Kind)},
+			{Name: "value", Value: strconv.FormatFloat(m.Value, 'f', -1, 64)},
+		}
+		if m.Unit != "" {
+			params = append(params, syslog.Param{Name: "unit", Value: m.Unit})
+		}
+		data := []syslog.Element{{ID: "metric@" + syslog.EnterpriseID, Params: params}}
+		if len(m.Labels) > 0 {
+			labels := syslog.Element{ID: "labels@" + syslog.EnterpriseID}
+			for _, k := range m.LabelKeys() {
+				labels.Params = append(labels.Params, syslog.Param{Name: k, Value: m.Labels[k]})
+			}
+			data = append(data, labels)
+		}
+		err := s.writer.Send(syslog.Message{
+			Severity: s.severity,
+			MsgID:    "metric",
+			Time:     m.Timestamp,
+			Data:     data,
+			Text:     metricText(m),
+		})
+		if err != nil {
+			// The connection is gone; the rest would fail the same way.
+			errs = append(errs, err)
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *SyslogSink) Close() error {
+	return s.writer.Close()
+}
+
+// metricText renders m for humans reading the log, as
+// "name{label=value,...} value unit".
+func metricText(m metric.Metric) string {
+	var sb strings.Builder
+	sb.WriteString(m.Name)
+	if len(m.Labels) > 0 {
+		sb.WriteByte('{')
+		for i, k := range m.LabelKeys() {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(k)
+			sb.WriteByte('=')
+			sb.WriteString(m.Labels[k])
+		}
+		sb.WriteByte('}')
+	}
+	sb.WriteByte(' ')
+	sb.WriteString(strconv.FormatFloat(m.Value, 'f', -1, 64))
+	if m.Unit != "" {
+		sb.WriteByte(' ')
+		sb.WriteString(m.Unit)
+	}
+	return sb.String()
+}
@@ -0,0 +1,46 @@
+package output
+
+import (
+	"context"
+
+	"glass/pkg/metric"
+)
+
+// Tag returns a sink that adds tags to the labels of every metric before
+// writing to next. A label the collector set keeps its value.
+func Tag(next Sink, tags map[string]string) Sink {
+	if len(tags) == 0 {
+		return next
+	}
+	return &tagSink{next: next, tags: tags}
+}
+
+type tagSink struct {
+	next Sink
+	tags map[string]string
+}
+
+func (s *tagSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	tagged := make([]metric.Metric, len(metrics))
+	for i, m := range metrics {
+		// Collectors may share label maps between metrics, so build a new one.
+		labels := make(map[string]string, len(s.tags)+len(m.Labels))
+		for k, v := range s.tags {
+			labels[k] = v
+		}
+		for k, v := range m.Labels {
+			labels[k] = v
+		}
+		m.Labels = labels
+		tagged[i] = m
+	}
+	return s.next.Write(ctx, collector, tagged)
+}
+
+func (s *tagSink) Unwrap() Sink {
+	return s.next
+}
+
+func (s *tagSink) Close() error {
+	return s.next.Close()
+}
@@ -0,0 +1,224 @@
+package output
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+	"glass/pkg/retry"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ZabbixSink pushes metrics to a Zabbix server or proxy with the sender
+// protocol, as zabbix_sender does. Each metric becomes the value of a
+// trapper item whose key is the metric name with the values of its labels,
+// in label-name order, as parameters: disk.used_percent[/dev/sda1,ext4,/].
+// Zabbix discards values for items that don't exist, so create them first.
+type ZabbixSink struct {
+	// Server is the trapper address of the Zabbix server or proxy.
+	Server string `json:"server"`
+	// Host is the host name as configured in Zabbix. It defaults to the
+	// hostname.
+	Host string `json:"host"`
+	// KeyPrefix is prepended to every item key.
+	KeyPrefix string `json:"key_prefix"`
+
+	FlushInterval config.Duration `json:"flush_interval"`
+	BatchSize     int             `json:"batch_size"`
+	MaxBuffer     int             `json:"max_buffer"`
+	Retries       int             `json:"retries"`
+	Timeout       config.Duration `json:"timeout"`
+
+	batcher *batcher[metric.Metric]
+}
+
+func NewZabbixSink(cfg config.OutputConfig) (*ZabbixSink, error) {
+	s := &ZabbixSink{
+		Server:        "127.0.0.1:10051",
+		FlushInterval: config.Duration(10 * time.Second),
+		// zabbix_sender sends at most 250 values per request too.
+		BatchSize: 250,
+		MaxBuffer: 100000,
+		Retries:   3,
+		Timeout:   config.Duration(10 * time.Second),
+	}
+	if err := cfg.Decode(s); err != nil {
+		return nil, err
+	}
+	if s.Server == "" {
+		return nil, errors.New("server is required")
+	}
+	if _, _, err := net.SplitHostPort(s.Server); err != nil {
+		s.Server = net.JoinHostPort(s.Server, "10051")
+	}
+	if s.Host == "" {
+		var err error
+		if s.Host, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("getting hostname: %w", err)
+		}
+	}
+	s.batcher = newBatcher("zabbix", s.FlushInterval.Duration(), s.BatchSize, s.MaxBuffer, s.Retries, s.send)
+	return s, nil
+}
+
+func (s *ZabbixSink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	s.batcher.add(metrics)
+	return nil
+}
+
+func (s *ZabbixSink) bufferStats() Stats {
+	return s.batcher.bufferStats()
+}
+
+func (s *ZabbixSink) Close() error {
+	return s.batcher.close(s.Timeout.Duration())
+}
+
+type zabbixValue struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Clock int64  `json:"clock"`
+	NS    int    `json:"ns"`
+}
+
+type zabbixRequest struct {
+	Request string        `json:"request"`
+	Data    []zabbixValue `json:"data"`
+	Clock   int64         `json:"clock"`
+	NS      int           `json:"ns"`
+}
+
+type zabbixResponse struct {
+	Response string `json:"response"`
+	Info     string `json:"info"`
+}
+
+// zabbixProcessed reads the counts out of a response's info, e.g.
+// "processed: 5; failed: 1; total: 6; seconds spent: 0.000057".
+var zabbixProcessed = regexp.MustCompile(`processed: (\d+); failed: (\d+)`)
+
+func (s *ZabbixSink) send(ctx context.Context, batch []metric.Metric) error {
+	now := time.Now()
+	req := zabbixRequest{Request: "sender data", Data: make([]zabbixValue, 0, len(batch)), Clock: now.Unix(), NS: now.Nanosecond()}
+	for _, m := range batch {
+		req.Data = append(req.Data, zabbixValue{
+			Host:  s.Host,
+			Key:   s.KeyPrefix + zabbixKey(m),
+			Value: strconv.FormatFloat(m.Value, 'f', -1, 64),
+			Clock: m.Timestamp.Unix(),
+			NS:    m.Timestamp.Nanosecond(),
+		})
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+
+	// The server closes the connection after answering, so each batch
+	// gets its own.
+	dialer := net.Dialer{Timeout: s.Timeout.Duration()}
+	conn, err := dialer.DialContext(ctx, "tcp", s.Server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(s.Timeout.Duration()))
+	if _, err := conn.Write(zabbixPacket(body)); err != nil {
+		return err
+	}
+	data, err := readZabbixPacket(conn)
+	if err != nil {
+		return fmt.Errorf("reading zabbix response: %w", err)
+	}
+	var resp zabbixResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("reading zabbix response: %w", err)
+	}
+	if resp.Response != "success" {
+		// The server understood the request and refused it; sending it
+		// again won't help.
+		return retry.Permanent(fmt.Errorf("zabbix returned %q: %s", resp.Response, resp.Info))
+	}
+	if m := zabbixProcessed.FindStringSubmatch(resp.Info); m != nil && m[2] != "0" {
+		log.Warn().Str("server", s.Server).Str("host", s.Host).Str("info", resp.Info).
+			Msg("Zabbix discarded values; check that the host and its trapper items exist")
+	}
+	return nil
+}
+
+// zabbixPacket frames data with the ZBXD header: protocol flags, then the
+// data length and a reserved field, both little-endian uint32.
+func zabbixPacket(data []byte) []byte {
+	out := make([]byte, 13, 13+len(data))
+	copy(out, "ZBXD\x01")
+	binary.LittleEndian.PutUint32(out[5:9], uint32(len(data)))
+	return append(out, data...)
+}
+
+// maxZabbixResponse bounds what readZabbixPacket accepts.
+const maxZabbixResponse = 16 << 20
+
+func readZabbixPacket(r io.Reader) ([]byte, error) {
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], []byte("ZBXD")) {
+		return nil, errors.New("not a zabbix protocol response")
+	}
+	flags := header[4]
+	size := binary.LittleEndian.Uint32(header[5:9])
+	if size > maxZabbixResponse {
+		return nil, fmt.Errorf("response of %d bytes is too large", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	if flags&0x02 == 0 {
+		return data, nil
+	}
+	// Compressed: the reserved field holds the uncompressed size.
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(io.LimitReader(zr, maxZabbixResponse))
+}
+
+// zabbixKey is the metric name with its label values as key parameters,
+// quoted where Zabbix needs them to be.
+func zabbixKey(m metric.Metric) string {
+	keys := m.LabelKeys()
+	if len(keys) == 0 {
+		return m.Name
+	}
+	params := make([]string, len(keys))
+	for i, k := range keys {
+		params[i] = zabbixParam(m.Labels[k])
+	}
+	return m.Name + "[" + strings.Join(params, ",") + "]"
+}
+
+func zabbixParam(v string) string {
+	if v == "" || !strings.ContainsAny(v, `,[]"`) && v[0] != ' ' {
+		return v
+	}
+	return `"` + strings.ReplaceAll(v, `"`, `\"`) + `"`
+}
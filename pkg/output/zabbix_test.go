@@ -0,0 +1,97 @@
+package output
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestZabbixPacket(t *testing.T) {
+	got := zabbixPacket([]byte(`{"request":"sender data"}`))
+	want := append([]byte("ZBXD\x01\x19\x00\x00\x00\x00\x00\x00\x00"), `{"request":"sender data"}`...)
+	if !bytes.Equal(got, want) {
+		t.Errorf("zabbixPacket() = %q, want %q", got, want)
+	}
+}
+
+// zabbixReply frames data as a server reply, compressing it as Zabbix 5.0
+// and later do when the flags ask for it.
+func zabbixReply(t *testing.T, flags byte, data string) []byte {
+	t.Helper()
+	body := []byte(data)
+	if flags&0x02 != 0 {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(body)
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		body = buf.Bytes()
+	}
+	header := []byte{'Z', 'B', 'X', 'D', flags, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(header[5:9], uint32(len(body)))
+	binary.LittleEndian.PutUint32(header[9:13], uint32(len(data)))
+	return append(header, body...)
+}
+
+func TestReadZabbixPacket(t *testing.T) {
+	const reply = `{"response":"success","info":"processed: 3; failed: 0; total: 3; seconds spent: 0.000055"}`
+	tests := []struct {
+		name    string
+		packet  []byte
+		want    string
+		wantErr string
+	}{
+		{name: "plain", packet: zabbixReply(t, 0x01, reply), want: reply},
+		{name: "compressed", packet: zabbixReply(t, 0x03, reply), want: reply},
+		{name: "empty", packet: zabbixReply(t, 0x01, ""), want: ""},
+		{name: "trailing data ignored", packet: append(zabbixReply(t, 0x01, reply), "ZBXD"...), want: reply},
+		{name: "not zabbix", packet: []byte("HTTP/1.1 400 Bad Request\r\n"), wantErr: "not a zabbix protocol response"},
+		{name: "short header", packet: []byte("ZBXD\x01\x05"), wantErr: "unexpected EOF"},
+		{name: "truncated data", packet: zabbixReply(t, 0x01, reply)[:40], wantErr: "unexpected EOF"},
+		{name: "too large", packet: []byte("ZBXD\x01\x00\x00\x00\x02\x00\x00\x00\x00"), wantErr: "response of 33554432 bytes is too large"},
+		{name: "corrupt compression", packet: append([]byte("ZBXD\x03\x04\x00\x00\x00\x10\x00\x00\x00"), "nope"...), wantErr: "zlib: invalid header"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readZabbixPacket(bytes.NewReader(tc.packet))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("readZabbixPacket() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readZabbixPacket() error = %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("readZabbixPacket() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestZabbixParam(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"eth0", "eth0"},
+		{"/var/lib/mysql", "/var/lib/mysql"},
+		{"C:", "C:"},
+		{"ipv4 stale", "ipv4 stale"},
+		{"a,b", `"a,b"`},
+		{"items[0]", `"items[0]"`},
+		{"]", `"]"`},
+		{` leading`, `" leading"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`"quoted"`, `"\"quoted\""`},
+	}
+	for _, tc := range tests {
+		if got := zabbixParam(tc.in); got != tc.want {
+			t.Errorf("zabbixParam(%q) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
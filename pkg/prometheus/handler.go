@@ -0,0 +1,28 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler calls collect on each scrape and renders the result in the
+// Prometheus text format, using node_exporter names if nodeExporter is set.
+func Handler(collect func(ctx context.Context) []metric.Metric, nodeExporter bool) http.Handler {
+	encode := Encode
+	if nodeExporter {
+		encode = EncodeNodeExporter
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics := collect(r.Context())
+		w.Header().Set("Content-Type", contentType)
+		if err := encode(w, metrics); err != nil {
+			log.Err(err).Msg("Error writing Prometheus response")
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package prometheus
+
+import (
+	"strings"
+
+	"glass/pkg/metric"
+)
+
+// nodeExporterNames maps glass metrics onto the names node_exporter uses,
+// so existing dashboards work unchanged. Metrics without an equivalent keep
+// their glass names.
+var nodeExporterNames = map[string]string{
+	"mem.total":        "node_memory_MemTotal_bytes",
+	"mem.available":    "node_memory_MemAvailable_bytes",
+	"mem.free":         "node_memory_MemFree_bytes",
+	"mem.committed":    "node_memory_Committed_AS_bytes",
+	"mem.commit_limit": "node_memory_CommitLimit_bytes",
+	"mem.swap_total":   "node_memory_SwapTotal_bytes",
+	"mem.swap_free":    "node_memory_SwapFree_bytes",
+
+	"disk.total":        "node_filesystem_size_bytes",
+	"disk.free":         "node_filesystem_avail_bytes",
+	"disk.inodes_total": "node_filesystem_files",
+	"disk.inodes_free":  "node_filesystem_files_free",
+
+	"net.bytes_recv":   "node_network_receive_bytes_total",
+	"net.bytes_sent":   "node_network_transmit_bytes_total",
+	"net.packets_recv": "node_network_receive_packets_total",
+	"net.packets_sent": "node_network_transmit_packets_total",
+	"net.err_in":       "node_network_receive_errs_total",
+	"net.err_out":      "node_network_transmit_errs_total",
+	"net.drop_in":      "node_network_receive_drop_total",
+	"net.drop_out":     "node_network_transmit_drop_total",
+	"net.mtu":          "node_network_mtu_bytes",
+	"net.speed":        "node_network_speed_bytes",
+	"net.up":           "node_network_up",
+
+	"host.load1":     "node_load1",
+	"host.load5":     "node_load5",
+	"host.load15":    "node_load15",
+	"host.boot_time": "node_boot_time_seconds",
+
+	"cpu.frequency": "node_cpu_frequency_hertz",
+
+	"vmstat.context_switches":  "node_context_switches_total",
+	"vmstat.interrupts":        "node_intr_total",
+	"vmstat.forks":             "node_forks_total",
+	"vmstat.procs_running":     "node_procs_running",
+	"vmstat.procs_blocked":     "node_procs_blocked",
+	"vmstat.page_faults":       "node_vmstat_pgfault",
+	"vmstat.major_page_faults": "node_vmstat_pgmajfault",
+	"vmstat.oom_kills":         "node_vmstat_oom_kill",
+
+	"conntrack.entries":         "node_nf_conntrack_entries",
+	"conntrack.max":             "node_nf_conntrack_entries_limit",
+	"conntrack.drops":           "node_nf_conntrack_stat_drop",
+	"conntrack.early_drops":     "node_nf_conntrack_stat_early_drop",
+	"conntrack.insert_failed":   "node_nf_conntrack_stat_insert_failed",
+	"conntrack.invalid":         "node_nf_conntrack_stat_invalid",
+	"conntrack.search_restarts": "node_nf_conntrack_stat_search_restart",
+
+	"tcp.active_opens":      "node_netstat_Tcp_ActiveOpens",
+	"tcp.passive_opens":     "node_netstat_Tcp_PassiveOpens",
+	"tcp.established":       "node_netstat_Tcp_CurrEstab",
+	"tcp.segments_in":       "node_netstat_Tcp_InSegs",
+	"tcp.segments_out":      "node_netstat_Tcp_OutSegs",
+	"tcp.retransmits":       "node_netstat_Tcp_RetransSegs",
+	"tcp.in_errors":         "node_netstat_Tcp_InErrs",
+	"tcp.resets_sent":       "node_netstat_Tcp_OutRsts",
+	"tcp.listen_overflows":  "node_netstat_TcpExt_ListenOverflows",
+	"tcp.listen_drops":      "node_netstat_TcpExt_ListenDrops",
+	"tcp.syncookies_sent":   "node_netstat_TcpExt_SyncookiesSent",
+	"tcp.syncookies_recv":   "node_netstat_TcpExt_SyncookiesRecv",
+	"tcp.syncookies_failed": "node_netstat_TcpExt_SyncookiesFailed",
+	"tcp.syn_retransmits":   "node_netstat_TcpExt_TCPSynRetrans",
+	"tcp.timeouts":          "node_netstat_TcpExt_TCPTimeouts",
+
+	"entropy.available": "node_entropy_available_bits",
+	"entropy.pool_size": "node_entropy_pool_size_bits",
+
+	"ntp.kernel_synced":          "node_timex_sync_status",
+	"ntp.kernel_offset":          "node_timex_offset_seconds",
+	"ntp.kernel_max_error":       "node_timex_maxerror_seconds",
+	"ntp.kernel_estimated_error": "node_timex_estimated_error_seconds",
+
+	"sensors.temperature":          "node_hwmon_temp_celsius",
+	"sensors.temperature_high":     "node_hwmon_temp_max_celsius",
+	"sensors.temperature_critical": "node_hwmon_temp_crit_celsius",
+	"sensors.fan_speed":            "node_hwmon_fan_rpm",
+}
+
+// nodeExporterLabels renames labels whose node_exporter name differs.
+var nodeExporterLabels = map[string]string{
+	"interface": "device",
+}
+
+// nodeExporterSeries names metrics the way node_exporter would.
+func nodeExporterSeries(metrics []metric.Metric) []series {
+	out := make([]series, 0, len(metrics))
+	for _, m := range metrics {
+		if m.Name == "cpu.time" {
+			if s, ok := nodeCPUSeconds(m); ok {
+				out = append(out, s)
+			}
+			continue
+		}
+		if m.Name == "psi.stall_time" {
+			out = append(out, nodePressureSeconds(m))
+			continue
+		}
+		name, ok := nodeExporterNames[m.Name]
+		if !ok {
+			out = append(out, series{name: Name(m), m: m})
+			continue
+		}
+		if m.Unit == "MHz" {
+			m.Value *= 1e6
+		}
+		m.Labels = renameLabels(m.Labels)
+		out = append(out, series{name: name, m: m})
+	}
+	return out
+}
+
+// nodeCPUSeconds reports per-core times as node_cpu_seconds_total with
+// cpu="0", "1", ... Guest time goes to node_cpu_guest_seconds_total as
+// node_exporter does. The aggregate is dropped since node_exporter has no
+// equivalent and dashboards sum the per-core series themselves; enable
+// per_core on the cpu collector to get them.
+func nodeCPUSeconds(m metric.Metric) (series, bool) {
+	cpu, ok := strings.CutPrefix(m.Labels["cpu"], "cpu")
+	if !ok || cpu == "-total" {
+		return series{}, false
+	}
+	name := "node_cpu_seconds_total"
+	mode := m.Labels["mode"]
+	switch mode {
+	case "guest":
+		name, mode = "node_cpu_guest_seconds_total", "user"
+	case "guest_nice":
+		name, mode = "node_cpu_guest_seconds_total", "nice"
+	}
+	m.Labels = map[string]string{"cpu": cpu, "mode": mode}
+	return series{name: name, m: m}, true
+}
+
+// nodePressureSeconds reports stall time as node_exporter's
+// node_pressure_<resource>_waiting_seconds_total for "some" and
+// _stalled_seconds_total for "full".
+func nodePressureSeconds(m metric.Metric) series {
+	state := "waiting"
+	if m.Labels["kind"] == "full" {
+		state = "stalled"
+	}
+	name := "node_pressure_" + m.Labels["resource"] + "_" + state + "_seconds_total"
+	labels := renameLabels(m.Labels)
+	delete(labels, "resource")
+	delete(labels, "kind")
+	m.Labels = labels
+	return series{name: name, m: m}
+}
+
+func renameLabels(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if renamed, ok := nodeExporterLabels[k]; ok {
+			k = renamed
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+package prometheus
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"glass/pkg/metric"
+)
+
+const namespace = "glass"
+
+// unitSuffixes maps metric units to the base-unit suffix Prometheus expects.
+var unitSuffixes = map[string]string{
+	"bytes":   "bytes",
+	"seconds": "seconds",
+	"percent": "percent",
+}
+
+// Name converts a glass metric name such as "mem.used_percent" into a
+// Prometheus metric name such as "glass_mem_used_percent".
+func Name(m metric.Metric) string {
+	name := namespace + "_" + sanitize(m.Name)
+	if suffix, ok := unitSuffixes[m.Unit]; ok && !hasSegment(name, suffix) {
+		name += "_" + suffix
+	}
+	if m.Kind == metric.Counter {
+		name += "_total"
+	}
+	return name
+}
+
+// series is a metric together with the name it is exposed under.
+type series struct {
+	name string
+	m    metric.Metric
+}
+
+// Encode writes metrics in the Prometheus text exposition format.
+func Encode(w io.Writer, metrics []metric.Metric) error {
+	out := make([]series, len(metrics))
+	for i, m := range metrics {
+		out[i] = series{name: Name(m), m: m}
+	}
+	return encode(w, out)
+}
+
+// EncodeNodeExporter is Encode with metrics that node_exporter also
+// provides renamed to node_exporter's names and labels.
+func EncodeNodeExporter(w io.Writer, metrics []metric.Metric) error {
+	return encode(w, nodeExporterSeries(metrics))
+}
+
+func encode(w io.Writer, all []series) error {
+	type sample struct {
+		labels string
+		value  float64
+	}
+	families := make(map[string][]sample)
+	kinds := make(map[string]metric.Kind)
+	for _, s := range all {
+		families[s.name] = append(families[s.name], sample{labels: formatLabels(s.m), value: s.m.Value})
+		kinds[s.name] = s.m.Kind
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		kind := "gauge"
+		if kinds[name] == metric.Counter {
+			kind = "counter"
+		}
+		bw.WriteString("# TYPE " + name + " " + kind + "\n")
+		samples := families[name]
+		sort.Slice(samples, func(i, j int) bool { return samples[i].labels < samples[j].labels })
+		for _, s := range samples {
+			bw.WriteString(name)
+			bw.WriteString(s.labels)
+			bw.WriteByte(' ')
+			bw.WriteString(strconv.FormatFloat(s.value, 'g', -1, 64))
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+func formatLabels(m metric.Metric) string {
+	if len(m.Labels) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, k := range m.LabelKeys() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(sanitize(k))
+		sb.WriteString(`="`)
+		sb.WriteString(escape(m.Labels[k]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func hasSegment(name, segment string) bool {
+	for _, part := range strings.Split(name, "_") {
+		if part == segment {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package rate
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+	"glass/pkg/output"
+)
+
+// staleAfter is how long a series may go unseen before its state is dropped.
+const staleAfter = 10 * time.Minute
+
+type sample struct {
+	value     float64
+	timestamp time.Time
+}
+
+// Engine remembers the previous sample of every counter and derives
+// per-second rates from consecutive samples.
+type Engine struct {
+	mu        sync.Mutex
+	last      map[string]sample
+	lastPrune time.Time
+}
+
+func New() *Engine {
+	return &Engine{last: make(map[string]sample)}
+}
+
+// Process returns metrics followed by the rates derived from them. For a
+// counter named "net.bytes_sent" the rate is the gauge
+// "net.bytes_sent_per_sec".
+func (e *Engine) Process(metrics []metric.Metric) []metric.Metric {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := metrics
+	for _, m := range metrics {
+		if m.Kind != metric.Counter {
+			continue
+		}
+		key := m.SeriesKey()
+		prev, ok := e.last[key]
+		e.last[key] = sample{m.Value, m.Timestamp}
+		if !ok {
+			continue
+		}
+		elapsed := m.Timestamp.Sub(prev.timestamp).Seconds()
+		if elapsed <= 0 {
+			continue
+		}
+		delta := m.Value - prev.value
+		if delta < 0 {
+			// The counter was reset (reboot, interface re-created, process
+			// restart); everything since the reset is the increase.
+			delta = m.Value
+		}
+
+		out = append(out, metric.Metric{
+			Name:      m.Name + "_per_sec",
+			Kind:      metric.Gauge,
+			Value:     delta / elapsed,
+			Unit:      perSecond(m.Unit),
+			Labels:    m.Labels,
+			Timestamp: m.Timestamp,
+		})
+	}
+
+	if now := time.Now(); now.Sub(e.lastPrune) > time.Minute {
+		for key, s := range e.last {
+			if now.Sub(s.timestamp) > staleAfter {
+				delete(e.last, key)
+			}
+		}
+		e.lastPrune = now
+	}
+	return out
+}
+
+func perSecond(unit string) string {
+	if unit == "" {
+		return "1/s"
+	}
+	return unit + "/s"
+}
+
+// Wrap returns a sink that adds derived rates before writing to next.
+func (e *Engine) Wrap(next output.Sink) output.Sink {
+	return &sink{engine: e, next: next}
+}
+
+type sink struct {
+	engine *Engine
+	next   output.Sink
+}
+
+func (s *sink) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	return s.next.Write(ctx, collector, s.engine.Process(metrics))
+}
+
+func (s *sink) Unwrap() output.Sink {
+	return s.next
+}
+
+func (s *sink) Close() error {
+	return s.next.Close()
+}
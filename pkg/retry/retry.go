@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy describes an exponential backoff schedule.
+type Policy struct {
+	Attempts int
+	Base     time.Duration
+	Max      time.Duration
+}
+
+var Default = Policy{Attempts: 3, Base: time.Second, Max: 30 * time.Second}
+
+type permanentError struct{ err error }
+
+func (p permanentError) Error() string { return p.err.Error() }
+func (p permanentError) Unwrap() error { return p.err }
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+func IsPermanent(err error) bool {
+	var perm permanentError
+	return errors.As(err, &perm)
+}
+
+// Do calls fn until it succeeds, returns a permanent error, the attempts are
+// exhausted, or ctx is cancelled. Delays double from Base up to Max, with up
+// to 50% random jitter.
+func Do(ctx context.Context, p Policy, fn func() error) error {
+	attempts := p.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := p.Base
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if IsPermanent(err) {
+			return err
+		}
+		if i == attempts-1 {
+			break
+		}
+		wait := delay
+		if wait > 0 {
+			wait += rand.N(wait/2 + 1)
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+		if delay *= 2; p.Max > 0 && delay > p.Max {
+			delay = p.Max
+		}
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"glass/pkg/collectors"
+	"glass/pkg/metric"
+)
+
+// SelfCollector is the collector name under which glass reports on its own
+// collection runs.
+const SelfCollector = "glass"
+
+// Result is the outcome of running one collector once.
+type Result struct {
+	Collector string
+	Metrics   []metric.Metric
+	Err       error
+	Start     time.Time
+	Duration  time.Duration
+}
+
+// Collect runs c with the given timeout. A panicking collector is turned
+// into an error, and a collector that ignores its context is abandoned once
+// the timeout passes so it can't stall the caller.
+func Collect(ctx context.Context, c collectors.Collector, timeout time.Duration) Result {
+	res := Result{Collector: c.Name(), Start: time.Now()}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan Result, 1)
+	go func() {
+		r := res
+		defer func() {
+			if p := recover(); p != nil {
+				r.Metrics = nil
+				r.Err = fmt.Errorf("collector panicked: %v\n%s", p, debug.Stack())
+			}
+			done <- r
+		}()
+		r.Metrics, r.Err = c.Collect(ctx)
+	}()
+
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		res.Err = fmt.Errorf("collector did not finish: %w", ctx.Err())
+	}
+	res.Duration = time.Since(res.Start)
+	return res
+}
+
+// CollectAll runs every collector concurrently, at most workers at a time
+// (unbounded when workers <= 0), and returns results in collector order.
+func CollectAll(ctx context.Context, cs []collectors.Collector, timeout func(name string) time.Duration, workers int) []Result {
+	if workers <= 0 {
+		workers = len(cs)
+	}
+	results := make([]Result, len(cs))
+	sem := make(chan struct{}, max(workers, 1))
+	var wg sync.WaitGroup
+	for i, c := range cs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = Collect(ctx, c, timeout(c.Name()))
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// SelfMetrics describes how each collector run went.
+func SelfMetrics(results ...Result) []metric.Metric {
+	b := metric.NewBuilder(time.Now())
+	for _, r := range results {
+		b.Gauge("glass.collector_success", boolValue(r.Err == nil), "", "collector", r.Collector)
+		b.Gauge("glass.collector_duration", r.Duration.Seconds(), "seconds", "collector", r.Collector)
+		b.Gauge("glass.collector_metrics", float64(len(r.Metrics)), "", "collector", r.Collector)
+	}
+	processMetrics(b)
+	return b.Metrics()
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
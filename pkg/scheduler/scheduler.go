@@ -0,0 +1,211 @@
+package scheduler
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"glass/pkg/collectors"
+	"glass/pkg/metric"
+	"glass/pkg/output"
+
+	"github.com/rs/zerolog/log"
+)
+
+const DefaultInterval = 10 * time.Second
+
+// Config controls how often each collector runs.
+type Config struct {
+	// DefaultInterval applies to collectors without an entry in Intervals.
+	DefaultInterval time.Duration
+	// Intervals overrides the interval per collector name.
+	Intervals map[string]time.Duration
+	// Jitter is the maximum random delay added before each run so that
+	// collectors (and fleets of agents) don't all fire at the same instant.
+	Jitter time.Duration
+	// Timeouts bounds each collector run. Collectors without an entry are
+	// bounded by their interval so runs never overlap.
+	Timeouts map[string]time.Duration
+}
+
+func (c Config) interval(name string) time.Duration {
+	if d, ok := c.Intervals[name]; ok && d > 0 {
+		return d
+	}
+	if c.DefaultInterval > 0 {
+		return c.DefaultInterval
+	}
+	return DefaultInterval
+}
+
+// Timeout returns how long a single run of the named collector may take.
+func (c Config) Timeout(name string) time.Duration {
+	interval := c.interval(name)
+	if d, ok := c.Timeouts[name]; ok && d > 0 && d < interval {
+		return d
+	}
+	return interval
+}
+
+// Status summarises a collector's recent runs.
+type Status struct {
+	Name                string    `json:"name"`
+	Interval            float64   `json:"interval_seconds"`
+	LastRun             time.Time `json:"last_run"`
+	LastSuccess         time.Time `json:"last_success"`
+	LastDuration        float64   `json:"last_duration_seconds"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastMetrics         int       `json:"last_metrics"`
+	Runs                uint64    `json:"runs"`
+	Failures            uint64    `json:"failures"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+}
+
+type Scheduler struct {
+	config     Config
+	collectors []collectors.Collector
+	sink       output.Sink
+
+	mu       sync.Mutex
+	statuses map[string]*Status
+	started  time.Time
+}
+
+func New(config Config, cs []collectors.Collector, sink output.Sink) *Scheduler {
+	s := &Scheduler{config: config, collectors: cs, sink: sink, statuses: make(map[string]*Status)}
+	for _, c := range cs {
+		s.statuses[c.Name()] = &Status{Name: c.Name(), Interval: config.interval(c.Name()).Seconds()}
+	}
+	return s
+}
+
+// Statuses reports on every scheduled collector, in scheduling order.
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Status, 0, len(s.collectors))
+	for _, c := range s.collectors {
+		out = append(out, *s.statuses[c.Name()])
+	}
+	return out
+}
+
+// Outputs reports on the queued outputs the scheduler writes to.
+func (s *Scheduler) Outputs() []output.Stats {
+	return output.SinkStats(s.sink)
+}
+
+// Started returns when Run was called, or the zero time before that.
+func (s *Scheduler) Started() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+// selfMetrics reports on every collector, not just the one that last ran,
+// and on the outputs and glass's own process, so each write of the self
+// collector is a complete picture.
+func (s *Scheduler) selfMetrics() []metric.Metric {
+	b := metric.NewBuilder(time.Now())
+	for _, st := range s.Statuses() {
+		if st.Runs == 0 {
+			continue
+		}
+		b.Gauge("glass.collector_success", boolValue(st.ConsecutiveFailures == 0), "", "collector", st.Name)
+		b.Gauge("glass.collector_duration", st.LastDuration, "seconds", "collector", st.Name)
+		b.Gauge("glass.collector_metrics", float64(st.LastMetrics), "", "collector", st.Name)
+		b.Counter("glass.collector_runs", float64(st.Runs), "", "collector", st.Name)
+		b.Counter("glass.collector_failures", float64(st.Failures), "", "collector", st.Name)
+		if !st.LastSuccess.IsZero() {
+			b.Gauge("glass.collector_last_success", float64(st.LastSuccess.Unix()), "seconds", "collector", st.Name)
+		}
+	}
+	outputMetrics(b, s.sink)
+	processMetrics(b)
+	return b.Metrics()
+}
+
+func (s *Scheduler) record(res Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.statuses[res.Collector]
+	st.Runs++
+	st.LastRun = res.Start
+	st.LastDuration = res.Duration.Seconds()
+	st.LastMetrics = len(res.Metrics)
+	if res.Err != nil {
+		st.Failures++
+		st.ConsecutiveFailures++
+		st.LastError = res.Err.Error()
+		return
+	}
+	st.LastSuccess = res.Start
+	st.ConsecutiveFailures = 0
+	st.LastError = ""
+}
+
+// Run starts one loop per collector and blocks until ctx is cancelled and
+// every in-flight collection has returned.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.started = time.Now()
+	s.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, c := range s.collectors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, c, s.config.interval(c.Name()))
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, c collectors.Collector, interval time.Duration) {
+	log.Debug().Str("collector", c.Name()).Dur("interval", interval).Msg("Scheduling collector")
+	timer := time.NewTimer(s.jitter())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		start := time.Now()
+		s.collect(ctx, c)
+		next := interval - time.Since(start)
+		if next < 0 {
+			next = 0
+		}
+		timer.Reset(next + s.jitter())
+	}
+}
+
+// collect runs a single collection and writes its metrics along with the
+// run's self-metrics.
+func (s *Scheduler) collect(ctx context.Context, c collectors.Collector) {
+	res := Collect(ctx, c, s.config.Timeout(c.Name()))
+	if ctx.Err() != nil {
+		return
+	}
+	s.record(res)
+	if res.Err != nil {
+		log.Err(res.Err).Str("collector", c.Name()).Msg("Error collecting metrics")
+	}
+	if len(res.Metrics) > 0 {
+		if err := s.sink.Write(ctx, c.Name(), res.Metrics); err != nil {
+			log.Err(err).Str("collector", c.Name()).Msg("Error writing metrics")
+		}
+	}
+	if err := s.sink.Write(ctx, SelfCollector, s.selfMetrics()); err != nil {
+		log.Err(err).Str("collector", SelfCollector).Msg("Error writing metrics")
+	}
+}
+
+func (s *Scheduler) jitter() time.Duration {
+	if s.config.Jitter <= 0 {
+		return 0
+	}
+	return rand.N(s.config.Jitter)
+}
@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"os"
+	"runtime"
+
+	"glass/pkg/metric"
+	"glass/pkg/output"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// self is glass's own process. It is nil if gopsutil can't see it, in
+// which case the process metrics are left out.
+var self, _ = process.NewProcess(int32(os.Getpid()))
+
+// processMetrics reports glass's own resource usage. CPU time is a counter,
+// so continuous modes also get glass.process_cpu_seconds_per_sec.
+func processMetrics(b *metric.Builder) {
+	b.Gauge("glass.goroutines", float64(runtime.NumGoroutine()), "")
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	b.Gauge("glass.go_heap", float64(mem.HeapAlloc), "bytes")
+	if self == nil {
+		return
+	}
+	if times, err := self.Times(); err == nil {
+		b.Counter("glass.process_cpu_seconds", times.User+times.System, "seconds")
+	}
+	if info, err := self.MemoryInfo(); err == nil {
+		b.Gauge("glass.process_rss", float64(info.RSS), "bytes")
+	}
+	if fds, err := self.NumFDs(); err == nil {
+		b.Gauge("glass.process_open_fds", float64(fds), "")
+	}
+}
+
+// outputMetrics reports on every queued output behind sink.
+func outputMetrics(b *metric.Builder, sink output.Sink) {
+	for _, st := range output.SinkStats(sink) {
+		b.Gauge("glass.output_queued", float64(st.Queued), "", "output", st.Name)
+		b.Counter("glass.output_dropped", float64(st.Dropped), "", "output", st.Name)
+		b.Counter("glass.output_writes", float64(st.Writes), "", "output", st.Name)
+		b.Counter("glass.output_errors", float64(st.Errors), "", "output", st.Name)
+		b.Gauge("glass.output_success", boolValue(st.ConsecutiveErrors == 0), "", "output", st.Name)
+		if !st.LastSuccess.IsZero() {
+			b.Gauge("glass.output_last_success", float64(st.LastSuccess.Unix()), "seconds", "output", st.Name)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"glass/pkg/metric"
+	"glass/pkg/store"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"collectors": s.opts.Latest.All()})
+}
+
+func (s *Server) handleCollectorMetrics(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("collector")
+	snapshot, ok := s.opts.Latest.Get(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no metrics for collector "+name)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+func (s *Server) handleCollectors(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"collectors": s.opts.Scheduler.Statuses()})
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("metric") == "" {
+		writeError(w, http.StatusBadRequest, "metric is required")
+		return
+	}
+	sel, err := metric.ParseSelector(q.Get("metric"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	now := time.Now()
+	from, err := store.ParseTime(q.Get("from"), now, now.Add(-s.opts.History.Retention()))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid from: "+err.Error())
+		return
+	}
+	to, err := store.ParseTime(q.Get("to"), now, now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid to: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"metric": sel.String(),
+		"from":   from,
+		"to":     to,
+		"series": s.opts.History.Query(sel, from, to),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Err(err).Msg("Error writing API response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"glass/pkg/config"
+)
+
+// Auth checks API requests for a bearer token or basic-auth credentials.
+type Auth struct {
+	tokens [][sha256.Size]byte
+	users  map[string][sha256.Size]byte
+	exempt []string
+}
+
+// NewAuth returns nil when cfg configures no credentials.
+func NewAuth(cfg config.AuthConfig) (*Auth, error) {
+	a := &Auth{users: map[string][sha256.Size]byte{}, exempt: cfg.Exempt}
+	if a.exempt == nil {
+		a.exempt = []string{"/healthz", "/readyz"}
+	}
+	tokens := cfg.Tokens
+	if cfg.TokensFile != "" {
+		lines, err := readLines(cfg.TokensFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading tokens file: %w", err)
+		}
+		tokens = append(tokens, lines...)
+	}
+	for _, t := range tokens {
+		if t == "" {
+			return nil, errors.New("empty API token")
+		}
+		a.tokens = append(a.tokens, sha256.Sum256([]byte(t)))
+	}
+	for user, password := range cfg.Users {
+		a.users[user] = sha256.Sum256([]byte(password))
+	}
+	if cfg.UsersFile != "" {
+		lines, err := readLines(cfg.UsersFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading users file: %w", err)
+		}
+		for i, line := range lines {
+			user, password, ok := strings.Cut(line, ":")
+			if !ok || user == "" {
+				return nil, fmt.Errorf("%s:%d: expected user:password", cfg.UsersFile, i+1)
+			}
+			a.users[user] = sha256.Sum256([]byte(password))
+		}
+	}
+	if len(a.tokens) == 0 && len(a.users) == 0 {
+		return nil, nil
+	}
+	return a, nil
+}
+
+// Wrap rejects requests to next that carry no valid credentials, except on
+// exempt paths.
+func (a *Auth) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.isExempt(r.URL.Path) || a.allowed(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(a.tokens) > 0 {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="glass"`)
+		}
+		if len(a.users) > 0 {
+			w.Header().Add("WWW-Authenticate", `Basic realm="glass", charset="UTF-8"`)
+		}
+		writeError(w, http.StatusUnauthorized, "authentication required")
+	})
+}
+
+func (a *Auth) isExempt(path string) bool {
+	for _, e := range a.exempt {
+		if path == e || strings.HasSuffix(e, "/") && strings.HasPrefix(path, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed compares hashes so that the comparison takes the same time
+// whatever the length of the guess.
+func (a *Auth) allowed(r *http.Request) bool {
+	if user, password, ok := r.BasicAuth(); ok {
+		want, known := a.users[user]
+		got := sha256.Sum256([]byte(password))
+		return known && subtle.ConstantTimeCompare(got[:], want[:]) == 1
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	got := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	match := 0
+	for _, want := range a.tokens {
+		match |= subtle.ConstantTimeCompare(got[:], want[:])
+	}
+	return match == 1
+}
+
+// readLines returns the non-empty lines of a file that aren't comments.
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"glass/pkg/grpcapi/glassv1"
+	"glass/pkg/store"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// grpcService implements the gRPC API on top of the same stores as the
+// JSON API.
+type grpcService struct {
+	glassv1.UnimplementedGlassServer
+	s *Server
+	// done ends the streams when the server shuts down, which graceful
+	// stopping waits for.
+	done <-chan struct{}
+}
+
+func (s *Server) grpcServer(done <-chan struct{}) *grpc.Server {
+	srv := grpc.NewServer(GRPCOptions(s.opts.TLS, s.opts.Auth)...)
+	glassv1.RegisterGlassServer(srv, &grpcService{s: s, done: done})
+	return srv
+}
+
+// GRPCOptions sets up a gRPC server with the same TLS and credentials as
+// the HTTP API; either may be nil.
+func GRPCOptions(tlsCfg *tls.Config, auth *Auth) []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if tlsCfg != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(grpcTLS(tlsCfg))))
+	}
+	if auth != nil {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if err := auth.checkGRPC(ctx); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := auth.checkGRPC(ss.Context()); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+	return opts
+}
+
+// grpcTLS advertises HTTP/2 on the configs TLSConfig hands out per
+// connection, which gRPC clients insist on and credentials.NewTLS only
+// sets on the outer config.
+func grpcTLS(cfg *tls.Config) *tls.Config {
+	c := cfg.Clone()
+	if get := c.GetConfigForClient; get != nil {
+		c.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			conf, err := get(hello)
+			if conf != nil {
+				conf.NextProtos = []string{"h2"}
+			}
+			return conf, err
+		}
+	}
+	return c
+}
+
+// checkGRPC accepts the same credentials as the HTTP API, sent as
+// authorization metadata.
+func (a *Auth) checkGRPC(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := &http.Request{Header: http.Header{"Authorization": md.Get("authorization")}}
+	if !a.allowed(r) {
+		return status.Error(codes.Unauthenticated, "authentication required")
+	}
+	return nil
+}
+
+func (g *grpcService) GetSnapshot(ctx context.Context, req *glassv1.GetSnapshotRequest) (*glassv1.GetSnapshotResponse, error) {
+	resp := &glassv1.GetSnapshotResponse{}
+	if len(req.Collectors) == 0 {
+		for _, snapshot := range g.s.opts.Latest.All() {
+			resp.Snapshots = append(resp.Snapshots, glassv1.FromSnapshot(snapshot))
+		}
+		return resp, nil
+	}
+	for _, name := range req.Collectors {
+		snapshot, ok := g.s.opts.Latest.Get(name)
+		if !ok {
+			return nil, status.Error(codes.NotFound, "no metrics for collector "+name)
+		}
+		resp.Snapshots = append(resp.Snapshots, glassv1.FromSnapshot(snapshot))
+	}
+	return resp, nil
+}
+
+func (g *grpcService) ListCollectors(ctx context.Context, req *glassv1.ListCollectorsRequest) (*glassv1.ListCollectorsResponse, error) {
+	resp := &glassv1.ListCollectorsResponse{}
+	for _, st := range g.s.opts.Scheduler.Statuses() {
+		resp.Collectors = append(resp.Collectors, &glassv1.CollectorStatus{
+			Name:                st.Name,
+			Interval:            durationpb.New(seconds(st.Interval)),
+			LastRun:             glassv1.Timestamp(st.LastRun),
+			LastSuccess:         glassv1.Timestamp(st.LastSuccess),
+			LastDuration:        durationpb.New(seconds(st.LastDuration)),
+			LastError:           st.LastError,
+			LastMetrics:         int64(st.LastMetrics),
+			Runs:                st.Runs,
+			Failures:            st.Failures,
+			ConsecutiveFailures: int64(st.ConsecutiveFailures),
+		})
+	}
+	return resp, nil
+}
+
+func (g *grpcService) WatchMetrics(req *glassv1.WatchMetricsRequest, stream grpc.ServerStreamingServer[glassv1.Snapshot]) error {
+	if g.s.opts.Hub == nil {
+		return status.Error(codes.Unimplemented, "streaming is not enabled")
+	}
+	c := &streamClient{
+		send:   make(chan store.Snapshot, streamBuffer),
+		filter: collectorFilter(req.Collectors),
+	}
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		c.remote = p.Addr.String()
+	}
+	g.s.opts.Hub.add(c)
+	defer g.s.opts.Hub.remove(c)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-g.done:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case snapshot, ok := <-c.send:
+			if !ok {
+				return status.Error(codes.Unavailable, "server is shutting down")
+			}
+			if err := stream.Send(glassv1.FromSnapshot(snapshot)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"glass/pkg/scheduler"
+)
+
+const (
+	// failingAfter is how many errors in a row make a collector or output
+	// failing.
+	failingAfter = 3
+	// staleAfter is how many intervals may pass without a successful run
+	// before a collector's data counts as stale.
+	staleAfter = 3
+)
+
+// Health states, from best to worst. An overall status of degraded still
+// passes the probes.
+const (
+	healthOK        = "ok"
+	healthPending   = "pending"
+	healthDegraded  = "degraded"
+	healthFailing   = "failing"
+	healthStale     = "stale"
+	healthUnhealthy = "unhealthy"
+)
+
+type componentHealth struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+type healthReport struct {
+	Status     string            `json:"status"`
+	Ready      bool              `json:"ready"`
+	Collectors []componentHealth `json:"collectors"`
+	Outputs    []componentHealth `json:"outputs"`
+}
+
+// health judges every collector and output. glass is unhealthy when none
+// of its collectors is producing data, and ready once every collector has
+// run at least once.
+func (s *Server) health(now time.Time) healthReport {
+	report := healthReport{Status: healthOK, Ready: true}
+	started := s.opts.Scheduler.Started()
+	producing := 0
+	for _, st := range s.opts.Scheduler.Statuses() {
+		c := componentHealth{Name: st.Name, Status: collectorHealth(st, started, now), LastError: st.LastError}
+		switch c.Status {
+		case healthOK:
+			producing++
+		case healthPending:
+			report.Ready = false
+		default:
+			report.Status = healthDegraded
+		}
+		report.Collectors = append(report.Collectors, c)
+	}
+	for _, st := range s.opts.Scheduler.Outputs() {
+		c := componentHealth{Name: st.Name, Status: healthOK, LastError: st.LastError}
+		if st.ConsecutiveErrors >= failingAfter {
+			c.Status = healthFailing
+			report.Status = healthDegraded
+		}
+		report.Outputs = append(report.Outputs, c)
+	}
+	if producing == 0 && len(report.Collectors) > 0 && report.Ready {
+		report.Status = healthUnhealthy
+	}
+	return report
+}
+
+func collectorHealth(st scheduler.Status, started, now time.Time) string {
+	stale := time.Duration(staleAfter * st.Interval * float64(time.Second))
+	switch {
+	case st.ConsecutiveFailures >= failingAfter:
+		return healthFailing
+	case !st.LastSuccess.IsZero() && now.Sub(st.LastSuccess) > stale:
+		return healthStale
+	case st.LastSuccess.IsZero() && !started.IsZero() && now.Sub(started) > stale:
+		return healthStale
+	case st.Runs == 0:
+		return healthPending
+	case st.LastSuccess.IsZero():
+		// Failed so far, but not often enough to call it failing.
+		return healthDegraded
+	}
+	return healthOK
+}
+
+// handleHealthz is the liveness probe: it fails only when no collector is
+// producing data, since restarting glass won't fix one broken collector.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	report := s.health(time.Now())
+	status := http.StatusOK
+	if report.Status == healthUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// handleReadyz is the readiness probe: it fails until every collector has
+// run once, so the first scrape isn't missing data, and while unhealthy.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := s.health(time.Now())
+	status := http.StatusOK
+	if !report.Ready || report.Status == healthUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
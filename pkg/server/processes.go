@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"glass/pkg/collectors"
+)
+
+// handleTopProcesses returns the heaviest processes. CPU and IO rates are
+// over the time since the previous request, so the first is a lifetime
+// average.
+func (s *Server) handleTopProcesses(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	by := q.Get("by")
+	if by == "" {
+		by = "cpu"
+	}
+	if !slices.Contains(collectors.ProcessSortKeys, by) {
+		writeError(w, http.StatusBadRequest, "by must be one of "+strings.Join(collectors.ProcessSortKeys, ", "))
+		return
+	}
+	n := 10
+	if v := q.Get("n"); v != "" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid n: "+v)
+			return
+		}
+	}
+	infos, err := s.processes.Sample(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if user := q.Get("user"); user != "" {
+		infos = slices.DeleteFunc(infos, func(p collectors.ProcessInfo) bool { return p.User != user })
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"by":        by,
+		"processes": collectors.TopProcesses(infos, by, n),
+	})
+}
+
+// handleOOMKills lists the OOM kills still in the kernel ring buffer,
+// newest first.
+func (s *Server) handleOOMKills(w http.ResponseWriter, r *http.Request) {
+	kills, err := collectors.RecentOOMKills()
+	if err != nil && len(kills) == 0 {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	slices.Reverse(kills)
+	writeJSON(w, http.StatusOK, map[string]any{"kills": kills})
+}
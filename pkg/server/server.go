@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"glass/pkg/alert"
+	"glass/pkg/collectors"
+	"glass/pkg/metric"
+	"glass/pkg/prometheus"
+	"glass/pkg/scheduler"
+	"glass/pkg/store"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+)
+
+type Options struct {
+	Listen string
+	// GRPCListen, when set, also serves the gRPC API on that address.
+	GRPCListen string
+	Latest     *store.Latest
+	History    *store.History
+	Hub        *Hub
+	Scheduler  *scheduler.Scheduler
+	// Alerts, when set, serves the engine's active alerts.
+	Alerts *alert.Engine
+	// NodeExporterNames exposes /metrics under node_exporter's names.
+	NodeExporterNames bool
+	// TLS serves HTTPS when set.
+	TLS *tls.Config
+	// Auth, when set, guards every route it doesn't exempt.
+	Auth *Auth
+}
+
+type Server struct {
+	opts      Options
+	traces    chan struct{}
+	processes collectors.ProcessSampler
+}
+
+func New(opts Options) *Server {
+	return &Server{opts: opts, traces: make(chan struct{}, maxTraces)}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleDashboard)
+	mux.Handle("GET /metrics", prometheus.Handler(func(context.Context) []metric.Metric {
+		return s.opts.Latest.Metrics()
+	}, s.opts.NodeExporterNames))
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /readyz", s.handleReadyz)
+	mux.HandleFunc("GET /api/v1/metrics", s.handleMetrics)
+	mux.HandleFunc("GET /api/v1/metrics/{collector}", s.handleCollectorMetrics)
+	mux.HandleFunc("GET /api/v1/collectors", s.handleCollectors)
+	mux.HandleFunc("GET /api/v1/processes/top", s.handleTopProcesses)
+	mux.HandleFunc("GET /api/v1/oom", s.handleOOMKills)
+	mux.HandleFunc("POST /api/v1/trace", s.handleTrace)
+	if s.opts.History != nil {
+		mux.HandleFunc("GET /api/v1/query", s.handleQuery)
+	}
+	if s.opts.Alerts != nil {
+		mux.HandleFunc("GET /api/v1/alerts", s.handleAlerts)
+	}
+	if s.opts.Hub != nil {
+		mux.HandleFunc("GET /api/v1/stream", s.handleStream)
+	}
+	if s.opts.Auth != nil {
+		return s.opts.Auth.Wrap(mux)
+	}
+	return mux
+}
+
+// Run serves until ctx is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	var grpcSrv *grpc.Server
+	if s.opts.GRPCListen != "" {
+		grpcSrv = s.grpcServer(ctx.Done())
+	}
+	return Serve(ctx, Listeners{
+		Listen:     s.opts.Listen,
+		Handler:    s.Handler(),
+		TLS:        s.opts.TLS,
+		GRPCListen: s.opts.GRPCListen,
+		GRPC:       grpcSrv,
+	})
+}
+
+// Listeners says what Serve serves where.
+type Listeners struct {
+	Listen  string
+	Handler http.Handler
+	// TLS serves HTTPS when set. A GRPC server brings its own credentials.
+	TLS        *tls.Config
+	GRPCListen string
+	GRPC       *grpc.Server
+}
+
+// Serve runs the HTTP server, and the gRPC server when there is one, until
+// ctx is cancelled or either fails.
+func Serve(ctx context.Context, l Listeners) error {
+	srv := &http.Server{
+		Addr:              l.Listen,
+		Handler:           l.Handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         l.TLS,
+	}
+	errCh := make(chan error, 2)
+	go func() {
+		log.Info().Str("listen", l.Listen).Bool("tls", l.TLS != nil).Msg("Serving metrics")
+		if l.TLS != nil {
+			// The certificate comes from TLSConfig, not from files here.
+			errCh <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		errCh <- srv.ListenAndServe()
+	}()
+	if l.GRPC != nil {
+		lis, err := net.Listen("tcp", l.GRPCListen)
+		if err != nil {
+			srv.Close()
+			return fmt.Errorf("listening for gRPC: %w", err)
+		}
+		go func() {
+			log.Info().Str("listen", l.GRPCListen).Bool("tls", l.TLS != nil).Msg("Serving gRPC")
+			if err := l.GRPC.Serve(lis); err != nil {
+				errCh <- fmt.Errorf("serving gRPC: %w", err)
+			}
+		}()
+	}
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if l.GRPC != nil {
+		stopGRPC(shutdownCtx, l.GRPC)
+	}
+	if err != nil {
+		srv.Close()
+		return err
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// stopGRPC lets in-flight calls finish, cutting them off once ctx
+// expires.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+	"glass/pkg/store"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	streamBuffer    = 64
+	streamWriteWait = 10 * time.Second
+	streamPingEvery = 30 * time.Second
+)
+
+// Hub fans each collection out to the connected stream clients. It
+// implements output.Sink.
+type Hub struct {
+	mu      sync.Mutex
+	clients map[*streamClient]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{clients: make(map[*streamClient]struct{})}
+}
+
+func (h *Hub) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	snapshot := store.Snapshot{Collector: collector, Timestamp: time.Now(), Metrics: metrics}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		if !c.wants(collector) {
+			continue
+		}
+		select {
+		case c.send <- snapshot:
+		default:
+			// A client that can't keep up loses samples rather than
+			// holding up the scheduler.
+			log.Warn().Str("remote", c.remote).Str("collector", collector).Msg("Stream client too slow, dropping metrics")
+		}
+	}
+	return nil
+}
+
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		close(c.send)
+		delete(h.clients, c)
+	}
+	return nil
+}
+
+func (h *Hub) add(c *streamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[c] = struct{}{}
+}
+
+func (h *Hub) remove(c *streamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; ok {
+		close(c.send)
+		delete(h.clients, c)
+	}
+}
+
+type streamClient struct {
+	remote string
+	send   chan store.Snapshot
+
+	mu     sync.RWMutex
+	filter map[string]bool
+}
+
+func (c *streamClient) wants(collector string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.filter) == 0 || c.filter[collector]
+}
+
+func (c *streamClient) setFilter(names []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filter = collectorFilter(names)
+}
+
+// collectorFilter accepts repeated and comma-separated names.
+func collectorFilter(values []string) map[string]bool {
+	filter := make(map[string]bool)
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				filter[name] = true
+			}
+		}
+	}
+	return filter
+}
+
+// streamRequest is what clients may send to change their filter after
+// connecting.
+type streamRequest struct {
+	Collectors []string `json:"collectors"`
+}
+
+// upgrader keeps gorilla's origin check: browsers may only connect from
+// pages served by glass itself, so another site can't read the stream with
+// a visitor's credentials. Clients that send no Origin are let through.
+var upgrader = websocket.Upgrader{}
+
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client.
+		log.Debug().Err(err).Msg("Error upgrading stream connection")
+		return
+	}
+	c := &streamClient{
+		remote: r.RemoteAddr,
+		send:   make(chan store.Snapshot, streamBuffer),
+		filter: collectorFilter(r.URL.Query()["collector"]),
+	}
+	s.opts.Hub.add(c)
+	log.Debug().Str("remote", c.remote).Msg("Stream client connected")
+
+	go s.readStream(conn, c)
+	writeStream(conn, c)
+	log.Debug().Str("remote", c.remote).Msg("Stream client disconnected")
+}
+
+func (s *Server) readStream(conn *websocket.Conn, c *streamClient) {
+	defer s.opts.Hub.remove(c)
+	conn.SetReadLimit(4096)
+	conn.SetReadDeadline(time.Now().Add(2 * streamPingEvery))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * streamPingEvery))
+	})
+	for {
+		var req streamRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		c.setFilter(req.Collectors)
+	}
+}
+
+func writeStream(conn *websocket.Conn, c *streamClient) {
+	defer conn.Close()
+	ping := time.NewTicker(streamPingEvery)
+	defer ping.Stop()
+	for {
+		select {
+		case snapshot, ok := <-c.send:
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := conn.WriteJSON(snapshot); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+
+	"github.com/rs/zerolog/log"
+)
+
+// reloadCheck is how often, at most, the certificate files are checked for
+// changes.
+const reloadCheck = 10 * time.Second
+
+// TLSConfig builds a server TLS config from cfg. Certificates and client
+// CAs are re-read when their files change; if a changed file doesn't load,
+// the previous one stays in use.
+func TLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("tls needs both cert_file and key_file")
+	}
+	base := &tls.Config{MinVersion: tls.VersionTLS12}
+	switch cfg.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		base.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("invalid tls min_version %q: must be 1.2 or 1.3", cfg.MinVersion)
+	}
+	switch cfg.ClientAuth {
+	case "", "require":
+		if cfg.ClientCAFile != "" {
+			base.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	case "request":
+		base.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("invalid tls client_auth %q: must be require or request", cfg.ClientAuth)
+	}
+	if base.ClientAuth != tls.NoClientCert && cfg.ClientCAFile == "" {
+		return nil, errors.New("tls client_auth needs a client_ca_file")
+	}
+
+	r := &certReloader{cfg: cfg}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	// Each handshake gets a config with the current certificate and CAs.
+	return &tls.Config{
+		MinVersion: base.MinVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.maybeReload()
+			c := base.Clone()
+			c.Certificates, c.ClientCAs = r.current()
+			return c, nil
+		},
+	}, nil
+}
+
+type certReloader struct {
+	cfg config.TLSConfig
+
+	mu        sync.Mutex
+	cert      tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  [3]time.Time
+	checked   time.Time
+}
+
+func (r *certReloader) files() [3]string {
+	return [3]string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.ClientCAFile}
+}
+
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("loading certificate: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("reading client CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", r.cfg.ClientCAFile)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert, r.clientCAs = cert, pool
+	r.modTimes = r.stat()
+	r.checked = time.Now()
+	return nil
+}
+
+func (r *certReloader) stat() [3]time.Time {
+	var times [3]time.Time
+	for i, name := range r.files() {
+		if name == "" {
+			continue
+		}
+		if fi, err := os.Stat(name); err == nil {
+			times[i] = fi.ModTime()
+		}
+	}
+	return times
+}
+
+func (r *certReloader) maybeReload() {
+	r.mu.Lock()
+	if time.Since(r.checked) < reloadCheck {
+		r.mu.Unlock()
+		return
+	}
+	r.checked = time.Now()
+	changed := r.stat() != r.modTimes
+	r.mu.Unlock()
+	if !changed {
+		return
+	}
+	if err := r.load(); err != nil {
+		log.Err(err).Msg("Error reloading TLS certificate, keeping the current one")
+		return
+	}
+	log.Info().Str("cert", r.cfg.CertFile).Msg("Reloaded TLS certificate")
+}
+
+func (r *certReloader) current() ([]tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return []tls.Certificate{r.cert}, r.clientCAs
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"glass/pkg/config"
+	"glass/pkg/trace"
+)
+
+// maxTraces caps how many traces run at once, since each holds a socket
+// and a request open for several seconds.
+const maxTraces = 4
+
+type traceRequest struct {
+	Host    string          `json:"host"`
+	MaxHops int             `json:"max_hops"`
+	Probes  int             `json:"probes"`
+	Timeout config.Duration `json:"timeout"`
+	NoDNS   bool            `json:"no_dns"`
+}
+
+func (s *Server) handleTrace(w http.ResponseWriter, r *http.Request) {
+	var req traceRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	if req.Host == "" {
+		writeError(w, http.StatusBadRequest, "host is required")
+		return
+	}
+	opts := trace.DefaultOptions()
+	if req.MaxHops > 0 {
+		opts.MaxHops = req.MaxHops
+	}
+	if req.Probes > 0 {
+		opts.Probes = req.Probes
+	}
+	if req.Timeout > 0 {
+		opts.Timeout = req.Timeout.Duration()
+	}
+	opts.Resolve = !req.NoDNS
+	if err := opts.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	select {
+	case s.traces <- struct{}{}:
+		defer func() { <-s.traces }()
+	default:
+		writeError(w, http.StatusTooManyRequests, "too many traces running")
+		return
+	}
+	result, err := trace.Run(r.Context(), req.Host, opts)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,107 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+// SnapshotFile is where a bundle keeps its Snapshot.
+const SnapshotFile = "snapshot.json"
+
+// File is an extra file to put in a bundle.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// WriteBundle writes a gzipped tar holding s as snapshot.json followed by
+// files, all under a directory named after the host and the time, as
+// sosreport does, so unpacking several bundles doesn't mix them up.
+func WriteBundle(w io.Writer, s *Snapshot, files ...File) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	dir := "glass-snapshot-" + s.TakenAt.UTC().Format("20060102T150405Z")
+	if s.Host != nil && s.Host.Hostname != "" {
+		dir = "glass-snapshot-" + s.Host.Hostname + "-" + s.TakenAt.UTC().Format("20060102T150405Z")
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	files = append([]File{{Name: SnapshotFile, Data: append(data, '\n')}}, files...)
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    path.Join(dir, f.Name),
+			Mode:    0o644,
+			Size:    int64(len(f.Data)),
+			ModTime: s.TakenAt,
+			Format:  tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("writing %s: %w", f.Name, err)
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return fmt.Errorf("writing %s: %w", f.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read loads a snapshot from a JSON file or from the snapshot.json in a
+// bundle.
+func Read(name string) (*Snapshot, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	magic, _ := r.Peek(2)
+	var src io.Reader = r
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		if src, err = fromBundle(r); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+	}
+	s := &Snapshot{}
+	if err := json.NewDecoder(src).Decode(s); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	if s.TakenAt.IsZero() {
+		return nil, fmt.Errorf("reading %s: not a glass snapshot", name)
+	}
+	return s, nil
+}
+
+// fromBundle returns the contents of the bundle's snapshot.json.
+func fromBundle(r io.Reader) (io.Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("no " + SnapshotFile + " in archive")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if path.Base(hdr.Name) == SnapshotFile {
+			return tr, nil
+		}
+	}
+}
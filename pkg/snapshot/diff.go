@@ -0,0 +1,244 @@
+package snapshot
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"glass/pkg/collectors"
+	"glass/pkg/metric"
+)
+
+// Thresholds decide which changes Compare reports.
+type Thresholds struct {
+	// Percent is the smallest change, in percentage points, of memory or
+	// filesystem usage worth reporting.
+	Percent float64
+	// RSSGrowth is the smallest growth, in bytes, of one process's
+	// resident memory worth reporting.
+	RSSGrowth uint64
+}
+
+// Diff is what changed between two snapshots.
+type Diff struct {
+	Before time.Time `json:"before"`
+	After  time.Time `json:"after"`
+	// Rebooted is set when the boot time differs.
+	Rebooted bool `json:"rebooted"`
+
+	Memory      []Change              `json:"memory,omitempty"`
+	Disks       []Change              `json:"disks,omitempty"`
+	Growing     []ProcessChange       `json:"growing_processes,omitempty"`
+	NewProcs    []ProcessSummary      `json:"new_processes,omitempty"`
+	GoneProcs   []ProcessSummary      `json:"exited_processes,omitempty"`
+	NewListen   []collectors.Listener `json:"new_listening,omitempty"`
+	GoneListen  []collectors.Listener `json:"closed_listening,omitempty"`
+	NewErrors   map[string]string     `json:"new_errors,omitempty"`
+	FixedErrors []string              `json:"fixed_errors,omitempty"`
+}
+
+// Empty reports whether nothing significant changed.
+func (d *Diff) Empty() bool {
+	return !d.Rebooted && len(d.Memory) == 0 && len(d.Disks) == 0 && len(d.Growing) == 0 &&
+		len(d.NewProcs) == 0 && len(d.GoneProcs) == 0 && len(d.NewListen) == 0 && len(d.GoneListen) == 0 &&
+		len(d.NewErrors) == 0 && len(d.FixedErrors) == 0
+}
+
+// Change is a value that moved. A series missing from one side has the
+// zero value there and Added or Removed set.
+type Change struct {
+	Name    string            `json:"name"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Unit    string            `json:"unit,omitempty"`
+	Before  float64           `json:"before"`
+	After   float64           `json:"after"`
+	Added   bool              `json:"added,omitempty"`
+	Removed bool              `json:"removed,omitempty"`
+}
+
+// ProcessChange is a process present in both snapshots whose memory grew.
+type ProcessChange struct {
+	ProcessSummary
+	RSSBefore uint64 `json:"rss_before"`
+	RSSAfter  uint64 `json:"rss_after"`
+}
+
+// ProcessSummary identifies a process.
+type ProcessSummary struct {
+	PID     int32  `json:"pid"`
+	Name    string `json:"name"`
+	User    string `json:"user"`
+	Cmdline string `json:"cmdline"`
+	RSS     uint64 `json:"rss"`
+}
+
+// Compare reports the significant differences from before to after.
+func Compare(before, after *Snapshot, t Thresholds) *Diff {
+	d := &Diff{Before: before.TakenAt, After: after.TakenAt}
+	if before.Host != nil && after.Host != nil && before.Host.BootTime != after.Host.BootTime {
+		d.Rebooted = true
+	}
+
+	b, a := index(before), index(after)
+	// Memory is reported in full when it moved past the threshold, so the
+	// bytes explain the percentage.
+	if pct, ok := changed(b, a, "mem.used_percent", t.Percent); ok {
+		d.Memory = append(d.Memory, pct)
+		for _, name := range []string{"mem.used", "mem.available", "mem.swap_used"} {
+			if c, ok := changed(b, a, name, -1); ok {
+				d.Memory = append(d.Memory, c)
+			}
+		}
+	}
+	d.Disks = seriesChanges(b, a, "disk.used_percent", t.Percent)
+
+	d.Growing, d.NewProcs, d.GoneProcs = processChanges(before.Processes, after.Processes, t.RSSGrowth)
+	d.NewListen, d.GoneListen = listenerChanges(before.Listening, after.Listening)
+
+	for what, msg := range after.Errors {
+		if _, ok := before.Errors[what]; !ok {
+			if d.NewErrors == nil {
+				d.NewErrors = map[string]string{}
+			}
+			d.NewErrors[what] = msg
+		}
+	}
+	for what := range before.Errors {
+		if _, ok := after.Errors[what]; !ok {
+			d.FixedErrors = append(d.FixedErrors, what)
+		}
+	}
+	sort.Strings(d.FixedErrors)
+	return d
+}
+
+// index maps each series key to its metric, across every collection.
+func index(s *Snapshot) map[string]metric.Metric {
+	out := map[string]metric.Metric{}
+	for _, c := range s.Collections {
+		for _, m := range c.Metrics {
+			out[m.SeriesKey()] = m
+		}
+	}
+	return out
+}
+
+// changed compares one unlabelled series; a negative threshold reports any
+// difference.
+func changed(b, a map[string]metric.Metric, name string, threshold float64) (Change, bool) {
+	mb, okb := b[name]
+	ma, oka := a[name]
+	if !okb || !oka {
+		return Change{}, false
+	}
+	return Change{Name: name, Unit: ma.Unit, Before: mb.Value, After: ma.Value}, significant(mb.Value, ma.Value, threshold)
+}
+
+func significant(before, after, threshold float64) bool {
+	if threshold < 0 {
+		return before != after
+	}
+	diff := after - before
+	return diff >= threshold || -diff >= threshold
+}
+
+// seriesChanges compares every series of one metric, whatever its labels,
+// and reports those that moved by threshold or appeared or disappeared.
+func seriesChanges(b, a map[string]metric.Metric, name string, threshold float64) []Change {
+	var out []Change
+	for key, ma := range a {
+		if ma.Name != name {
+			continue
+		}
+		mb, ok := b[key]
+		if !ok {
+			out = append(out, Change{Name: name, Labels: ma.Labels, Unit: ma.Unit, After: ma.Value, Added: true})
+			continue
+		}
+		if significant(mb.Value, ma.Value, threshold) {
+			out = append(out, Change{Name: name, Labels: ma.Labels, Unit: ma.Unit, Before: mb.Value, After: ma.Value})
+		}
+	}
+	for key, mb := range b {
+		if _, ok := a[key]; mb.Name == name && !ok {
+			out = append(out, Change{Name: name, Labels: mb.Labels, Unit: mb.Unit, Before: mb.Value, Removed: true})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return metric.Metric{Name: name, Labels: out[i].Labels}.SeriesKey() < metric.Metric{Name: name, Labels: out[j].Labels}.SeriesKey()
+	})
+	return out
+}
+
+// processKey tells a process apart from a later one that reused its PID.
+type processKey struct {
+	pid     int32
+	created int64
+}
+
+func keyOf(p collectors.ProcessInfo) processKey {
+	return processKey{p.PID, p.CreateTime.Unix()}
+}
+
+func summarize(p collectors.ProcessInfo) ProcessSummary {
+	return ProcessSummary{PID: p.PID, Name: p.Name, User: p.User, Cmdline: p.Cmdline, RSS: p.RSS}
+}
+
+// processChanges lists processes whose RSS grew by at least growth, sorted
+// by growth, and those that started or exited, sorted by RSS.
+func processChanges(before, after []collectors.ProcessInfo, growth uint64) (growing []ProcessChange, started, exited []ProcessSummary) {
+	old := make(map[processKey]collectors.ProcessInfo, len(before))
+	for _, p := range before {
+		old[keyOf(p)] = p
+	}
+	seen := make(map[processKey]bool, len(after))
+	for _, p := range after {
+		k := keyOf(p)
+		seen[k] = true
+		prev, ok := old[k]
+		if !ok {
+			started = append(started, summarize(p))
+			continue
+		}
+		if p.RSS > prev.RSS && p.RSS-prev.RSS >= growth {
+			growing = append(growing, ProcessChange{ProcessSummary: summarize(p), RSSBefore: prev.RSS, RSSAfter: p.RSS})
+		}
+	}
+	for _, p := range before {
+		if !seen[keyOf(p)] {
+			exited = append(exited, summarize(p))
+		}
+	}
+	sort.Slice(growing, func(i, j int) bool {
+		return growing[i].RSSAfter-growing[i].RSSBefore > growing[j].RSSAfter-growing[j].RSSBefore
+	})
+	byRSS := func(ps []ProcessSummary) {
+		sort.SliceStable(ps, func(i, j int) bool { return ps[i].RSS > ps[j].RSS })
+	}
+	byRSS(started)
+	byRSS(exited)
+	return growing, started, exited
+}
+
+// listenerChanges ignores which process holds a socket, so a restarted
+// service isn't reported as closing and reopening its port.
+func listenerChanges(before, after []collectors.Listener) (opened, closed []collectors.Listener) {
+	key := func(l collectors.Listener) string { return fmt.Sprintf("%s %s %d", l.Protocol, l.Address, l.Port) }
+	had := make(map[string]bool, len(before))
+	for _, l := range before {
+		had[key(l)] = true
+	}
+	has := make(map[string]bool, len(after))
+	for _, l := range after {
+		has[key(l)] = true
+		if !had[key(l)] {
+			opened = append(opened, l)
+		}
+	}
+	for _, l := range before {
+		if !has[key(l)] {
+			closed = append(closed, l)
+		}
+	}
+	return opened, closed
+}
@@ -0,0 +1,47 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// KernelErrors returns up to lines recent kernel messages of priority err
+// or worse, from the journal where there is one and from dmesg otherwise.
+func KernelErrors(ctx context.Context, lines int) ([]byte, error) {
+	var errs []error
+	for _, args := range [][]string{
+		{"journalctl", "--dmesg", "--priority=err", "--lines", fmt.Sprint(lines), "--no-pager", "--output=short-iso"},
+		{"dmesg", "--level=emerg,alert,crit,err", "--time-format=iso"},
+	} {
+		out, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+				err = fmt.Errorf("%w: %s", err, bytes.TrimSpace(exitErr.Stderr))
+			}
+			errs = append(errs, fmt.Errorf("running %s: %w", args[0], err))
+			continue
+		}
+		return tail(out, lines), nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// tail keeps the last n lines of out.
+func tail(out []byte, n int) []byte {
+	out = bytes.TrimRight(out, "\n")
+	for i, count := len(out)-1, 0; i >= 0; i-- {
+		if out[i] == '\n' {
+			if count++; count == n {
+				return append(out[i+1:], '\n')
+			}
+		}
+	}
+	if len(out) == 0 {
+		return out
+	}
+	return append(out, '\n')
+}
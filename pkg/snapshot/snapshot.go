@@ -0,0 +1,118 @@
+// Package snapshot captures the state of a host at one moment, for support
+// bundles and before/after comparisons.
+package snapshot
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	"glass/pkg/collectors"
+	"glass/pkg/rate"
+	"glass/pkg/scheduler"
+	"glass/pkg/store"
+
+	"github.com/rs/zerolog/log"
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+// Snapshot is everything glass can see on a host at one time.
+type Snapshot struct {
+	Version string         `json:"version"`
+	TakenAt time.Time      `json:"taken_at"`
+	Host    *host.InfoStat `json:"host,omitempty"`
+	// Collections holds one run of every enabled collector, including the
+	// _per_sec rates of its counters.
+	Collections []store.Snapshot         `json:"collections"`
+	Processes   []collectors.ProcessInfo `json:"processes"`
+	Listening   []collectors.Listener    `json:"listening"`
+	// Errors maps what couldn't be captured, a collector name or "host",
+	// "processes" or "listening", to why.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Options control how a snapshot is taken.
+type Options struct {
+	Version string
+	// Timeout returns each collector's timeout, as scheduler.Config does.
+	Timeout func(name string) time.Duration
+	Workers int
+	// Delay is how long apart the two collections are, over which rates
+	// and process CPU and IO are measured. Zero collects once and leaves
+	// them out.
+	Delay time.Duration
+}
+
+// Take collects from cs and looks at the host, its processes and its
+// listening sockets. What can't be read is recorded in Errors rather than
+// failing the snapshot; only a cancelled ctx is an error.
+func Take(ctx context.Context, cs []collectors.Collector, opts Options) (*Snapshot, error) {
+	s := &Snapshot{Version: opts.Version, Errors: map[string]string{}}
+	rates := rate.New()
+	var sampler collectors.ProcessSampler
+	if opts.Delay > 0 {
+		for _, res := range scheduler.CollectAll(ctx, cs, opts.Timeout, opts.Workers) {
+			rates.Process(res.Metrics)
+		}
+		sampler.Sample(ctx)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(opts.Delay):
+		}
+	}
+
+	s.TakenAt = time.Now()
+	for _, res := range scheduler.CollectAll(ctx, cs, opts.Timeout, opts.Workers) {
+		if res.Err != nil {
+			s.Errors[res.Collector] = res.Err.Error()
+		}
+		if len(res.Metrics) > 0 {
+			s.Collections = append(s.Collections, store.Snapshot{
+				Collector: res.Collector,
+				Timestamp: res.Start,
+				Metrics:   rates.Process(res.Metrics),
+			})
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(s.Collections, func(i, j int) bool { return s.Collections[i].Collector < s.Collections[j].Collector })
+
+	var err error
+	if s.Host, err = host.InfoWithContext(ctx); err != nil {
+		s.Errors["host"] = err.Error()
+	}
+	procs, err := sampler.Sample(ctx)
+	if err != nil {
+		s.Errors["processes"] = err.Error()
+	}
+	// Leave glass itself out, or every diff would show it starting and
+	// exiting.
+	self := int32(os.Getpid())
+	for _, p := range procs {
+		if p.PID != self {
+			s.Processes = append(s.Processes, p)
+		}
+	}
+	collectors.SortProcesses(s.Processes, "cpu")
+	if s.Listening, err = listening(ctx, s.Processes); err != nil {
+		s.Errors["listening"] = err.Error()
+	}
+	for what, msg := range s.Errors {
+		log.Warn().Str("section", what).Str("error", msg).Msg("Snapshot is incomplete")
+	}
+	return s, nil
+}
+
+// listening names the owners of listening sockets after the processes
+// already sampled.
+func listening(ctx context.Context, procs []collectors.ProcessInfo) ([]collectors.Listener, error) {
+	names := make(map[int32]string, len(procs))
+	for _, p := range procs {
+		names[p.PID] = p.Name
+	}
+	return collectors.Listening(ctx, names)
+}
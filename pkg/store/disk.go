@@ -0,0 +1,388 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"glass/pkg/config"
+	"glass/pkg/metric"
+
+	"github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	seriesBucket = []byte("series")
+	rawBucket    = []byte("raw")
+	downBucket   = []byte("downsampled")
+)
+
+// ErrLocked is returned when another process holds the database open.
+var ErrLocked = errors.New("store is locked by another process")
+
+// maxPending bounds the samples held in memory while flushes are failing.
+const maxPending = 1000000
+
+// Disk persists samples to a bbolt database. Writes are buffered and
+// flushed periodically, and the database is only held open while flushing
+// so that `glass export` can read it while the daemon runs. Samples older
+// than DownsampleAfter are averaged into DownsampleResolution buckets, and
+// everything older than Retention is deleted. It implements output.Sink.
+type Disk struct {
+	cfg config.StorageConfig
+
+	mu          sync.Mutex
+	pending     []metric.Metric
+	lastCompact time.Time
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+func OpenDisk(cfg config.StorageConfig) (*Disk, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("storage path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating storage directory: %w", err)
+	}
+	d := &Disk{cfg: cfg, done: make(chan struct{})}
+	// Fail at startup rather than on the first flush if the path is unusable.
+	if err := d.update(func(tx *bolt.Tx) error { return nil }); err != nil {
+		return nil, err
+	}
+	d.wg.Add(1)
+	go d.loop()
+	return d, nil
+}
+
+func (d *Disk) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending = append(d.pending, metrics...)
+	return nil
+}
+
+func (d *Disk) Close() error {
+	close(d.done)
+	d.wg.Wait()
+	return d.flush()
+}
+
+func (d *Disk) loop() {
+	defer d.wg.Done()
+	ticker := time.NewTicker(d.cfg.FlushInterval.Duration())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			if err := d.flush(); err != nil {
+				log.Err(err).Str("path", d.cfg.Path).Msg("Error flushing metrics to storage")
+			}
+		}
+	}
+}
+
+func (d *Disk) flush() error {
+	d.mu.Lock()
+	pending := d.pending
+	d.pending = nil
+	compact := time.Since(d.lastCompact) >= d.cfg.CompactInterval.Duration()
+	d.mu.Unlock()
+	if len(pending) == 0 && !compact {
+		return nil
+	}
+
+	err := d.update(func(tx *bolt.Tx) error {
+		if err := writePoints(tx, pending); err != nil {
+			return err
+		}
+		if compact {
+			return d.compact(tx, time.Now())
+		}
+		return nil
+	})
+	if err != nil {
+		// Keep the samples for the next attempt, e.g. while an export
+		// holds the lock.
+		d.mu.Lock()
+		d.pending = append(pending, d.pending...)
+		if over := len(d.pending) - maxPending; over > 0 {
+			d.pending = d.pending[over:]
+		}
+		d.mu.Unlock()
+		return err
+	}
+	if compact {
+		d.mu.Lock()
+		d.lastCompact = time.Now()
+		d.mu.Unlock()
+	}
+	return nil
+}
+
+func (d *Disk) update(fn func(tx *bolt.Tx) error) error {
+	db, err := openBolt(d.cfg.Path, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(fn)
+}
+
+func openBolt(path string, readOnly bool) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: readOnly})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("opening %s: %w", path, ErrLocked)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return db, nil
+}
+
+type seriesMeta struct {
+	Name   string            `json:"name"`
+	Kind   metric.Kind       `json:"kind"`
+	Unit   string            `json:"unit,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func writePoints(tx *bolt.Tx, metrics []metric.Metric) error {
+	meta, err := tx.CreateBucketIfNotExists(seriesBucket)
+	if err != nil {
+		return err
+	}
+	raw, err := tx.CreateBucketIfNotExists(rawBucket)
+	if err != nil {
+		return err
+	}
+	for _, m := range metrics {
+		key := []byte(m.SeriesKey())
+		if meta.Get(key) == nil {
+			data, err := json.Marshal(seriesMeta{Name: m.Name, Kind: m.Kind, Unit: m.Unit, Labels: m.Labels})
+			if err != nil {
+				return err
+			}
+			if err := meta.Put(key, data); err != nil {
+				return err
+			}
+		}
+		b, err := raw.CreateBucketIfNotExists(key)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(encodeTime(m.Timestamp), encodeValue(m.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compact downsamples raw points older than DownsampleAfter and drops
+// anything older than Retention.
+func (d *Disk) compact(tx *bolt.Tx, now time.Time) error {
+	meta, raw := tx.Bucket(seriesBucket), tx.Bucket(rawBucket)
+	if meta == nil || raw == nil {
+		return nil
+	}
+	down, err := tx.CreateBucketIfNotExists(downBucket)
+	if err != nil {
+		return err
+	}
+	retainFrom := encodeTime(now.Add(-d.cfg.Retention.Duration()))
+	rawFrom := now.Add(-d.cfg.DownsampleAfter.Duration())
+	resolution := d.cfg.DownsampleResolution.Duration()
+
+	var empty [][]byte
+	err = meta.ForEach(func(key, v []byte) error {
+		var sm seriesMeta
+		if err := json.Unmarshal(v, &sm); err != nil {
+			return err
+		}
+		rb := raw.Bucket(key)
+		db, err := down.CreateBucketIfNotExists(key)
+		if err != nil {
+			return err
+		}
+		if rb != nil && resolution > 0 {
+			if err := downsample(rb, db, sm.Kind, rawFrom, resolution); err != nil {
+				return err
+			}
+		}
+		for _, b := range []*bolt.Bucket{rb, db} {
+			if b != nil {
+				if err := deleteBefore(b, retainFrom); err != nil {
+					return err
+				}
+			}
+		}
+		if isEmpty(rb) && isEmpty(db) {
+			empty = append(empty, append([]byte(nil), key...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range empty {
+		meta.Delete(key)
+		raw.DeleteBucket(key)
+		down.DeleteBucket(key)
+	}
+	return nil
+}
+
+// downsample moves raw points before cutoff into resolution-sized buckets:
+// gauges are averaged, counters keep their last value so that rates stay
+// computable.
+func downsample(raw, down *bolt.Bucket, kind metric.Kind, cutoff time.Time, resolution time.Duration) error {
+	end := encodeTime(cutoff.Truncate(resolution))
+	var (
+		bucket   time.Time
+		sum      float64
+		n        int
+		consumed [][]byte
+	)
+	emit := func() error {
+		if n == 0 {
+			return nil
+		}
+		v := sum / float64(n)
+		if kind == metric.Counter {
+			v = sum
+		}
+		return down.Put(encodeTime(bucket), encodeValue(v))
+	}
+	c := raw.Cursor()
+	for k, v := c.First(); k != nil && bytes.Compare(k, end) < 0; k, v = c.Next() {
+		ts := decodeTime(k).Truncate(resolution)
+		if !ts.Equal(bucket) {
+			if err := emit(); err != nil {
+				return err
+			}
+			bucket, sum, n = ts, 0, 0
+		}
+		if kind == metric.Counter {
+			sum = decodeValue(v)
+		} else {
+			sum += decodeValue(v)
+		}
+		n++
+		consumed = append(consumed, append([]byte(nil), k...))
+	}
+	if err := emit(); err != nil {
+		return err
+	}
+	for _, k := range consumed {
+		if err := raw.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isEmpty(b *bolt.Bucket) bool {
+	if b == nil {
+		return true
+	}
+	k, _ := b.Cursor().First()
+	return k == nil
+}
+
+func deleteBefore(b *bolt.Bucket, end []byte) error {
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.First() {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadDisk returns the stored points of every series matching sel between
+// from and to inclusive, merging downsampled and raw points.
+func ReadDisk(path string, sel *metric.Selector, from, to time.Time) ([]Series, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := openBolt(path, true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var out []Series
+	err = db.View(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(seriesBucket)
+		if meta == nil {
+			return nil
+		}
+		return meta.ForEach(func(key, v []byte) error {
+			var sm seriesMeta
+			if err := json.Unmarshal(v, &sm); err != nil {
+				return err
+			}
+			if sel != nil && !sel.Match(metric.Metric{Name: sm.Name, Labels: sm.Labels}) {
+				return nil
+			}
+			s := Series{Name: sm.Name, Kind: sm.Kind, Unit: sm.Unit, Labels: sm.Labels, Points: []Point{}}
+			for _, name := range [][]byte{downBucket, rawBucket} {
+				if parent := tx.Bucket(name); parent != nil {
+					if b := parent.Bucket(key); b != nil {
+						s.Points = append(s.Points, readRange(b, from, to)...)
+					}
+				}
+			}
+			if len(s.Points) == 0 {
+				return nil
+			}
+			sort.Slice(s.Points, func(i, j int) bool { return s.Points[i].Timestamp.Before(s.Points[j].Timestamp) })
+			out = append(out, s)
+			return nil
+		})
+	})
+	return out, err
+}
+
+func readRange(b *bolt.Bucket, from, to time.Time) []Point {
+	var points []Point
+	end := encodeTime(to)
+	c := b.Cursor()
+	for k, v := c.Seek(encodeTime(from)); k != nil && bytes.Compare(k, end) <= 0; k, v = c.Next() {
+		points = append(points, Point{Timestamp: decodeTime(k), Value: decodeValue(v)})
+	}
+	return points
+}
+
+// Keys are big-endian nanoseconds so that byte order is time order.
+func encodeTime(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
+
+func decodeTime(b []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b))).UTC()
+}
+
+func encodeValue(v float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	return b
+}
+
+func decodeValue(b []byte) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(b))
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+)
+
+// Point is one sample of a series.
+type Point struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// Series is the samples of one metric name and label set.
+type Series struct {
+	Name   string            `json:"name"`
+	Kind   metric.Kind       `json:"kind"`
+	Unit   string            `json:"unit,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Points []Point           `json:"points"`
+}
+
+// History keeps recent samples of every series in fixed-size ring buffers.
+// Samples are bucketed by resolution; a later sample in the same bucket
+// replaces the earlier one. It implements output.Sink.
+type History struct {
+	retention  time.Duration
+	resolution time.Duration
+
+	mu     sync.RWMutex
+	series map[string]*ring
+}
+
+type ring struct {
+	Series
+	start, n int
+}
+
+func NewHistory(retention, resolution time.Duration) *History {
+	if resolution <= 0 {
+		resolution = time.Second
+	}
+	if retention < resolution {
+		retention = resolution
+	}
+	return &History{retention: retention, resolution: resolution, series: make(map[string]*ring)}
+}
+
+func (h *History) Retention() time.Duration {
+	return h.retention
+}
+
+func (h *History) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, m := range metrics {
+		key := m.SeriesKey()
+		r, ok := h.series[key]
+		if !ok {
+			r = &ring{Series: Series{
+				Name:   m.Name,
+				Kind:   m.Kind,
+				Unit:   m.Unit,
+				Labels: m.Labels,
+				Points: make([]Point, h.capacity()),
+			}}
+			h.series[key] = r
+		}
+		r.add(Point{Timestamp: m.Timestamp.Truncate(h.resolution), Value: m.Value})
+	}
+	h.prune(time.Now().Add(-h.retention))
+	return nil
+}
+
+func (h *History) Close() error {
+	return nil
+}
+
+func (h *History) capacity() int {
+	return int(h.retention/h.resolution) + 1
+}
+
+func (r *ring) add(p Point) {
+	if r.n > 0 {
+		last := &r.Points[(r.start+r.n-1)%len(r.Points)]
+		if !p.Timestamp.After(last.Timestamp) {
+			if p.Timestamp.Equal(last.Timestamp) {
+				last.Value = p.Value
+			}
+			return
+		}
+	}
+	if r.n < len(r.Points) {
+		r.Points[(r.start+r.n)%len(r.Points)] = p
+		r.n++
+		return
+	}
+	r.Points[r.start] = p
+	r.start = (r.start + 1) % len(r.Points)
+}
+
+func (r *ring) at(i int) Point {
+	return r.Points[(r.start+i)%len(r.Points)]
+}
+
+// prune drops series that have had no samples within the retention window,
+// e.g. processes that have exited.
+func (h *History) prune(cutoff time.Time) {
+	for key, r := range h.series {
+		if r.n == 0 || r.at(r.n-1).Timestamp.Before(cutoff) {
+			delete(h.series, key)
+		}
+	}
+}
+
+// Query returns the points of every series matching sel between from and
+// to inclusive, ordered by series key.
+func (h *History) Query(sel metric.Selector, from, to time.Time) []Series {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	keys := make([]string, 0)
+	for key, r := range h.series {
+		if sel.Match(metric.Metric{Name: r.Name, Labels: r.Labels}) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	out := make([]Series, 0, len(keys))
+	for _, key := range keys {
+		r := h.series[key]
+		s := r.Series
+		s.Points = []Point{}
+		for i := 0; i < r.n; i++ {
+			p := r.at(i)
+			if p.Timestamp.Before(from) || p.Timestamp.After(to) {
+				continue
+			}
+			s.Points = append(s.Points, p)
+		}
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"glass/pkg/metric"
+)
+
+// Snapshot is the most recent output of one collector.
+type Snapshot struct {
+	Collector string          `json:"collector"`
+	Timestamp time.Time       `json:"timestamp"`
+	Metrics   []metric.Metric `json:"metrics"`
+}
+
+// Latest keeps the last snapshot of every collector. It implements
+// output.Sink.
+type Latest struct {
+	mu        sync.RWMutex
+	snapshots map[string]Snapshot
+}
+
+func NewLatest() *Latest {
+	return &Latest{snapshots: make(map[string]Snapshot)}
+}
+
+func (l *Latest) Write(ctx context.Context, collector string, metrics []metric.Metric) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.snapshots[collector] = Snapshot{Collector: collector, Timestamp: time.Now(), Metrics: metrics}
+	return nil
+}
+
+func (l *Latest) Close() error {
+	return nil
+}
+
+func (l *Latest) Get(collector string) (Snapshot, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	s, ok := l.snapshots[collector]
+	return s, ok
+}
+
+// All returns every snapshot ordered by collector name.
+func (l *Latest) All() []Snapshot {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make([]Snapshot, 0, len(l.snapshots))
+	for _, s := range l.snapshots {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Collector < out[j].Collector })
+	return out
+}
+
+// Metrics flattens the latest snapshots into one slice.
+func (l *Latest) Metrics() []metric.Metric {
+	var out []metric.Metric
+	for _, s := range l.All() {
+		out = append(out, s.Metrics...)
+	}
+	return out
+}
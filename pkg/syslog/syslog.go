@@ -0,0 +1,244 @@
+// Package syslog sends RFC 5424 messages with structured data, which the
+// standard library's log/syslog can't do. It also holds what the syslog
+// and journald outputs share.
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Severity is a message's syslog severity, most severe first.
+type Severity int
+
+const (
+	Emergency Severity = iota
+	Alert
+	Critical
+	Error
+	Warning
+	Notice
+	Info
+	Debug
+)
+
+var severities = map[string]Severity{
+	"emerg": Emergency, "emergency": Emergency, "alert": Alert,
+	"crit": Critical, "critical": Critical, "err": Error, "error": Error,
+	"warning": Warning, "warn": Warning, "notice": Notice, "info": Info, "debug": Debug,
+}
+
+// ParseSeverity looks up a severity by its syslog keyword or full name.
+func ParseSeverity(s string) (Severity, bool) {
+	sev, ok := severities[strings.ToLower(s)]
+	return sev, ok
+}
+
+// EnterpriseID qualifies glass's SD-IDs. 32473 is the number IANA reserves
+// for documentation, as glass has none of its own.
+const EnterpriseID = "32473"
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Element is one SD-ELEMENT. Params keep their order.
+type Element struct {
+	ID     string
+	Params []Param
+}
+
+type Param struct {
+	Name, Value string
+}
+
+// Message is one syslog message. A zero Time means now.
+type Message struct {
+	Severity Severity
+	MsgID    string
+	Time     time.Time
+	Data     []Element
+	Text     string
+}
+
+// Writer sends messages to a syslog daemon, reconnecting when a send
+// fails.
+type Writer struct {
+	network  string
+	address  string
+	facility int
+	hostname string
+	app      string
+	pid      string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Dial connects to a syslog daemon. An empty network means the local
+// daemon on /dev/log. Stream connections frame messages with their length
+// as RFC 6587 describes.
+func Dial(network, address, facility, app string) (*Writer, error) {
+	f, ok := facilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown facility %q", facility)
+	}
+	switch network {
+	case "":
+		network, address = "unixgram", "/dev/log"
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unknown network %q", network)
+	}
+	if address == "" {
+		return nil, errors.New("address is required")
+	}
+	hostname, _ := os.Hostname()
+	w := &Writer{
+		network:  network,
+		address:  address,
+		facility: f,
+		hostname: header(hostname, 255),
+		app:      header(app, 48),
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) connect() error {
+	conn, err := net.DialTimeout(w.network, w.address, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// Send writes m, retrying once on a fresh connection.
+func (w *Writer) Send(m Message) error {
+	data := w.format(m)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if err = w.connect(); err != nil {
+				continue
+			}
+		}
+		w.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if _, err = w.conn.Write(data); err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return err
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *Writer) format(m Message) []byte {
+	ts := m.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<%d>1 %s %s %s %s %s ",
+		w.facility*8+int(m.Severity), ts.Format("2006-01-02T15:04:05.000000Z07:00"),
+		w.hostname, w.app, w.pid, header(m.MsgID, 32))
+	if len(m.Data) == 0 {
+		sb.WriteByte('-')
+	}
+	for _, e := range m.Data {
+		sb.WriteByte('[')
+		sb.WriteString(sdName(e.ID))
+		for _, p := range e.Params {
+			sb.WriteByte(' ')
+			sb.WriteString(sdName(p.Name))
+			sb.WriteString(`="`)
+			sb.WriteString(paramValue(p.Value))
+			sb.WriteByte('"')
+		}
+		sb.WriteByte(']')
+	}
+	if m.Text != "" {
+		sb.WriteByte(' ')
+		sb.WriteString(m.Text)
+	}
+	msg := sb.String()
+	if w.network == "tcp" || w.network == "unix" {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+	return []byte(msg)
+}
+
+// header makes s a valid header field: printable ASCII without spaces, at
+// most max bytes, or "-" when empty.
+func header(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// sdName makes s a valid SD-ID or PARAM-NAME, which can't contain '=', ' ',
+// ']' or '"' and are at most 32 bytes.
+func sdName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return s
+}
+
+var paramValue = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace
+
+// JournalField makes s usable in a journal field name, which may only hold
+// upper case letters, digits and underscores.
+func JournalField(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+}
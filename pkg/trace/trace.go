@@ -0,0 +1,377 @@
+// Package trace maps the network path to a host MTR-style: every hop is
+// probed several times with TTL-limited ICMP echo requests and each gets
+// latency and loss statistics.
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Limits on Options, so that a single request can't run unbounded.
+const (
+	MaxHopsLimit = 64
+	ProbesLimit  = 100
+)
+
+type Options struct {
+	MaxHops int
+	// Probes is the number of rounds; each round sends one probe per hop.
+	Probes  int
+	Timeout time.Duration
+	// Resolve looks up a name for each hop address.
+	Resolve bool
+}
+
+func DefaultOptions() Options {
+	return Options{MaxHops: 30, Probes: 5, Timeout: time.Second, Resolve: true}
+}
+
+// Validate checks the options against the limits.
+func (o Options) Validate() error {
+	if o.MaxHops < 1 || o.MaxHops > MaxHopsLimit {
+		return fmt.Errorf("max hops must be between 1 and %d", MaxHopsLimit)
+	}
+	if o.Probes < 1 || o.Probes > ProbesLimit {
+		return fmt.Errorf("probes must be between 1 and %d", ProbesLimit)
+	}
+	if o.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	return nil
+}
+
+type Result struct {
+	Host    string `json:"host"`
+	Address string `json:"address"`
+	// Reached is false when no probe got as far as the host.
+	Reached bool  `json:"reached"`
+	Hops    []Hop `json:"hops"`
+}
+
+// Hop holds the statistics for one TTL. Times are in seconds and are zero
+// when nothing answered.
+type Hop struct {
+	TTL int `json:"ttl"`
+	// Addresses lists every router that answered, as load balancing can
+	// spread probes over several paths.
+	Addresses []string `json:"addresses"`
+	// Hosts has the name of each address, or the address itself when it
+	// doesn't resolve.
+	Hosts    []string `json:"hosts,omitempty"`
+	Sent     int      `json:"sent"`
+	Received int      `json:"received"`
+	Loss     float64  `json:"loss_percent"`
+	Last     float64  `json:"rtt_last"`
+	Min      float64  `json:"rtt_min"`
+	Avg      float64  `json:"rtt_avg"`
+	Max      float64  `json:"rtt_max"`
+	Stddev   float64  `json:"rtt_stddev"`
+
+	rtts []time.Duration
+}
+
+type family struct {
+	network  string
+	proto    int
+	request  icmp.Type
+	reply    icmp.Type
+	exceeded icmp.Type
+	unreach  icmp.Type
+	// header is the length of the fixed IP header quoted in ICMP errors.
+	header func([]byte) int
+}
+
+var (
+	familyIPv4 = family{
+		network: "ip4:icmp", proto: 1,
+		request: ipv4.ICMPTypeEcho, reply: ipv4.ICMPTypeEchoReply,
+		exceeded: ipv4.ICMPTypeTimeExceeded, unreach: ipv4.ICMPTypeDestinationUnreachable,
+		header: func(b []byte) int { return int(b[0]&0x0f) * 4 },
+	}
+	familyIPv6 = family{
+		network: "ip6:ipv6-icmp", proto: 58,
+		request: ipv6.ICMPTypeEchoRequest, reply: ipv6.ICMPTypeEchoReply,
+		exceeded: ipv6.ICMPTypeTimeExceeded, unreach: ipv6.ICMPTypeDestinationUnreachable,
+		header: func([]byte) int { return ipv6.HeaderLen },
+	}
+)
+
+// probe is an echo request awaiting an answer.
+type probe struct {
+	ttl  int
+	sent time.Time
+}
+
+// tracer holds the state of one trace while its replies come in.
+type tracer struct {
+	opts   Options
+	fam    family
+	conn   *icmp.PacketConn
+	dst    net.IP
+	id     int
+	result *Result
+
+	mu      sync.Mutex
+	pending map[int]probe
+	hops    []Hop
+	// final is the lowest TTL the host itself answered at, or 0.
+	final int
+}
+
+// Run traces the path to host. It needs raw ICMP sockets, so root or
+// CAP_NET_RAW.
+func Run(ctx context.Context, host string, opts Options) (*Result, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	dst := addrs[0].IP
+	fam := familyIPv6
+	if dst.To4() != nil {
+		fam = familyIPv4
+	}
+	conn, err := icmp.ListenPacket(fam.network, "")
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil, fmt.Errorf("opening raw ICMP socket (needs root or CAP_NET_RAW): %w", err)
+		}
+		return nil, err
+	}
+	defer conn.Close()
+
+	t := &tracer{
+		opts:    opts,
+		fam:     fam,
+		conn:    conn,
+		dst:     dst,
+		id:      rand.IntN(0xffff),
+		result:  &Result{Host: host, Address: dst.String()},
+		pending: make(map[int]probe),
+		hops:    make([]Hop, opts.MaxHops),
+	}
+	for i := range t.hops {
+		t.hops[i].TTL = i + 1
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t.read()
+	}()
+	err = t.send(ctx)
+	// Closing the socket stops the reader.
+	conn.Close()
+	<-done
+	if err != nil {
+		return nil, err
+	}
+	t.finish(ctx)
+	return t.result, nil
+}
+
+// send runs the probing rounds, waiting Timeout after each so that
+// replies arrive before the next round starts.
+func (t *tracer) send(ctx context.Context) error {
+	for round := 0; round < t.opts.Probes; round++ {
+		t.mu.Lock()
+		limit := t.opts.MaxHops
+		if t.final > 0 {
+			limit = t.final
+		}
+		t.mu.Unlock()
+
+		for ttl := 1; ttl <= limit; ttl++ {
+			if err := t.sendProbe(round, ttl); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(t.opts.Timeout):
+		}
+	}
+	return nil
+}
+
+func (t *tracer) sendProbe(round, ttl int) error {
+	var err error
+	if t.fam.proto == 1 {
+		err = t.conn.IPv4PacketConn().SetTTL(ttl)
+	} else {
+		err = t.conn.IPv6PacketConn().SetHopLimit(ttl)
+	}
+	if err != nil {
+		return fmt.Errorf("setting TTL: %w", err)
+	}
+	// Sequence numbers encode the TTL so that errors quoting the probe can
+	// be matched to it.
+	seq := round*MaxHopsLimit + ttl
+	msg := icmp.Message{
+		Type: t.fam.request,
+		Body: &icmp.Echo{ID: t.id, Seq: seq, Data: []byte("glass trace")},
+	}
+	wb, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.pending[seq] = probe{ttl: ttl, sent: time.Now()}
+	t.hops[ttl-1].Sent++
+	t.mu.Unlock()
+	if _, err := t.conn.WriteTo(wb, &net.IPAddr{IP: t.dst}); err != nil {
+		return fmt.Errorf("sending probe: %w", err)
+	}
+	return nil
+}
+
+func (t *tracer) read() {
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := t.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		now := time.Now()
+		msg, err := icmp.ParseMessage(t.fam.proto, buf[:n])
+		if err != nil {
+			continue
+		}
+		var (
+			id, seq int
+			reached bool
+		)
+		switch msg.Type {
+		case t.fam.reply:
+			echo, ok := msg.Body.(*icmp.Echo)
+			if !ok {
+				continue
+			}
+			id, seq, reached = echo.ID, echo.Seq, true
+		case t.fam.exceeded, t.fam.unreach:
+			var quoted []byte
+			switch body := msg.Body.(type) {
+			case *icmp.TimeExceeded:
+				quoted = body.Data
+			case *icmp.DstUnreach:
+				quoted = body.Data
+			default:
+				continue
+			}
+			var ok bool
+			if id, seq, ok = t.quotedEcho(quoted); !ok {
+				continue
+			}
+			// Only the host itself ends the path; routers can also send
+			// unreachables for it.
+			reached = msg.Type == t.fam.unreach && peer.(*net.IPAddr).IP.Equal(t.dst)
+		default:
+			continue
+		}
+		if id != t.id {
+			continue
+		}
+		t.record(seq, peer.(*net.IPAddr).IP.String(), now, reached)
+	}
+}
+
+// quotedEcho extracts the ID and sequence of the echo request quoted in
+// an ICMP error.
+func (t *tracer) quotedEcho(b []byte) (id, seq int, ok bool) {
+	if len(b) < 1 {
+		return 0, 0, false
+	}
+	h := t.fam.header(b)
+	if len(b) < h+8 {
+		return 0, 0, false
+	}
+	echo := b[h:]
+	return int(echo[4])<<8 | int(echo[5]), int(echo[6])<<8 | int(echo[7]), true
+}
+
+func (t *tracer) record(seq int, addr string, at time.Time, reached bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.pending[seq]
+	if !ok {
+		return
+	}
+	delete(t.pending, seq)
+	rtt := at.Sub(p.sent)
+	if rtt > t.opts.Timeout {
+		return
+	}
+	hop := &t.hops[p.ttl-1]
+	hop.Received++
+	hop.rtts = append(hop.rtts, rtt)
+	if !slices.Contains(hop.Addresses, addr) {
+		hop.Addresses = append(hop.Addresses, addr)
+	}
+	if reached && (t.final == 0 || p.ttl < t.final) {
+		t.final = p.ttl
+	}
+}
+
+// finish trims hops past the host, or trailing silent ones when it was
+// never reached, and computes the statistics.
+func (t *tracer) finish(ctx context.Context) {
+	hops := t.hops
+	if t.final > 0 {
+		hops = hops[:t.final]
+		t.result.Reached = true
+	} else {
+		for len(hops) > 0 && hops[len(hops)-1].Received == 0 {
+			hops = hops[:len(hops)-1]
+		}
+	}
+	for i := range hops {
+		h := &hops[i]
+		if h.Addresses == nil {
+			h.Addresses = []string{}
+		}
+		h.Loss = 100 * float64(h.Sent-h.Received) / float64(h.Sent)
+		if len(h.rtts) > 0 {
+			h.Last = h.rtts[len(h.rtts)-1].Seconds()
+			h.Min, h.Max = math.Inf(1), 0
+			var sum float64
+			for _, d := range h.rtts {
+				h.Min, h.Max = min(h.Min, d.Seconds()), max(h.Max, d.Seconds())
+				sum += d.Seconds()
+			}
+			h.Avg = sum / float64(len(h.rtts))
+			var sq float64
+			for _, d := range h.rtts {
+				sq += (d.Seconds() - h.Avg) * (d.Seconds() - h.Avg)
+			}
+			h.Stddev = math.Sqrt(sq / float64(len(h.rtts)))
+		}
+		if t.opts.Resolve {
+			for _, a := range h.Addresses {
+				name := a
+				if names, err := net.DefaultResolver.LookupAddr(ctx, a); err == nil && len(names) > 0 {
+					name = strings.TrimSuffix(names[0], ".")
+				}
+				h.Hosts = append(h.Hosts, name)
+			}
+		}
+	}
+	t.result.Hops = hops
+}
@@ -0,0 +1,267 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"glass/pkg/alert"
+)
+
+const (
+	bold   = "\x1b[1m"
+	dim    = "\x1b[2m"
+	red    = "\x1b[31m"
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+	cyan   = "\x1b[36m"
+	reset  = "\x1b[0m"
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// labelWidth is the space before each sparkline: its name and value.
+const labelWidth = 20
+
+// screen accumulates the lines of one frame, cut to the terminal's size.
+type screen struct {
+	width, height int
+	color         bool
+	lines         []string
+}
+
+// add appends a line, styled with style when colors are on. It reports
+// false once the screen is full.
+func (s *screen) add(style, text string) bool {
+	if len(s.lines) >= s.height {
+		return false
+	}
+	if r := []rune(text); len(r) > s.width {
+		text = string(r[:s.width])
+	}
+	if s.color && style != "" {
+		text = style + text + reset
+	}
+	s.lines = append(s.lines, text)
+	return true
+}
+
+// addGraph appends label followed by graph, which alone is colored so the
+// label stays readable.
+func (s *screen) addGraph(label, graph string) bool {
+	if len(s.lines) >= s.height {
+		return false
+	}
+	label = truncate(label, s.width)
+	graph = truncate(graph, s.width-len(label))
+	if s.color {
+		graph = green + graph + reset
+	}
+	s.lines = append(s.lines, label+graph)
+	return true
+}
+
+func (s *screen) room() int {
+	return s.height - len(s.lines)
+}
+
+// render draws the whole frame, homing the cursor first and clearing
+// whatever the previous frame left beyond each line.
+func (d *dashboard) render(width, height int) string {
+	s := &screen{width: width, height: height, color: d.color}
+	status := fmt.Sprintf("glass tui  %s  %s  every %s", d.host, d.updated.Format(time.TimeOnly), d.opts.Interval)
+	if len(d.failing) > 0 {
+		status += fmt.Sprintf("  %d collector(s) failing: %s", len(d.failing), strings.Join(d.failing, ", "))
+	}
+	s.add(bold, status)
+	s.add("", "")
+
+	d.sparkline(s, "CPU", d.cpu, percent, 100)
+	d.sparkline(s, "Memory", d.mem, percent, 100)
+	d.sparkline(s, "Net in", d.netIn, rate, 0)
+	d.sparkline(s, "Net out", d.netOut, rate, 0)
+	d.sparkline(s, "Disk IO", d.diskIO, rate, 0)
+	for i, u := range d.disks {
+		if i == 4 {
+			break
+		}
+		s.add("", fmt.Sprintf("%-*s %s %5.1f%%  %s / %s", labelWidth-1, truncate(u.mountpoint, labelWidth-1),
+			bar(u.percent, 20), u.percent, bytes(u.used), bytes(u.total)))
+	}
+	s.add("", "")
+
+	d.alertPanel(s)
+	s.add("", "")
+
+	// Keep the key help on the last line.
+	footer := "q quit   sort: c cpu  m memory  i io  f fds"
+	s.height--
+	d.processTable(s)
+	s.height++
+	for s.room() > 1 {
+		s.add("", "")
+	}
+	s.add(dim, footer)
+
+	return "\x1b[H" + strings.Join(s.lines, "\x1b[K\r\n") + "\x1b[K\x1b[J"
+}
+
+func percent(v float64) string {
+	return strconv.FormatFloat(v, 'f', 1, 64) + "%"
+}
+
+func rate(v float64) string {
+	return bytes(v) + "/s"
+}
+
+// sparkline draws the most recent samples that fit, scaled to scaleMax,
+// or to the largest sample shown when scaleMax is 0.
+func (d *dashboard) sparkline(s *screen, name string, values series, format func(float64) string, scaleMax float64) {
+	current := "-"
+	if v, ok := values.last(); ok {
+		current = format(v)
+	}
+	width := s.width - labelWidth
+	if width < 1 {
+		s.add("", fmt.Sprintf("%-9s %9s", name, current))
+		return
+	}
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+	scale := scaleMax
+	if scale == 0 {
+		for _, v := range values {
+			scale = math.Max(scale, v)
+		}
+	}
+	var sb strings.Builder
+	for _, v := range values {
+		i := 0
+		if scale > 0 {
+			i = int(v / scale * float64(len(sparks)-1))
+		}
+		sb.WriteRune(sparks[min(max0(i), len(sparks)-1)])
+	}
+	s.addGraph(fmt.Sprintf("%-9s %9s ", name, current), sb.String())
+}
+
+func max0(i int) int {
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+func bar(pct float64, width int) string {
+	filled := int(math.Round(pct / 100 * float64(width)))
+	filled = min(max0(filled), width)
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
+func (d *dashboard) alertPanel(s *screen) {
+	if d.opts.Alerts == nil {
+		s.add(bold, "Alerts")
+		s.add(dim, "No alert rules configured.")
+		return
+	}
+	alerts := append([]alert.Alert(nil), d.alerts...)
+	// Firing before pending, then the longest-standing first.
+	sort.SliceStable(alerts, func(i, j int) bool {
+		if alerts[i].State != alerts[j].State {
+			return alerts[i].State == alert.Firing
+		}
+		return alerts[i].ActiveSince.Before(alerts[j].ActiveSince)
+	})
+	s.add(bold, fmt.Sprintf("Alerts (%d)", len(alerts)))
+	if len(alerts) == 0 {
+		s.add(dim, "No active alerts.")
+		return
+	}
+	for i, a := range alerts {
+		if i == 5 {
+			s.add(dim, fmt.Sprintf("... and %d more", len(alerts)-i))
+			return
+		}
+		style := yellow
+		if a.State == alert.Firing {
+			style = red
+		}
+		s.add(style, fmt.Sprintf("%-8s %-9s %-20s %s = %s (threshold %s) for %s",
+			strings.ToUpper(string(a.State)), a.Severity, a.Rule, seriesName(a.Metric, a.Labels),
+			strconv.FormatFloat(a.Value, 'g', 4, 64), strconv.FormatFloat(a.Threshold, 'g', 4, 64),
+			time.Since(a.ActiveSince).Round(time.Second)))
+	}
+}
+
+func seriesName(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + labels[k]
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (d *dashboard) processTable(s *screen) {
+	if d.err != nil {
+		s.add(red, "Error reading processes: "+d.err.Error())
+		return
+	}
+	columns := []struct{ title, key string }{
+		{"    PID", ""}, {"USER      ", ""}, {"  CPU%", "cpu"}, {"  MEM%", "memory"},
+		{"     RSS", ""}, {"    IO/s", "io"}, {"   FDS", "fds"}, {"COMMAND", ""},
+	}
+	titles := make([]string, len(columns))
+	for i, c := range columns {
+		titles[i] = c.title
+		if c.key != "" && c.key == d.sortBy {
+			// Mark the sort column without changing its width.
+			titles[i] = strings.Replace(c.title, " ", "", 1) + "▼"
+		}
+	}
+	s.add(bold+cyan, strings.Join(titles, " "))
+	for _, p := range d.procs {
+		command := strings.Join(strings.Fields(p.Cmdline), " ")
+		if command == "" {
+			command = "[" + p.Name + "]"
+		}
+		line := fmt.Sprintf("%7d %-10s %6.1f %6.1f %8s %8s %6d %s",
+			p.PID, truncate(p.User, 10), p.CPUPercent, p.MemoryPercent,
+			bytes(float64(p.RSS)), bytes(p.ReadRate+p.WriteRate), p.FDs, command)
+		if !s.add("", line) {
+			return
+		}
+	}
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+// bytes formats n with a binary unit suffix, as top does.
+func bytes(n float64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return strconv.FormatFloat(n, 'f', 0, 64)
+	}
+	i := -1
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	return strconv.FormatFloat(n, 'f', 1, 64) + string(units[i])
+}
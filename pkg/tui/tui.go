@@ -0,0 +1,289 @@
+// Package tui draws glass's live terminal dashboard.
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"glass/pkg/alert"
+	"glass/pkg/collectors"
+	"glass/pkg/metric"
+	"glass/pkg/store"
+
+	"golang.org/x/term"
+)
+
+// historySize is how many samples each sparkline keeps; more than any
+// terminal is wide.
+const historySize = 512
+
+// Options are what the dashboard shows and where.
+type Options struct {
+	// Latest holds the collections the dashboard reads, fed by a
+	// scheduler that runs every Interval.
+	Latest   *store.Latest
+	Interval time.Duration
+	// Alerts returns the active alerts; nil means no rules are configured.
+	Alerts func() []alert.Alert
+	// Failing returns the names of collectors whose last run failed.
+	Failing func() []string
+	// SortBy is the initial process order, one of
+	// collectors.ProcessSortKeys.
+	SortBy string
+	In     *os.File
+	Out    *os.File
+}
+
+type dashboard struct {
+	opts    Options
+	sampler collectors.ProcessSampler
+	sortBy  string
+	color   bool
+
+	host    string
+	updated time.Time
+	samples int
+
+	cpu, mem, netIn, netOut, diskIO series
+	disks                           []diskUsage
+	procs                           []collectors.ProcessInfo
+	alerts                          []alert.Alert
+	failing                         []string
+	err                             error
+}
+
+type series []float64
+
+func (s *series) add(v float64) {
+	*s = append(*s, v)
+	if len(*s) > historySize {
+		*s = (*s)[len(*s)-historySize:]
+	}
+}
+
+func (s series) last() (float64, bool) {
+	if len(s) == 0 {
+		return 0, false
+	}
+	return s[len(s)-1], true
+}
+
+type diskUsage struct {
+	mountpoint  string
+	used, total float64
+	percent     float64
+}
+
+// Run draws the dashboard on opts.Out until ctx is cancelled or the user
+// quits. The terminal is put in raw mode on the alternate screen and
+// restored on return.
+func Run(ctx context.Context, opts Options) error {
+	inFd, outFd := int(opts.In.Fd()), int(opts.Out.Fd())
+	if !term.IsTerminal(inFd) || !term.IsTerminal(outFd) {
+		return errors.New("glass tui needs a terminal")
+	}
+	state, err := term.MakeRaw(inFd)
+	if err != nil {
+		return fmt.Errorf("setting up terminal: %w", err)
+	}
+	defer term.Restore(inFd, state)
+	io.WriteString(opts.Out, "\x1b[?1049h\x1b[?25l")
+	defer io.WriteString(opts.Out, "\x1b[?25h\x1b[?1049l")
+
+	d := &dashboard{opts: opts, sortBy: opts.SortBy, color: os.Getenv("NO_COLOR") == ""}
+	d.host, _ = os.Hostname()
+	keys := make(chan byte)
+	go readKeys(opts.In, keys)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	tick := time.NewTicker(opts.Interval)
+	defer tick.Stop()
+	// The size is polled rather than signalled, which works everywhere.
+	resize := time.NewTicker(250 * time.Millisecond)
+	defer resize.Stop()
+
+	d.sample(ctx)
+	width, height, _ := term.GetSize(outFd)
+	d.draw(width, height)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+			d.sample(ctx)
+		case <-resize.C:
+			w, h, _ := term.GetSize(outFd)
+			if w == width && h == height {
+				continue
+			}
+			width, height = w, h
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch k {
+			case 'q', 'Q', 3, 4: // q, Ctrl-C, Ctrl-D
+				return nil
+			case 'c':
+				d.sortBy = "cpu"
+			case 'm':
+				d.sortBy = "memory"
+			case 'i':
+				d.sortBy = "io"
+			case 'f':
+				d.sortBy = "fds"
+			default:
+				continue
+			}
+			collectors.SortProcesses(d.procs, d.sortBy)
+		}
+		d.draw(width, height)
+	}
+}
+
+func readKeys(in io.Reader, keys chan<- byte) {
+	defer close(keys)
+	buf := make([]byte, 16)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, b := range buf[:n] {
+			keys <- b
+		}
+	}
+}
+
+func (d *dashboard) draw(width, height int) {
+	if width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+	io.WriteString(d.opts.Out, d.render(width, height))
+}
+
+// sample reads the latest collections and takes a fresh look at the
+// processes.
+func (d *dashboard) sample(ctx context.Context) {
+	d.updated = time.Now()
+	d.samples++
+	if s, ok := d.opts.Latest.Get("cpu"); ok {
+		if v, ok := find(s.Metrics, "cpu.usage_percent", "cpu", "cpu-total"); ok {
+			d.cpu.add(v)
+		}
+	}
+	if s, ok := d.opts.Latest.Get("mem"); ok {
+		if v, ok := find(s.Metrics, "mem.used_percent"); ok {
+			d.mem.add(v)
+		}
+	}
+	if s, ok := d.opts.Latest.Get("net"); ok {
+		// Rates appear from the second collection on.
+		if in, ok := sumRates(s.Metrics, "net.bytes_recv_per_sec"); ok {
+			d.netIn.add(in)
+		}
+		if out, ok := sumRates(s.Metrics, "net.bytes_sent_per_sec"); ok {
+			d.netOut.add(out)
+		}
+	}
+	if s, ok := d.opts.Latest.Get("disk"); ok {
+		d.disks = diskUsages(s.Metrics)
+	}
+
+	procs, err := d.sampler.Sample(ctx)
+	d.err = err
+	if err == nil {
+		collectors.SortProcesses(procs, d.sortBy)
+		d.procs = procs
+		// The first sample's IO rates are lifetime averages.
+		if d.samples > 1 {
+			var total float64
+			for _, p := range procs {
+				total += p.ReadRate + p.WriteRate
+			}
+			d.diskIO.add(total)
+		}
+	}
+	if d.opts.Alerts != nil {
+		d.alerts = d.opts.Alerts()
+	}
+	if d.opts.Failing != nil {
+		d.failing = d.opts.Failing()
+	}
+}
+
+// find returns the value of the first metric with the given name and
+// label pairs.
+func find(metrics []metric.Metric, name string, labels ...string) (float64, bool) {
+	want := metric.Labels(labels...)
+next:
+	for _, m := range metrics {
+		if m.Name != name {
+			continue
+		}
+		for k, v := range want {
+			if m.Labels[k] != v {
+				continue next
+			}
+		}
+		return m.Value, true
+	}
+	return 0, false
+}
+
+// sumRates adds up a per-interface rate over every interface but
+// loopback.
+func sumRates(metrics []metric.Metric, name string) (float64, bool) {
+	var total float64
+	found := false
+	for _, m := range metrics {
+		if m.Name != name || m.Labels["interface"] == "lo" || strings.HasPrefix(m.Labels["interface"], "lo:") {
+			continue
+		}
+		total += m.Value
+		found = true
+	}
+	return total, found
+}
+
+// diskUsages lists filesystems, fullest first.
+func diskUsages(metrics []metric.Metric) []diskUsage {
+	byMount := map[string]*diskUsage{}
+	for _, m := range metrics {
+		mp := m.Labels["mountpoint"]
+		if mp == "" {
+			continue
+		}
+		u, ok := byMount[mp]
+		if !ok {
+			u = &diskUsage{mountpoint: mp}
+			byMount[mp] = u
+		}
+		switch m.Name {
+		case "disk.used":
+			u.used = m.Value
+		case "disk.total":
+			u.total = m.Value
+		case "disk.used_percent":
+			u.percent = m.Value
+		}
+	}
+	out := make([]diskUsage, 0, len(byMount))
+	for _, u := range byMount {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].percent != out[j].percent {
+			return out[i].percent > out[j].percent
+		}
+		return out[i].mountpoint < out[j].mountpoint
+	})
+	return out
+}